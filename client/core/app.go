@@ -10,10 +10,12 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -28,7 +30,9 @@ import (
 	"github.com/ikafly144/au_mod_installer/client/discord"
 	"github.com/ikafly144/au_mod_installer/client/rest"
 	commonrest "github.com/ikafly144/au_mod_installer/common/rest"
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
+	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
 	"github.com/ikafly144/au_mod_installer/pkg/profile"
 	"github.com/ikafly144/au_mod_installer/pkg/progress"
 )
@@ -336,6 +340,48 @@ func (a *App) ClearModCache() error {
 	return nil
 }
 
+// ModCacheUsage reports the disk usage of the mod cache per mod and per profile.
+func (a *App) ModCacheUsage() (*modmgr.CacheReport, error) {
+	refs, err := a.ProfileManager.CacheReferences()
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect profile references: %w", err)
+	}
+	return modmgr.ReportCacheUsage(filepath.Join(a.ConfigDir, "mods"), refs)
+}
+
+// CollectModCache removes cached mod versions that no profile uses anymore, within the budget given by opts.
+func (a *App) CollectModCache(opts modmgr.CacheGCOptions) (*modmgr.CacheGCResult, error) {
+	refs, err := a.ProfileManager.CacheReferences()
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect profile references: %w", err)
+	}
+	result, err := modmgr.CollectCacheGarbage(filepath.Join(a.ConfigDir, "mods"), refs, opts)
+	if err != nil || opts.DryRun {
+		return result, err
+	}
+	freed, err := a.LocalModStore().Prune(refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune local mods: %w", err)
+	}
+	result.FreedSize += freed
+	return result, nil
+}
+
+// LocalModStore returns the store of files added to profiles as local mods.
+func (a *App) LocalModStore() *modmgr.LocalModStore {
+	return modmgr.NewLocalModStore(filepath.Join(a.ConfigDir, "local_mods"))
+}
+
+// ImportLocalMod stores a plugin DLL, zip archive or folder and returns a mod version to add to a profile.
+func (a *App) ImportLocalMod(path string) (*modmgr.ModVersion, error) {
+	mod, err := a.LocalModStore().Import(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import local mod: %w", err)
+	}
+	slog.Info("Imported local mod", "modID", mod.ModID, "source", path)
+	return mod, nil
+}
+
 func (a *App) HandleSharedProfile(uri string) (*profile.SharedProfile, error) {
 	var ok bool
 	if uri, ok = strings.CutPrefix(uri, "mod-of-us://profile/"); !ok {
@@ -467,13 +513,22 @@ func (a *App) HandleJoinGameDownload(sessionID string, serverBase string) (*prof
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	aupack := rs.Aupack
+	if len(aupack) == 0 {
+		if rs.AupackSize > ProfileArchiveDownloadMaxBytes {
+			return nil, nil, nil, fmt.Errorf("archive is too large: %d bytes (max %d)", rs.AupackSize, ProfileArchiveDownloadMaxBytes)
+		}
+		if aupack, err = client.GetSharedAupack(rs.AupackSHA256); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to download shared archive: %w", err)
+		}
+	}
 
 	tmpFile, err := os.CreateTemp("", "mod-of-us-join-*.aupack")
 	if err != nil {
 		return nil, nil, nil, err
 	}
 	defer os.Remove(tmpFile.Name())
-	if _, err := tmpFile.Write(rs.Aupack); err != nil {
+	if _, err := tmpFile.Write(aupack); err != nil {
 		_ = tmpFile.Close()
 		return nil, nil, nil, err
 	}
@@ -540,10 +595,17 @@ func (a *App) ImportSharedProfile(shared *profile.SharedProfile, iconPNG []byte)
 	}
 
 	// Fetch mod version infos
-	for modID, versionID := range shared.ModVersions {
-		info, err := a.Rest.GetModVersion(modID, versionID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch mod version info for %s:%s: %w", modID, versionID, err)
+	refs := make([]model.ModVersionRef, 0, len(shared.ModVersions))
+	for _, modID := range slices.Sorted(maps.Keys(shared.ModVersions)) {
+		refs = append(refs, model.ModVersionRef{ModID: modID, VersionID: shared.ModVersions[modID]})
+	}
+	infos, err := a.Rest.GetModVersions(refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch mod version infos: %w", err)
+	}
+	for i, info := range infos {
+		if info == nil {
+			return nil, fmt.Errorf("failed to fetch mod version info for %s:%s: version not found", refs[i].ModID, refs[i].VersionID)
 		}
 		prof.AddModVersion(*info)
 	}
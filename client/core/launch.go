@@ -10,8 +10,10 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
 	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
+	"github.com/ikafly144/au_mod_installer/pkg/profile"
 	"github.com/ikafly144/au_mod_installer/pkg/progress"
 )
 
@@ -24,17 +26,143 @@ type LaunchJoinInfo struct {
 	GameVersion    string
 }
 
-// ResolveProfileDependencies resolves all required dependencies for the given profile.
-func (a *App) ResolveProfileDependencies(profileID uuid.UUID) ([]modmgr.ModVersion, error) {
-	profile, found := a.ProfileManager.Get(profileID)
+// ResolveProfileDependencies returns the dependency graph pinned by the profile lock.
+// The lock is written on first use, whenever the mods picked in the profile have changed since it was resolved,
+// and when a locked dependency is incompatible with the installed game.
+func (a *App) ResolveProfileDependencies(profileID uuid.UUID, binaryType aumgr.BinaryType, gameVersion string) ([]modmgr.ModVersion, error) {
+	prof, found := a.ProfileManager.Get(profileID)
 	if !found {
 		return nil, fmt.Errorf("profile not found: %s", profileID)
 	}
-	return a.ResolveDependencies(profile.Versions())
+	return a.resolveLockedDependencies(prof, binaryType, gameVersion)
+}
+
+func (a *App) resolveLockedDependencies(prof profile.Profile, binaryType aumgr.BinaryType, gameVersion string) ([]modmgr.ModVersion, error) {
+	lock, err := a.ProfileManager.LoadLock(prof.ID)
+	if err != nil {
+		slog.Warn("Failed to load profile lock, resolving dependencies again", "profileId", prof.ID, "error", err)
+	}
+	if lock.Matches(prof) && lock.CompatibleWith(binaryType, gameVersion) {
+		return lock.Versions(), nil
+	}
+
+	resolved, err := a.ResolveDependencies(prof.Versions(), binaryType, gameVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ProfileManager.SaveLock(prof.ID, profile.NewLock(prof, resolved)); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// LockUpdate is a pending re-resolution of a profile lock against the latest versions on the server.
+type LockUpdate struct {
+	ProfileID uuid.UUID
+	Current   *profile.Lock
+	Proposed  *profile.Lock
+	Changes   []profile.LockChange
+}
+
+// PlanProfileLockUpdate resolves the profile's dependencies again, ignoring its lock, and reports what would change.
+func (a *App) PlanProfileLockUpdate(profileID uuid.UUID, binaryType aumgr.BinaryType, gameVersion string) (*LockUpdate, error) {
+	prof, found := a.ProfileManager.Get(profileID)
+	if !found {
+		return nil, fmt.Errorf("profile not found: %s", profileID)
+	}
+	current, err := a.ProfileManager.LoadLock(profileID)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := a.ResolveDependencies(prof.Versions(), binaryType, gameVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+	proposed := profile.NewLock(prof, resolved)
+	return &LockUpdate{
+		ProfileID: profileID,
+		Current:   current,
+		Proposed:  proposed,
+		Changes:   profile.DiffLocks(current, proposed),
+	}, nil
+}
+
+// ApplyProfileLockUpdate saves the proposed lock. It fails if the profile's mods were edited after the update was planned.
+func (a *App) ApplyProfileLockUpdate(update *LockUpdate) error {
+	prof, found := a.ProfileManager.Get(update.ProfileID)
+	if !found {
+		return fmt.Errorf("profile not found: %s", update.ProfileID)
+	}
+	if !update.Proposed.Matches(prof) {
+		return fmt.Errorf("profile mods changed since the lock update was planned")
+	}
+	return a.ProfileManager.SaveLock(prof.ID, update.Proposed)
 }
 
-func (a *App) ResolveDependencies(initialMods []modmgr.ModVersion) ([]modmgr.ModVersion, error) {
-	resolvedMap, err := modmgr.ResolveDependencies(initialMods, a.Rest)
+// PlanProfileUpdates checks the mods picked in prof, which may have unsaved edits, for newer versions compatible with
+// the installed game, checking their latest versions in one request. Apply the plan with profile.Profile.ApplyUpdates.
+func (a *App) PlanProfileUpdates(prof profile.Profile, binaryType aumgr.BinaryType, gameVersion string) (*modmgr.UpdatePlan, error) {
+	plan, err := modmgr.PlanUpdates(prof.Versions(), a.Rest, modmgr.WithBinaryType(binaryType), modmgr.WithGameVersion(gameVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	for modID, err := range plan.Errors {
+		slog.Warn("Failed to check mod for updates", "profileId", prof.ID, "modId", modID, "error", err)
+	}
+	return plan, nil
+}
+
+// FlaggedProfileVersions looks up the current state of the mods and locked dependencies used by the profiles and
+// returns, per profile, the versions that have been yanked or deprecated since they were added. Profiles without
+// such versions are left out.
+func (a *App) FlaggedProfileVersions(profiles []profile.Profile) (map[uuid.UUID][]modmgr.ModVersion, error) {
+	used := make(map[uuid.UUID][]model.ModVersionRef, len(profiles))
+	var refs []model.ModVersionRef
+	for _, prof := range profiles {
+		lock, err := a.ProfileManager.LoadLock(prof.ID)
+		if err != nil {
+			slog.Warn("Failed to load profile lock, checking picked mods only", "profileId", prof.ID, "error", err)
+		}
+		for _, v := range append(prof.Versions(), lock.Versions()...) {
+			ref := model.ModVersionRef{ModID: v.ModID, VersionID: v.VersionID}
+			if v.IsLocal() || slices.Contains(used[prof.ID], ref) {
+				continue
+			}
+			used[prof.ID] = append(used[prof.ID], ref)
+			if !slices.Contains(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	infos, err := a.Rest.GetModVersions(refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch mod versions: %w", err)
+	}
+	flagged := make(map[model.ModVersionRef]modmgr.ModVersion)
+	for i, info := range infos {
+		if info != nil && info.Status != model.VersionStatusActive {
+			flagged[refs[i]] = *info
+		}
+	}
+	result := make(map[uuid.UUID][]modmgr.ModVersion)
+	for profileID, profRefs := range used {
+		for _, ref := range profRefs {
+			if v, ok := flagged[ref]; ok {
+				result[profileID] = append(result[profileID], v)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ResolveDependencies resolves the dependencies of the mods, skipping versions incompatible with the binary type
+// or game version. Empty values are not checked.
+func (a *App) ResolveDependencies(initialMods []modmgr.ModVersion, binaryType aumgr.BinaryType, gameVersion string) ([]modmgr.ModVersion, error) {
+	resolvedMap, err := modmgr.ResolveDependencies(initialMods, a.Rest, modmgr.WithBinaryType(binaryType), modmgr.WithGameVersion(gameVersion))
 	if err != nil {
 		return nil, err
 	}
@@ -61,11 +189,6 @@ func (a *App) PrepareLaunch(gamePath string, profileID uuid.UUID) (string, func(
 		return "", nil, fmt.Errorf("profile not found: %s", profileID)
 	}
 
-	resolvedVersions, err := a.ResolveDependencies(profile.Versions())
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to resolve dependencies: %w", err)
-	}
-
 	cacheDir := filepath.Join(a.ConfigDir, "mods")
 	profileDir := filepath.Join(a.ConfigDir, "profiles", profileID.String())
 	binaryType, err := aumgr.GetBinaryType(gamePath)
@@ -78,6 +201,11 @@ func (a *App) PrepareLaunch(gamePath string, profileID uuid.UUID) (string, func(
 		return "", nil, fmt.Errorf("failed to get game version: %w", err)
 	}
 
+	resolvedVersions, err := a.resolveLockedDependencies(profile, binaryType, gameVersion)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+
 	needSync := false
 
 	// Check profile compatibility
@@ -99,7 +227,7 @@ func (a *App) PrepareLaunch(gamePath string, profileID uuid.UUID) (string, func(
 		}
 	}
 
-	if err := modmgr.PrepareProfileDirectory(profileDir, gamePath, cacheDir, resolvedVersions, binaryType, gameVersion, false, nil); err != nil {
+	if err := modmgr.PrepareProfileDirectory(profileDir, gamePath, cacheDir, resolvedVersions, binaryType, gameVersion, false, nil, modmgr.WithPreservePaths(profile.PreservePaths...)); err != nil {
 		return "", nil, err
 	}
 
@@ -127,7 +255,7 @@ func (a *App) SyncProfile(profileID uuid.UUID, binaryType aumgr.BinaryType, game
 		return fmt.Errorf("profile not found: %s", profileID)
 	}
 
-	resolvedVersions, err := a.ResolveDependencies(profile.Versions())
+	resolvedVersions, err := a.resolveLockedDependencies(profile, binaryType, gameVersion)
 	if err != nil {
 		return fmt.Errorf("failed to resolve dependencies: %w", err)
 	}
@@ -135,7 +263,7 @@ func (a *App) SyncProfile(profileID uuid.UUID, binaryType aumgr.BinaryType, game
 	cacheDir := filepath.Join(a.ConfigDir, "mods")
 	profileDir := filepath.Join(a.ConfigDir, "profiles", profileID.String())
 
-	return modmgr.PrepareProfileDirectory(profileDir, "", cacheDir, resolvedVersions, binaryType, gameVersion, true, progressListener)
+	return modmgr.PrepareProfileDirectory(profileDir, "", cacheDir, resolvedVersions, binaryType, gameVersion, true, progressListener, modmgr.WithPreservePaths(profile.PreservePaths...))
 }
 
 // ExecuteLaunch launches the game and blocks until it exits.
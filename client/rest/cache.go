@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"sync"
+)
+
+// defaultResponseCacheEntries bounds the responses kept by a client, which are small JSON documents.
+const defaultResponseCacheEntries = 1024
+
+// responseCache keeps the GET responses that carry an ETag, so they are revalidated with If-None-Match instead of
+// downloaded again while unchanged.
+type responseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+func newResponseCache(maxEntries int) *responseCache {
+	return &responseCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]cachedResponse),
+	}
+}
+
+func (c *responseCache) get(url string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rs, ok := c.entries[url]
+	return rs, ok
+}
+
+func (c *responseCache) put(url string, rs cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[url]; !ok && len(c.entries) >= c.maxEntries {
+		// Drop an arbitrary entry; a miss only costs a full download
+		for key := range c.entries {
+			delete(c.entries, key)
+			break
+		}
+	}
+	c.entries[url] = rs
+}
+
+func (c *responseCache) delete(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, url)
+}
@@ -14,6 +14,8 @@ type clientImpl struct {
 	BaseURL    string
 	UserAgent  string
 	HTTPClient *http.Client
+
+	cache *responseCache
 }
 
 type encodedRequestBody struct {
@@ -31,10 +33,21 @@ func WithHTTPClient(httpClient *http.Client) config {
 	}
 }
 
+// WithResponseCache keeps up to maxEntries GET responses for revalidation. Zero disables the cache.
+func WithResponseCache(maxEntries int) config {
+	return func(c *clientImpl) {
+		c.cache = nil
+		if maxEntries > 0 {
+			c.cache = newResponseCache(maxEntries)
+		}
+	}
+}
+
 func NewClient(baseURL string, configs ...config) *clientImpl {
 	client := &clientImpl{
 		BaseURL:    baseURL,
 		HTTPClient: &http.Client{},
+		cache:      newResponseCache(defaultResponseCacheEntries),
 	}
 	for _, config := range configs {
 		config(client)
@@ -83,25 +96,49 @@ func (c *clientImpl) do(endpoint *rest.CompiledEndpoint, rqBody any, rsBody any,
 		rq.Header.Set("Content-Type", contentType)
 	}
 
+	// Only JSON documents are kept; raw downloads such as aupacks can be large
+	_, rawBody := rsBody.(*[]byte)
+	cacheable := c.cache != nil && rq.Method == http.MethodGet && rsBody != nil && !rawBody
+	var cached cachedResponse
+	if cacheable {
+		var ok bool
+		if cached, ok = c.cache.get(rq.URL.String()); ok {
+			rq.Header.Set("If-None-Match", cached.etag)
+		}
+	}
+
 	resp, err := c.HTTPClient.Do(rq)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("request failed with status code %d", resp.StatusCode)
+	var body io.Reader = resp.Body
+	switch {
+	case cacheable && resp.StatusCode == http.StatusNotModified && cached.etag != "":
+		body = bytes.NewReader(cached.body)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return &StatusError{StatusCode: resp.StatusCode}
+	case cacheable && resp.Header.Get("ETag") != "":
+		raw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		c.cache.put(rq.URL.String(), cachedResponse{etag: resp.Header.Get("ETag"), body: raw})
+		body = bytes.NewReader(raw)
+	case cacheable:
+		c.cache.delete(rq.URL.String())
 	}
 	if rsBody != nil {
 		switch v := rsBody.(type) {
 		case *[]byte:
 			var err error
-			*v, err = io.ReadAll(resp.Body)
+			*v, err = io.ReadAll(body)
 			if err != nil {
 				return err
 			}
 		default:
-			if err := json.UnmarshalRead(resp.Body, rsBody); err != nil {
+			if err := json.UnmarshalRead(body, rsBody); err != nil {
 				return err
 			}
 		}
@@ -109,6 +146,15 @@ func (c *clientImpl) do(endpoint *rest.CompiledEndpoint, rqBody any, rsBody any,
 	return nil
 }
 
+// StatusError is returned when the server responds with a non-2xx status code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status code %d", e.StatusCode)
+}
+
 func (c *clientImpl) GetHealthStatus() (*rest.HealthStatus, error) {
 	var status rest.HealthStatus
 	err := c.do(rest.EndpointHealth.Compile(nil), nil, &status, 1)
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/ikafly144/au_mod_installer/common/rest"
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
 )
 
@@ -79,6 +82,57 @@ func (f *FileClient) GetModIDs(limit int, after string, before string) ([]string
 	return modIDs, nil
 }
 
+func (f *FileClient) SearchModIDs(query model.ModSearchQuery, limit int, after string) ([]string, error) {
+	var mods []modmgr.Mod
+	for _, m := range f.modStore {
+		if !query.MatchesMod(m.ModDetails) {
+			continue
+		}
+		if query.HasVersionFilters() {
+			latest, _ := f.GetLatestModVersion(m.ID)
+			if latest == nil || !query.MatchesVersion(latest.ModVersionDetails) {
+				continue
+			}
+		}
+		mods = append(mods, m)
+	}
+	slices.SortFunc(mods, func(a, b modmgr.Mod) int {
+		var c int
+		switch query.Sort {
+		case model.ModSortName:
+			c = strings.Compare(a.Name, b.Name)
+		case model.ModSortUpdatedAt:
+			c = b.UpdatedAt.Compare(a.UpdatedAt)
+		default:
+			c = b.CreatedAt.Compare(a.CreatedAt)
+		}
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		return c
+	})
+
+	start := 0
+	if after != "" {
+		start = len(mods)
+		for i, m := range mods {
+			if m.ID == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := len(mods)
+	if limit > 0 {
+		end = min(end, start+limit)
+	}
+	var modIDs []string
+	for _, m := range mods[start:end] {
+		modIDs = append(modIDs, m.ID)
+	}
+	return modIDs, nil
+}
+
 func (f *FileClient) GetMod(modID string) (*modmgr.Mod, error) {
 	m, ok := f.modStore[modID]
 	if !ok {
@@ -119,6 +173,26 @@ func (f *FileClient) GetLatestModVersion(modID string) (*modmgr.ModVersion, erro
 	return f.GetModVersion(modID, mod.LatestVersionID)
 }
 
+func (f *FileClient) GetMods(modIDs []string) ([]*modmgr.Mod, error) {
+	mods := make([]*modmgr.Mod, len(modIDs))
+	for i, modID := range modIDs {
+		mods[i], _ = f.GetMod(modID)
+	}
+	return mods, nil
+}
+
+func (f *FileClient) GetModVersions(refs []model.ModVersionRef) ([]*modmgr.ModVersion, error) {
+	versions := make([]*modmgr.ModVersion, len(refs))
+	for i, ref := range refs {
+		if ref.VersionID == "" {
+			versions[i], _ = f.GetLatestModVersion(ref.ModID)
+		} else {
+			versions[i], _ = f.GetModVersion(ref.ModID, ref.VersionID)
+		}
+	}
+	return versions, nil
+}
+
 func (f *FileClient) CheckForUpdates(installedVersions map[string]string) (map[string]*modmgr.ModVersion, error) {
 	updates := make(map[string]*modmgr.ModVersion)
 	for modID, currentVersion := range installedVersions {
@@ -126,14 +200,22 @@ func (f *FileClient) CheckForUpdates(installedVersions map[string]string) (map[s
 		if err != nil {
 			continue
 		}
-		if latest != nil && latest.VersionID != currentVersion {
+		if latest == nil || latest.VersionID != currentVersion {
 			updates[modID] = latest
 		}
 	}
 	return updates, nil
 }
 
-func (f *FileClient) GetModThumbnail(modID string) ([]byte, error) {
+func (f *FileClient) GetModThumbnail(modID string, size model.ImageSize) ([]byte, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *FileClient) GetModGallery(modID string) ([]model.ModImage, error) {
+	return nil, nil
+}
+
+func (f *FileClient) GetModImage(modID, imageID string, size model.ImageSize) ([]byte, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 
@@ -152,3 +234,7 @@ func (f *FileClient) UpdateSharedGameExpiration(sessionID, hostKey string) (*res
 func (f *FileClient) GetJoinGameDownload(sessionID string) (*rest.JoinGameDownloadResponse, error) {
 	return nil, fmt.Errorf("local mode: join game download not available")
 }
+
+func (f *FileClient) GetSharedAupack(sha256 string) ([]byte, error) {
+	return nil, fmt.Errorf("local mode: shared aupack download not available")
+}
@@ -2,9 +2,16 @@ package rest
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"maps"
 	"mime/multipart"
+	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 
 	"github.com/ikafly144/au_mod_installer/common/rest"
 	"github.com/ikafly144/au_mod_installer/common/rest/model"
@@ -29,6 +36,21 @@ func (c *clientImpl) GetModIDs(limit int, after string, before string) ([]string
 	return mods.IDs, err
 }
 
+func (c *clientImpl) SearchModIDs(query model.ModSearchQuery, limit int, after string) ([]string, error) {
+	var mods model.ModListResult
+
+	values := query.Values()
+	if limit > 0 {
+		values.Set("limit", fmt.Sprint(limit))
+	}
+	if after != "" {
+		values.Set("after", after)
+	}
+
+	err := c.do(rest.EndpointGetModList.Compile(values, nil), nil, &mods, 1)
+	return mods.IDs, err
+}
+
 func (c *clientImpl) GetMod(modID string) (*modmgr.Mod, error) {
 	var mod model.ModDetails
 	err := c.do(rest.EndpointGetModDetail.Compile(nil, modID), nil, &mod, 1)
@@ -47,12 +69,24 @@ func (c *clientImpl) GetModVersion(modID string, versionID string) (*modmgr.ModV
 	return &modVersion, err
 }
 
-func (c *clientImpl) GetModThumbnail(modID string) ([]byte, error) {
+func (c *clientImpl) GetModThumbnail(modID string, size model.ImageSize) ([]byte, error) {
 	var thumbnail []byte
-	err := c.do(rest.EndpointGetModThumbnail.Compile(nil, modID), nil, &thumbnail, 1)
+	err := c.do(rest.EndpointGetModThumbnail.Compile(url.Values{"size": {string(size)}}, modID), nil, &thumbnail, 1)
 	return thumbnail, err
 }
 
+func (c *clientImpl) GetModGallery(modID string) ([]model.ModImage, error) {
+	var gallery model.ModGallery
+	err := c.do(rest.EndpointGetModGallery.Compile(nil, modID), nil, &gallery, 1)
+	return gallery.Images, err
+}
+
+func (c *clientImpl) GetModImage(modID, imageID string, size model.ImageSize) ([]byte, error) {
+	var image []byte
+	err := c.do(rest.EndpointGetModImage.Compile(url.Values{"size": {string(size)}}, modID, imageID), nil, &image, 1)
+	return image, err
+}
+
 func (c *clientImpl) GetLatestModVersion(modID string) (*modmgr.ModVersion, error) {
 	mod, err := c.GetMod(modID)
 	if err != nil {
@@ -64,20 +98,117 @@ func (c *clientImpl) GetLatestModVersion(modID string) (*modmgr.ModVersion, erro
 	return c.GetModVersion(modID, mod.LatestVersionID)
 }
 
+func (c *clientImpl) GetMods(modIDs []string) ([]*modmgr.Mod, error) {
+	mods := make([]*modmgr.Mod, 0, len(modIDs))
+	for chunk := range slices.Chunk(modIDs, model.MaxBatchSize) {
+		var rs model.ModBatchResult
+		err := c.do(rest.EndpointGetModsBatch.Compile(nil), model.ModBatchRequest{IDs: chunk}, &rs, 1)
+		if isBatchUnsupported(err) {
+			for _, modID := range chunk {
+				mod, err := c.GetMod(modID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to fetch mod %s: %w", modID, err)
+				}
+				mods = append(mods, mod)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch mods: %w", err)
+		}
+		if len(rs.Mods) != len(chunk) {
+			return nil, fmt.Errorf("failed to fetch mods: got %d results for %d mods", len(rs.Mods), len(chunk))
+		}
+		for _, mod := range rs.Mods {
+			if mod == nil {
+				mods = append(mods, nil)
+				continue
+			}
+			mods = append(mods, &modmgr.Mod{ModDetails: *mod})
+		}
+	}
+	return mods, nil
+}
+
+func (c *clientImpl) GetModVersions(refs []model.ModVersionRef) ([]*modmgr.ModVersion, error) {
+	versions := make([]*modmgr.ModVersion, 0, len(refs))
+	for chunk := range slices.Chunk(refs, model.MaxBatchSize) {
+		var rs model.ModVersionBatchResult
+		err := c.do(rest.EndpointGetModVersionsBatch.Compile(nil), model.ModVersionBatchRequest{Versions: chunk}, &rs, 1)
+		if isBatchUnsupported(err) {
+			for _, ref := range chunk {
+				var v *modmgr.ModVersion
+				if ref.VersionID == "" {
+					v, err = c.GetLatestModVersion(ref.ModID)
+				} else {
+					v, err = c.GetModVersion(ref.ModID, ref.VersionID)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to fetch %s (version: %s): %w", ref.ModID, ref.VersionID, err)
+				}
+				versions = append(versions, v)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch mod versions: %w", err)
+		}
+		if len(rs.Versions) != len(chunk) {
+			return nil, fmt.Errorf("failed to fetch mod versions: got %d results for %d versions", len(rs.Versions), len(chunk))
+		}
+		for _, v := range rs.Versions {
+			if v == nil {
+				versions = append(versions, nil)
+				continue
+			}
+			versions = append(versions, &modmgr.ModVersion{ModVersionDetails: *v})
+		}
+	}
+	return versions, nil
+}
+
 func (c *clientImpl) CheckForUpdates(installedVersions map[string]string) (map[string]*modmgr.ModVersion, error) {
 	updates := make(map[string]*modmgr.ModVersion)
-	for modID, currentVersion := range installedVersions {
-		latest, err := c.GetLatestModVersion(modID)
+	for chunk := range slices.Chunk(slices.Sorted(maps.Keys(installedVersions)), model.MaxBatchSize) {
+		installed := make(map[string]string, len(chunk))
+		for _, modID := range chunk {
+			installed[modID] = installedVersions[modID]
+		}
+
+		var rs model.ModUpdateCheckResult
+		err := c.do(rest.EndpointCheckModUpdates.Compile(nil), model.ModUpdateCheckRequest{Installed: installed}, &rs, 1)
+		if isBatchUnsupported(err) {
+			for _, modID := range chunk {
+				latest, err := c.GetLatestModVersion(modID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to check for updates for mod %s: %w", modID, err)
+				}
+				if latest != nil && latest.VersionID != installed[modID] {
+					updates[modID] = latest
+				}
+			}
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to check for updates for mod %s: %w", modID, err)
+			return nil, fmt.Errorf("failed to check for updates: %w", err)
 		}
-		if latest != nil && latest.VersionID != currentVersion {
-			updates[modID] = latest
+		for _, modID := range rs.Missing {
+			updates[modID] = nil
+		}
+		for modID, latest := range rs.Updates {
+			updates[modID] = &modmgr.ModVersion{ModVersionDetails: latest}
 		}
 	}
 	return updates, nil
 }
 
+// isBatchUnsupported reports whether a batch request failed because the server predates the batch endpoints.
+// The caller then falls back to one request per item.
+func isBatchUnsupported(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusMethodNotAllowed)
+}
+
 func (c *clientImpl) ShareGame(aupack []byte, room rest.RoomInfo) (*rest.ShareGameResponse, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -145,10 +276,22 @@ func (c *clientImpl) UpdateSharedGameExpiration(sessionID, hostKey string) (*res
 func (c *clientImpl) GetJoinGameDownload(sessionID string) (*rest.JoinGameDownloadResponse, error) {
 	values := make(url.Values)
 	values.Set("session_id", sessionID)
-	values.Set("download", "1")
+	values.Set("download", "ref")
 	var rs rest.JoinGameDownloadResponse
 	if err := c.do(rest.EndpointJoinGame.Compile(values), nil, &rs, 1); err != nil {
 		return nil, err
 	}
 	return &rs, nil
 }
+
+func (c *clientImpl) GetSharedAupack(hash string) ([]byte, error) {
+	var data []byte
+	if err := c.do(rest.EndpointGetSharedAupack.Compile(nil, hash), nil, &data, 1); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), hash) {
+		return nil, fmt.Errorf("aupack does not match its sha256 %s", hash)
+	}
+	return data, nil
+}
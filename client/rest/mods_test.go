@@ -1,7 +1,9 @@
 package rest
 
 import (
+	"cmp"
 	"encoding/json/v2"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -16,10 +18,74 @@ import (
 )
 
 func TestClientImpl_CheckForUpdates(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/mods/updates", r.URL.Path)
+		var rq model.ModUpdateCheckRequest
+		require.NoError(t, json.UnmarshalRead(r.Body, &rq))
+		assert.Equal(t, map[string]string{"mod-1": "v1.0.0", "mod-2": "v2.0.0", "removed": "v1.0.0"}, rq.Installed)
+
+		require.NoError(t, json.MarshalWrite(w, model.ModUpdateCheckResult{
+			Updates: map[string]model.ModVersionDetails{"mod-1": {ModID: "mod-1", VersionID: "v1.1.0"}},
+			Missing: []string{"removed"},
+		}))
+	}))
+	defer server.Close()
+
+	updates, err := NewClient(server.URL).CheckForUpdates(map[string]string{"mod-1": "v1.0.0", "mod-2": "v2.0.0", "removed": "v1.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+	require.Len(t, updates, 2)
+	assert.Equal(t, "v1.1.0", updates["mod-1"].VersionID)
+	assert.Contains(t, updates, "removed")
+	assert.Nil(t, updates["removed"])
+}
+
+func TestClientImpl_GetModVersions(t *testing.T) {
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/mods/versions/batch", r.URL.Path)
+		var rq model.ModVersionBatchRequest
+		require.NoError(t, json.UnmarshalRead(r.Body, &rq))
+		batches = append(batches, len(rq.Versions))
+
+		rs := model.ModVersionBatchResult{Versions: make([]*model.ModVersionDetails, len(rq.Versions))}
+		for i, ref := range rq.Versions {
+			if ref.ModID != "missing" {
+				rs.Versions[i] = &model.ModVersionDetails{ModID: ref.ModID, VersionID: cmp.Or(ref.VersionID, "latest")}
+			}
+		}
+		require.NoError(t, json.MarshalWrite(w, rs))
+	}))
+	defer server.Close()
+
+	refs := make([]model.ModVersionRef, model.MaxBatchSize+1)
+	for i := range refs {
+		refs[i] = model.ModVersionRef{ModID: fmt.Sprint("mod-", i), VersionID: "v1.0.0"}
+	}
+	refs[1] = model.ModVersionRef{ModID: "missing"}
+	refs[2] = model.ModVersionRef{ModID: "mod-2"}
+
+	versions, err := NewClient(server.URL).GetModVersions(refs)
+	require.NoError(t, err)
+	assert.Equal(t, []int{model.MaxBatchSize, 1}, batches)
+	require.Len(t, versions, len(refs))
+	assert.Equal(t, "v1.0.0", versions[0].VersionID)
+	assert.Nil(t, versions[1])
+	assert.Equal(t, "latest", versions[2].VersionID)
+	assert.Equal(t, "mod-100", versions[100].ModID)
+}
+
+func TestClientImpl_CheckForUpdates_WithoutBatchEndpoint(t *testing.T) {
 	// モックサーバーのセットアップ
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Logf("Received request: %s %s", r.Method, r.URL.Path)
 		switch r.URL.Path {
+		case "/mods/updates":
+			// バッチエンドポイントのない古いサーバー
+			w.WriteHeader(http.StatusNotFound)
 		case "/mod/mod-1":
 			// Mod 1 の詳細（最新バージョンは v1.1.0）
 			mod := model.ModDetails{ID: "mod-1", LatestVersionID: "v1.1.0"}
@@ -108,3 +174,36 @@ func TestClientImpl_ShareGame_UsesMultipartFormData(t *testing.T) {
 	assert.Equal(t, "s1", rs.SessionID)
 	assert.Equal(t, "h1", rs.HostKey)
 }
+
+func TestClientImpl_RevalidatesCachedResponses(t *testing.T) {
+	var ifNoneMatch []string
+	name := "My Mod"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/mod/my-mod", r.URL.Path)
+		ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+		etag := `"` + name + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		require.NoError(t, json.MarshalWrite(w, model.ModDetails{ID: "my-mod", Name: name}))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	for range 2 {
+		mod, err := client.GetMod("my-mod")
+		require.NoError(t, err)
+		assert.Equal(t, "My Mod", mod.Name)
+	}
+	name = "Renamed"
+	mod, err := client.GetMod("my-mod")
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", mod.Name)
+	assert.Equal(t, []string{"", `"My Mod"`, `"My Mod"`}, ifNoneMatch)
+
+	_, err = NewClient(server.URL, WithResponseCache(0)).GetMod("my-mod")
+	require.NoError(t, err)
+	assert.Equal(t, "", ifNoneMatch[3])
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/ikafly144/au_mod_installer/common/rest"
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
 )
 
@@ -33,6 +34,10 @@ func (c *OfflineClient) GetModIDs(limit int, after string, before string) ([]str
 	return nil, errors.New("offline mode: mod IDs not available")
 }
 
+func (c *OfflineClient) SearchModIDs(query model.ModSearchQuery, limit int, after string) ([]string, error) {
+	return nil, errors.New("offline mode: mod IDs not available")
+}
+
 func (c *OfflineClient) GetMod(modID string) (*modmgr.Mod, error) {
 	return nil, errors.New("offline mode: mod details not available")
 }
@@ -49,14 +54,30 @@ func (c *OfflineClient) GetLatestModVersion(modID string) (*modmgr.ModVersion, e
 	return nil, errors.New("offline mode: latest mod version details not available")
 }
 
+func (c *OfflineClient) GetMods(modIDs []string) ([]*modmgr.Mod, error) {
+	return nil, errors.New("offline mode: mod details not available")
+}
+
+func (c *OfflineClient) GetModVersions(refs []model.ModVersionRef) ([]*modmgr.ModVersion, error) {
+	return nil, errors.New("offline mode: mod version details not available")
+}
+
 func (c *OfflineClient) CheckForUpdates(installedVersions map[string]string) (map[string]*modmgr.ModVersion, error) {
 	return nil, errors.New("offline mode: update check not available")
 }
 
-func (c *OfflineClient) GetModThumbnail(modID string) ([]byte, error) {
+func (c *OfflineClient) GetModThumbnail(modID string, size model.ImageSize) ([]byte, error) {
 	return nil, errors.New("offline mode: thumbnail not available")
 }
 
+func (c *OfflineClient) GetModGallery(modID string) ([]model.ModImage, error) {
+	return nil, errors.New("offline mode: gallery not available")
+}
+
+func (c *OfflineClient) GetModImage(modID, imageID string, size model.ImageSize) ([]byte, error) {
+	return nil, errors.New("offline mode: image not available")
+}
+
 func (c *OfflineClient) ShareGame(aupack []byte, room rest.RoomInfo) (*rest.ShareGameResponse, error) {
 	return nil, errors.New("offline mode: share game not available")
 }
@@ -72,3 +93,7 @@ func (c *OfflineClient) UpdateSharedGameExpiration(sessionID, hostKey string) (*
 func (c *OfflineClient) GetJoinGameDownload(sessionID string) (*rest.JoinGameDownloadResponse, error) {
 	return nil, errors.New("offline mode: join game download not available")
 }
+
+func (c *OfflineClient) GetSharedAupack(sha256 string) ([]byte, error) {
+	return nil, errors.New("offline mode: shared aupack download not available")
+}
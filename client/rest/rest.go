@@ -2,6 +2,7 @@ package rest
 
 import (
 	"github.com/ikafly144/au_mod_installer/common/rest"
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
 )
 
@@ -10,14 +11,36 @@ type Client interface {
 	GetHealthStatus() (*rest.HealthStatus, error)
 	GetVersionInfo() (*rest.VersionInfo, error)
 	GetModIDs(limit int, after string, before string) ([]string, error)
+	// SearchModIDs lists the IDs of mods matching the query, starting after the mod with the given ID.
+	SearchModIDs(query model.ModSearchQuery, limit int, after string) ([]string, error)
 	GetMod(modID string) (*modmgr.Mod, error)
 	GetModVersionIDs(modID string, limit int, after string) ([]string, error)
 	GetModVersion(modID string, versionID string) (*modmgr.ModVersion, error)
 	GetLatestModVersion(modID string) (*modmgr.ModVersion, error)
-	GetModThumbnail(modID string) ([]byte, error)
+	// GetMods returns the details of the mods in the order of modIDs, with nil for mods that do not exist.
+	GetMods(modIDs []string) ([]*modmgr.Mod, error)
+	// GetModVersions returns the versions in the order of refs, with nil for versions that do not exist.
+	// A ref without a version ID asks for the latest version of the mod.
+	GetModVersions(refs []model.ModVersionRef) ([]*modmgr.ModVersion, error)
+	// GetModThumbnail downloads the thumbnail of a mod resized to size. Servers that do not host thumbnails send the
+	// original image instead.
+	GetModThumbnail(modID string, size model.ImageSize) ([]byte, error)
+	// GetModGallery lists the gallery of a mod in display order.
+	GetModGallery(modID string) ([]model.ModImage, error)
+	GetModImage(modID, imageID string, size model.ImageSize) ([]byte, error)
+	// CheckForUpdates maps each installed mod whose latest version differs from the installed one to that version,
+	// and each mod that does not exist or has no versions to nil.
 	CheckForUpdates(installedVersions map[string]string) (map[string]*modmgr.ModVersion, error)
 	ShareGame(aupack []byte, room rest.RoomInfo) (*rest.ShareGameResponse, error)
 	UpdateSharedGameExpiration(sessionID, hostKey string) (*rest.ShareGameResponse, error)
 	DeleteSharedGame(sessionID, hostKey string) error
 	GetJoinGameDownload(sessionID string) (*rest.JoinGameDownloadResponse, error)
+	// GetSharedAupack downloads the aupack of a shared game by the hex sha256 of its content and verifies it.
+	GetSharedAupack(sha256 string) ([]byte, error)
 }
+
+// Clients batch the lookups of dependency resolution and update checks.
+var (
+	_ modmgr.BatchVersionProvider = Client(nil)
+	_ modmgr.UpdateChecker        = Client(nil)
+)
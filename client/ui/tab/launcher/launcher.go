@@ -13,10 +13,12 @@ import (
 	imagedraw "image/draw"
 	"image/png"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -41,6 +43,7 @@ import (
 	"github.com/ikafly144/au_mod_installer/client/discord"
 	"github.com/ikafly144/au_mod_installer/client/ui/uicommon"
 	"github.com/ikafly144/au_mod_installer/common/rest"
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
 	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
 	"github.com/ikafly144/au_mod_installer/pkg/profile"
@@ -100,6 +103,12 @@ type Launcher struct {
 	profileIconCache   map[uuid.UUID]image.Image
 	profileIconFetched map[uuid.UUID]bool
 
+	flaggedMu        sync.Mutex
+	flaggedVersions  map[uuid.UUID][]modmgr.ModVersion
+	flaggedCheckKey  string
+	flaggedCheckedAt time.Time
+	flaggedWarning   *widget.Label
+
 	canLaunchListener binding.DataListener
 
 	content *fyne.Container
@@ -183,6 +192,7 @@ func NewLauncherTab(s *uicommon.State) *Launcher {
 		friendAvatarLoading:    map[uint64]bool{},
 		profileIconCache:       map[uuid.UUID]image.Image{},
 		profileIconFetched:     map[uuid.UUID]bool{},
+		flaggedWarning:         widget.NewLabel(""),
 	}
 	l.createProfileButton.Importance = widget.HighImportance
 	l.shareRoomButton.Importance = widget.MediumImportance
@@ -192,6 +202,9 @@ func NewLauncherTab(s *uicommon.State) *Launcher {
 	l.copyRoomLinkButton.Disable()
 	l.unpublishRoomButton.Importance = widget.LowImportance
 	l.unpublishRoomButton.Disable()
+	l.flaggedWarning.Importance = widget.WarningImportance
+	l.flaggedWarning.Wrapping = fyne.TextWrapWord
+	l.flaggedWarning.Hide()
 	l.roomLinkEntry.Selectable = true
 	l.roomLinkEntry.Wrapping = fyne.TextWrapOff
 	l.roomVisibilitySelector.SetSelectedIndex(func() int {
@@ -1347,6 +1360,16 @@ func (l *Launcher) shareProfile(prof profile.Profile) {
 		shareArchiveCopyBtn,
 		shareArchiveSaveBtn,
 	)
+	if localMods := prof.LocalMods(); len(localMods) > 0 {
+		names := make([]string, len(localMods))
+		for i, mod := range localMods {
+			names[i] = filepath.Base(mod.LocalSource())
+		}
+		warning := widget.NewLabel(lang.LocalizeKey("profile.share.local_mods_warning", "Local files are not included in the share and must be sent separately: {{.Files}}", map[string]any{"Files": strings.Join(names, ", ")}))
+		warning.Importance = widget.WarningImportance
+		warning.Wrapping = fyne.TextWrapWord
+		content.Add(warning)
+	}
 
 	d = dialog.NewCustom(
 		lang.LocalizeKey("profile.share.options_title", "Share Profile"),
@@ -1933,12 +1956,14 @@ func (l *Launcher) setupProfileList() {
 		l.selectedProfileID = l.profiles[id].ID
 		_ = l.state.ActiveProfile.Set(l.selectedProfileID.String())
 		l.checkLaunchState()
+		l.updateFlaggedWarning()
 		l.refreshProfileGrid()
 		l.profileList.Refresh()
 	}
 	l.profileList.OnUnselected = func(id widget.ListItemID) {
 		l.selectedProfileID = uuid.Nil
 		l.checkLaunchState()
+		l.updateFlaggedWarning()
 		l.refreshProfileGrid()
 		l.profileList.Refresh()
 	}
@@ -2262,6 +2287,7 @@ func (l *Launcher) Tab() (*container.TabItem, error) {
 	)
 
 	footer := container.NewVBox(
+		l.flaggedWarning,
 		l.roomLinkTray,
 		l.launchButton,
 		l.state.ErrorText,
@@ -2298,6 +2324,12 @@ func (l *Launcher) runLaunch() {
 		return
 	}
 
+	gameVersion, err := aumgr.GetVersion(path)
+	if err != nil {
+		dialog.ShowError(err, l.state.Window)
+		return
+	}
+
 	// Find selected profile
 	var targetProfile profile.Profile
 	for _, prof := range l.profiles {
@@ -2349,7 +2381,7 @@ func (l *Launcher) runLaunch() {
 		}()
 
 		// Resolve dependencies
-		resolvedVersions, err := l.state.Core.ResolveDependencies(targetProfile.Versions())
+		resolvedVersions, err := l.state.Core.ResolveProfileDependencies(targetProfile.ID, binaryType, gameVersion)
 		if err != nil {
 			launchErr = errors.New(lang.LocalizeKey("launcher.error.failed_to_resolve_dependencies", "Failed to resolve dependencies: {{.Error}}", map[string]any{"Error": err.Error()}))
 			return
@@ -2363,7 +2395,7 @@ func (l *Launcher) runLaunch() {
 		}
 		cacheDir := filepath.Join(configDir, "au_mod_installer", "mods")
 
-		if err := modmgr.DownloadMods(cacheDir, resolvedVersions, binaryType, launchProgress, false); err != nil {
+		if err := modmgr.DownloadMods(cacheDir, resolvedVersions, binaryType, launchProgress, false, modmgr.WithLocalModStore(l.state.Core.LocalModStore())); err != nil {
 			launchErr = err
 			return
 		}
@@ -2495,7 +2527,7 @@ func (l *Launcher) syncProfile(prof profile.Profile) {
 		}()
 
 		// Resolve dependencies
-		resolvedVersions, err := l.state.Core.ResolveDependencies(prof.Versions())
+		resolvedVersions, err := l.state.Core.ResolveProfileDependencies(prof.ID, binaryType, gameVersion)
 		if err != nil {
 			syncErr = errors.New(lang.LocalizeKey("launcher.error.failed_to_resolve_dependencies", "Failed to resolve dependencies: {{.Error}}", map[string]any{"Error": err.Error()}))
 			return
@@ -2510,7 +2542,7 @@ func (l *Launcher) syncProfile(prof profile.Profile) {
 		cacheDir := filepath.Join(configDir, "au_mod_installer", "mods")
 
 		downloadProgress := progress.NewPhaseProgress(syncProgress, 0.0, 0.5)
-		if err := modmgr.DownloadMods(cacheDir, resolvedVersions, binaryType, downloadProgress, true); err != nil {
+		if err := modmgr.DownloadMods(cacheDir, resolvedVersions, binaryType, downloadProgress, true, modmgr.WithLocalModStore(l.state.Core.LocalModStore())); err != nil {
 			syncErr = err
 			return
 		}
@@ -2524,6 +2556,68 @@ func (l *Launcher) syncProfile(prof profile.Profile) {
 	}()
 }
 
+// updateProfileLock re-resolves the dependencies of the profile and, after the user reviews the changes, replaces its lock.
+func (l *Launcher) updateProfileLock(prof profile.Profile) {
+	progressDialog, _ := l.newProgressDialog(
+		"launcher.update_lock.title",
+		"Updating Dependencies",
+		"launcher.update_lock.in_progress",
+		"Checking for dependency updates. Please wait...",
+	)
+	progressDialog.Show()
+
+	binaryType, gameVersion := l.state.InstalledGame()
+	go func() {
+		update, err := l.state.Core.PlanProfileLockUpdate(prof.ID, binaryType, gameVersion)
+		fyne.Do(func() {
+			progressDialog.Hide()
+			if err != nil {
+				dialog.ShowError(errors.New(lang.LocalizeKey("launcher.error.failed_to_resolve_dependencies", "Failed to resolve dependencies: {{.Error}}", map[string]any{"Error": err.Error()})), l.state.Window)
+				return
+			}
+			if len(update.Changes) == 0 {
+				dialog.ShowInformation(
+					lang.LocalizeKey("launcher.update_lock.title", "Update Dependencies"),
+					lang.LocalizeKey("launcher.update_lock.up_to_date", "All dependencies are up to date."),
+					l.state.Window,
+				)
+				return
+			}
+
+			lines := make([]string, 0, len(update.Changes))
+			for _, change := range update.Changes {
+				lines = append(lines, change.String())
+			}
+			changes := widget.NewLabel(strings.Join(lines, "\n"))
+			changes.Wrapping = fyne.TextWrapWord
+			content := container.NewBorder(
+				widget.NewLabel(lang.LocalizeKey("launcher.update_lock.confirm_message", "The following dependencies will change on the next launch.")),
+				nil, nil, nil,
+				container.NewVScroll(changes),
+			)
+			d := dialog.NewCustomConfirm(
+				lang.LocalizeKey("launcher.update_lock.title", "Update Dependencies"),
+				lang.LocalizeKey("launcher.update_lock.apply", "Update"),
+				lang.LocalizeKey("common.cancel", "Cancel"),
+				content,
+				func(confirm bool) {
+					if !confirm {
+						return
+					}
+					if err := l.state.Core.ApplyProfileLockUpdate(update); err != nil {
+						dialog.ShowError(err, l.state.Window)
+						return
+					}
+					l.refreshProfiles()
+				},
+				l.state.Window,
+			)
+			d.Resize(fyne.NewSize(480, 320))
+			d.Show()
+		})
+	}()
+}
+
 func (l *Launcher) newSyncProgressDialog() (*dialog.CustomDialog, *progress.FyneProgress) {
 	return l.newProgressDialog(
 		"launcher.sync.title",
@@ -2572,6 +2666,7 @@ func (l *Launcher) refreshProfiles() {
 	l.sortProfiles()
 	l.profileList.Refresh()
 	l.refreshProfileGrid()
+	l.refreshFlaggedVersions()
 
 	// Select active profile
 	activeIDStr, _ := l.state.ActiveProfile.Get()
@@ -2605,12 +2700,98 @@ func (l *Launcher) sortProfiles() {
 }
 
 func (l *Launcher) profileMetaText(p profile.Profile) string {
+	var text string
 	if p.LastLaunchedAt.IsZero() {
-		return lang.LocalizeKey("launcher.meta.never_launched", "Never launched")
+		text = lang.LocalizeKey("launcher.meta.never_launched", "Never launched")
+	} else {
+		text = lang.LocalizeKey("launcher.meta.last_launched", "Last: {{.Date}}", map[string]any{
+			"Date": p.LastLaunchedAt.Format("2006-01-02"),
+		})
 	}
-	return lang.LocalizeKey("launcher.meta.last_launched", "Last: {{.Date}}", map[string]any{
-		"Date": p.LastLaunchedAt.Format("2006-01-02"),
-	})
+	if flagged := l.flaggedProfileVersions(p.ID); len(flagged) > 0 {
+		text += "  " + lang.LocalizeKey("launcher.meta.flagged_versions", "{{.Count}} withdrawn", map[string]any{"Count": len(flagged)})
+	}
+	return text
+}
+
+// flaggedVersionsTTL is how long the yanked and deprecated versions found in the profiles are trusted before the
+// server is asked again.
+const flaggedVersionsTTL = 10 * time.Minute
+
+// refreshFlaggedVersions looks up in the background which versions used by the profiles were yanked or deprecated.
+func (l *Launcher) refreshFlaggedVersions() {
+	profiles := slices.Clone(l.profiles)
+	var key strings.Builder
+	for _, p := range profiles {
+		fmt.Fprintf(&key, "%s:%d;", p.ID, p.UpdatedAt.UnixNano())
+	}
+
+	l.flaggedMu.Lock()
+	if l.flaggedCheckKey == key.String() && time.Since(l.flaggedCheckedAt) < flaggedVersionsTTL {
+		l.flaggedMu.Unlock()
+		return
+	}
+	l.flaggedCheckKey = key.String()
+	l.flaggedCheckedAt = time.Now()
+	l.flaggedMu.Unlock()
+
+	go func() {
+		flagged, err := l.state.Core.FlaggedProfileVersions(profiles)
+		if err != nil {
+			slog.Warn("Failed to check profiles for yanked versions", "error", err)
+			l.flaggedMu.Lock()
+			l.flaggedCheckKey = ""
+			l.flaggedMu.Unlock()
+			return
+		}
+		l.flaggedMu.Lock()
+		l.flaggedVersions = flagged
+		l.flaggedMu.Unlock()
+		fyne.Do(func() {
+			l.profileList.Refresh()
+			l.refreshProfileGrid()
+			l.updateFlaggedWarning()
+		})
+	}()
+}
+
+func (l *Launcher) flaggedProfileVersions(profileID uuid.UUID) []modmgr.ModVersion {
+	l.flaggedMu.Lock()
+	defer l.flaggedMu.Unlock()
+	return l.flaggedVersions[profileID]
+}
+
+// flaggedVersion returns the current state of v if it was yanked or deprecated after being added to the profile.
+func (l *Launcher) flaggedVersion(profileID uuid.UUID, v modmgr.ModVersion) (modmgr.ModVersion, bool) {
+	for _, flagged := range l.flaggedProfileVersions(profileID) {
+		if flagged.ModID == v.ModID && flagged.VersionID == v.VersionID {
+			return flagged, true
+		}
+	}
+	return modmgr.ModVersion{}, false
+}
+
+// updateFlaggedWarning lists the yanked and deprecated versions used by the selected profile above the launch button.
+func (l *Launcher) updateFlaggedWarning() {
+	flagged := l.flaggedProfileVersions(l.selectedProfileID)
+	if l.selectedProfileID == uuid.Nil || len(flagged) == 0 {
+		l.flaggedWarning.Hide()
+		return
+	}
+	lines := []string{lang.LocalizeKey("launcher.flagged_versions.title", "This profile uses versions their authors have withdrawn:")}
+	for _, v := range flagged {
+		lines = append(lines, "• "+flaggedVersionText(v))
+	}
+	l.flaggedWarning.SetText(strings.Join(lines, "\n"))
+	l.flaggedWarning.Show()
+}
+
+func flaggedVersionText(v modmgr.ModVersion) string {
+	params := map[string]any{"Mod": v.ModID, "Version": v.VersionID, "Reason": v.StatusReason}
+	if v.IsYanked() {
+		return lang.LocalizeKey("launcher.flagged_versions.yanked", "{{.Mod}} {{.Version}} was yanked: {{.Reason}}", params)
+	}
+	return lang.LocalizeKey("launcher.flagged_versions.deprecated", "{{.Mod}} {{.Version}} is deprecated: {{.Reason}}", params)
 }
 
 func normalizeSortMode(mode string) string {
@@ -2693,6 +2874,9 @@ func (l *Launcher) showProfileMenuAt(pos fyne.Position, prof profile.Profile) {
 	syncItem := fyne.NewMenuItem(lang.LocalizeKey("profile.sync", "Sync (Clear & Re-download)"), func() {
 		l.syncProfile(prof)
 	})
+	updateLockItem := fyne.NewMenuItem(lang.LocalizeKey("profile.update_lock", "Update Dependencies"), func() {
+		l.updateProfileLock(prof)
+	})
 	shareItem := fyne.NewMenuItem(lang.LocalizeKey("profile.share", "Share"), func() {
 		l.shareProfile(prof)
 	})
@@ -2708,6 +2892,7 @@ func (l *Launcher) showProfileMenuAt(pos fyne.Position, prof profile.Profile) {
 	if isBusy {
 		editItem.Disabled = true
 		syncItem.Disabled = true
+		updateLockItem.Disabled = true
 		shareItem.Disabled = true
 		openFolderItem.Disabled = true
 		duplicateItem.Disabled = true
@@ -2716,6 +2901,7 @@ func (l *Launcher) showProfileMenuAt(pos fyne.Position, prof profile.Profile) {
 	menu := fyne.NewMenu("",
 		editItem,
 		syncItem,
+		updateLockItem,
 		shareItem,
 		openFolderItem,
 		duplicateItem,
@@ -2843,12 +3029,79 @@ func (l *Launcher) showDuplicateDialog(prof profile.Profile) {
 				return
 			}
 		}
+		// The copy keeps the same pinned dependencies
+		if lock, err := l.state.ProfileManager.LoadLock(prof.ID); err != nil {
+			slog.Warn("Failed to load profile lock", "profileId", prof.ID, "error", err)
+		} else if lock != nil {
+			if err := l.state.ProfileManager.SaveLock(newProf.ID, lock); err != nil {
+				dialog.ShowError(err, l.state.Window)
+				return
+			}
+		}
 		l.refreshProfiles()
 	}, l.state.Window)
 	d.Resize(fyne.NewSize(400, 200))
 	d.Show()
 }
 
+// showProfileUpdatesDialog lets the user review the changes of each available update and apply the checked ones together.
+func (l *Launcher) showProfileUpdatesDialog(plan *modmgr.UpdatePlan, available map[string]modmgr.ModUpdate, apply func(modIDs ...string)) {
+	checked := make(map[string]bool, len(available))
+	items := container.NewVBox()
+	if plan.Conflict != nil {
+		warning := widget.NewLabel(lang.LocalizeKey("profile.updates.conflict", "Not all updates can be applied together: {{.Error}}", map[string]any{"Error": plan.Conflict.Summary()}))
+		warning.Wrapping = fyne.TextWrapWord
+		warning.Importance = widget.WarningImportance
+		items.Add(warning)
+	}
+	for _, update := range plan.Updates {
+		if _, ok := available[update.ModID]; !ok {
+			continue
+		}
+		checked[update.ModID] = plan.Conflict == nil
+		check := widget.NewCheck(update.ModID+" ("+update.Current.VersionID+" → "+update.Latest.VersionID+")", func(b bool) {
+			checked[update.ModID] = b
+		})
+		check.SetChecked(checked[update.ModID])
+		diffText := update.Diff.String()
+		if diffText == "" {
+			diffText = lang.LocalizeKey("profile.updates.no_changes", "No changes to files, dependencies or features.")
+		}
+		diff := widget.NewLabelWithStyle(diffText, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+		diff.Wrapping = fyne.TextWrapWord
+		items.Add(widget.NewCard("", "", container.NewVBox(check, diff)))
+	}
+	for _, modID := range slices.Sorted(maps.Keys(plan.Errors)) {
+		failed := widget.NewLabel(lang.LocalizeKey("profile.updates.check_failed", "Could not check {{.ModID}} for updates.", map[string]any{"ModID": modID}))
+		failed.Importance = widget.LowImportance
+		items.Add(failed)
+	}
+
+	d := dialog.NewCustomConfirm(
+		lang.LocalizeKey("profile.updates.title", "Available Updates"),
+		lang.LocalizeKey("profile.updates.apply", "Apply Selected"),
+		lang.LocalizeKey("common.cancel", "Cancel"),
+		container.NewVScroll(items),
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			var modIDs []string
+			for _, modID := range slices.Sorted(maps.Keys(checked)) {
+				if checked[modID] {
+					modIDs = append(modIDs, modID)
+				}
+			}
+			if len(modIDs) > 0 {
+				apply(modIDs...)
+			}
+		},
+		l.state.Window,
+	)
+	d.Resize(fyne.NewSize(560, 480))
+	d.Show()
+}
+
 func (l *Launcher) openProfileEditor(prof profile.Profile) {
 	currentProfile := prof
 
@@ -2870,7 +3123,21 @@ func (l *Launcher) openProfileEditor(prof profile.Profile) {
 		}
 		return nil
 	}
-	nameForm := widget.NewForm(widget.NewFormItem(lang.LocalizeKey("profile.name", "Profile Name"), nameEntry))
+	preservePathsEntry := widget.NewMultiLineEntry()
+	preservePathsEntry.SetPlaceHolder(lang.LocalizeKey("profile.preserve_paths_placeholder", "One path per line, e.g. BepInEx/plugins/MyMod/data"))
+	preservePathsEntry.SetText(strings.Join(currentProfile.PreservePaths, "\n"))
+	preservePathsEntry.SetMinRowsVisible(2)
+	preservePathsEntry.Validator = func(s string) error {
+		for _, path := range parsePreservePaths(s) {
+			if !filepath.IsLocal(filepath.FromSlash(path)) {
+				return errors.New(lang.LocalizeKey("profile.error_preserve_path_invalid", "Preserved path must be inside the profile: {{.Path}}", map[string]any{"Path": path}))
+			}
+		}
+		return nil
+	}
+	preservePathsItem := widget.NewFormItem(lang.LocalizeKey("profile.preserve_paths", "Preserved Paths"), preservePathsEntry)
+	preservePathsItem.HintText = lang.LocalizeKey("profile.preserve_paths_hint", "Kept when the profile is reinstalled. BepInEx/config is always kept.")
+	nameForm := widget.NewForm(widget.NewFormItem(lang.LocalizeKey("profile.name", "Profile Name"), nameEntry), preservePathsItem)
 
 	lastLaunchedText := lang.LocalizeKey("profile.stats.never_launched", "Last Launch: Never")
 	if !currentProfile.LastLaunchedAt.IsZero() {
@@ -2949,8 +3216,22 @@ func (l *Launcher) openProfileEditor(prof profile.Profile) {
 		},
 	)
 
-	updatesAvailable := make(map[string]string) // ModID -> LatestVersionID
+	var updatePlan *modmgr.UpdatePlan
+	updatesAvailable := make(map[string]modmgr.ModUpdate)
 	var applyLatestBtn *widget.Button
+	applyUpdates := func(modIDs ...string) {
+		if err := currentProfile.ApplyUpdates(updatePlan, modIDs...); err != nil {
+			dialog.ShowError(err, l.state.Window)
+			return
+		}
+		for _, modID := range modIDs {
+			delete(updatesAvailable, modID)
+		}
+		if len(updatesAvailable) == 0 {
+			applyLatestBtn.Hide()
+		}
+		modList.Refresh()
+	}
 	applyLatestBtn = widget.NewButtonWithIcon(
 		lang.LocalizeKey("profile.apply_latest", "Apply Latest Version"),
 		theme.DownloadIcon(),
@@ -2958,39 +3239,29 @@ func (l *Launcher) openProfileEditor(prof profile.Profile) {
 			if len(updatesAvailable) == 0 {
 				return
 			}
-			for modID, latestID := range updatesAvailable {
-				version, fetchErr := l.state.Rest.GetModVersion(modID, latestID)
-				if fetchErr != nil {
-					dialog.ShowError(errors.New(lang.LocalizeKey("profile.error.failed_to_fetch_latest_version", "Failed to fetch latest version for {{.ModID}}:{{.VersionID}}: {{.Error}}", map[string]any{"ModID": modID, "VersionID": latestID, "Error": fetchErr.Error()})), l.state.Window)
-					return
-				}
-				currentProfile.AddModVersion(*version)
-				delete(updatesAvailable, modID)
-			}
-			applyLatestBtn.Hide()
-			modList.Refresh()
+			l.showProfileUpdatesDialog(updatePlan, updatesAvailable, applyUpdates)
 		},
 	)
 	applyLatestBtn.Hide()
 	go func() {
-		installed := make(map[string]string)
-		for _, v := range currentProfile.Versions() {
-			installed[v.ModID] = v.VersionID
+		binaryType, gameVersion := l.state.InstalledGame()
+		plan, err := l.state.Core.PlanProfileUpdates(currentProfile.Clone(), binaryType, gameVersion)
+		if err != nil {
+			slog.Warn("Failed to check profile for updates", "error", err)
+			return
 		}
-		updates, err := l.state.Rest.CheckForUpdates(installed)
-		if err == nil {
-			for modID, latest := range updates {
-				updatesAvailable[modID] = latest.VersionID
+		fyne.Do(func() {
+			updatePlan = plan
+			for _, update := range plan.Updates {
+				updatesAvailable[update.ModID] = update
 			}
-			fyne.Do(func() {
-				if len(updatesAvailable) > 0 {
-					applyLatestBtn.Show()
-				} else {
-					applyLatestBtn.Hide()
-				}
-				modList.Refresh()
-			})
-		}
+			if len(updatesAvailable) > 0 {
+				applyLatestBtn.Show()
+			} else {
+				applyLatestBtn.Hide()
+			}
+			modList.Refresh()
+		})
 	}()
 
 	// Hook up update item to ensure closure correctness
@@ -3009,34 +3280,44 @@ func (l *Launcher) openProfileEditor(prof profile.Profile) {
 		updateBtn := buttonsArea.Objects[0].(*widget.Button)
 		delBtn := buttonsArea.Objects[1].(*widget.Button)
 
-		l.refreshModThumbnailCanvas(thumb, v.ModID, 64)
-		l.ensureModThumbnailLoaded(v.ModID, modList.Refresh)
-		label.SetText(v.ModID + " (" + v.VersionID + ")")
 		label.Wrapping = fyne.TextWrapOff
 		label.Truncation = fyne.TextTruncateEllipsis
 
-		if latestID, ok := updatesAvailable[v.ModID]; ok {
-			badge.SetText(lang.LocalizeKey("repository.update_available", "Update Available") + " (" + latestID + ")")
+		update, hasUpdate := updatesAvailable[v.ModID]
+		switch {
+		case v.IsLocal():
+			// Local mods have no thumbnail or updates on the server
+			l.refreshModThumbnailCanvas(thumb, "", 64)
+			label.SetText(filepath.Base(v.LocalSource()) + " (" + v.VersionID + ")")
+			badge.SetText(lang.LocalizeKey("profile.local_mod", "Local File"))
+			badge.Importance = widget.LowImportance
+			badge.Show()
+			updateBtn.Hide()
+		case hasUpdate && update.Current.VersionID == v.VersionID:
+			l.refreshModThumbnailCanvas(thumb, v.ModID, 64)
+			l.ensureModThumbnailLoaded(v.ModID, modList.Refresh)
+			label.SetText(v.ModID + " (" + v.VersionID + ")")
+			badge.SetText(lang.LocalizeKey("repository.update_available", "Update Available") + " (" + update.Latest.VersionID + ")")
 			badge.Importance = widget.WarningImportance
 			badge.Show()
 			updateBtn.Show()
 			updateBtn.OnTapped = func() {
-				modID := v.ModID
-				latestVersionID := latestID
-				version, fetchErr := l.state.Rest.GetModVersion(modID, latestVersionID)
-				if fetchErr != nil {
-					dialog.ShowError(errors.New(lang.LocalizeKey("profile.error.failed_to_fetch_latest_version", "Failed to fetch latest version for {{.ModID}}:{{.VersionID}}: {{.Error}}", map[string]any{"ModID": modID, "VersionID": latestVersionID, "Error": fetchErr.Error()})), l.state.Window)
-					return
-				}
-				currentProfile.AddModVersion(*version)
-				delete(updatesAvailable, modID)
-				if len(updatesAvailable) == 0 {
-					applyLatestBtn.Hide()
+				applyUpdates(update.ModID)
+			}
+		default:
+			l.refreshModThumbnailCanvas(thumb, v.ModID, 64)
+			l.ensureModThumbnailLoaded(v.ModID, modList.Refresh)
+			label.SetText(v.ModID + " (" + v.VersionID + ")")
+			if flagged, ok := l.flaggedVersion(currentProfile.ID, v); ok {
+				badge.SetText(flaggedVersionText(flagged))
+				badge.Importance = widget.WarningImportance
+				if flagged.IsYanked() {
+					badge.Importance = widget.DangerImportance
 				}
-				modList.Refresh()
+				badge.Show()
+			} else {
+				badge.Hide()
 			}
-		} else {
-			badge.Hide()
 			updateBtn.Hide()
 		}
 
@@ -3058,6 +3339,39 @@ func (l *Launcher) openProfileEditor(prof profile.Profile) {
 			modList.Refresh()
 		})
 	})
+	addLocalMod := func(path string) {
+		go func() {
+			mod, err := l.state.Core.ImportLocalMod(path)
+			fyne.Do(func() {
+				if err != nil {
+					dialog.ShowError(err, l.state.Window)
+					return
+				}
+				currentProfile.AddModVersion(*mod)
+				modList.Refresh()
+			})
+		}()
+	}
+	addLocalFileBtn := widget.NewButtonWithIcon(lang.LocalizeKey("profile.add_local_file", "Add Local File"), theme.FileIcon(), func() {
+		path, err := l.state.ExplorerOpenFile(lang.LocalizeKey("profile.local_mod_file_type", "Plugin DLL or Archive"), "*.dll;*.zip")
+		if err != nil {
+			slog.Info("File selection cancelled or failed", "error", err)
+			return
+		}
+		addLocalMod(path)
+	})
+	addLocalFolderBtn := widget.NewButtonWithIcon(lang.LocalizeKey("profile.add_local_folder", "Add Local Folder"), theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, l.state.Window)
+				return
+			}
+			if uri == nil {
+				return
+			}
+			addLocalMod(uri.Path())
+		}, l.state.Window)
+	})
 
 	saveBtn = widget.NewButtonWithIcon(lang.LocalizeKey("common.save", "Save"), theme.DocumentSaveIcon(),
 		func() {
@@ -3073,6 +3387,7 @@ func (l *Launcher) openProfileEditor(prof profile.Profile) {
 
 			oldID := prof.ID
 			currentProfile.Name = newName
+			currentProfile.PreservePaths = parsePreservePaths(preservePathsEntry.Text)
 			currentProfile.UpdatedAt = time.Now()
 
 			if err := l.state.ProfileManager.Add(currentProfile); err != nil {
@@ -3134,7 +3449,7 @@ func (l *Launcher) openProfileEditor(prof profile.Profile) {
 				applyLatestBtn,
 			),
 		),
-		addModBtn, nil, nil,
+		container.NewGridWithColumns(3, addModBtn, addLocalFileBtn, addLocalFolderBtn), nil, nil,
 		modList,
 	)
 
@@ -3153,6 +3468,16 @@ func (l *Launcher) openProfileEditor(prof profile.Profile) {
 	d.Show()
 }
 
+func parsePreservePaths(text string) []string {
+	var paths []string
+	for line := range strings.Lines(text) {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
 func (l *Launcher) showProfileIconSelectionDialog(prof profile.Profile, onSelect func([]byte)) {
 	var d *dialog.CustomDialog
 	selectFromExplorerBtn := widget.NewButtonWithIcon(
@@ -3294,7 +3619,7 @@ func (l *Launcher) profileIconPNGFromModThumbnail(modID string) ([]byte, error)
 		return nil, errors.New(lang.LocalizeKey("profile.icon.mod_thumbnail_unavailable", "MOD thumbnail is unavailable."))
 	}
 
-	thumbBytes, err := l.state.Rest.GetModThumbnail(modID)
+	thumbBytes, err := l.state.Rest.GetModThumbnail(modID, restmodel.ImageSizeList)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", lang.LocalizeKey("profile.icon.mod_thumbnail_load_failed", "Failed to load MOD thumbnail."), err)
 	}
@@ -3373,35 +3698,34 @@ func (l *Launcher) showAddModDialog(onAdd func([]modmgr.ModVersion)) {
 			contentBox.Refresh()
 		})
 
-		for i, modID := range modIDs {
-			go func(index int, id string) {
-				mod, fetchErr := l.state.Rest.GetMod(id)
-				fyne.Do(func() {
-					if index >= len(contentBox.Objects) {
-						return
-					}
-					if fetchErr != nil || mod == nil {
-						if fetchErr != nil {
-							slog.Warn("Failed to fetch mod details", "modID", id, "error", fetchErr)
-						}
-						title := lang.LocalizeKey("profile.failed_mod", "Failed to load mod '{{.ID}}'", map[string]any{"ID": id})
-						subtitle := lang.LocalizeKey("profile.failed_mod_description", "Reopen this dialog to retry")
-						contentBox.Objects[index] = buildItem(id, title, subtitle, nil)
-						contentBox.Refresh()
-						return
-					}
+		mods, fetchErr := l.state.Rest.GetMods(modIDs)
+		if fetchErr != nil {
+			slog.Warn("Failed to fetch mod details", "error", fetchErr)
+			mods = make([]*modmgr.Mod, len(modIDs))
+		}
+		fyne.Do(func() {
+			for index, mod := range mods {
+				if index >= len(contentBox.Objects) {
+					break
+				}
+				if mod == nil {
+					id := modIDs[index]
+					title := lang.LocalizeKey("profile.failed_mod", "Failed to load mod '{{.ID}}'", map[string]any{"ID": id})
+					subtitle := lang.LocalizeKey("profile.failed_mod_description", "Reopen this dialog to retry")
+					contentBox.Objects[index] = buildItem(id, title, subtitle, nil)
+					continue
+				}
 
-					contentBox.Objects[index] = buildItem(mod.ID, mod.Name, mod.Author, func() {
-						detailsDialog := l.newModDetailsDialog(mod, func(v modmgr.ModVersion) {
-							onAdd([]modmgr.ModVersion{v})
-							d.Dismiss()
-						})
-						detailsDialog.Show()
+				contentBox.Objects[index] = buildItem(mod.ID, mod.Name, mod.Author, func() {
+					detailsDialog := l.newModDetailsDialog(mod, func(v modmgr.ModVersion) {
+						onAdd([]modmgr.ModVersion{v})
+						d.Dismiss()
 					})
-					contentBox.Refresh()
+					detailsDialog.Show()
 				})
-			}(i, modID)
-		}
+			}
+			contentBox.Refresh()
+		})
 	}()
 
 	d = dialog.NewCustom(
@@ -3560,11 +3884,12 @@ func (l *Launcher) ensureModThumbnailLoaded(modID string, onLoaded func()) {
 	l.modThumbMu.Unlock()
 
 	go func(targetModID string) {
-		thumbBytes, err := l.state.Rest.GetModThumbnail(targetModID)
+		thumbBytes, err := l.state.Rest.GetModThumbnail(targetModID, restmodel.ImageSizeList)
 		var decoded image.Image
 		if err == nil && len(thumbBytes) > 0 {
 			decoded, _, err = image.Decode(bytes.NewReader(thumbBytes))
 			if err == nil {
+				// Servers hosting thumbnails send a square already; older ones redirect to the original image
 				decoded = centerCropSquare(decoded)
 			}
 		}
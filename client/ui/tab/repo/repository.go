@@ -8,6 +8,7 @@ import (
 	"image/color"
 	imagedraw "image/draw"
 	"log/slog"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -26,6 +27,7 @@ import (
 
 	"github.com/ikafly144/au_mod_installer/client/core"
 	"github.com/ikafly144/au_mod_installer/client/ui/uicommon"
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
 	"github.com/ikafly144/au_mod_installer/pkg/profile"
 
@@ -38,6 +40,7 @@ const (
 	ModsPerPage          = 10
 	repositoryThumbSize  = float32(114)
 	repositoryDetailSize = float32(114)
+	galleryImageWidth    = float32(480)
 )
 
 type Repository struct {
@@ -53,7 +56,10 @@ type Repository struct {
 	lastModID  string
 	noMoreMods bool
 	loading    bool
-	modsBind   binding.List[*modmgr.Mod]
+	// generation is incremented on reload, so pages of a previous search are dropped
+	generation  int
+	searchTimer *time.Timer
+	modsBind    binding.List[*modmgr.Mod]
 
 	// Containers
 	mainContainer *fyne.Container // Stack container for switching views
@@ -64,6 +70,10 @@ type Repository struct {
 	modListContainer *fyne.Container
 	modScroll        *container.Scroll
 	searchBar        *widget.Entry
+	sortSelect       *widget.Select
+	typeSelect       *widget.Select
+	compatibleCheck  *widget.Check
+	directJoinCheck  *widget.Check
 	reloadBtn        *widget.Button
 	stateLabel       *widget.Label
 }
@@ -84,10 +94,26 @@ func NewRepository(state *uicommon.State) *Repository {
 
 	// Initialize UI components
 	repo.searchBar = widget.NewEntry()
-	repo.searchBar.SetPlaceHolder(lang.LocalizeKey("repository.search_placeholder", "Filter mods by name"))
-	repo.searchBar.OnChanged = func(s string) {
-		go repo.updateModList(s)
+	repo.searchBar.SetPlaceHolder(lang.LocalizeKey("repository.search_placeholder", "Search mods by name, description or author"))
+	repo.searchBar.OnChanged = func(string) {
+		repo.scheduleSearch()
 	}
+	repo.sortSelect = widget.NewSelect(sortOptionLabels(), nil)
+	repo.sortSelect.SetSelectedIndex(0)
+	repo.sortSelect.OnChanged = func(string) {
+		go repo.reloadMods()
+	}
+	repo.typeSelect = widget.NewSelect(typeOptionLabels(), nil)
+	repo.typeSelect.SetSelectedIndex(0)
+	repo.typeSelect.OnChanged = func(string) {
+		go repo.reloadMods()
+	}
+	repo.compatibleCheck = widget.NewCheck(lang.LocalizeKey("repository.filter.compatible", "Compatible with installed game"), func(bool) {
+		go repo.reloadMods()
+	})
+	repo.directJoinCheck = widget.NewCheck(lang.LocalizeKey("repository.filter.direct_join", "Supports direct join"), func(bool) {
+		go repo.reloadMods()
+	})
 
 	repo.reloadBtn = widget.NewButtonWithIcon(lang.LocalizeKey("repository.reload", "Reload"), theme.ViewRefreshIcon(), func() {
 		repo.reloadBtn.Disable()
@@ -110,9 +136,12 @@ func NewRepository(state *uicommon.State) *Repository {
 	}
 
 	// Build List View
-	top := container.New(layout.NewBorderLayout(nil, nil, nil, repo.reloadBtn),
-		repo.searchBar,
-		repo.reloadBtn,
+	top := container.NewVBox(
+		container.New(layout.NewBorderLayout(nil, nil, nil, repo.reloadBtn),
+			repo.searchBar,
+			repo.reloadBtn,
+		),
+		container.NewHBox(repo.sortSelect, repo.typeSelect, repo.compatibleCheck, repo.directJoinCheck),
 	)
 	bottom := container.NewVBox(
 		repo.state.ErrorText,
@@ -131,7 +160,7 @@ func NewRepository(state *uicommon.State) *Repository {
 	repo.detailView.Hide()
 
 	state.ActiveProfile.AddListener(binding.NewDataListener(func() {
-		repo.updateModList(repo.searchBar.Text)
+		repo.updateModList()
 	}))
 
 	return repo
@@ -148,7 +177,7 @@ func (r *Repository) Tab() (*container.TabItem, error) {
 	return container.NewTabItem(lang.LocalizeKey("repository.tab_name", "Repository"), r.mainContainer), nil
 }
 
-func (r *Repository) updateModList(filter string) {
+func (r *Repository) updateModList() {
 	defer fyne.Do(r.reloadBtn.Enable)
 	var objs []fyne.CanvasObject
 	mods, err := r.modsBind.Get()
@@ -157,12 +186,7 @@ func (r *Repository) updateModList(filter string) {
 		return
 	}
 
-	searchText := strings.ToLower(filter)
 	for _, mod := range mods {
-		if searchText != "" && !strings.Contains(strings.ToLower(mod.Name), searchText) {
-			continue
-		}
-
 		thumb := r.newModThumbnailCanvas(mod.ID, repositoryThumbSize, 3)
 		r.ensureThumbnailLoaded(mod.ID)
 		thumbBg := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
@@ -270,13 +294,9 @@ func (r *Repository) showModDetails(mod *modmgr.Mod) {
 	authorLabel.Truncation = fyne.TextTruncateEllipsis
 	headerText := container.NewVBox(titleLabel, authorLabel)
 
-	// if mod.Website != "" {
-	// 	if u, err := url.Parse(mod.Website); err == nil {
-	// 		headerText.Add(widget.NewHyperlink(lang.LocalizeKey("repository.website", "Website"), u))
-	// 	} else {
-	// 		slog.Warn("Failed to parse mod website URL", "url", mod.Website, "error", err)
-	// 	}
-	// }
+	if links := modLinks(mod.Links); len(links) > 0 {
+		headerText.Add(container.NewHBox(links...))
+	}
 
 	headerText.Add(widget.NewButton(lang.LocalizeKey("repository.install_latest", "Install Latest"), func() {
 		r.installModVersion(mod, mod.LatestVersionID)
@@ -288,8 +308,13 @@ func (r *Repository) showModDetails(mod *modmgr.Mod) {
 	)
 
 	// Tabs
+	details := container.NewVBox(widget.NewLabel(mod.Description))
+	if metadata := modMetadata(mod); len(metadata) > 0 {
+		details.Add(widget.NewSeparator())
+		details.Objects = append(details.Objects, metadata...)
+	}
 	detailsTab := container.NewTabItem(lang.LocalizeKey("repository.tab.details", "Details"),
-		container.NewVScroll(widget.NewLabel(mod.Description)),
+		container.NewVScroll(details),
 	)
 
 	versionsList := container.NewVBox()
@@ -301,21 +326,43 @@ func (r *Repository) showModDetails(mod *modmgr.Mod) {
 	versionsList.Add(widget.NewProgressBarInfinite())
 	go func() {
 		versions, err := r.state.Rest.GetModVersionIDs(mod.ID, 100, "")
+		// Release dates and changelogs are extras, so the list still shows when they fail to load
+		var details []*modmgr.ModVersion
+		if err == nil && len(versions) > 0 {
+			refs := make([]restmodel.ModVersionRef, len(versions))
+			for i, v := range versions {
+				refs[i] = restmodel.ModVersionRef{ModID: mod.ID, VersionID: v}
+			}
+			var detailsErr error
+			if details, detailsErr = r.state.Rest.GetModVersions(refs); detailsErr != nil {
+				slog.Debug("Failed to load mod version details", "modID", mod.ID, "error", detailsErr)
+			}
+		}
 		fyne.Do(func() {
 			versionsList.Objects = nil
 			if err != nil {
 				versionsList.Add(widget.NewLabel(lang.LocalizeKey("repository.error.failed_to_load_versions", "Failed to load versions: {{.Error}}", map[string]any{"Error": err.Error()})))
 				return
 			}
-			for _, v := range versions {
-				verLabel := widget.NewLabel(v)
+			for i, v := range versions {
+				var version *modmgr.ModVersion
+				if i < len(details) {
+					version = details[i]
+				}
+				verLabel := widget.NewLabel(versionLabel(v, version))
 				verLabel.Wrapping = fyne.TextWrapOff
 				verLabel.Truncation = fyne.TextTruncateEllipsis
 				addBtn := widget.NewButton(lang.LocalizeKey("repository.add_to_profile", "Add to Profile"), func() {
 					r.installModVersion(mod, v)
 				})
-				row := container.New(layout.NewBorderLayout(nil, nil, nil, addBtn),
-					addBtn,
+				buttons := container.NewHBox(addBtn)
+				if version != nil && strings.TrimSpace(version.Changelog) != "" {
+					buttons.Objects = append([]fyne.CanvasObject{widget.NewButton(lang.LocalizeKey("repository.changelog", "Changelog"), func() {
+						r.showChangelog(mod, version)
+					})}, buttons.Objects...)
+				}
+				row := container.New(layout.NewBorderLayout(nil, nil, nil, buttons),
+					buttons,
 					verLabel,
 				)
 				versionsList.Add(row)
@@ -326,6 +373,7 @@ func (r *Repository) showModDetails(mod *modmgr.Mod) {
 	}()
 
 	tabs := container.NewAppTabs(detailsTab, versionsTab)
+	go r.loadGallery(mod.ID, tabs)
 
 	// Assemble Detail View
 	detailContent := container.New(layout.NewBorderLayout(header, nil, nil, nil),
@@ -345,6 +393,120 @@ func (r *Repository) showModDetails(mod *modmgr.Mod) {
 	r.detailView.Show()
 }
 
+// modLinks returns hyperlinks to the pages of a mod, skipping links that do not parse.
+func modLinks(links restmodel.ModLinks) []fyne.CanvasObject {
+	var objects []fyne.CanvasObject
+	for _, link := range []struct{ key, fallback, url string }{
+		{"repository.website", "Website", links.Homepage},
+		{"repository.link.source", "Source", links.Source},
+		{"repository.link.issues", "Issues", links.Issues},
+		{"repository.link.discord", "Discord", links.Discord},
+	} {
+		if link.url == "" {
+			continue
+		}
+		u, err := url.Parse(link.url)
+		if err != nil {
+			slog.Warn("Failed to parse mod link", "url", link.url, "error", err)
+			continue
+		}
+		objects = append(objects, widget.NewHyperlink(lang.LocalizeKey(link.key, link.fallback), u))
+	}
+	return objects
+}
+
+// modMetadata returns labels for the tags, license and languages of a mod that are set.
+func modMetadata(mod *modmgr.Mod) []fyne.CanvasObject {
+	var labels []fyne.CanvasObject
+	add := func(text string) {
+		label := widget.NewLabel(text)
+		label.Wrapping = fyne.TextWrapWord
+		labels = append(labels, label)
+	}
+	if len(mod.Tags) > 0 {
+		add(lang.LocalizeKey("repository.tags", "Tags: {{.Tags}}", map[string]any{"Tags": strings.Join(mod.Tags, ", ")}))
+	}
+	if mod.License != "" {
+		add(lang.LocalizeKey("repository.license", "License: {{.License}}", map[string]any{"License": mod.License}))
+	}
+	if len(mod.Languages) > 0 {
+		add(lang.LocalizeKey("repository.languages", "Languages: {{.Languages}}", map[string]any{"Languages": strings.Join(mod.Languages, ", ")}))
+	}
+	return labels
+}
+
+// versionLabel shows the release date next to a version ID when the details of the version are loaded.
+func versionLabel(versionID string, version *modmgr.ModVersion) string {
+	if version == nil {
+		return versionID
+	}
+	released := version.ReleasedAt
+	if released.IsZero() {
+		released = version.CreatedAt
+	}
+	if released.IsZero() {
+		return versionID
+	}
+	return lang.LocalizeKey("repository.version_released", "{{.Version}} (released {{.Date}})", map[string]any{"Version": versionID, "Date": released.Local().Format(time.DateOnly)})
+}
+
+func (r *Repository) showChangelog(mod *modmgr.Mod, version *modmgr.ModVersion) {
+	changelog := widget.NewRichTextFromMarkdown(version.Changelog)
+	changelog.Wrapping = fyne.TextWrapWord
+	scroll := container.NewVScroll(changelog)
+	scroll.SetMinSize(fyne.NewSize(480, 320))
+	dialog.ShowCustom(
+		lang.LocalizeKey("repository.changelog_title", "{{.ModName}} {{.Version}}", map[string]any{"ModName": mod.Name, "Version": version.VersionID}),
+		lang.LocalizeKey("common.close", "Close"),
+		scroll,
+		r.state.Window,
+	)
+}
+
+// loadGallery adds a tab showing the gallery of the mod to tabs, unless the mod has no gallery.
+func (r *Repository) loadGallery(modID string, tabs *container.AppTabs) {
+	if r.state.Rest == nil {
+		return
+	}
+	images, err := r.state.Rest.GetModGallery(modID)
+	if err != nil {
+		slog.Debug("Failed to load mod gallery", "modID", modID, "error", err)
+		return
+	}
+	var items []fyne.CanvasObject
+	for _, meta := range images {
+		data, err := r.state.Rest.GetModImage(modID, meta.ID, restmodel.ImageSizeDetail)
+		if err != nil {
+			slog.Debug("Failed to load gallery image", "modID", modID, "imageID", meta.ID, "error", err)
+			continue
+		}
+		decoded, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			slog.Debug("Failed to decode gallery image", "modID", modID, "imageID", meta.ID, "error", err)
+			continue
+		}
+		bounds := decoded.Bounds()
+		img := canvas.NewImageFromImage(decoded)
+		img.FillMode = canvas.ImageFillContain
+		img.CornerRadius = 6
+		img.SetMinSize(fyne.NewSize(galleryImageWidth, galleryImageWidth*float32(bounds.Dy())/float32(bounds.Dx())))
+		items = append(items, container.NewCenter(img))
+		if meta.Caption != "" {
+			caption := widget.NewLabelWithStyle(meta.Caption, fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+			caption.Wrapping = fyne.TextWrapWord
+			items = append(items, caption)
+		}
+	}
+	if len(items) == 0 {
+		return
+	}
+	fyne.Do(func() {
+		tabs.Append(container.NewTabItem(lang.LocalizeKey("repository.tab.gallery", "Gallery"),
+			container.NewVScroll(container.NewVBox(items...)),
+		))
+	})
+}
+
 func (r *Repository) installModVersion(mod *modmgr.Mod, versionID string) {
 	r.stateLabel.Hide()
 
@@ -487,7 +649,7 @@ func (r *Repository) LoadNext() {
 
 func (r *Repository) fetchMods() (error, bool) {
 	defer func() {
-		r.updateModList(r.currentSearchText())
+		r.updateModList()
 	}()
 	if r.state.Rest != nil {
 		mods, err := r.modsBind.Get()
@@ -499,13 +661,26 @@ func (r *Repository) fetchMods() (error, bool) {
 		if r.lastModID != "" && len(mods) > 0 {
 			afterId = r.lastModID
 		}
+		generation := r.generation
 		r.mu.Unlock()
 
-		slog.Info("Refreshing mods", "afterId", afterId)
+		query := r.searchQuery()
+		slog.Info("Refreshing mods", "afterId", afterId, "query", query)
 
-		if modIDs, err := r.state.Rest.GetModIDs(ModsPerPage, afterId, ""); err != nil {
+		modIDs, err := r.state.Rest.SearchModIDs(query, ModsPerPage, afterId)
+		r.mu.Lock()
+		stale := generation != r.generation
+		r.mu.Unlock()
+		if stale {
+			return nil, false
+		}
+		if err != nil {
 			return err, false
 		} else if len(modIDs) > 0 {
+			mods, err := r.modsBind.Get()
+			if err != nil {
+				return err, false
+			}
 			startIndex := len(mods)
 			for _, modID := range modIDs {
 				loadingMod := &modmgr.Mod{}
@@ -540,11 +715,52 @@ func (r *Repository) fetchMods() (error, bool) {
 	return nil, false
 }
 
-func (r *Repository) currentSearchText() string {
-	if r.searchBar == nil {
-		return ""
+// scheduleSearch reloads the mods once the search text has not changed for a moment.
+func (r *Repository) scheduleSearch() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.searchTimer != nil {
+		r.searchTimer.Stop()
+	}
+	r.searchTimer = time.AfterFunc(300*time.Millisecond, r.reloadMods)
+}
+
+// searchQuery builds the server-side search from the search bar and filters.
+func (r *Repository) searchQuery() restmodel.ModSearchQuery {
+	query := restmodel.ModSearchQuery{
+		Query: strings.TrimSpace(r.searchBar.Text),
+		Sort:  sortOptions[max(r.sortSelect.SelectedIndex(), 0)],
+		Type:  typeOptions[max(r.typeSelect.SelectedIndex(), 0)],
+	}
+	if r.compatibleCheck.Checked {
+		binaryType, gameVersion := r.state.InstalledGame()
+		query.GameVersion = gameVersion
+		query.TargetPlatform = binaryType.TargetPlatform()
+	}
+	if r.directJoinCheck.Checked {
+		query.Features = []string{modmgr.FeatureDirectJoin}
+	}
+	return query
+}
+
+var sortOptions = []restmodel.ModSort{restmodel.ModSortCreatedAt, restmodel.ModSortUpdatedAt, restmodel.ModSortName}
+
+func sortOptionLabels() []string {
+	return []string{
+		lang.LocalizeKey("repository.sort.created_at", "Newest"),
+		lang.LocalizeKey("repository.sort.updated_at", "Recently Updated"),
+		lang.LocalizeKey("repository.sort.name", "Name"),
+	}
+}
+
+var typeOptions = []restmodel.ModType{"", restmodel.ModTypeMod, restmodel.ModTypeLibrary}
+
+func typeOptionLabels() []string {
+	return []string{
+		lang.LocalizeKey("repository.filter.type_all", "All Types"),
+		lang.LocalizeKey("repository.filter.type_mod", "Mods"),
+		lang.LocalizeKey("repository.filter.type_library", "Libraries"),
 	}
-	return r.searchBar.Text
 }
 
 func (r *Repository) loadModDetailsAsync(modID string, listIndex int) {
@@ -574,7 +790,7 @@ func (r *Repository) loadModDetailsAsync(modID string, listIndex int) {
 		return
 	}
 
-	r.updateModList(r.currentSearchText())
+	r.updateModList()
 }
 
 func (r *Repository) reloadMods() {
@@ -583,6 +799,7 @@ func (r *Repository) reloadMods() {
 	r.lastModID = ""
 	r.noMoreMods = false
 	r.loading = false
+	r.generation++
 	r.mu.Unlock()
 	r.thumbMu.Lock()
 	r.thumbnailImageCache = map[string]image.Image{}
@@ -657,7 +874,7 @@ func (r *Repository) ensureThumbnailLoaded(modID string) {
 	r.thumbMu.Unlock()
 
 	go func(targetModID string) {
-		thumbBytes, err := r.state.Rest.GetModThumbnail(targetModID)
+		thumbBytes, err := r.state.Rest.GetModThumbnail(targetModID, restmodel.ImageSizeList)
 		var decoded image.Image
 		if err == nil && len(thumbBytes) > 0 {
 			decoded, _, err = image.Decode(bytes.NewReader(thumbBytes))
@@ -677,7 +894,7 @@ func (r *Repository) ensureThumbnailLoaded(modID string) {
 		}
 		r.thumbMu.Unlock()
 
-		r.updateModList(r.currentSearchText())
+		r.updateModList()
 	}(modID)
 }
 
@@ -36,6 +36,7 @@ import (
 
 	"github.com/ikafly144/au_mod_installer/client/ui/uicommon"
 	"github.com/ikafly144/au_mod_installer/common/versioning"
+	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
 )
 
 type Settings struct {
@@ -50,6 +51,7 @@ type Settings struct {
 	DisplayScaleSlider      *widget.Slider
 	DisplayScaleSelect      *widget.Select
 	ClearCacheButton        *widget.Button
+	CleanCacheButton        *widget.Button
 	DeleteAmongUsDataButton *widget.Button
 	CheckForUpdatesButton   *widget.Button
 
@@ -225,6 +227,7 @@ func NewSettings(state *uicommon.State) *Settings {
 	s.discordLogoutButton = widget.NewButton(lang.LocalizeKey("settings.discord_logout", "Logout"), s.discordLogout)
 	s.discordLogoutButton.Hide()
 
+	s.CleanCacheButton = widget.NewButtonWithIcon(lang.LocalizeKey("settings.clean_cache", "Clean Up Unused Mods"), theme.ContentClearIcon(), s.cleanCache)
 	s.ClearCacheButton = widget.NewButtonWithIcon(lang.LocalizeKey("settings.clear_cache", "Clear Mod Cache"), theme.DeleteIcon(), s.clearCache)
 
 	s.DeleteAmongUsDataButton = widget.NewButtonWithIcon(lang.LocalizeKey("settings.delete_among_us_data", "Delete Among Us Data"), theme.DeleteIcon(), s.deleteAmongUsData)
@@ -291,6 +294,41 @@ func (s *Settings) clearCache() {
 	}, s.state.Window)
 }
 
+func (s *Settings) cleanCache() {
+	s.CleanCacheButton.Disable()
+	go func() {
+		defer fyne.Do(s.CleanCacheButton.Enable)
+		report, err := s.state.Core.ModCacheUsage()
+		if err != nil {
+			fyne.Do(func() { dialog.ShowError(err, s.state.Window) })
+			return
+		}
+		fyne.Do(func() {
+			message := lang.LocalizeKey("settings.clean_cache_confirm_message", "The mod cache uses {{.Total}}. Removing mods that no profile uses will free {{.Reclaimable}}. Continue?", map[string]any{
+				"Total":       uicommon.FormatBytes(report.TotalSize),
+				"Reclaimable": uicommon.FormatBytes(report.ReclaimableSize),
+			})
+			dialog.ShowConfirm(lang.LocalizeKey("settings.clean_cache", "Clean Up Unused Mods"), message, func(confirm bool) {
+				if !confirm {
+					return
+				}
+				go func() {
+					result, err := s.state.Core.CollectModCache(modmgr.CacheGCOptions{})
+					fyne.Do(func() {
+						if err != nil {
+							dialog.ShowError(err, s.state.Window)
+							return
+						}
+						dialog.ShowInformation(lang.LocalizeKey("common.success", "Success"), lang.LocalizeKey("settings.cache_cleaned", "Freed {{.Freed}}.", map[string]any{
+							"Freed": uicommon.FormatBytes(result.FreedSize),
+						}), s.state.Window)
+					})
+				}()
+			}, s.state.Window)
+		})
+	}()
+}
+
 func (s *Settings) Tab() (*container.TabItem, error) {
 	s.startAccountPolling()
 	entry := widget.NewLabelWithData(s.state.SelectedGamePath)
@@ -416,7 +454,7 @@ func (s *Settings) Tab() (*container.TabItem, error) {
 		widget.NewCard(
 			lang.LocalizeKey("settings.cache_management", "Cache Management"),
 			"",
-			container.NewVBox(s.ClearCacheButton),
+			container.NewVBox(s.CleanCacheButton, s.ClearCacheButton),
 		),
 	))
 
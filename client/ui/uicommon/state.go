@@ -16,6 +16,7 @@ import (
 	"github.com/ikafly144/au_mod_installer/client/core"
 	"github.com/ikafly144/au_mod_installer/client/discord"
 	"github.com/ikafly144/au_mod_installer/client/rest"
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
 	"github.com/ikafly144/au_mod_installer/pkg/profile"
 )
 
@@ -199,6 +200,23 @@ func (s *State) ModInstallDir() string {
 	return path
 }
 
+// InstalledGame returns the binary type and version of the selected game, or empty values if they cannot be detected.
+func (s *State) InstalledGame() (aumgr.BinaryType, string) {
+	path := s.ModInstallDir()
+	if path == "" {
+		return "", ""
+	}
+	binaryType, err := aumgr.GetBinaryType(path)
+	if err != nil {
+		binaryType = ""
+	}
+	gameVersion, err := aumgr.GetVersion(path)
+	if err != nil {
+		gameVersion = ""
+	}
+	return binaryType, gameVersion
+}
+
 type Tab interface {
 	Tab() (*container.TabItem, error)
 }
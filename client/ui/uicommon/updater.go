@@ -178,8 +178,8 @@ func (s *State) PerformUpdate(tag string) {
 					progressBar.SetValue(ratio)
 					statusLabel.SetText(fmt.Sprintf("%s (%s / %s)",
 						lang.LocalizeKey("update.downloading", "Downloading and applying update..."),
-						formatBytes(downloaded),
-						formatBytes(total),
+						FormatBytes(downloaded),
+						FormatBytes(total),
 					))
 				})
 			}
@@ -214,7 +214,7 @@ func (s *State) PerformUpdate(tag string) {
 	}()
 }
 
-func formatBytes(b int64) string {
+func FormatBytes(b int64) string {
 	const unit = 1024
 	if b < unit {
 		return fmt.Sprintf("%d B", b)
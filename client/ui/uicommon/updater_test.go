@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	restcommon "github.com/ikafly144/au_mod_installer/common/rest"
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
 )
 
@@ -48,6 +49,10 @@ func (m *mockRestClient) GetModIDs(limit int, after string, before string) ([]st
 	return nil, nil
 }
 
+func (m *mockRestClient) SearchModIDs(query model.ModSearchQuery, limit int, after string) ([]string, error) {
+	return nil, nil
+}
+
 func (m *mockRestClient) GetMod(modID string) (*modmgr.Mod, error) {
 	return nil, nil
 }
@@ -64,7 +69,23 @@ func (m *mockRestClient) GetLatestModVersion(modID string) (*modmgr.ModVersion,
 	return nil, nil
 }
 
-func (m *mockRestClient) GetModThumbnail(modID string) ([]byte, error) {
+func (m *mockRestClient) GetMods(modIDs []string) ([]*modmgr.Mod, error) {
+	return make([]*modmgr.Mod, len(modIDs)), nil
+}
+
+func (m *mockRestClient) GetModVersions(refs []model.ModVersionRef) ([]*modmgr.ModVersion, error) {
+	return make([]*modmgr.ModVersion, len(refs)), nil
+}
+
+func (m *mockRestClient) GetModThumbnail(modID string, size model.ImageSize) ([]byte, error) {
+	return nil, nil
+}
+
+func (m *mockRestClient) GetModGallery(modID string) ([]model.ModImage, error) {
+	return nil, nil
+}
+
+func (m *mockRestClient) GetModImage(modID, imageID string, size model.ImageSize) ([]byte, error) {
 	return nil, nil
 }
 
@@ -88,6 +109,10 @@ func (m *mockRestClient) GetJoinGameDownload(sessionID string) (*restcommon.Join
 	return nil, nil
 }
 
+func (m *mockRestClient) GetSharedAupack(sha256 string) ([]byte, error) {
+	return nil, nil
+}
+
 func TestCheckForUpdatesNoUpdate(t *testing.T) {
 	mock := &mockRestClient{
 		versionInfo: &restcommon.VersionInfo{
@@ -106,10 +131,10 @@ func TestCheckForUpdatesNoUpdate(t *testing.T) {
 }
 
 func TestFormatBytes(t *testing.T) {
-	assert.Equal(t, "500 B", formatBytes(500))
-	assert.Equal(t, "1.0 KB", formatBytes(1024))
-	assert.Equal(t, "1.5 MB", formatBytes(1572864))
-	assert.Equal(t, "10.0 MB", formatBytes(10485760))
+	assert.Equal(t, "500 B", FormatBytes(500))
+	assert.Equal(t, "1.0 KB", FormatBytes(1024))
+	assert.Equal(t, "1.5 MB", FormatBytes(1572864))
+	assert.Equal(t, "10.0 MB", FormatBytes(10485760))
 }
 
 func TestResolveLatestUpdateTag(t *testing.T) {
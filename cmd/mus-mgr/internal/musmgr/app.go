@@ -14,7 +14,7 @@ func NewApp() *cli.Command {
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "db",
-				Usage:       "PostgreSQL connection string",
+				Usage:       "Database URL: postgres://... or sqlite://path",
 				Sources:     cli.EnvVars("DATABASE_URL"),
 				Destination: &dbURL,
 			},
@@ -24,6 +24,10 @@ func NewApp() *cli.Command {
 			factory.newMigrateCommand(),
 			factory.newModCommand(),
 			factory.newVersionCommand(),
+			factory.newTokenCommand(),
+			factory.newAuditCommand(),
+			factory.newExportCommand(),
+			factory.newImportCommand(),
 		},
 	}
 }
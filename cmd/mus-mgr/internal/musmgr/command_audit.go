@@ -0,0 +1,50 @@
+package musmgr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+func (f *commandFactory) newAuditCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "audit",
+		Usage: "Show changes made through the write API, newest first",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "mod-id", Usage: "Only show changes to this mod"},
+			&cli.IntFlag{Name: "limit", Usage: "Maximum number of entries", Value: 50},
+			&cli.BoolFlag{Name: "detail", Usage: "Print the request body of each change"},
+		},
+		ShellComplete: f.makeShellComplete(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			repo, err := f.newRepository()
+			if err != nil {
+				return err
+			}
+
+			entries, err := repo.ListAuditLogEntries(cmd.String("mod-id"), cmd.Int("limit"))
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				target := e.ModID
+				if e.VersionID != "" {
+					target += "@" + e.VersionID
+				}
+				if e.FileID != "" {
+					target += " file " + e.FileID
+				}
+				fmt.Printf("%s\t%s\t%s\t%s (%s)\t%s\n", e.CreatedAt.Format(time.RFC3339), e.Action, target, e.TokenName, e.TokenID, e.RemoteIP)
+				if cmd.Bool("detail") && e.Detail != "" {
+					fmt.Printf("\t%s\n", e.Detail)
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package musmgr
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ikafly144/au_mod_installer/server/service"
+)
+
+func (f *commandFactory) newExportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "Export mods and their versions to a mods.json file for the client's local mode",
+		ArgsUsage: "[mod-id...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "File to write (default: stdout)"},
+		},
+		ShellComplete: f.makeShellComplete(f.modIDCompleter()),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			repo, err := f.newRepository()
+			if err != nil {
+				return err
+			}
+
+			mods, err := service.NewModService(repo).ExportRegistry(cmd.Args().Slice())
+			if err != nil {
+				return err
+			}
+			b, err := json.Marshal(mods, jsontext.WithIndent("  "))
+			if err != nil {
+				return err
+			}
+			b = append(b, '\n')
+
+			output := cmd.String("output")
+			if output == "" {
+				_, err = os.Stdout.Write(b)
+				return err
+			}
+			if err := os.WriteFile(output, b, 0o644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", output, err)
+			}
+			fmt.Fprintf(os.Stderr, "Exported %d mods to %s\n", len(mods), output)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package musmgr
+
+import (
+	"context"
+	"encoding/json/v2"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ikafly144/au_mod_installer/server/service"
+)
+
+func (f *commandFactory) newImportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "Create or update mods and versions from a mods.json file written by export",
+		ArgsUsage: "<file>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "dry-run", Usage: "Print the changes without making them"},
+		},
+		ShellComplete: f.makeShellComplete(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			if cmd.NArg() < 1 {
+				return fmt.Errorf("file required")
+			}
+			b, err := os.ReadFile(cmd.Args().First())
+			if err != nil {
+				return err
+			}
+			var mods []service.ArchivedMod
+			if err := json.Unmarshal(b, &mods); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", cmd.Args().First(), err)
+			}
+
+			repo, err := f.newRepository()
+			if err != nil {
+				return err
+			}
+			dryRun := cmd.Bool("dry-run")
+			changes, err := service.NewModService(repo).ImportRegistry(mods, dryRun)
+			for _, change := range changes {
+				fmt.Println(change)
+			}
+			if err != nil {
+				return err
+			}
+			switch {
+			case len(changes) == 0:
+				fmt.Println("No changes.")
+			case dryRun:
+				fmt.Printf("%d changes (dry run, nothing was written)\n", len(changes))
+			default:
+				fmt.Printf("Imported %d changes.\n", len(changes))
+			}
+			return nil
+		},
+	}
+}
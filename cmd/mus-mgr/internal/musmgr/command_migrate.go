@@ -3,14 +3,34 @@ package musmgr
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/urfave/cli/v3"
+
+	gormrepo "github.com/ikafly144/au_mod_installer/server/repository/gorm"
 )
 
 func (f *commandFactory) newMigrateCommand() *cli.Command {
+	up := f.newMigrateUpCommand()
 	return &cli.Command{
 		Name:          "migrate",
-		Usage:         "Migrate the database schema",
+		Usage:         "Migrate the database schema (runs up without a subcommand)",
+		ShellComplete: f.makeShellComplete(),
+		Commands: []*cli.Command{
+			f.newMigrateStatusCommand(),
+			up,
+			f.newMigrateDownCommand(),
+			f.newMigrateToCommand(),
+		},
+		Action: up.Action,
+	}
+}
+
+func (f *commandFactory) newMigrateStatusCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "status",
+		Usage:         "List the migrations and whether they have been applied",
 		ShellComplete: f.makeShellComplete(),
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			if err := requireDB(cmd); err != nil {
@@ -20,11 +40,104 @@ func (f *commandFactory) newMigrateCommand() *cli.Command {
 			if err != nil {
 				return err
 			}
-			if err := repo.Migrate(); err != nil {
+			status, err := repo.MigrationStatus()
+			if err != nil {
 				return err
 			}
-			fmt.Println("Migration successful.")
+			for _, s := range status {
+				state := "pending"
+				switch {
+				case s.Unknown:
+					state = "applied by a newer release at " + s.AppliedAt.Format(time.RFC3339)
+				case s.AppliedAt != nil:
+					state = "applied at " + s.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Printf("%d\t%s\t%s\n", s.Version, s.Name, state)
+			}
 			return nil
 		},
 	}
 }
+
+func (f *commandFactory) newMigrateUpCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "up",
+		Usage:         "Apply every pending migration",
+		ShellComplete: f.makeShellComplete(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return f.migrateTo(cmd, func(int) int { return gormrepo.LatestSchemaVersion() })
+		},
+	}
+}
+
+func (f *commandFactory) newMigrateDownCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "down",
+		Usage: "Revert the newest applied migrations",
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "steps", Usage: "Number of migrations to revert", Value: 1},
+			newForceFlag(),
+		},
+		ShellComplete: f.makeShellComplete(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			steps := cmd.Int("steps")
+			if steps < 1 {
+				return fmt.Errorf("steps must be at least 1")
+			}
+			return f.migrateTo(cmd, func(current int) int { return max(current-steps, 0) })
+		},
+	}
+}
+
+func (f *commandFactory) newMigrateToCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "to",
+		Usage:         "Apply or revert migrations until the schema is at a version (0 reverts all)",
+		ArgsUsage:     "<version>",
+		Flags:         []cli.Flag{newForceFlag()},
+		ShellComplete: f.makeShellComplete(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.NArg() < 1 {
+				return fmt.Errorf("version required")
+			}
+			target, err := strconv.Atoi(cmd.Args().First())
+			if err != nil {
+				return fmt.Errorf("invalid version %q", cmd.Args().First())
+			}
+			return f.migrateTo(cmd, func(int) int { return target })
+		},
+	}
+}
+
+// newForceFlag confirms reverting the first migration, which drops every table along with its data.
+func newForceFlag() cli.Flag {
+	return &cli.BoolFlag{Name: "force", Usage: "Allow reverting every migration, which drops every table and its data"}
+}
+
+// migrateTo migrates to the version target picks from the current one.
+func (f *commandFactory) migrateTo(cmd *cli.Command, target func(current int) int) error {
+	if err := requireDB(cmd); err != nil {
+		return err
+	}
+	repo, err := f.newRepository()
+	if err != nil {
+		return err
+	}
+	current, err := repo.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	version := target(current)
+	if version == current {
+		fmt.Printf("Schema is already at version %d.\n", current)
+		return nil
+	}
+	if version == 0 && !cmd.Bool("force") {
+		return fmt.Errorf("reverting migration 1 drops every table and its data; pass --force to do so")
+	}
+	if err := repo.MigrateTo(version); err != nil {
+		return err
+	}
+	fmt.Printf("Migrated schema from version %d to %d.\n", current, version)
+	return nil
+}
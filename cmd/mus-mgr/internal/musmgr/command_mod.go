@@ -13,6 +13,7 @@ func (f *commandFactory) newModCommand() *cli.Command {
 			f.newModInfoCommand(),
 			f.newModEditCommand(),
 			f.newModDeleteCommand(),
+			f.newModImageCommand(),
 		},
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/urfave/cli/v3"
 
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/server/model"
 )
 
@@ -20,6 +21,14 @@ func (f *commandFactory) newModAddCommand() *cli.Command {
 			&cli.StringFlag{Name: "author", Usage: "Mod author (required)"},
 			&cli.StringFlag{Name: "desc", Usage: "Mod description"},
 			&cli.StringFlag{Name: "thumbnail-url", Usage: "Mod thumbnail URL"},
+			&cli.StringFlag{Name: "type", Usage: "Mod type: mod or library", Value: string(model.ModTypeMod)},
+			&cli.StringSliceFlag{Name: "tag", Usage: "Tags such as roles or cosmetics. Multiple flags or comma separated values supported"},
+			&cli.StringFlag{Name: "homepage-url", Usage: "Mod homepage URL"},
+			&cli.StringFlag{Name: "source-url", Usage: "Source code URL"},
+			&cli.StringFlag{Name: "issues-url", Usage: "Issue tracker URL"},
+			&cli.StringFlag{Name: "discord-url", Usage: "Discord invite URL"},
+			&cli.StringFlag{Name: "license", Usage: "SPDX license expression, e.g. GPL-3.0-only"},
+			&cli.StringSliceFlag{Name: "language", Usage: "Supported languages as BCP 47 tags, e.g. en or ja. Multiple flags or comma separated values supported"},
 		},
 		DisableSliceFlagSeparator: true,
 		ShellComplete:             f.makeShellComplete(),
@@ -34,6 +43,28 @@ func (f *commandFactory) newModAddCommand() *cli.Command {
 				return fmt.Errorf("author required")
 			}
 
+			modType, err := parseModType(cmd.String("type"))
+			if err != nil {
+				return err
+			}
+			tags, err := parseTags(cmd.StringSlice("tag"))
+			if err != nil {
+				return err
+			}
+			links, err := parseModLinks(cmd)
+			if err != nil {
+				return err
+			}
+			if cmd.IsSet("license") {
+				if err := restmodel.ValidateLicense(cmd.String("license")); err != nil {
+					return err
+				}
+			}
+			languages, err := parseLanguages(cmd.StringSlice("language"))
+			if err != nil {
+				return err
+			}
+
 			repo, err := f.newRepository()
 			if err != nil {
 				return err
@@ -49,6 +80,11 @@ func (f *commandFactory) newModAddCommand() *cli.Command {
 				Name:        cmd.String("name"),
 				Author:      cmd.String("author"),
 				Description: cmd.String("desc"),
+				Type:        modType,
+				Tags:        tags,
+				Links:       links,
+				License:     cmd.String("license"),
+				Languages:   languages,
 			}
 
 			if cmd.IsSet("thumbnail-url") {
@@ -5,6 +5,10 @@ import (
 	"fmt"
 
 	"github.com/urfave/cli/v3"
+
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/service"
 )
 
 func (f *commandFactory) newModEditCommand() *cli.Command {
@@ -17,10 +21,20 @@ func (f *commandFactory) newModEditCommand() *cli.Command {
 			&cli.StringFlag{Name: "name", Usage: "Updated mod name"},
 			&cli.StringFlag{Name: "author", Usage: "Updated mod author"},
 			&cli.StringFlag{Name: "desc", Usage: "Updated mod description"},
+			&cli.StringFlag{Name: "type", Usage: "Updated mod type: mod or library"},
 			&cli.StringFlag{Name: "thumbnail-url", Usage: "Updated mod thumbnail URL"},
 			&cli.BoolFlag{Name: "clear-thumbnail", Usage: "Clear thumbnail URL"},
 			&cli.StringFlag{Name: "latest-version-id", Usage: "Updated latest version ID"},
 			&cli.BoolFlag{Name: "clear-latest-version", Usage: "Clear latest version ID"},
+			&cli.StringSliceFlag{Name: "tag", Usage: "Replace tags. Multiple flags or comma separated values supported"},
+			&cli.BoolFlag{Name: "clear-tags", Usage: "Remove every tag"},
+			&cli.StringFlag{Name: "homepage-url", Usage: "Updated homepage URL, empty to remove"},
+			&cli.StringFlag{Name: "source-url", Usage: "Updated source code URL, empty to remove"},
+			&cli.StringFlag{Name: "issues-url", Usage: "Updated issue tracker URL, empty to remove"},
+			&cli.StringFlag{Name: "discord-url", Usage: "Updated Discord invite URL, empty to remove"},
+			&cli.StringFlag{Name: "license", Usage: "Updated SPDX license expression, empty to remove"},
+			&cli.StringSliceFlag{Name: "language", Usage: "Replace supported languages. Multiple flags or comma separated values supported"},
+			&cli.BoolFlag{Name: "clear-languages", Usage: "Remove every supported language"},
 		},
 		DisableSliceFlagSeparator: true,
 		Action: func(ctx context.Context, cmd *cli.Command) error {
@@ -30,6 +44,12 @@ func (f *commandFactory) newModEditCommand() *cli.Command {
 			if cmd.NArg() < 1 {
 				return fmt.Errorf("mod-id required")
 			}
+			if cmd.IsSet("tag") && cmd.Bool("clear-tags") {
+				return fmt.Errorf("tag and clear-tags cannot be used together")
+			}
+			if cmd.IsSet("language") && cmd.Bool("clear-languages") {
+				return fmt.Errorf("language and clear-languages cannot be used together")
+			}
 
 			repo, err := f.newRepository()
 			if err != nil {
@@ -48,6 +68,44 @@ func (f *commandFactory) newModEditCommand() *cli.Command {
 			if cmd.IsSet("desc") {
 				updates["description"] = cmd.String("desc")
 			}
+			if cmd.IsSet("type") {
+				modType, err := parseModType(cmd.String("type"))
+				if err != nil {
+					return err
+				}
+				updates["type"] = modType
+			}
+
+			if cmd.IsSet("tag") || cmd.Bool("clear-tags") {
+				tags, err := parseTags(cmd.StringSlice("tag"))
+				if err != nil {
+					return err
+				}
+				updates["tags"] = tags
+			}
+			if _, err := parseModLinks(cmd); err != nil {
+				return err
+			}
+			for _, link := range modLinkFlags {
+				if cmd.IsSet(link.flag) {
+					updates[link.column] = cmd.String(link.flag)
+				}
+			}
+			if cmd.IsSet("license") {
+				if license := cmd.String("license"); license != "" {
+					if err := restmodel.ValidateLicense(license); err != nil {
+						return err
+					}
+				}
+				updates["license"] = cmd.String("license")
+			}
+			if cmd.IsSet("language") || cmd.Bool("clear-languages") {
+				languages, err := parseLanguages(cmd.StringSlice("language"))
+				if err != nil {
+					return err
+				}
+				updates["languages"] = languages
+			}
 
 			if cmd.Bool("clear-thumbnail") {
 				updates["thumbnail_uri"] = nil
@@ -63,6 +121,9 @@ func (f *commandFactory) newModEditCommand() *cli.Command {
 				if err != nil {
 					return fmt.Errorf("version %s not found for mod %s: %w", targetVer, modID, err)
 				}
+				if verDetails.Status == model.VersionStatusYanked {
+					return fmt.Errorf("%w: %s cannot be the latest version", service.ErrVersionYanked, targetVer)
+				}
 				updates["latest_version_id"] = verDetails.ID
 			}
 
@@ -73,6 +134,14 @@ func (f *commandFactory) newModEditCommand() *cli.Command {
 			if err := repo.UpdateModFields(modID, updates); err != nil {
 				return err
 			}
+			// An uploaded thumbnail would keep being served over the new URL
+			if _, ok := updates["thumbnail_uri"]; ok {
+				if thumbnail, err := repo.GetModThumbnail(modID); err == nil {
+					if err := repo.DeleteModImage(modID, thumbnail.ID); err != nil {
+						return err
+					}
+				}
+			}
 			fmt.Println("Updated mod:", modID)
 			return nil
 		},
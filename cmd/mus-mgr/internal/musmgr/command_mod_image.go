@@ -0,0 +1,183 @@
+package musmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+	"github.com/ikafly144/au_mod_installer/server/service"
+)
+
+func (f *commandFactory) newModImageCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "image",
+		Usage:         "Manage the thumbnail and gallery hosted for a mod",
+		ShellComplete: f.makeShellComplete(),
+		Commands: []*cli.Command{
+			f.newModImageThumbnailCommand(),
+			f.newModImageAddCommand(),
+			f.newModImageListCommand(),
+			f.newModImageRemoveCommand(),
+		},
+	}
+}
+
+func (f *commandFactory) newModImageThumbnailCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "thumbnail",
+		Usage:         "Replace the thumbnail of a mod with an image file or the image at a URL",
+		ArgsUsage:     "<mod-id> <file-or-url>",
+		ShellComplete: f.makeShellComplete(f.modIDCompleter()),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			if cmd.NArg() < 2 {
+				return fmt.Errorf("mod-id and file or URL required")
+			}
+			srv, err := f.newImageService()
+			if err != nil {
+				return err
+			}
+			src, err := readImageSource(cmd.Args().Get(1))
+			if err != nil {
+				return err
+			}
+			image, err := srv.StoreModThumbnail(ctx, cmd.Args().First(), src)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Stored thumbnail %s (%dx%d)\n", image.ID, image.Width, image.Height)
+			return nil
+		},
+	}
+}
+
+func (f *commandFactory) newModImageAddCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "add",
+		Usage:     "Add an image file or the image at a URL to the gallery of a mod",
+		ArgsUsage: "<mod-id> <file-or-url>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "caption", Usage: "Caption shown with the image"},
+		},
+		ShellComplete: f.makeShellComplete(f.modIDCompleter()),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			if cmd.NArg() < 2 {
+				return fmt.Errorf("mod-id and file or URL required")
+			}
+			srv, err := f.newImageService()
+			if err != nil {
+				return err
+			}
+			src, err := readImageSource(cmd.Args().Get(1))
+			if err != nil {
+				return err
+			}
+			image, err := srv.AddModGalleryImage(ctx, cmd.Args().First(), src, cmd.String("caption"))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Added gallery image %s (%dx%d)\n", image.ID, image.Width, image.Height)
+			return nil
+		},
+	}
+}
+
+func (f *commandFactory) newModImageListCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "list",
+		Usage:         "List the thumbnail and gallery of a mod",
+		ArgsUsage:     "<mod-id>",
+		ShellComplete: f.makeShellComplete(f.modIDCompleter()),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			if cmd.NArg() < 1 {
+				return fmt.Errorf("mod-id required")
+			}
+			repo, err := f.newRepository()
+			if err != nil {
+				return err
+			}
+			modID := cmd.Args().First()
+
+			var images []model.ModImage
+			thumbnail, err := repo.GetModThumbnail(modID)
+			if err == nil {
+				images = append(images, *thumbnail)
+			} else if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			gallery, err := repo.ListModGallery(modID)
+			if err != nil {
+				return err
+			}
+			for _, image := range append(images, gallery...) {
+				source := image.SourceURL
+				if source == "" {
+					source = "uploaded"
+				}
+				fmt.Printf("%s\t%s\t%dx%d\t%s\t%s\n", image.ID, image.Kind, image.Width, image.Height, source, image.Caption)
+			}
+			return nil
+		},
+	}
+}
+
+func (f *commandFactory) newModImageRemoveCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "remove",
+		Usage:         "Remove an image of a mod",
+		ArgsUsage:     "<mod-id> <image-id>",
+		ShellComplete: f.makeShellComplete(f.modIDCompleter()),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			if cmd.NArg() < 2 {
+				return fmt.Errorf("mod-id and image-id required")
+			}
+			srv, err := f.newImageService()
+			if err != nil {
+				return err
+			}
+			if err := srv.RemoveModImage(cmd.Args().First(), cmd.Args().Get(1)); err != nil {
+				return err
+			}
+			fmt.Println("Removed image:", cmd.Args().Get(1))
+			return nil
+		},
+	}
+}
+
+func (f *commandFactory) newImageService() (*service.ModService, error) {
+	repo, err := f.newRepository()
+	if err != nil {
+		return nil, err
+	}
+	return service.NewModService(repo, service.WithImageRepository(repo, &http.Client{})), nil
+}
+
+// readImageSource reads an image file, or leaves an http or https URL for the service to fetch.
+func readImageSource(arg string) (service.ImageSource, error) {
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		return service.ImageSource{URL: arg}, nil
+	}
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		return service.ImageSource{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	return service.ImageSource{Data: data}, nil
+}
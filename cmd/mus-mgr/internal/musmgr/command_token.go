@@ -0,0 +1,128 @@
+package musmgr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ikafly144/au_mod_installer/server/service"
+)
+
+func (f *commandFactory) newTokenCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "token",
+		Usage:         "Manage API tokens of the write API",
+		ShellComplete: f.makeShellComplete(),
+		Commands: []*cli.Command{
+			f.newTokenCreateCommand(),
+			f.newTokenListCommand(),
+			f.newTokenRevokeCommand(),
+		},
+	}
+}
+
+func (f *commandFactory) newTokenCreateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "create",
+		Usage: "Create an API token and print it once",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "name", Usage: "Token name, e.g. the maintainer or CI job (required)"},
+			&cli.StringSliceFlag{Name: "mod", Usage: "Mod IDs the token may create and edit. Multiple flags supported. Glob patterns such as 'author-*' are allowed (required)"},
+		},
+		DisableSliceFlagSeparator: true,
+		ShellComplete:             f.makeShellComplete(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			if cmd.String("name") == "" {
+				return fmt.Errorf("name required")
+			}
+			modIDs := cmd.StringSlice("mod")
+			if len(modIDs) == 0 {
+				return fmt.Errorf("at least one --mod required")
+			}
+
+			repo, err := f.newRepository()
+			if err != nil {
+				return err
+			}
+
+			secret, token, err := service.NewAPIToken(cmd.String("name"), modIDs)
+			if err != nil {
+				return err
+			}
+			if err := repo.CreateAPIToken(token); err != nil {
+				return err
+			}
+			fmt.Printf("Created token: %s\n", token.ID)
+			fmt.Printf("Token (shown only once): %s\n", secret)
+			return nil
+		},
+	}
+}
+
+func (f *commandFactory) newTokenListCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "list",
+		Usage:         "List API tokens",
+		ShellComplete: f.makeShellComplete(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			repo, err := f.newRepository()
+			if err != nil {
+				return err
+			}
+
+			tokens, err := repo.ListAPITokens()
+			if err != nil {
+				return err
+			}
+			for _, token := range tokens {
+				status := "active"
+				if token.RevokedAt != nil {
+					status = "revoked"
+				}
+				lastUsed := "never"
+				if token.LastUsedAt != nil {
+					lastUsed = token.LastUsedAt.Format(time.RFC3339)
+				}
+				fmt.Printf("%s\t%s\t%s\t%s\tlast used: %s\n", token.ID, token.Name, strings.Join(token.ModIDs, ","), status, lastUsed)
+			}
+			return nil
+		},
+	}
+}
+
+func (f *commandFactory) newTokenRevokeCommand() *cli.Command {
+	return &cli.Command{
+		Name:          "revoke",
+		Usage:         "Revoke an API token",
+		ArgsUsage:     "<token-id>",
+		ShellComplete: f.makeShellComplete(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			if cmd.NArg() < 1 {
+				return fmt.Errorf("token-id required")
+			}
+
+			repo, err := f.newRepository()
+			if err != nil {
+				return err
+			}
+
+			if err := repo.RevokeAPIToken(cmd.Args().First(), time.Now()); err != nil {
+				return fmt.Errorf("failed to revoke token %s: %w", cmd.Args().First(), err)
+			}
+			fmt.Println("Revoked token:", cmd.Args().First())
+			return nil
+		},
+	}
+}
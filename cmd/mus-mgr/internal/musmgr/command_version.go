@@ -12,6 +12,9 @@ func (f *commandFactory) newVersionCommand() *cli.Command {
 			f.newVersionListCommand(),
 			f.newVersionInfoCommand(),
 			f.newVersionEditCommand(),
+			f.newVersionYankCommand(),
+			f.newVersionDeprecateCommand(),
+			f.newVersionRestoreCommand(),
 			f.newVersionDeleteCommand(),
 		},
 	}
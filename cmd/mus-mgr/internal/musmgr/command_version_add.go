@@ -3,6 +3,7 @@ package musmgr
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/urfave/cli/v3"
@@ -17,10 +18,15 @@ func (f *commandFactory) newVersionAddCommand() *cli.Command {
 		Flags: []cli.Flag{
 			&cli.StringFlag{Name: "mod-id", Usage: "Target mod ID (required)"},
 			&cli.StringFlag{Name: "version-id", Usage: "Version ID (default: auto-incremented SemVer)"},
+			&cli.StringSliceFlag{Name: "game-version", Usage: "Supported game versions. Multiple flags or comma separated values supported (default: every version)"},
 			&cli.StringSliceFlag{Name: "file", Usage: "Files to add. Multiple flags supported. Format: path=...,type=...,url=...,extract_path=...,target_platform=... or direct URL/Path"},
 			&cli.StringSliceFlag{Name: "dependency", Usage: "Dependencies to add. Multiple flags supported. Format: mod_id:version_id:type (type is optional, default: required)"},
 			&cli.StringSliceFlag{Name: "feature", Usage: "Features to set. Format: name=true|false (e.g. direct_join=true)"},
+			&cli.StringFlag{Name: "changelog", Usage: "Changes in this version, in Markdown"},
+			&cli.StringFlag{Name: "changelog-file", Usage: "Markdown file with the changes in this version"},
+			&cli.StringFlag{Name: "released-at", Usage: "Release date as YYYY-MM-DD or RFC 3339 (default: now)"},
 			&cli.BoolFlag{Name: "set-latest", Usage: "Set this version as the latest version for the mod"},
+			&cli.BoolFlag{Name: "skip-dependency-check", Usage: "Add the version even if its dependencies do not validate against the registry"},
 		},
 		DisableSliceFlagSeparator: true,
 		ShellComplete:             f.makeShellComplete(),
@@ -32,6 +38,17 @@ func (f *commandFactory) newVersionAddCommand() *cli.Command {
 				return fmt.Errorf("mod-id required")
 			}
 
+			changelog, err := readChangelog(cmd)
+			if err != nil {
+				return err
+			}
+			releasedAt := time.Now()
+			if cmd.IsSet("released-at") {
+				if releasedAt, err = parseReleaseDate(cmd.String("released-at")); err != nil {
+					return err
+				}
+			}
+
 			repo, err := f.newRepository()
 			if err != nil {
 				return err
@@ -52,8 +69,17 @@ func (f *commandFactory) newVersionAddCommand() *cli.Command {
 				ID:           uuid.New().String(),
 				VersionID:    versionID,
 				ModID:        modID,
+				GameVersions: parseGameVersions(cmd.StringSlice("game-version")),
 				Dependencies: parseDependencies(cmd.StringSlice("dependency")),
 				Features:     parseFeatures(cmd.StringSlice("feature")),
+				Changelog:    changelog,
+				ReleasedAt:   &releasedAt,
+			}
+
+			if !cmd.Bool("skip-dependency-check") {
+				if err := checkDependencies(repo, modID, versionID, ver.Dependencies); err != nil {
+					return err
+				}
 			}
 
 			for _, fileFlag := range cmd.StringSlice("file") {
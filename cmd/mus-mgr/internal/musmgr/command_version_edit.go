@@ -7,6 +7,7 @@ import (
 	"github.com/urfave/cli/v3"
 
 	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/service"
 )
 
 func (f *commandFactory) newVersionEditCommand() *cli.Command {
@@ -16,10 +17,16 @@ func (f *commandFactory) newVersionEditCommand() *cli.Command {
 		ArgsUsage:     "<mod-id> <version-id>",
 		ShellComplete: f.makeShellComplete(f.modIDCompleter(), f.versionIDCompleter()),
 		Flags: []cli.Flag{
+			&cli.StringSliceFlag{Name: "game-version", Usage: "Replace supported game versions"},
+			&cli.BoolFlag{Name: "clear-game-versions", Usage: "Support every game version"},
 			&cli.StringSliceFlag{Name: "dependency", Usage: "Replace dependencies. Format: mod_id:version_id:type"},
 			&cli.StringSliceFlag{Name: "feature", Usage: "Replace features. Format: name=true|false"},
+			&cli.StringFlag{Name: "changelog", Usage: "Replace the changelog, in Markdown"},
+			&cli.StringFlag{Name: "changelog-file", Usage: "Replace the changelog with a Markdown file"},
+			&cli.StringFlag{Name: "released-at", Usage: "Updated release date as YYYY-MM-DD or RFC 3339"},
 			&cli.BoolFlag{Name: "set-latest", Usage: "Set this version as latest on the mod"},
 			&cli.BoolFlag{Name: "clear-latest-version", Usage: "Clear latest version on the mod"},
+			&cli.BoolFlag{Name: "skip-dependency-check", Usage: "Replace the dependencies even if they do not validate against the registry"},
 		},
 		DisableSliceFlagSeparator: true,
 		Action: func(ctx context.Context, cmd *cli.Command) error {
@@ -32,6 +39,9 @@ func (f *commandFactory) newVersionEditCommand() *cli.Command {
 			if cmd.Bool("set-latest") && cmd.Bool("clear-latest-version") {
 				return fmt.Errorf("set-latest and clear-latest-version cannot be used together")
 			}
+			if cmd.IsSet("game-version") && cmd.Bool("clear-game-versions") {
+				return fmt.Errorf("game-version and clear-game-versions cannot be used together")
+			}
 
 			repo, err := f.newRepository()
 			if err != nil {
@@ -41,10 +51,36 @@ func (f *commandFactory) newVersionEditCommand() *cli.Command {
 			modID := cmd.Args().Get(0)
 			versionID := cmd.Args().Get(1)
 			changed := false
+			// Checked before anything is edited, so a refused version is left as it was
+			var verDetails *model.ModVersionDetails
+			if cmd.Bool("set-latest") {
+				verDetails, err = repo.GetModVersionDetails(modID, versionID)
+				if err != nil {
+					return fmt.Errorf("failed to get version details: %w", err)
+				}
+				if verDetails.Status == model.VersionStatusYanked {
+					return fmt.Errorf("%w: %s cannot be the latest version", service.ErrVersionYanked, versionID)
+				}
+			}
+			dependencies := model.DependencyArray(parseDependencies(cmd.StringSlice("dependency")))
+			if cmd.IsSet("dependency") && !cmd.Bool("skip-dependency-check") {
+				if err := checkDependencies(repo, modID, versionID, dependencies); err != nil {
+					return err
+				}
+			}
 
+			if cmd.Bool("clear-game-versions") || cmd.IsSet("game-version") {
+				updates := map[string]any{
+					"game_versions": parseGameVersions(cmd.StringSlice("game-version")),
+				}
+				if err := repo.UpdateModVersionFields(modID, versionID, updates); err != nil {
+					return err
+				}
+				changed = true
+			}
 			if cmd.IsSet("dependency") {
 				updates := map[string]any{
-					"dependencies": model.DependencyArray(parseDependencies(cmd.StringSlice("dependency"))),
+					"dependencies": dependencies,
 				}
 				if err := repo.UpdateModVersionFields(modID, versionID, updates); err != nil {
 					return err
@@ -61,11 +97,28 @@ func (f *commandFactory) newVersionEditCommand() *cli.Command {
 				changed = true
 			}
 
-			if cmd.Bool("set-latest") {
-				verDetails, err := repo.GetModVersionDetails(modID, versionID)
+			if cmd.IsSet("changelog") || cmd.IsSet("changelog-file") {
+				changelog, err := readChangelog(cmd)
 				if err != nil {
-					return fmt.Errorf("failed to get version details: %w", err)
+					return err
+				}
+				if err := repo.UpdateModVersionFields(modID, versionID, map[string]any{"changelog": changelog}); err != nil {
+					return err
+				}
+				changed = true
+			}
+			if cmd.IsSet("released-at") {
+				releasedAt, err := parseReleaseDate(cmd.String("released-at"))
+				if err != nil {
+					return err
+				}
+				if err := repo.UpdateModVersionFields(modID, versionID, map[string]any{"released_at": releasedAt}); err != nil {
+					return err
 				}
+				changed = true
+			}
+
+			if cmd.Bool("set-latest") {
 				update := &model.ModDetails{LatestVersionID: &verDetails.ID}
 				if err := repo.UpdateMod(modID, update); err != nil {
 					return fmt.Errorf("failed to update latest version: %w", err)
@@ -0,0 +1,67 @@
+package musmgr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+)
+
+func (f *commandFactory) newVersionYankCommand() *cli.Command {
+	return f.newVersionStatusCommand("yank", "Yank a mod version so it is only installed when pinned exactly", model.VersionStatusYanked)
+}
+
+func (f *commandFactory) newVersionDeprecateCommand() *cli.Command {
+	return f.newVersionStatusCommand("deprecate", "Deprecate a mod version", model.VersionStatusDeprecated)
+}
+
+func (f *commandFactory) newVersionRestoreCommand() *cli.Command {
+	return f.newVersionStatusCommand("restore", "Clear the yanked or deprecated state of a mod version", model.VersionStatusActive)
+}
+
+func (f *commandFactory) newVersionStatusCommand(name, usage string, status model.VersionStatus) *cli.Command {
+	var flags []cli.Flag
+	if status != model.VersionStatusActive {
+		flags = append(flags, &cli.StringFlag{Name: "reason", Usage: "Reason shown to users of the version", Required: true})
+	}
+	return &cli.Command{
+		Name:          name,
+		Usage:         usage,
+		ArgsUsage:     "<mod-id> <version-id>",
+		ShellComplete: f.makeShellComplete(f.modIDCompleter(), f.versionIDCompleter()),
+		Flags:         flags,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if err := requireDB(cmd); err != nil {
+				return err
+			}
+			if cmd.NArg() < 2 {
+				return fmt.Errorf("mod-id and version-id required")
+			}
+			reason := strings.TrimSpace(cmd.String("reason"))
+			if status != model.VersionStatusActive && reason == "" {
+				return fmt.Errorf("reason required")
+			}
+
+			repo, err := f.newRepository()
+			if err != nil {
+				return err
+			}
+
+			modID := cmd.Args().Get(0)
+			versionID := cmd.Args().Get(1)
+			if err := repo.SetModVersionStatus(modID, versionID, status, reason); err != nil {
+				return err
+			}
+			switch status {
+			case model.VersionStatusActive:
+				fmt.Printf("Restored version %s of mod %s\n", versionID, modID)
+			default:
+				fmt.Printf("Marked version %s of mod %s as %s: %s\n", versionID, modID, status, reason)
+			}
+			return nil
+		},
+	}
+}
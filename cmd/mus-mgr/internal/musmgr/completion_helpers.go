@@ -190,6 +190,7 @@ func (f *commandFactory) completeFlagValue(ctx context.Context, cmd *cli.Command
 		features := []string{
 			"direct_join=true",
 			"direct_join=false",
+			"preserve_paths=",
 		}
 		var matches []string
 		for _, feat := range features {
@@ -3,9 +3,6 @@ package musmgr
 import (
 	"fmt"
 
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-
 	gormrepo "github.com/ikafly144/au_mod_installer/server/repository/gorm"
 )
 
@@ -21,7 +18,7 @@ func (f *commandFactory) newRepository() (*gormrepo.GormRepository, error) {
 	if f == nil || f.dbURL == nil || *f.dbURL == "" {
 		return nil, fmt.Errorf("db URL not set")
 	}
-	db, err := gorm.Open(postgres.Open(*f.dbURL))
+	db, err := gormrepo.Open(*f.dbURL)
 	if err != nil {
 		return nil, err
 	}
@@ -3,18 +3,25 @@ package musmgr
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/urfave/cli/v3"
 	"golang.org/x/mod/semver"
 
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+	"github.com/ikafly144/au_mod_installer/server/service"
 )
 
 func parseFileFlag(val string) *parsedFile {
@@ -86,6 +93,111 @@ func parseFeatures(raw []string) map[string]any {
 	return features
 }
 
+func parseModType(raw string) (model.ModType, error) {
+	switch t := model.ModType(strings.ToLower(strings.TrimSpace(raw))); t {
+	case model.ModTypeMod, model.ModTypeLibrary:
+		return t, nil
+	default:
+		return "", fmt.Errorf("invalid mod type %q: must be mod or library", raw)
+	}
+}
+
+func parseGameVersions(raw []string) model.StringArray {
+	versions := model.StringArray{}
+	for _, item := range raw {
+		for v := range strings.SplitSeq(item, ",") {
+			if v = strings.TrimSpace(v); v != "" && !slices.Contains(versions, v) {
+				versions = append(versions, v)
+			}
+		}
+	}
+	return versions
+}
+
+// splitList splits repeated flags that may also hold comma separated values.
+func splitList(raw []string) []string {
+	var items []string
+	for _, item := range raw {
+		for v := range strings.SplitSeq(item, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				items = append(items, v)
+			}
+		}
+	}
+	return items
+}
+
+func parseTags(raw []string) (model.StringArray, error) {
+	tags := restmodel.NormalizeTags(splitList(raw))
+	if err := restmodel.ValidateTags(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func parseLanguages(raw []string) (model.StringArray, error) {
+	languages := model.StringArray(splitList(raw))
+	if languages == nil {
+		languages = model.StringArray{}
+	}
+	if err := restmodel.ValidateLanguages(languages); err != nil {
+		return nil, err
+	}
+	return languages, nil
+}
+
+// modLinkFlags maps the link flags of mod add and mod edit to their columns.
+var modLinkFlags = []struct{ flag, column string }{
+	{"homepage-url", "link_homepage"},
+	{"source-url", "link_source"},
+	{"issues-url", "link_issues"},
+	{"discord-url", "link_discord"},
+}
+
+func parseModLinks(cmd *cli.Command) (model.ModLinks, error) {
+	links := model.ModLinks{
+		Homepage: cmd.String("homepage-url"),
+		Source:   cmd.String("source-url"),
+		Issues:   cmd.String("issues-url"),
+		Discord:  cmd.String("discord-url"),
+	}
+	if err := restmodel.ModLinks(links).Validate(); err != nil {
+		return model.ModLinks{}, err
+	}
+	return links, nil
+}
+
+// readChangelog reads the changelog of a version from --changelog or the file given by --changelog-file.
+func readChangelog(cmd *cli.Command) (string, error) {
+	if cmd.IsSet("changelog") && cmd.IsSet("changelog-file") {
+		return "", fmt.Errorf("changelog and changelog-file cannot be used together")
+	}
+	changelog := cmd.String("changelog")
+	if cmd.IsSet("changelog-file") {
+		data, err := os.ReadFile(cmd.String("changelog-file"))
+		if err != nil {
+			return "", fmt.Errorf("failed to read changelog: %w", err)
+		}
+		changelog = string(data)
+	}
+	if err := restmodel.ValidateChangelog(changelog); err != nil {
+		return "", err
+	}
+	return changelog, nil
+}
+
+// parseReleaseDate accepts an RFC 3339 time or a plain date, which is taken as midnight UTC.
+func parseReleaseDate(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid release date %q: use YYYY-MM-DD or RFC 3339", raw)
+	}
+	return t, nil
+}
+
 func nextVersionID(existingIDs []string) string {
 	highest := ""
 	for _, id := range existingIDs {
@@ -147,6 +259,20 @@ func parseDependencies(rawDeps []string) []model.ModVersionDependency {
 	return deps
 }
 
+// checkDependencies validates dependencies like the write API does and prints every problem found.
+func checkDependencies(repo repository.ModRepository, modID, versionID string, deps model.DependencyArray) error {
+	err := service.NewModService(repo).ValidateDependencies(modID, versionID, deps)
+	var depErr *service.DependencyError
+	if !errors.As(err, &depErr) {
+		return err
+	}
+	fmt.Println("Dependency check failed:")
+	for _, problem := range depErr.Problems {
+		fmt.Printf("  %s\n", problem)
+	}
+	return fmt.Errorf("invalid dependencies (use --skip-dependency-check to ignore)")
+}
+
 func fileMetadataFromParsedFile(pf *parsedFile) (filename string, size int64, hash string, err error) {
 	hasher := sha256.New()
 
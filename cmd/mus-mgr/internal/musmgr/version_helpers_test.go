@@ -2,6 +2,7 @@ package musmgr
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -89,3 +90,39 @@ func TestParseFileFlag(t *testing.T) {
 		assert.Equal(t, "plugins/mod.dll", *pf.ExtractPath)
 	})
 }
+
+func TestParseModType(t *testing.T) {
+	modType, err := parseModType(" Library ")
+	assert.NoError(t, err)
+	assert.Equal(t, model.ModTypeLibrary, modType)
+
+	_, err = parseModType("modpack")
+	assert.Error(t, err)
+}
+
+func TestParseGameVersions(t *testing.T) {
+	assert.Equal(t, model.StringArray{"2025.3.25", "2025.4.15"}, parseGameVersions([]string{"2025.3.25, 2025.4.15", "2025.3.25"}))
+	assert.Empty(t, parseGameVersions(nil))
+}
+
+func TestParseTags(t *testing.T) {
+	tags, err := parseTags([]string{"Roles, cosmetics", "roles"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.StringArray{"roles", "cosmetics"}, tags)
+
+	_, err = parseTags([]string{"quality of life"})
+	assert.Error(t, err)
+}
+
+func TestParseReleaseDate(t *testing.T) {
+	date, err := parseReleaseDate("2025-03-01")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), date)
+
+	date, err = parseReleaseDate("2025-03-01T12:00:00+09:00")
+	assert.NoError(t, err)
+	assert.True(t, date.Equal(time.Date(2025, 3, 1, 3, 0, 0, 0, time.UTC)))
+
+	_, err = parseReleaseDate("March 1")
+	assert.Error(t, err)
+}
@@ -11,12 +11,31 @@ var (
 	EndpointGetModList          = NewEndpoint("GET", "/mods")
 	EndpointGetModDetail        = NewEndpoint("GET", "/mod/:mod_id")
 	EndpointGetModThumbnail     = NewEndpoint("GET", "/mod/:mod_id/thumbnail")
+	EndpointGetModGallery       = NewEndpoint("GET", "/mod/:mod_id/gallery")
+	EndpointGetModImage         = NewEndpoint("GET", "/mod/:mod_id/image/:image_id")
 	EndpointGetModVersionList   = NewEndpoint("GET", "/mod/:mod_id/versions")
 	EndpointGetModVersionDetail = NewEndpoint("GET", "/mod/:mod_id/version/:version_id")
+	EndpointGetModsBatch        = NewEndpoint("POST", "/mods/batch")
+	EndpointGetModVersionsBatch = NewEndpoint("POST", "/mods/versions/batch")
+	EndpointCheckModUpdates     = NewEndpoint("POST", "/mods/updates")
+	EndpointCreateMod           = NewEndpoint("POST", "/mods")
+	EndpointUpdateMod           = NewEndpoint("PATCH", "/mod/:mod_id")
+	EndpointDeleteMod           = NewEndpoint("DELETE", "/mod/:mod_id")
+	EndpointSetModThumbnail     = NewEndpoint("PUT", "/mod/:mod_id/thumbnail")
+	EndpointAddModImage         = NewEndpoint("POST", "/mod/:mod_id/gallery")
+	EndpointDeleteModImage      = NewEndpoint("DELETE", "/mod/:mod_id/image/:image_id")
+	EndpointCreateModVersion    = NewEndpoint("POST", "/mod/:mod_id/versions")
+	EndpointUpdateModVersion    = NewEndpoint("PATCH", "/mod/:mod_id/version/:version_id")
+	EndpointDeleteModVersion    = NewEndpoint("DELETE", "/mod/:mod_id/version/:version_id")
+	EndpointSetModVersionStatus = NewEndpoint("PUT", "/mod/:mod_id/version/:version_id/status")
+	EndpointCreateModFile       = NewEndpoint("POST", "/mod/:mod_id/version/:version_id/files")
+	EndpointDeleteModFile       = NewEndpoint("DELETE", "/mod/:mod_id/version/:version_id/file/:file_id")
+	EndpointGetMirroredFile     = NewEndpoint("GET", "/file/:file_id")
 	EndpointShareGame           = NewEndpoint("POST", "/share_game")
 	EndpointUpdateShareGame     = NewEndpoint("PUT", "/share_game")
 	EndpointDeleteShareGame     = NewEndpoint("DELETE", "/share_game")
 	EndpointJoinGame            = NewEndpoint("GET", "/join_game")
+	EndpointGetSharedAupack     = NewEndpoint("GET", "/aupack/:sha256")
 	EndpointGetVersionInfo      = NewEndpoint("GET", "/version_info")
 )
 
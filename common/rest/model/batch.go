@@ -0,0 +1,94 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxBatchSize is the most mods or versions a single batch request may ask for.
+const MaxBatchSize = 100
+
+// ModBatchRequest asks for the details of several mods at once.
+type ModBatchRequest struct {
+	IDs []string `json:"ids"`
+}
+
+func (r ModBatchRequest) Validate() error {
+	if err := validateBatchSize(len(r.IDs)); err != nil {
+		return err
+	}
+	for _, id := range r.IDs {
+		if strings.TrimSpace(id) == "" {
+			return errors.New("mod ID is required")
+		}
+	}
+	return nil
+}
+
+// ModBatchResult holds the details of the requested mods in request order. A mod that does not exist is null.
+type ModBatchResult struct {
+	Mods []*ModDetails `json:"mods"`
+}
+
+// ModVersionRef names a version of a mod. An empty VersionID refers to the latest version.
+type ModVersionRef struct {
+	ModID     string `json:"mod_id"`
+	VersionID string `json:"version_id,omitempty"`
+}
+
+// ModVersionBatchRequest asks for several mod versions at once.
+type ModVersionBatchRequest struct {
+	Versions []ModVersionRef `json:"versions"`
+}
+
+func (r ModVersionBatchRequest) Validate() error {
+	if err := validateBatchSize(len(r.Versions)); err != nil {
+		return err
+	}
+	for _, ref := range r.Versions {
+		if strings.TrimSpace(ref.ModID) == "" {
+			return errors.New("mod ID is required")
+		}
+	}
+	return nil
+}
+
+// ModVersionBatchResult holds the requested versions in request order. A version that does not exist is null.
+type ModVersionBatchResult struct {
+	Versions []*ModVersionDetails `json:"versions"`
+}
+
+// ModUpdateCheckRequest maps the ID of each installed mod to its installed version.
+type ModUpdateCheckRequest struct {
+	Installed map[string]string `json:"installed"`
+}
+
+func (r ModUpdateCheckRequest) Validate() error {
+	if err := validateBatchSize(len(r.Installed)); err != nil {
+		return err
+	}
+	for modID := range r.Installed {
+		if strings.TrimSpace(modID) == "" {
+			return errors.New("mod ID is required")
+		}
+	}
+	return nil
+}
+
+// ModUpdateCheckResult holds the latest version of every installed mod whose latest version differs from the installed one.
+type ModUpdateCheckResult struct {
+	Updates map[string]ModVersionDetails `json:"updates"`
+	// Missing lists the installed mods that do not exist or have no versions.
+	Missing []string `json:"missing,omitempty"`
+}
+
+func validateBatchSize(n int) error {
+	switch {
+	case n == 0:
+		return errors.New("batch is empty")
+	case n > MaxBatchSize:
+		return fmt.Errorf("batch of %d exceeds the limit of %d", n, MaxBatchSize)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// ImageSize names a variant of a hosted image. Clients request the smallest size that fits what they show.
+type ImageSize string
+
+const (
+	// ImageSizeIcon is a 64x64 square crop for profile icons.
+	ImageSizeIcon ImageSize = "icon"
+	// ImageSizeList is a 256x256 square crop for mod lists.
+	ImageSizeList ImageSize = "list"
+	// ImageSizeDetail fits within 1280x720 without cropping, for detail views and galleries.
+	ImageSizeDetail ImageSize = "detail"
+)
+
+// ImageSizes lists every variant generated for a hosted image.
+var ImageSizes = []ImageSize{ImageSizeIcon, ImageSizeList, ImageSizeDetail}
+
+// ParseImageSize parses the size query of the image endpoints. An empty size is ImageSizeDetail, the closest to the
+// original image that older clients received.
+func ParseImageSize(raw string) (ImageSize, error) {
+	switch size := ImageSize(raw); size {
+	case "":
+		return ImageSizeDetail, nil
+	case ImageSizeIcon, ImageSizeList, ImageSizeDetail:
+		return size, nil
+	}
+	return "", fmt.Errorf("unknown image size %q", raw)
+}
+
+const (
+	// MaxGalleryImages bounds the gallery of a mod.
+	MaxGalleryImages = 16
+	// MaxImageCaptionLength bounds the caption of a gallery image, in characters.
+	MaxImageCaptionLength = 200
+)
+
+// ModImage is an image hosted for a mod, the thumbnail or one in its gallery.
+type ModImage struct {
+	ID      string `json:"id"`
+	Caption string `json:"caption,omitempty"`
+	// Width and Height are of the detail variant.
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ModGallery lists the gallery of a mod in display order.
+type ModGallery struct {
+	Images []ModImage `json:"images"`
+}
+
+// ModImageRequest asks the server to fetch an image from URL, as an alternative to uploading the image itself.
+type ModImageRequest struct {
+	URL     string `json:"url"`
+	Caption string `json:"caption,omitempty"`
+}
+
+func (r ModImageRequest) Validate() error {
+	if r.URL == "" {
+		return errors.New("url is required")
+	}
+	if err := validateHTTPURL("url", r.URL); err != nil {
+		return err
+	}
+	return ValidateImageCaption(r.Caption)
+}
+
+func ValidateImageCaption(caption string) error {
+	if utf8.RuneCountInString(caption) > MaxImageCaptionLength {
+		return fmt.Errorf("caption must be at most %d characters", MaxImageCaptionLength)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// MaxModTags bounds the tags of a mod.
+	MaxModTags = 10
+	// MaxModTagLength bounds a tag, in characters.
+	MaxModTagLength = 32
+	// MaxLicenseLength bounds the SPDX license expression of a mod.
+	MaxLicenseLength = 128
+	// MaxChangelogLength bounds the changelog of a version, in bytes.
+	MaxChangelogLength = 64 << 10
+)
+
+// ModLinks are the places users can find out more about a mod. Every link is optional.
+type ModLinks struct {
+	Homepage string `json:"homepage,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Issues   string `json:"issues,omitempty"`
+	Discord  string `json:"discord,omitempty"`
+}
+
+func (l ModLinks) Validate() error {
+	for _, link := range []struct{ field, url string }{
+		{"links.homepage", l.Homepage},
+		{"links.source", l.Source},
+		{"links.issues", l.Issues},
+		{"links.discord", l.Discord},
+	} {
+		if link.url == "" {
+			continue
+		}
+		if err := validateHTTPURL(link.field, link.url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NormalizeTags lowercases and trims tags, dropping empty and repeated ones.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// ValidateTags checks that tags are short lowercase words joined by '-', like "roles" or "quality-of-life".
+func ValidateTags(tags []string) error {
+	if len(tags) > MaxModTags {
+		return fmt.Errorf("a mod may have at most %d tags", MaxModTags)
+	}
+	for i, tag := range tags {
+		if tag == "" || len(tag) > MaxModTagLength {
+			return fmt.Errorf("tag %q must be 1 to %d characters", tag, MaxModTagLength)
+		}
+		for _, r := range tag {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("tag %q may only contain lowercase letters, digits and '-'", tag)
+			}
+		}
+		if slices.Contains(tags[:i], tag) {
+			return fmt.Errorf("duplicate tag %q", tag)
+		}
+	}
+	return nil
+}
+
+// ValidateLanguages checks that languages are BCP 47 tags such as "en", "ja" or "pt-BR".
+func ValidateLanguages(languages []string) error {
+	for i, lang := range languages {
+		subtags := strings.Split(lang, "-")
+		if n := len(subtags[0]); n < 2 || n > 3 || !isASCIIAlnum(subtags[0], false) {
+			return fmt.Errorf("invalid language tag %q", lang)
+		}
+		for _, subtag := range subtags[1:] {
+			if subtag == "" || len(subtag) > 8 || !isASCIIAlnum(subtag, true) {
+				return fmt.Errorf("invalid language tag %q", lang)
+			}
+		}
+		if slices.ContainsFunc(languages[:i], func(l string) bool { return strings.EqualFold(l, lang) }) {
+			return fmt.Errorf("duplicate language %q", lang)
+		}
+	}
+	return nil
+}
+
+func isASCIIAlnum(s string, digits bool) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || digits && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateLicense checks the syntax of an SPDX license expression such as "MIT", "GPL-3.0-or-later" or
+// "(MIT OR Apache-2.0) AND LicenseRef-Assets". License identifiers are not checked against the SPDX list.
+func ValidateLicense(expr string) error {
+	if len(expr) > MaxLicenseLength {
+		return fmt.Errorf("license must be at most %d characters", MaxLicenseLength)
+	}
+	p := licenseParser{tokens: strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr))}
+	if len(p.tokens) == 0 {
+		return errors.New("license must not be empty")
+	}
+	if err := p.expression(); err != nil {
+		return fmt.Errorf("invalid license expression %q: %w", expr, err)
+	}
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("invalid license expression %q: unexpected %q", expr, p.tokens[p.pos])
+	}
+	return nil
+}
+
+// licenseParser parses the grammar of SPDX license expressions, where WITH binds tighter than AND, and AND tighter
+// than OR.
+type licenseParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *licenseParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token
+}
+
+func (p *licenseParser) accept(token string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos] == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *licenseParser) expression() error {
+	for {
+		if err := p.and(); err != nil {
+			return err
+		}
+		if !p.accept("OR") {
+			return nil
+		}
+	}
+}
+
+func (p *licenseParser) and() error {
+	for {
+		if err := p.with(); err != nil {
+			return err
+		}
+		if !p.accept("AND") {
+			return nil
+		}
+	}
+}
+
+func (p *licenseParser) with() error {
+	if p.accept("(") {
+		if err := p.expression(); err != nil {
+			return err
+		}
+		if !p.accept(")") {
+			return errors.New("missing ')'")
+		}
+		return nil
+	}
+	if err := licenseID(p.next(), true); err != nil {
+		return err
+	}
+	if p.accept("WITH") {
+		return licenseID(p.next(), false)
+	}
+	return nil
+}
+
+func licenseID(token string, allowPlus bool) error {
+	if allowPlus {
+		token = strings.TrimSuffix(token, "+")
+	}
+	if token == "" {
+		return errors.New("missing license identifier")
+	}
+	if slices.Contains([]string{"AND", "OR", "WITH", "(", ")"}, token) {
+		return fmt.Errorf("unexpected %q", token)
+	}
+	for _, r := range token {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == ':') {
+			return fmt.Errorf("invalid license identifier %q", token)
+		}
+	}
+	return nil
+}
+
+// ValidateChangelog bounds the Markdown changelog of a version.
+func ValidateChangelog(changelog string) error {
+	if len(changelog) > MaxChangelogLength {
+		return fmt.Errorf("changelog must be at most %d bytes", MaxChangelogLength)
+	}
+	return nil
+}
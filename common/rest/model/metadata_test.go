@@ -0,0 +1,47 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLicense(t *testing.T) {
+	for _, expr := range []string{
+		"MIT",
+		"GPL-3.0-or-later",
+		"GPL-2.0+",
+		"MIT OR Apache-2.0",
+		"(MIT OR Apache-2.0) AND LicenseRef-Assets",
+		"GPL-3.0-only WITH Classpath-exception-2.0",
+		"((MIT))",
+	} {
+		assert.NoError(t, ValidateLicense(expr), expr)
+	}
+	for _, expr := range []string{
+		"",
+		"MIT OR",
+		"AND MIT",
+		"(MIT",
+		"MIT)",
+		"MIT Apache-2.0",
+		"GPL-3.0 WITH",
+		"MIT/Apache-2.0",
+		strings.Repeat("MIT OR ", 20) + "MIT",
+	} {
+		assert.Error(t, ValidateLicense(expr), expr)
+	}
+}
+
+func TestValidateTagsAndLanguages(t *testing.T) {
+	assert.NoError(t, ValidateTags([]string{"roles", "quality-of-life"}))
+	assert.Error(t, ValidateTags([]string{"Roles"}))
+	assert.Error(t, ValidateTags([]string{"roles", "roles"}))
+	assert.Equal(t, []string{"roles", "cosmetics"}, NormalizeTags([]string{" Roles", "", "cosmetics", "ROLES"}))
+
+	assert.NoError(t, ValidateLanguages([]string{"en", "ja", "pt-BR", "zh-Hant"}))
+	assert.Error(t, ValidateLanguages([]string{"english"}))
+	assert.Error(t, ValidateLanguages([]string{"en-"}))
+	assert.Error(t, ValidateLanguages([]string{"en", "EN"}))
+}
@@ -10,10 +10,19 @@ type ModListResult struct {
 }
 
 type ModDetails struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Author      string `json:"author"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Author      string  `json:"author"`
+	Type        ModType `json:"type,omitempty"`
+
+	// Tags are lowercase categories such as "roles" or "cosmetics".
+	Tags  []string `json:"tags,omitempty"`
+	Links ModLinks `json:"links,omitzero"`
+	// License is an SPDX license expression such as "GPL-3.0-only" or "MIT OR Apache-2.0".
+	License string `json:"license,omitempty"`
+	// Languages are the BCP 47 tags of the languages the mod supports, such as "en" or "ja".
+	Languages []string `json:"languages,omitempty"`
 
 	LatestVersionID string `json:"latest_version,omitempty"`
 
@@ -21,6 +30,25 @@ type ModDetails struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type ModType string
+
+const (
+	ModTypeMod     ModType = "mod"
+	ModTypeLibrary ModType = "library"
+	// Deprecated: should not be used anymore
+	// ModPack will be represented as profile.Profile now
+	ModTypeModPack ModType = "modpack"
+)
+
+func (mt ModType) IsVisible() bool {
+	switch mt {
+	case ModTypeMod, ModTypeModPack:
+		return true
+	default:
+		return false
+	}
+}
+
 type ModVersionListResult struct {
 	IDs []string `json:"ids"`
 }
@@ -35,10 +63,39 @@ type ModVersionDetails struct {
 	Dependencies []ModVersionDependency `json:"dependencies,omitempty"`
 	Features     map[string]any         `json:"features,omitempty"`
 
+	Status VersionStatus `json:"status,omitempty"`
+	// StatusReason explains to users why the version was yanked or deprecated.
+	StatusReason string `json:"status_reason,omitempty"`
+
+	// Changelog describes the changes of the version in Markdown.
+	Changelog string `json:"changelog,omitempty"`
+	// ReleasedAt is when the version was released, which may be before it was added to the registry. It is zero for
+	// versions added before release dates were recorded.
+	ReleasedAt time.Time `json:"released_at,omitzero"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+func (v ModVersionDetails) IsYanked() bool {
+	return v.Status == VersionStatusYanked
+}
+
+func (v ModVersionDetails) IsDeprecated() bool {
+	return v.Status == VersionStatusDeprecated
+}
+
+// VersionStatus marks a version that should no longer be used. Versions without a status are active.
+type VersionStatus string
+
+const (
+	VersionStatusActive VersionStatus = ""
+	// Deprecated versions are still resolved, but users are warned about them.
+	VersionStatusDeprecated VersionStatus = "deprecated"
+	// Yanked versions can only be installed by their exact version ID.
+	VersionStatusYanked VersionStatus = "yanked"
+)
+
 type ModVersionFile struct {
 	ID string `json:"id"`
 
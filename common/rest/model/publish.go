@@ -0,0 +1,280 @@
+package model
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ModCreateRequest creates a mod through the write API. The ID must be covered by the API token.
+type ModCreateRequest struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Description  string  `json:"description,omitempty"`
+	Author       string  `json:"author"`
+	Type         ModType `json:"type,omitempty"`
+	ThumbnailURL string  `json:"thumbnail_url,omitempty"`
+
+	Tags      []string `json:"tags,omitempty"`
+	Links     ModLinks `json:"links,omitzero"`
+	License   string   `json:"license,omitempty"`
+	Languages []string `json:"languages,omitempty"`
+}
+
+func (r ModCreateRequest) Validate() error {
+	if err := ValidateModID(r.ID); err != nil {
+		return err
+	}
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(r.Author) == "" {
+		return errors.New("author is required")
+	}
+	if err := validatePublishedModType(r.Type); err != nil {
+		return err
+	}
+	if r.ThumbnailURL != "" {
+		if err := validateHTTPURL("thumbnail_url", r.ThumbnailURL); err != nil {
+			return err
+		}
+	}
+	return validateModMetadata(r.Tags, r.Links, r.License, r.Languages)
+}
+
+// ModUpdateRequest changes the fields of a mod that are set.
+type ModUpdateRequest struct {
+	Name         *string  `json:"name,omitzero"`
+	Description  *string  `json:"description,omitzero"`
+	Author       *string  `json:"author,omitzero"`
+	Type         *ModType `json:"type,omitzero"`
+	ThumbnailURL *string  `json:"thumbnail_url,omitzero"`
+	// LatestVersion is the version ID to mark as the latest version.
+	LatestVersion *string `json:"latest_version,omitzero"`
+	// Tags, Links and Languages replace the previous values as a whole. An empty License or Links removes them.
+	Tags      *[]string `json:"tags,omitzero"`
+	Links     *ModLinks `json:"links,omitzero"`
+	License   *string   `json:"license,omitzero"`
+	Languages *[]string `json:"languages,omitzero"`
+}
+
+func (r ModUpdateRequest) Validate() error {
+	if r == (ModUpdateRequest{}) {
+		return errors.New("no fields to update")
+	}
+	if r.Name != nil && strings.TrimSpace(*r.Name) == "" {
+		return errors.New("name must not be empty")
+	}
+	if r.Author != nil && strings.TrimSpace(*r.Author) == "" {
+		return errors.New("author must not be empty")
+	}
+	if r.Type != nil {
+		if err := validatePublishedModType(*r.Type); err != nil {
+			return err
+		}
+	}
+	if r.ThumbnailURL != nil {
+		if err := validateHTTPURL("thumbnail_url", *r.ThumbnailURL); err != nil {
+			return err
+		}
+	}
+	if r.LatestVersion != nil && strings.TrimSpace(*r.LatestVersion) == "" {
+		return errors.New("latest_version must not be empty")
+	}
+	if r.Tags != nil {
+		if err := ValidateTags(*r.Tags); err != nil {
+			return err
+		}
+	}
+	if r.Links != nil {
+		if err := r.Links.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.License != nil && *r.License != "" {
+		if err := ValidateLicense(*r.License); err != nil {
+			return err
+		}
+	}
+	if r.Languages != nil {
+		return ValidateLanguages(*r.Languages)
+	}
+	return nil
+}
+
+// ModVersionCreateRequest publishes a version of a mod together with its files.
+type ModVersionCreateRequest struct {
+	VersionID    string                 `json:"version_id"`
+	GameVersions []string               `json:"game_versions,omitempty"`
+	Files        []ModVersionFile       `json:"files,omitempty"`
+	Dependencies []ModVersionDependency `json:"dependencies,omitempty"`
+	Features     map[string]any         `json:"features,omitempty"`
+	Changelog    string                 `json:"changelog,omitempty"`
+	// ReleasedAt defaults to the time the version is published.
+	ReleasedAt time.Time `json:"released_at,omitzero"`
+	// SetLatest marks the version as the latest version of the mod.
+	SetLatest bool `json:"set_latest,omitempty"`
+}
+
+func (r ModVersionCreateRequest) Validate() error {
+	if strings.TrimSpace(r.VersionID) == "" {
+		return errors.New("version_id is required")
+	}
+	for _, file := range r.Files {
+		if err := ValidatePublishedFile(file); err != nil {
+			return err
+		}
+	}
+	if err := ValidateChangelog(r.Changelog); err != nil {
+		return err
+	}
+	return validatePublishedDependencies(r.Dependencies)
+}
+
+// ModVersionUpdateRequest changes the fields of a version that are set.
+type ModVersionUpdateRequest struct {
+	GameVersions *[]string               `json:"game_versions,omitzero"`
+	Dependencies *[]ModVersionDependency `json:"dependencies,omitzero"`
+	Features     map[string]any          `json:"features,omitempty"`
+	// Changelog replaces the changelog of the version. An empty changelog removes it.
+	Changelog  *string    `json:"changelog,omitzero"`
+	ReleasedAt *time.Time `json:"released_at,omitzero"`
+}
+
+func (r ModVersionUpdateRequest) Validate() error {
+	if r.GameVersions == nil && r.Dependencies == nil && r.Features == nil && r.Changelog == nil && r.ReleasedAt == nil {
+		return errors.New("no fields to update")
+	}
+	if r.Changelog != nil {
+		if err := ValidateChangelog(*r.Changelog); err != nil {
+			return err
+		}
+	}
+	if r.ReleasedAt != nil && r.ReleasedAt.IsZero() {
+		return errors.New("released_at must not be empty")
+	}
+	if r.Dependencies != nil {
+		return validatePublishedDependencies(*r.Dependencies)
+	}
+	return nil
+}
+
+// ModVersionStatusRequest yanks, deprecates or restores a version. Restoring clears the reason.
+type ModVersionStatusRequest struct {
+	Status VersionStatus `json:"status"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+func (r ModVersionStatusRequest) Validate() error {
+	switch r.Status {
+	case VersionStatusActive:
+		return nil
+	case VersionStatusDeprecated, VersionStatusYanked:
+		if strings.TrimSpace(r.Reason) == "" {
+			return fmt.Errorf("a reason is required to mark a version %s", r.Status)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid version status %q", r.Status)
+}
+
+// ValidateModID checks that a mod ID is safe to use in URLs and does not collide with the IDs of local mods.
+func ValidateModID(id string) error {
+	if id == "" {
+		return errors.New("mod ID is required")
+	}
+	if len(id) > 64 {
+		return errors.New("mod ID must be at most 64 characters")
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return fmt.Errorf("mod ID %q may only contain letters, digits, '-', '_' and '.'", id)
+		}
+	}
+	// Mods added from local files use this prefix in the client
+	if strings.HasPrefix(id, "local-") {
+		return fmt.Errorf("mod ID %q must not start with \"local-\"", id)
+	}
+	return nil
+}
+
+// ValidatePublishedFile checks a file uploaded through the write API. Files are hosted elsewhere, so at least one
+// download URL and the sha256 hash are required for the client to verify what it downloads.
+func ValidatePublishedFile(file ModVersionFile) error {
+	if file.Filename == "" || strings.ContainsAny(file.Filename, `/\`) {
+		return fmt.Errorf("invalid filename %q", file.Filename)
+	}
+	switch file.ContentType {
+	case ContentTypeArchive, ContentTypePluginDll, ContentTypeBinary:
+	default:
+		return fmt.Errorf("file %s: invalid content type %q", file.Filename, file.ContentType)
+	}
+	switch file.TargetPlatform {
+	case "", TargetPlatformAny, TargetPlatformX64, TargetPlatformX86, TargetPlatformAArch64:
+	default:
+		return fmt.Errorf("file %s: invalid target platform %q", file.Filename, file.TargetPlatform)
+	}
+	if file.Size <= 0 {
+		return fmt.Errorf("file %s: size is required", file.Filename)
+	}
+	if sum, err := hex.DecodeString(file.Hashes["sha256"]); err != nil || len(sum) != 32 {
+		return fmt.Errorf("file %s: a sha256 hash is required", file.Filename)
+	}
+	if len(file.Downloads) == 0 {
+		return fmt.Errorf("file %s: a download URL is required", file.Filename)
+	}
+	for _, download := range file.Downloads {
+		if err := validateHTTPURL("file "+file.Filename+" download", download); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePublishedDependencies(deps []ModVersionDependency) error {
+	for _, dep := range deps {
+		if dep.ModID == "" {
+			return errors.New("dependency mod_id is required")
+		}
+		switch dep.DependencyType {
+		case "", DependencyTypeRequired, DependencyTypeOptional, DependencyTypeConflict, DependencyTypeEmbedded:
+		default:
+			return fmt.Errorf("dependency %s: invalid dependency type %q", dep.ModID, dep.DependencyType)
+		}
+	}
+	return nil
+}
+
+func validateModMetadata(tags []string, links ModLinks, license string, languages []string) error {
+	if err := ValidateTags(tags); err != nil {
+		return err
+	}
+	if err := links.Validate(); err != nil {
+		return err
+	}
+	if license != "" {
+		if err := ValidateLicense(license); err != nil {
+			return err
+		}
+	}
+	return ValidateLanguages(languages)
+}
+
+func validatePublishedModType(t ModType) error {
+	switch t {
+	case "", ModTypeMod, ModTypeLibrary:
+		return nil
+	}
+	return fmt.Errorf("invalid mod type %q", t)
+}
+
+func validateHTTPURL(field, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s must be an http or https URL", field)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+type ModSort string
+
+const (
+	// ModSortCreatedAt lists the newest mods first. It is the default order.
+	ModSortCreatedAt ModSort = "created_at"
+	// ModSortUpdatedAt lists the most recently updated mods first.
+	ModSortUpdatedAt ModSort = "updated_at"
+	// ModSortName lists mods alphabetically by name.
+	ModSortName ModSort = "name"
+)
+
+// ModSearchQuery filters and orders the mod list. The zero value lists every mod, newest first.
+type ModSearchQuery struct {
+	// Query is split into words, and each word must appear in the name, description or author of a mod.
+	Query string  `json:"q,omitempty"`
+	Type  ModType `json:"type,omitempty"`
+
+	// The version filters apply to the latest version of each mod.
+	GameVersion    string         `json:"game_version,omitempty"`
+	TargetPlatform TargetPlatform `json:"target_platform,omitempty"`
+	// Features lists feature flags the latest version must enable, e.g. "direct_join".
+	Features []string `json:"feature,omitempty"`
+
+	Sort ModSort `json:"sort,omitempty"`
+}
+
+// ParseModSearchQuery reads a search query from the parameters of a mod list request.
+func ParseModSearchQuery(values url.Values) (ModSearchQuery, error) {
+	q := ModSearchQuery{
+		Query:          strings.TrimSpace(values.Get("q")),
+		Type:           ModType(values.Get("type")),
+		GameVersion:    strings.TrimSpace(values.Get("game_version")),
+		TargetPlatform: TargetPlatform(values.Get("target_platform")),
+		Sort:           ModSort(values.Get("sort")),
+	}
+	for _, feature := range values["feature"] {
+		for f := range strings.SplitSeq(feature, ",") {
+			if f = strings.TrimSpace(f); f != "" && !slices.Contains(q.Features, f) {
+				q.Features = append(q.Features, f)
+			}
+		}
+	}
+
+	switch q.Type {
+	case "", ModTypeMod, ModTypeLibrary, ModTypeModPack:
+	default:
+		return ModSearchQuery{}, fmt.Errorf("invalid mod type %q", q.Type)
+	}
+	switch q.TargetPlatform {
+	case "", TargetPlatformAny, TargetPlatformX64, TargetPlatformX86, TargetPlatformAArch64:
+	default:
+		return ModSearchQuery{}, fmt.Errorf("invalid target platform %q", q.TargetPlatform)
+	}
+	switch q.Sort {
+	case "", ModSortCreatedAt, ModSortUpdatedAt, ModSortName:
+	default:
+		return ModSearchQuery{}, fmt.Errorf("invalid sort %q", q.Sort)
+	}
+	return q, nil
+}
+
+// Values encodes the query as request parameters, omitting unset fields.
+func (q ModSearchQuery) Values() url.Values {
+	values := make(url.Values)
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set("q", q.Query)
+	set("type", string(q.Type))
+	set("game_version", q.GameVersion)
+	set("target_platform", string(q.TargetPlatform))
+	set("sort", string(q.Sort))
+	for _, feature := range q.Features {
+		values.Add("feature", feature)
+	}
+	return values
+}
+
+// Terms returns the lowercased words of Query.
+func (q ModSearchQuery) Terms() []string {
+	return strings.Fields(strings.ToLower(q.Query))
+}
+
+// HasVersionFilters reports whether the query filters on the latest version of each mod.
+func (q ModSearchQuery) HasVersionFilters() bool {
+	return q.GameVersion != "" || (q.TargetPlatform != "" && q.TargetPlatform != TargetPlatformAny) || len(q.Features) > 0
+}
+
+// MatchesMod reports whether the mod matches the text and type filters.
+func (q ModSearchQuery) MatchesMod(mod ModDetails) bool {
+	if q.Type != "" && mod.Type != q.Type && !(q.Type == ModTypeMod && mod.Type == "") {
+		return false
+	}
+	text := strings.ToLower(mod.Name + "\n" + mod.Description + "\n" + mod.Author)
+	for _, term := range q.Terms() {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesVersion reports whether the version matches the version filters.
+// A version without game versions supports every game version, and one without files supports every platform.
+func (q ModSearchQuery) MatchesVersion(version ModVersionDetails) bool {
+	if q.GameVersion != "" && len(version.GameVersions) > 0 && !slices.Contains(version.GameVersions, q.GameVersion) {
+		return false
+	}
+	if q.TargetPlatform != "" && q.TargetPlatform != TargetPlatformAny && len(version.Files) > 0 &&
+		!slices.ContainsFunc(version.Files, func(f ModVersionFile) bool {
+			return f.TargetPlatform == q.TargetPlatform || f.TargetPlatform == TargetPlatformAny || f.TargetPlatform == ""
+		}) {
+		return false
+	}
+	for _, feature := range q.Features {
+		if !featureEnabled(version.Features[feature]) {
+			return false
+		}
+	}
+	return true
+}
+
+func featureEnabled(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "false"
+	default:
+		return true
+	}
+}
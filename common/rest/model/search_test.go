@@ -0,0 +1,55 @@
+package model
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModSearchQuery(t *testing.T) {
+	query := ModSearchQuery{
+		Query:          "town of us",
+		Type:           ModTypeMod,
+		GameVersion:    "2025.3.25",
+		TargetPlatform: TargetPlatformX64,
+		Features:       []string{"direct_join", "preserve_paths"},
+		Sort:           ModSortName,
+	}
+	parsed, err := ParseModSearchQuery(query.Values())
+	require.NoError(t, err)
+	assert.Equal(t, query, parsed)
+
+	parsed, err = ParseModSearchQuery(url.Values{"feature": {"direct_join, preserve_paths", "direct_join"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"direct_join", "preserve_paths"}, parsed.Features)
+
+	assert.Empty(t, ModSearchQuery{}.Values())
+	_, err = ParseModSearchQuery(url.Values{"sort": {"downloads"}})
+	assert.Error(t, err)
+	_, err = ParseModSearchQuery(url.Values{"target_platform": {"arm"}})
+	assert.Error(t, err)
+}
+
+func TestModSearchQuery_Matches(t *testing.T) {
+	mod := ModDetails{Name: "Town of Us", Description: "Adds roles", Author: "Someone"}
+	assert.True(t, ModSearchQuery{Query: "TOWN roles"}.MatchesMod(mod))
+	assert.False(t, ModSearchQuery{Query: "town hats"}.MatchesMod(mod))
+	// Mods created before types existed are plain mods
+	assert.True(t, ModSearchQuery{Type: ModTypeMod}.MatchesMod(mod))
+	assert.False(t, ModSearchQuery{Type: ModTypeLibrary}.MatchesMod(mod))
+
+	version := ModVersionDetails{
+		GameVersions: []string{"2025.3.25"},
+		Files:        []ModVersionFile{{TargetPlatform: TargetPlatformX86}},
+		Features:     map[string]any{"direct_join": true, "legacy": false},
+	}
+	assert.True(t, ModSearchQuery{GameVersion: "2025.3.25", TargetPlatform: TargetPlatformX86, Features: []string{"direct_join"}}.MatchesVersion(version))
+	assert.False(t, ModSearchQuery{GameVersion: "2024.6.18"}.MatchesVersion(version))
+	assert.False(t, ModSearchQuery{TargetPlatform: TargetPlatformX64}.MatchesVersion(version))
+	assert.False(t, ModSearchQuery{Features: []string{"legacy"}}.MatchesVersion(version))
+	assert.False(t, ModSearchQuery{Features: []string{"missing"}}.MatchesVersion(version))
+	// A version without game versions or files supports everything
+	assert.True(t, ModSearchQuery{GameVersion: "2024.6.18", TargetPlatform: TargetPlatformX64}.MatchesVersion(ModVersionDetails{}))
+}
@@ -43,8 +43,12 @@ type ShareGameResponse struct {
 }
 
 type JoinGameDownloadResponse struct {
-	SessionID string    `json:"session_id"`
-	Aupack    []byte    `json:"aupack"`
-	Room      RoomInfo  `json:"room"`
-	ExpiresAt time.Time `json:"expires_at"`
+	SessionID string `json:"session_id"`
+	// Aupack is only set when the pack is requested inline. Otherwise it is fetched from AupackURL.
+	Aupack       []byte    `json:"aupack,omitempty"`
+	AupackSHA256 string    `json:"aupack_sha256"`
+	AupackSize   int64     `json:"aupack_size"`
+	AupackURL    string    `json:"aupack_url,omitempty"`
+	Room         RoomInfo  `json:"room"`
+	ExpiresAt    time.Time `json:"expires_at"`
 }
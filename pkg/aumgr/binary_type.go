@@ -10,6 +10,18 @@ const (
 	BinaryType64Bit   BinaryType = "x64"
 )
 
+// TargetPlatform returns the platform of mod files built for the binary type, or an empty value if it is unknown.
+func (bt BinaryType) TargetPlatform() model.TargetPlatform {
+	switch bt {
+	case BinaryType32Bit:
+		return model.TargetPlatformX86
+	case BinaryType64Bit:
+		return model.TargetPlatformX64
+	default:
+		return ""
+	}
+}
+
 func (bt BinaryType) IsCompatibleWith(target model.TargetPlatform) bool {
 	switch target {
 	case model.TargetPlatformAny:
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package modmgr
+
+import "errors"
+
+func cloneFile(src, dst string) error {
+	return errors.ErrUnsupported
+}
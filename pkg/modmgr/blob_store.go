@@ -0,0 +1,168 @@
+package modmgr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	blobStoreDirName   = "blobs"
+	blobAlgorithm      = "sha256"
+	blobPartialDirName = "tmp"
+)
+
+// BlobStore is a content-addressed store of mod files keyed by their sha256 hash.
+// Cached mod versions only keep a manifest that references blobs, so identical files
+// shared by many versions and profiles are stored once.
+//
+// Layout: <cacheDir>/blobs/sha256/<first two hex chars>/<hex hash>
+type BlobStore struct {
+	dir string
+}
+
+func NewBlobStore(cacheDir string) *BlobStore {
+	return &BlobStore{dir: filepath.Join(cacheDir, blobStoreDirName)}
+}
+
+func (s *BlobStore) Dir() string {
+	return s.dir
+}
+
+// Path returns the location of the blob with the given sha256 hash.
+func (s *BlobStore) Path(sum string) (string, error) {
+	if !isValidBlobSum(sum) {
+		return "", fmt.Errorf("invalid blob hash %q", sum)
+	}
+	return filepath.Join(s.dir, blobAlgorithm, sum[:2], sum), nil
+}
+
+func (s *BlobStore) Has(sum string) bool {
+	path, err := s.Path(sum)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func (s *BlobStore) Open(sum string) (*os.File, error) {
+	path, err := s.Path(sum)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Verify checks that the blob exists and matches both its own name and the given hashes.
+func (s *BlobStore) Verify(sum string, hashes map[string]string) error {
+	f, err := s.Open(sum)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sha := sha256.New()
+	writer := io.Writer(sha)
+	var hashChecker HashCheckingWriter
+	if len(hashes) > 0 {
+		hashChecker = newHashWriters(hashes)
+		writer = io.MultiWriter(sha, hashChecker)
+	}
+	if _, err := io.Copy(writer, f); err != nil {
+		return err
+	}
+	if computed := hex.EncodeToString(sha.Sum(nil)); computed != sum {
+		return fmt.Errorf("blob %s is corrupted: content hash is %s", sum, computed)
+	}
+	if hashChecker != nil {
+		if _, err := hashChecker.Sum(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Put moves the file at srcPath into the store under the given hash.
+// The caller must have verified that the content matches sum.
+func (s *BlobStore) Put(srcPath, sum string) error {
+	path, err := s.Path(sum)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if err := os.Rename(srcPath, path); err != nil {
+		return fmt.Errorf("failed to move file into blob store: %w", err)
+	}
+	return nil
+}
+
+func (s *BlobStore) Remove(sum string) error {
+	path, err := s.Path(sum)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// partialPath returns a stable location for an in-progress download so it can be resumed by a later run.
+func (s *BlobStore) partialPath(key string) string {
+	return filepath.Join(s.dir, blobPartialDirName, key+partialFileSuffix)
+}
+
+// Link materializes the blob at dst, which must not exist yet.
+// It prefers a copy-on-write clone, then a hard link, and falls back to a plain copy
+// when the filesystem supports neither (e.g. the profile lives on another volume).
+func (s *BlobStore) Link(sum, dst string) error {
+	src, err := s.Path(sum)
+	if err != nil {
+		return err
+	}
+	if err := cloneFile(src, dst); err == nil {
+		return nil
+	} else if !errors.Is(err, errors.ErrUnsupported) {
+		slog.Debug("Failed to clone blob, falling back to hard link", "blob", sum, "dst", dst, "error", err)
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	} else {
+		slog.Debug("Failed to hard link blob, falling back to copy", "blob", sum, "dst", dst, "error", err)
+	}
+	return copyFile(src, dst)
+}
+
+func copyFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		_ = dstFile.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	return dstFile.Close()
+}
+
+func isValidBlobSum(sum string) bool {
+	if len(sum) != sha256.Size*2 || strings.ToLower(sum) != sum {
+		return false
+	}
+	_, err := hex.DecodeString(sum)
+	return err == nil
+}
@@ -0,0 +1,82 @@
+package modmgr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
+)
+
+func TestDownloadMods_SharesBlobsAcrossVersions(t *testing.T) {
+	content := []byte("BepInEx core")
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	v1 := downloadTestVersion("bepinex", content, server.URL+"/bepinex.dll")
+	require.NoError(t, DownloadMods(cacheDir, []ModVersion{v1}, aumgr.BinaryType64Bit, nil, false))
+
+	// Only the metadata changed, so the content must not be fetched or stored again.
+	v2 := v1
+	v2.Features = map[string]any{"direct_join": true}
+	require.NoError(t, DownloadMods(cacheDir, []ModVersion{v2}, aumgr.BinaryType64Bit, nil, false))
+	require.EqualValues(t, 1, hits.Load())
+
+	blobs, err := filepath.Glob(filepath.Join(cacheDir, blobStoreDirName, blobAlgorithm, "*", "*"))
+	require.NoError(t, err)
+	require.Len(t, blobs, 1)
+}
+
+func TestPrepareProfileDirectory_InstallsFromBlobStore(t *testing.T) {
+	content := []byte("plugin")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	mod := downloadTestVersion("a", content, server.URL+"/a.dll")
+	require.NoError(t, DownloadMods(cacheDir, []ModVersion{mod}, aumgr.BinaryType64Bit, nil, false))
+
+	profiles := []string{filepath.Join(t.TempDir(), "p1"), filepath.Join(t.TempDir(), "p2")}
+	for _, profileDir := range profiles {
+		require.NoError(t, PrepareProfileDirectory(profileDir, "", cacheDir, []ModVersion{mod}, aumgr.BinaryType64Bit, "2025.1.1", false, nil))
+		got, err := os.ReadFile(filepath.Join(profileDir, fileDestinationPath(mod.Files[0])))
+		require.NoError(t, err)
+		require.Equal(t, content, got)
+	}
+
+	// Removing the file from one profile must not affect the store or other profiles.
+	require.NoError(t, os.Remove(filepath.Join(profiles[0], fileDestinationPath(mod.Files[0]))))
+	require.NoError(t, NewBlobStore(cacheDir).Verify(mod.Files[0].Hashes["sha256"], mod.Files[0].Hashes))
+	require.FileExists(t, filepath.Join(profiles[1], fileDestinationPath(mod.Files[0])))
+}
+
+func TestDownloadMods_ImportsLegacyCacheLayout(t *testing.T) {
+	content := []byte("legacy")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected download request: %s", r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	mod := downloadTestVersion("a", content, server.URL+"/a.dll")
+	legacyPath := filepath.Join(modCacheDirForTest(t, cacheDir, mod), fileDestinationPath(mod.Files[0]))
+	require.NoError(t, os.MkdirAll(filepath.Dir(legacyPath), 0755))
+	require.NoError(t, os.WriteFile(legacyPath, content, 0644))
+
+	require.NoError(t, DownloadMods(cacheDir, []ModVersion{mod}, aumgr.BinaryType64Bit, nil, false))
+	require.True(t, NewBlobStore(cacheDir).Has(mod.Files[0].Hashes["sha256"]))
+	require.NoFileExists(t, legacyPath)
+}
@@ -0,0 +1,488 @@
+package modmgr
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
+)
+
+// cacheGCGracePeriod protects files that may belong to a download still in progress:
+// blobs whose manifest has not been written yet and cache entries without a manifest.
+const cacheGCGracePeriod = time.Hour
+
+// CacheReference is a set of mod versions that must be kept in the cache, typically the mods of one profile.
+type CacheReference struct {
+	ProfileID   string
+	ProfileName string
+	// BinaryType restricts the reference to one binary type. An empty value keeps the versions of every binary type.
+	BinaryType  aumgr.BinaryType
+	ModVersions []ModVersion
+	// Unknown marks a profile whose mods could not be read. While one is present nothing is collected, as the profile
+	// may need any entry.
+	Unknown bool
+}
+
+type CacheGCOptions struct {
+	// MaxAge keeps unreferenced entries that were used within this duration.
+	MaxAge time.Duration
+	// MaxSize keeps unreferenced entries, most recently used first, as long as the cache fits in this many bytes.
+	MaxSize int64
+	// DryRun reports what would be removed without touching the cache.
+	DryRun bool
+}
+
+// CacheEntry is a cached mod version: a manifest in <cacheDir>/<binaryType>/<modID>/<hash> and the blobs it references.
+type CacheEntry struct {
+	ModID      string           `json:"mod_id"`
+	VersionID  string           `json:"version_id,omitempty"`
+	BinaryType aumgr.BinaryType `json:"binary_type"`
+	Dir        string           `json:"dir"`
+	// Size is the size of the blobs referenced by the entry, including blobs shared with other entries.
+	Size       int64     `json:"size"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Referenced bool      `json:"referenced"`
+
+	blobs     []string
+	localSize int64
+	hasMeta   bool
+}
+
+type ModCacheUsage struct {
+	ModID    string `json:"mod_id"`
+	Versions int    `json:"versions"`
+	Size     int64  `json:"size"`
+}
+
+type ProfileCacheUsage struct {
+	ProfileID   string `json:"profile_id"`
+	ProfileName string `json:"profile_name,omitempty"`
+	Mods        int    `json:"mods"`
+	Size        int64  `json:"size"`
+}
+
+type CacheReport struct {
+	// TotalSize is the disk usage of the cache with every blob counted once.
+	TotalSize int64 `json:"total_size"`
+	BlobCount int   `json:"blob_count"`
+	// PartialSize is the size of abandoned partial downloads.
+	PartialSize int64 `json:"partial_size"`
+	// ReclaimableSize is the size a garbage collection without a budget would free.
+	ReclaimableSize int64               `json:"reclaimable_size"`
+	Entries         []CacheEntry        `json:"entries"`
+	Mods            []ModCacheUsage     `json:"mods"`
+	Profiles        []ProfileCacheUsage `json:"profiles"`
+}
+
+type CacheGCResult struct {
+	Removed      []CacheEntry `json:"removed"`
+	RemovedBlobs int          `json:"removed_blobs"`
+	FreedSize    int64        `json:"freed_size"`
+}
+
+type cacheScan struct {
+	cacheDir     string
+	store        *BlobStore
+	entries      []*CacheEntry
+	blobSizes    map[string]int64
+	blobModTimes map[string]time.Time
+	partials     []string
+	partialSize  int64
+	// keepAll is set when a reference is Unknown.
+	keepAll bool
+	// profileEntries maps each reference to the entries it keeps alive.
+	profileEntries [][]*CacheEntry
+}
+
+// ReportCacheUsage returns the disk usage of the cache per mod and per referencing profile.
+func ReportCacheUsage(cacheDir string, refs []CacheReference) (*CacheReport, error) {
+	scan, err := scanCache(cacheDir, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CacheReport{
+		TotalSize:   scan.totalSize(),
+		BlobCount:   len(scan.blobSizes),
+		PartialSize: scan.partialSize,
+		Entries:     make([]CacheEntry, 0, len(scan.entries)),
+	}
+	_, _, report.ReclaimableSize = scan.plan(CacheGCOptions{}, time.Now())
+
+	mods := make(map[string]*ModCacheUsage)
+	modBlobs := make(map[string]map[string]struct{})
+	for _, entry := range scan.entries {
+		report.Entries = append(report.Entries, *entry)
+		usage, ok := mods[entry.ModID]
+		if !ok {
+			usage = &ModCacheUsage{ModID: entry.ModID}
+			mods[entry.ModID] = usage
+			modBlobs[entry.ModID] = make(map[string]struct{})
+		}
+		usage.Versions++
+		usage.Size += entry.localSize + scan.uniqueBlobSize(modBlobs[entry.ModID], entry.blobs)
+	}
+	for _, usage := range mods {
+		report.Mods = append(report.Mods, *usage)
+	}
+	slices.SortFunc(report.Mods, func(a, b ModCacheUsage) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.ModID, b.ModID))
+	})
+
+	// A profile may be referenced more than once, e.g. by its selected and its installed versions
+	profiles := make(map[string]int)
+	profileMods := make(map[string]map[string]struct{})
+	profileBlobs := make(map[string]map[string]struct{})
+	profileEntries := make(map[string]map[*CacheEntry]struct{})
+	for i, ref := range refs {
+		idx, ok := profiles[ref.ProfileID]
+		if !ok {
+			idx = len(report.Profiles)
+			profiles[ref.ProfileID] = idx
+			report.Profiles = append(report.Profiles, ProfileCacheUsage{ProfileID: ref.ProfileID})
+			profileMods[ref.ProfileID] = make(map[string]struct{})
+			profileBlobs[ref.ProfileID] = make(map[string]struct{})
+			profileEntries[ref.ProfileID] = make(map[*CacheEntry]struct{})
+		}
+		usage := &report.Profiles[idx]
+		usage.ProfileName = cmp.Or(usage.ProfileName, ref.ProfileName)
+		for _, mod := range ref.ModVersions {
+			profileMods[ref.ProfileID][mod.ModID] = struct{}{}
+		}
+		usage.Mods = len(profileMods[ref.ProfileID])
+		for _, entry := range scan.profileEntries[i] {
+			if _, ok := profileEntries[ref.ProfileID][entry]; ok {
+				continue
+			}
+			profileEntries[ref.ProfileID][entry] = struct{}{}
+			usage.Size += entry.localSize + scan.uniqueBlobSize(profileBlobs[ref.ProfileID], entry.blobs)
+		}
+	}
+	return report, nil
+}
+
+// CollectCacheGarbage removes cache entries that are not kept by any reference, then the blobs and
+// stale partial downloads that are no longer needed.
+// An unreferenced entry survives only while it is within the budget given by opts:
+// it was used within MaxAge, or the cache still fits in MaxSize (least recently used entries go first).
+// Without a budget every unreferenced entry is removed.
+func CollectCacheGarbage(cacheDir string, refs []CacheReference, opts CacheGCOptions) (*CacheGCResult, error) {
+	scan, err := scanCache(cacheDir, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	removeEntries, removeBlobs, freed := scan.plan(opts, time.Now())
+	result := &CacheGCResult{
+		RemovedBlobs: len(removeBlobs),
+		FreedSize:    freed,
+	}
+	for _, entry := range removeEntries {
+		result.Removed = append(result.Removed, *entry)
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	var errs []error
+	for _, entry := range removeEntries {
+		slog.Info("Removing unused mod cache entry", "modId", entry.ModID, "versionId", entry.VersionID, "binaryType", entry.BinaryType, "lastUsedAt", entry.LastUsedAt)
+		if err := os.RemoveAll(entry.Dir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove cache entry %s: %w", entry.Dir, err))
+			continue
+		}
+		// Drop the mod directory once its last version is gone
+		_ = os.Remove(filepath.Dir(entry.Dir))
+	}
+	for _, sum := range removeBlobs {
+		if err := scan.store.Remove(sum); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove blob %s: %w", sum, err))
+			continue
+		}
+		if path, err := scan.store.Path(sum); err == nil {
+			_ = os.Remove(filepath.Dir(path))
+		}
+	}
+	for _, path := range scan.partials {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("failed to remove partial download %s: %w", path, err))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// plan decides which entries and blobs to remove and how many bytes that frees, including stale partial downloads.
+func (s *cacheScan) plan(opts CacheGCOptions, now time.Time) ([]*CacheEntry, []string, int64) {
+	refCount := make(map[string]int, len(s.blobSizes))
+	for _, entry := range s.entries {
+		for _, sum := range entry.blobs {
+			refCount[sum]++
+		}
+	}
+
+	var candidates []*CacheEntry
+	for _, entry := range s.entries {
+		if entry.Referenced || s.keepAll || (!entry.hasMeta && now.Sub(entry.LastUsedAt) < cacheGCGracePeriod) {
+			continue
+		}
+		candidates = append(candidates, entry)
+	}
+	slices.SortFunc(candidates, func(a, b *CacheEntry) int {
+		return a.LastUsedAt.Compare(b.LastUsedAt)
+	})
+
+	total := s.totalSize()
+	var (
+		removeEntries []*CacheEntry
+		removeBlobs   []string
+		freed         = s.partialSize
+	)
+	noBudget := opts.MaxAge <= 0 && opts.MaxSize <= 0
+	for _, entry := range candidates {
+		expired := opts.MaxAge > 0 && now.Sub(entry.LastUsedAt) > opts.MaxAge
+		overBudget := opts.MaxSize > 0 && total > opts.MaxSize
+		if !noBudget && !expired && !overBudget {
+			continue
+		}
+		removeEntries = append(removeEntries, entry)
+		entryFreed := entry.localSize
+		for _, sum := range entry.blobs {
+			refCount[sum]--
+			if refCount[sum] == 0 {
+				removeBlobs = append(removeBlobs, sum)
+				entryFreed += s.blobSizes[sum]
+			}
+		}
+		total -= entryFreed
+		freed += entryFreed
+	}
+
+	// Blobs no manifest has ever referenced, e.g. left behind by an interrupted run
+	for sum, size := range s.blobSizes {
+		if _, ok := refCount[sum]; !ok && !s.keepAll && now.Sub(s.blobModTimes[sum]) >= cacheGCGracePeriod {
+			removeBlobs = append(removeBlobs, sum)
+			freed += size
+		}
+	}
+	slices.Sort(removeBlobs)
+	return removeEntries, removeBlobs, freed
+}
+
+func (s *cacheScan) totalSize() int64 {
+	total := s.partialSize
+	for _, size := range s.blobSizes {
+		total += size
+	}
+	for _, entry := range s.entries {
+		total += entry.localSize
+	}
+	return total
+}
+
+// uniqueBlobSize adds up the sizes of blobs not in seen and records them there.
+func (s *cacheScan) uniqueBlobSize(seen map[string]struct{}, blobs []string) int64 {
+	var size int64
+	for _, sum := range blobs {
+		if _, ok := seen[sum]; ok {
+			continue
+		}
+		seen[sum] = struct{}{}
+		size += s.blobSizes[sum]
+	}
+	return size
+}
+
+func scanCache(cacheDir string, refs []CacheReference) (*cacheScan, error) {
+	scan := &cacheScan{
+		cacheDir:       cacheDir,
+		store:          NewBlobStore(cacheDir),
+		blobSizes:      make(map[string]int64),
+		blobModTimes:   make(map[string]time.Time),
+		profileEntries: make([][]*CacheEntry, len(refs)),
+	}
+	if err := scan.scanBlobs(); err != nil {
+		return nil, err
+	}
+	if err := scan.scanEntries(); err != nil {
+		return nil, err
+	}
+
+	type entryKey struct {
+		binaryType aumgr.BinaryType
+		modID      string
+		hash       string
+	}
+	byKey := make(map[entryKey][]*CacheEntry)
+	for _, entry := range scan.entries {
+		key := entryKey{entry.BinaryType, entry.ModID, filepath.Base(entry.Dir)}
+		byKey[key] = append(byKey[key], entry)
+		// Entries for any binary type are keyed once more without it
+		key.binaryType = ""
+		byKey[key] = append(byKey[key], entry)
+	}
+	for i, ref := range refs {
+		scan.keepAll = scan.keepAll || ref.Unknown
+		for _, mod := range ref.ModVersions {
+			hashStr, err := hashModVersion(mod)
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash mod version: %w", err)
+			}
+			for _, entry := range byKey[entryKey{ref.BinaryType, mod.ModID, hashStr}] {
+				entry.Referenced = true
+				scan.profileEntries[i] = append(scan.profileEntries[i], entry)
+			}
+		}
+	}
+	return scan, nil
+}
+
+func (s *cacheScan) scanBlobs() error {
+	blobRoot := filepath.Join(s.store.Dir(), blobAlgorithm)
+	err := filepath.WalkDir(blobRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !isValidBlobSum(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		s.blobSizes[d.Name()] = info.Size()
+		s.blobModTimes[d.Name()] = info.ModTime()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan blob store: %w", err)
+	}
+
+	partialDir := filepath.Join(s.store.Dir(), blobPartialDirName)
+	partials, err := os.ReadDir(partialDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to scan partial downloads: %w", err)
+	}
+	for _, d := range partials {
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		// Partial downloads are kept for resuming unless they have been abandoned
+		if time.Since(info.ModTime()) < cacheGCGracePeriod {
+			continue
+		}
+		s.partials = append(s.partials, filepath.Join(partialDir, d.Name()))
+		s.partialSize += info.Size()
+	}
+	return nil
+}
+
+// scanEntries reads every <binaryType>/<modID>/<hash> directory of the cache.
+func (s *cacheScan) scanEntries() error {
+	binaryTypes, err := os.ReadDir(s.cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	for _, bt := range binaryTypes {
+		if !bt.IsDir() || bt.Name() == blobStoreDirName {
+			continue
+		}
+		modDirs, err := os.ReadDir(filepath.Join(s.cacheDir, bt.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read cache directory: %w", err)
+		}
+		for _, modDir := range modDirs {
+			if !modDir.IsDir() {
+				continue
+			}
+			versionDirs, err := os.ReadDir(filepath.Join(s.cacheDir, bt.Name(), modDir.Name()))
+			if err != nil {
+				return fmt.Errorf("failed to read cache directory: %w", err)
+			}
+			for _, versionDir := range versionDirs {
+				if !versionDir.IsDir() {
+					continue
+				}
+				entry, err := s.scanEntry(filepath.Join(s.cacheDir, bt.Name(), modDir.Name(), versionDir.Name()), aumgr.BinaryType(bt.Name()), modDir.Name())
+				if err != nil {
+					return err
+				}
+				s.entries = append(s.entries, entry)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *cacheScan) scanEntry(dir string, binaryType aumgr.BinaryType, modID string) (*CacheEntry, error) {
+	entry := &CacheEntry{
+		ModID:      modID,
+		BinaryType: binaryType,
+		Dir:        dir,
+	}
+	// Files other than the manifest are leftovers of the cache layout before the blob store
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(entry.LastUsedAt) {
+			entry.LastUsedAt = info.ModTime()
+		}
+		if !d.IsDir() {
+			entry.localSize += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cache entry %s: %w", dir, err)
+	}
+
+	metadata, err := loadCacheMetadata(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Failed to load mod cache metadata", "dir", dir, "error", err)
+		}
+		return entry, nil
+	}
+	entry.hasMeta = true
+	entry.VersionID = metadata.ModVersion.VersionID
+	if info, err := os.Stat(filepath.Join(dir, "metadata.json")); err == nil {
+		entry.LastUsedAt = info.ModTime()
+	}
+	for _, sum := range metadata.Blobs {
+		if _, ok := s.blobSizes[sum]; ok {
+			entry.blobs = append(entry.blobs, sum)
+		}
+	}
+	slices.Sort(entry.blobs)
+	entry.blobs = slices.Compact(entry.blobs)
+	for _, sum := range entry.blobs {
+		entry.Size += s.blobSizes[sum]
+	}
+	return entry, nil
+}
+
+// touchCacheEntry records that the cache entry was used, which is what the garbage collector's LRU order is based on.
+func touchCacheEntry(modCacheDir string) {
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(modCacheDir, "metadata.json"), now, now); err != nil {
+		slog.Debug("Failed to update mod cache last use time", "dir", modCacheDir, "error", err)
+	}
+}
@@ -0,0 +1,134 @@
+package modmgr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
+)
+
+func setupCacheGCTest(t *testing.T) (string, []ModVersion) {
+	t.Helper()
+	contents := map[string][]byte{
+		"/shared.dll": []byte("shared library"),
+		"/a.dll":      []byte("mod a"),
+		"/b.dll":      []byte("mod b, which is larger"),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(contents[r.URL.Path])
+	}))
+	t.Cleanup(server.Close)
+
+	shared := downloadTestVersion("shared", contents["/shared.dll"], server.URL+"/shared.dll").Files[0]
+	a := downloadTestVersion("a", contents["/a.dll"], server.URL+"/a.dll")
+	b := downloadTestVersion("b", contents["/b.dll"], server.URL+"/b.dll")
+	a.Files = append(a.Files, model.ModVersionFile{ID: "a-shared", Filename: shared.Filename, ContentType: shared.ContentType, Size: shared.Size, TargetPlatform: shared.TargetPlatform, Hashes: shared.Hashes, Downloads: shared.Downloads})
+	b.Files = append(b.Files, model.ModVersionFile{ID: "b-shared", Filename: shared.Filename, ContentType: shared.ContentType, Size: shared.Size, TargetPlatform: shared.TargetPlatform, Hashes: shared.Hashes, Downloads: shared.Downloads})
+
+	cacheDir := t.TempDir()
+	mods := []ModVersion{a, b}
+	require.NoError(t, DownloadMods(cacheDir, mods, aumgr.BinaryType64Bit, nil, false))
+	return cacheDir, mods
+}
+
+func setCacheEntryLastUsed(t *testing.T, cacheDir string, mod ModVersion, at time.Time) {
+	t.Helper()
+	require.NoError(t, os.Chtimes(filepath.Join(modCacheDirForTest(t, cacheDir, mod), "metadata.json"), at, at))
+}
+
+func TestCollectCacheGarbage_RemovesUnreferencedEntries(t *testing.T) {
+	cacheDir, mods := setupCacheGCTest(t)
+	a, b := mods[0], mods[1]
+	refs := []CacheReference{{ProfileID: "p1", BinaryType: aumgr.BinaryType64Bit, ModVersions: []ModVersion{a}}}
+
+	manifest, err := os.Stat(filepath.Join(modCacheDirForTest(t, cacheDir, b), "metadata.json"))
+	require.NoError(t, err)
+
+	dryRun, err := CollectCacheGarbage(cacheDir, refs, CacheGCOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, dryRun.Removed, 1)
+	require.DirExists(t, modCacheDirForTest(t, cacheDir, b))
+
+	result, err := CollectCacheGarbage(cacheDir, refs, CacheGCOptions{})
+	require.NoError(t, err)
+	require.Equal(t, dryRun, result)
+	require.Equal(t, "b", result.Removed[0].ModID)
+	require.Equal(t, 1, result.RemovedBlobs)
+	require.Equal(t, b.Files[0].Size+manifest.Size(), result.FreedSize)
+
+	store := NewBlobStore(cacheDir)
+	require.NoDirExists(t, filepath.Join(cacheDir, string(aumgr.BinaryType64Bit), "b"))
+	require.False(t, store.Has(b.Files[0].Hashes["sha256"]))
+	// The blob shared with a is still referenced
+	require.True(t, store.Has(b.Files[1].Hashes["sha256"]))
+	require.True(t, isModVersionCached(store, modCacheDirForTest(t, cacheDir, a), a, aumgr.BinaryType64Bit))
+}
+
+func TestCollectCacheGarbage_KeepsEverythingForUnknownProfile(t *testing.T) {
+	cacheDir, mods := setupCacheGCTest(t)
+	a, b := mods[0], mods[1]
+	refs := []CacheReference{
+		{ProfileID: "p1", BinaryType: aumgr.BinaryType64Bit, ModVersions: []ModVersion{a}},
+		{ProfileID: "p2", Unknown: true},
+	}
+
+	result, err := CollectCacheGarbage(cacheDir, refs, CacheGCOptions{})
+	require.NoError(t, err)
+	require.Empty(t, result.Removed)
+	require.Zero(t, result.RemovedBlobs)
+	require.True(t, isModVersionCached(NewBlobStore(cacheDir), modCacheDirForTest(t, cacheDir, b), b, aumgr.BinaryType64Bit))
+}
+
+func TestCollectCacheGarbage_Budget(t *testing.T) {
+	cacheDir, mods := setupCacheGCTest(t)
+	a, b := mods[0], mods[1]
+	now := time.Now()
+	setCacheEntryLastUsed(t, cacheDir, a, now.Add(-48*time.Hour))
+	setCacheEntryLastUsed(t, cacheDir, b, now.Add(-time.Hour))
+
+	// Only a has not been used within a day
+	result, err := CollectCacheGarbage(cacheDir, nil, CacheGCOptions{MaxAge: 24 * time.Hour, DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, result.Removed, 1)
+	require.Equal(t, "a", result.Removed[0].ModID)
+
+	// Evicting the least recently used entry is enough to fit the budget
+	report, err := ReportCacheUsage(cacheDir, nil)
+	require.NoError(t, err)
+	result, err = CollectCacheGarbage(cacheDir, nil, CacheGCOptions{MaxSize: report.TotalSize - 1})
+	require.NoError(t, err)
+	require.Len(t, result.Removed, 1)
+	require.Equal(t, "a", result.Removed[0].ModID)
+	require.True(t, isModVersionCached(NewBlobStore(cacheDir), modCacheDirForTest(t, cacheDir, b), b, aumgr.BinaryType64Bit))
+}
+
+func TestReportCacheUsage(t *testing.T) {
+	cacheDir, mods := setupCacheGCTest(t)
+	a, b := mods[0], mods[1]
+	refs := []CacheReference{
+		{ProfileID: "p1", ProfileName: "Profile", ModVersions: []ModVersion{a}},
+		{ProfileID: "p1", BinaryType: aumgr.BinaryType64Bit, ModVersions: []ModVersion{a, b}},
+	}
+
+	report, err := ReportCacheUsage(cacheDir, refs)
+	require.NoError(t, err)
+	require.Equal(t, 3, report.BlobCount)
+	require.Zero(t, report.ReclaimableSize)
+	require.Len(t, report.Entries, 2)
+	require.Len(t, report.Mods, 2)
+	require.Equal(t, "b", report.Mods[0].ModID)
+
+	blobs := a.Files[0].Size + b.Files[0].Size + a.Files[1].Size
+	require.Len(t, report.Profiles, 1)
+	require.Equal(t, "Profile", report.Profiles[0].ProfileName)
+	require.Equal(t, 2, report.Profiles[0].Mods)
+	require.Greater(t, report.Profiles[0].Size, blobs)
+	require.Less(t, report.Profiles[0].Size, report.TotalSize+1)
+}
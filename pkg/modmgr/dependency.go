@@ -3,14 +3,19 @@ package modmgr
 import (
 	"fmt"
 	"maps"
+	"regexp"
 	"slices"
 	"strings"
 
 	version "github.com/mcuadros/go-version"
 
 	"github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
 )
 
+// maxResolveAttempts bounds the number of candidate versions the solver tries before giving up.
+const maxResolveAttempts = 10000
+
 // VersionProvider is an interface to fetch mod version details.
 type VersionProvider interface {
 	GetModVersion(modID string, versionID string) (*ModVersion, error)
@@ -18,537 +23,705 @@ type VersionProvider interface {
 	GetModVersionIDs(modID string, limit int, after string) ([]string, error)
 }
 
-// ResolveDependencies recursively finds all required dependencies for a given set of mod versions.
-// It returns a map of ModID to ModVersion containing all original mods and their required dependencies.
-// The algorithm attempts to find the best compatible version combination, trying newer versions first
-// but backtracking to older versions if conflicts arise.
-// When multiple MODs require the same dependency, it merges constraints to find a version that satisfies all.
-func ResolveDependencies(initialMods []ModVersion, provider VersionProvider) (map[string]ModVersion, error) {
-	resolved := make(map[string]ModVersion)
-	lockedModIDs := make(map[string]bool)
-	for _, m := range initialMods {
-		resolved[m.ModID] = m
-		lockedModIDs[m.ModID] = true
+// BatchVersionProvider is a VersionProvider that can fetch many versions in one request.
+// ResolveDependencies uses it to prefetch the dependencies of the selected mods together instead of one at a time.
+type BatchVersionProvider interface {
+	VersionProvider
+	// GetModVersions returns the versions in the order of refs, with nil for versions that do not exist.
+	// A ref without a version ID asks for the latest version of the mod.
+	GetModVersions(refs []model.ModVersionRef) ([]*ModVersion, error)
+}
+
+type resolveConfig struct {
+	binaryType  aumgr.BinaryType
+	gameVersion string
+}
+
+type ResolveOption func(*resolveConfig)
+
+// WithBinaryType makes the resolver skip dependency versions without files for the binary type.
+func WithBinaryType(binaryType aumgr.BinaryType) ResolveOption {
+	return func(c *resolveConfig) {
+		c.binaryType = binaryType
 	}
+}
 
-	// Collect all required dependencies and their constraints first
-	allDeps := collectAllRequiredDependencies(initialMods, resolved)
+// WithGameVersion makes the resolver skip dependency versions that do not support the game version.
+func WithGameVersion(gameVersion string) ResolveOption {
+	return func(c *resolveConfig) {
+		c.gameVersion = gameVersion
+	}
+}
 
-	// Resolve dependencies with merged constraints
-	if err := resolveDependenciesWithConstraints(resolved, allDeps, lockedModIDs, map[string][]model.ModVersionDependency{}, provider); err != nil {
+// ResolveDependencies finds a version for every required dependency of the given mod versions.
+// It returns a map of ModID to ModVersion containing all original mods and their required dependencies.
+//
+// The initial mods are fixed. Required, optional, conflict and embedded edges of every selected version are
+// considered together, and the solver backtracks over the whole graph, preferring the latest version of each
+// dependency and then newer versions over older ones. Mods and edges are visited in a fixed order, so the
+// same input always gives the same result.
+//
+// With WithBinaryType or WithGameVersion, dependency versions incompatible with the installed game are skipped in
+// favour of older compatible ones. The initial mods are never rejected for compatibility.
+//
+// When no consistent set exists the error is a *ResolutionError explaining which mod could not be resolved,
+// the chain of mods that required it and the constraint each candidate version broke.
+//
+// If the provider is a BatchVersionProvider, the versions the solver is about to need are fetched in batches.
+func ResolveDependencies(initialMods []ModVersion, provider VersionProvider, opts ...ResolveOption) (map[string]ModVersion, error) {
+	var config resolveConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	s := &dependencySolver{
+		config:     config,
+		provider:   provider,
+		versions:   make(map[string]*ModVersion),
+		versionIDs: make(map[string][]string),
+		latest:     make(map[string]*ModVersion),
+	}
+
+	selected := make(map[string]ModVersion, len(initialMods))
+	for _, m := range initialMods {
+		selected[m.ModID] = m
+	}
+	if conflict, err := s.checkSelected(selected); err != nil {
 		return nil, err
+	} else if conflict != nil {
+		return nil, conflict
 	}
 
-	// Final validation
-	if err := validateResolvedDependencies(resolved, provider, nil); err != nil {
+	resolved, conflict, err := s.solve(selected, map[string]DependencyEdge{})
+	if err != nil {
 		return nil, err
 	}
-
+	if conflict != nil {
+		return nil, conflict
+	}
 	return resolved, nil
 }
 
-// dependencyConstraints holds all constraints for a single dependency
-type dependencyConstraints struct {
-	modID       string
-	constraints []model.ModVersionDependency
+// DependencyEdge is a dependency declared by a mod version.
+type DependencyEdge struct {
+	From        string            `json:"from"`
+	FromVersion string            `json:"from_version"`
+	To          string            `json:"to"`
+	Constraint  string            `json:"constraint"`
+	Type        ModDependencyType `json:"type"`
+}
+
+func (e DependencyEdge) String() string {
+	verb := "requires"
+	switch e.Type {
+	case ModDependencyTypeOptional:
+		verb = "optionally uses"
+	case ModDependencyTypeConflict:
+		verb = "conflicts with"
+	case ModDependencyTypeEmbedded:
+		verb = "embeds"
+	}
+	return fmt.Sprintf("%s@%s %s %s %s", e.From, e.FromVersion, verb, e.To, constraintString(e.Constraint))
 }
 
-// collectAllRequiredDependencies recursively collects all required dependencies and their constraints
-func collectAllRequiredDependencies(queue []ModVersion, alreadyResolved map[string]ModVersion) map[string]*dependencyConstraints {
-	allDeps := make(map[string]*dependencyConstraints)
-	visited := make(map[string]bool)
+// ConstraintViolation is a version of a mod that does not satisfy a dependency edge pointing at it.
+type ConstraintViolation struct {
+	Edge      DependencyEdge `json:"edge"`
+	VersionID string         `json:"version_id"`
+	// Expected is the constraint as it was evaluated, e.g. "latest (v2.0.0)".
+	Expected string `json:"expected"`
+}
 
-	// Mark already resolved as visited, but still collect their dependencies
-	for _, mod := range alreadyResolved {
-		visited[mod.ModID] = true
+func (v *ConstraintViolation) Error() string {
+	switch v.Edge.Type {
+	case ModDependencyTypeOptional:
+		return fmt.Sprintf("optional dependency version conflict for mod %s: %s@%s accepts %s but resolved %s", v.Edge.To, v.Edge.From, v.Edge.FromVersion, v.Expected, v.VersionID)
+	case ModDependencyTypeConflict:
+		return fmt.Sprintf("dependency conflict for mod %s: %s@%s conflicts with %s and resolved %s", v.Edge.To, v.Edge.From, v.Edge.FromVersion, v.Expected, v.VersionID)
+	default:
+		return fmt.Sprintf("version conflict for mod %s: %s@%s required %s but resolved %s", v.Edge.To, v.Edge.From, v.Edge.FromVersion, v.Expected, v.VersionID)
 	}
+}
 
-	collectRecursive := func(mods []ModVersion) {
-		for _, current := range mods {
-			visited[current.ModID] = true
+// RejectedVersion is a candidate version the solver could not use.
+// Exactly one of NotFound, Yanked, Incompatible, Violation and Cause is set.
+type RejectedVersion struct {
+	VersionID string `json:"version_id"`
+	NotFound  bool   `json:"not_found,omitempty"`
+	// Yanked is set for yanked versions, which are only used when a constraint pins them exactly.
+	Yanked       bool                 `json:"yanked,omitempty"`
+	Incompatible *Incompatibility     `json:"incompatible,omitempty"`
+	Violation    *ConstraintViolation `json:"violation,omitempty"`
+	// Cause explains why the dependencies of the version could not be resolved.
+	Cause *ResolutionError `json:"cause,omitempty"`
+}
 
-			for _, dep := range current.Dependencies {
-				depType, err := normalizeDependencyType(dep.DependencyType)
-				if err != nil || depType != ModDependencyTypeRequired {
-					continue
-				}
+// ResolutionError explains why no consistent set of mod versions exists.
+type ResolutionError struct {
+	// ModID is the mod no version could be chosen for.
+	ModID string `json:"mod_id"`
+	// Chain is the path of required dependencies from a profile mod to ModID.
+	Chain []DependencyEdge `json:"chain,omitempty"`
+	// Constraints are the edges of the selected mods pointing at ModID.
+	Constraints []DependencyEdge `json:"constraints,omitempty"`
+	// Violation is set when mods fixed by the profile clash with each other.
+	Violation *ConstraintViolation `json:"violation,omitempty"`
+	Rejected  []RejectedVersion    `json:"rejected,omitempty"`
+}
 
-				// Skip if this dependency is already resolved
-				if _, alreadyResolved := alreadyResolved[dep.ModID]; alreadyResolved {
-					continue
-				}
+func (e *ResolutionError) Error() string {
+	var b strings.Builder
+	e.write(&b, "", 0)
+	return strings.TrimRight(b.String(), "\n")
+}
 
-				if allDeps[dep.ModID] == nil {
-					allDeps[dep.ModID] = &dependencyConstraints{
-						modID:       dep.ModID,
-						constraints: []model.ModVersionDependency{},
-					}
-				}
-				allDeps[dep.ModID].constraints = append(allDeps[dep.ModID].constraints, dep)
-			}
+// Summary returns the first line of the explanation.
+func (e *ResolutionError) Summary() string {
+	if e.Violation != nil {
+		return e.Violation.Error()
+	}
+	for _, r := range e.Rejected {
+		if r.Cause != nil {
+			return fmt.Sprintf("failed to resolve %s with any candidate version", e.ModID)
 		}
 	}
-
-	collectRecursive(queue)
-	return allDeps
+	if len(e.Rejected) > 0 && !slices.ContainsFunc(e.Rejected, func(r RejectedVersion) bool { return r.Incompatible == nil }) {
+		return fmt.Sprintf("no version of %s satisfying all constraints is compatible with the installed game", e.ModID)
+	}
+	constraints := make([]string, len(e.Constraints))
+	for i, c := range e.Constraints {
+		constraints[i] = c.String()
+	}
+	return fmt.Sprintf("failed to find version for %s satisfying all constraints: %s", e.ModID, strings.Join(constraints, "; "))
 }
 
-// resolveDependenciesWithConstraints resolves dependencies considering all constraints
-func resolveDependenciesWithConstraints(
-	resolved map[string]ModVersion,
-	allDeps map[string]*dependencyConstraints,
-	lockedModIDs map[string]bool,
-	activeConstraintGuards map[string][]model.ModVersionDependency,
-	provider VersionProvider,
-) error {
-	// Keep resolving until no more dependencies to add
-	for len(allDeps) > 0 {
-		// Pick one dependency to resolve
-		var depConstraint *dependencyConstraints
-		for _, dc := range allDeps {
-			depConstraint = dc
-			break
-		}
+// maxExplanationDepth and maxExplainedVersions keep the rendered explanation readable;
+// the full tree stays available in the fields.
+const (
+	maxExplanationDepth  = 4
+	maxExplainedVersions = 5
+)
 
-		embeddedProviders, err := collectEmbeddedProviders(resolved)
-		if err != nil {
-			return err
+func (e *ResolutionError) write(b *strings.Builder, indent string, depth int) {
+	fmt.Fprintf(b, "%s\n", e.Summary())
+	if len(e.Chain) > 0 {
+		path := make([]string, 0, len(e.Chain)+1)
+		for _, edge := range e.Chain {
+			path = append(path, edge.From+"@"+edge.FromVersion)
 		}
-		if len(embeddedProviders[depConstraint.modID]) > 0 {
-			// This dependency is satisfied by an embedded provider
-			delete(allDeps, depConstraint.modID)
-			continue
-		}
-
-		// Get candidates that satisfy ALL constraints
-		candidates, err := getCandidateVersionsForConstraints(provider, depConstraint.constraints)
-		if err != nil {
-			return err
+		path = append(path, e.ModID)
+		fmt.Fprintf(b, "%s  required by: %s\n", indent, strings.Join(path, " -> "))
+	}
+	if e.Violation == nil && len(e.Constraints) > 0 {
+		fmt.Fprintf(b, "%s  constraints on %s:\n", indent, e.ModID)
+		for _, c := range e.Constraints {
+			fmt.Fprintf(b, "%s    %s\n", indent, c)
 		}
-
-		if len(candidates) == 0 {
-			constraintStrs := make([]string, len(depConstraint.constraints))
-			for i, c := range depConstraint.constraints {
-				constraintStrs[i] = c.VersionID
-			}
-			return fmt.Errorf("failed to find version for %s satisfying all constraints: %v", depConstraint.modID, constraintStrs)
-		}
-
-		// Try each candidate (ordered newest to oldest)
-		var lastErr error
-		resolved_any := false
-		var remainingDepsAfterResolve map[string]*dependencyConstraints
-		for _, candidate := range candidates {
-			// Create a test resolution
-			testResolved := make(map[string]ModVersion)
-			maps.Copy(testResolved, resolved)
-			testResolved[depConstraint.modID] = *candidate
-
-			// Collect new dependencies introduced by this candidate.
-			// Use an empty resolved map so transitive constraints are not dropped,
-			// then handle locked initial mods explicitly below.
-			newDeps := collectAllRequiredDependencies([]ModVersion{*candidate}, map[string]ModVersion{})
-
-			// Merge with remaining dependencies
-			testAllDeps := make(map[string]*dependencyConstraints)
-			for k, v := range allDeps {
-				if k == depConstraint.modID {
-					continue // Skip the one we just resolved
-				}
-				// Deep copy the constraints
-				testAllDeps[k] = &dependencyConstraints{
-					modID:       v.modID,
-					constraints: append([]model.ModVersionDependency{}, v.constraints...),
-				}
-			}
-			candidateConflict := false
-			for k, v := range newDeps {
-				if lockedModIDs[k] {
-					lockedResolved, found := testResolved[k]
-					if !found {
-						lastErr = fmt.Errorf("locked mod %s is missing from resolved set", k)
-						candidateConflict = true
-						break
-					}
-
-					for _, depConstraint := range v.constraints {
-						if err := checkDependencyConstraint(lockedResolved, depConstraint, provider); err != nil {
-							lastErr = err
-							candidateConflict = true
-							break
-						}
-					}
-					if candidateConflict {
-						break
-					}
-					continue
-				}
-
-				if testAllDeps[k] == nil {
-					testAllDeps[k] = &dependencyConstraints{
-						modID:       v.modID,
-						constraints: append([]model.ModVersionDependency{}, v.constraints...),
-					}
-				} else {
-					// Merge constraints
-					testAllDeps[k].constraints = append(testAllDeps[k].constraints, v.constraints...)
-				}
-			}
-			if candidateConflict {
-				continue
-			}
-
-			testConstraintGuards := make(map[string][]model.ModVersionDependency, len(activeConstraintGuards)+1)
-			for modID, constraints := range activeConstraintGuards {
-				testConstraintGuards[modID] = append([]model.ModVersionDependency{}, constraints...)
-			}
-			testConstraintGuards[depConstraint.modID] = append(
-				testConstraintGuards[depConstraint.modID],
-				depConstraint.constraints...,
-			)
-
-			// Try to resolve the rest
-			if err := resolveDependenciesWithConstraints(testResolved, testAllDeps, lockedModIDs, testConstraintGuards, provider); err != nil {
-				lastErr = err
-				continue
-			}
-
-			// Ensure dependencies chosen in outer frames still satisfy their original constraints.
-			constraintGuardFailed := false
-			for modID, constraints := range testConstraintGuards {
-				resolvedMod, found := testResolved[modID]
-				if !found {
-					lastErr = fmt.Errorf("resolved dependency %s disappeared during backtracking", modID)
-					constraintGuardFailed = true
-					break
-				}
-				for _, constraint := range constraints {
-					if err := checkDependencyConstraint(resolvedMod, constraint, provider); err != nil {
-						lastErr = err
-						constraintGuardFailed = true
-						break
-					}
-				}
-				if constraintGuardFailed {
-					break
-				}
-			}
-			if constraintGuardFailed {
-				continue
-			}
-
-			// Success! Update resolved
-			maps.Copy(resolved, testResolved)
-			remainingDepsAfterResolve = testAllDeps
-			resolved_any = true
+	}
+	for i, r := range e.Rejected {
+		if i == maxExplainedVersions {
+			fmt.Fprintf(b, "%s  ... and %d more versions of %s\n", indent, len(e.Rejected)-i, e.ModID)
 			break
 		}
-
-		if !resolved_any {
-			if lastErr != nil {
-				return lastErr
-			}
-			return fmt.Errorf("failed to resolve dependency %s with any candidate version", depConstraint.modID)
+		switch {
+		case r.NotFound:
+			fmt.Fprintf(b, "%s  %s@%s: version not found\n", indent, e.ModID, r.VersionID)
+		case r.Yanked:
+			fmt.Fprintf(b, "%s  %s@%s: version was yanked\n", indent, e.ModID, r.VersionID)
+		case r.Incompatible != nil:
+			fmt.Fprintf(b, "%s  %s@%s: %s\n", indent, e.ModID, r.VersionID, r.Incompatible)
+		case r.Violation != nil:
+			fmt.Fprintf(b, "%s  %s@%s: %s\n", indent, e.ModID, r.VersionID, r.Violation)
+		case r.Cause != nil && depth < maxExplanationDepth:
+			fmt.Fprintf(b, "%s  %s@%s: ", indent, e.ModID, r.VersionID)
+			r.Cause.write(b, indent+"    ", depth+1)
+		case r.Cause != nil:
+			fmt.Fprintf(b, "%s  %s@%s: %s\n", indent, e.ModID, r.VersionID, r.Cause.Summary())
 		}
-
-		// Continue from the successfully resolved branch's remaining dependency state.
-		clear(allDeps)
-		maps.Copy(allDeps, remainingDepsAfterResolve)
 	}
+}
 
-	return nil
+type dependencySolver struct {
+	config     resolveConfig
+	provider   VersionProvider
+	versions   map[string]*ModVersion
+	versionIDs map[string][]string
+	latest     map[string]*ModVersion
+	attempts   int
 }
 
-// getCandidateVersionsForConstraints returns versions that satisfy ALL given constraints
-func getCandidateVersionsForConstraints(provider VersionProvider, constraints []model.ModVersionDependency) ([]*ModVersion, error) {
-	if len(constraints) == 0 {
-		return nil, fmt.Errorf("no constraints provided")
+// solve picks a version for the first unsatisfied required dependency and recurses until none is left.
+// A non-nil *ResolutionError means the selection cannot be completed; error is reserved for provider failures.
+func (s *dependencySolver) solve(selected map[string]ModVersion, requiredBy map[string]DependencyEdge) (map[string]ModVersion, *ResolutionError, error) {
+	if err := s.prefetch(selected); err != nil {
+		return nil, nil, err
 	}
-
-	modID := constraints[0].ModID
-
-	// Check if all constraints are exact and identical
-	firstConstraint := strings.TrimSpace(constraints[0].VersionID)
-	allSame := true
-	for _, c := range constraints {
-		if strings.TrimSpace(c.VersionID) != firstConstraint {
-			allSame = false
-			break
-		}
+	target, edge, ok, err := nextOpenRequirement(selected)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	if allSame {
-		// All constraints are the same, use single constraint logic
-		return getCandidateVersions(provider, constraints[0])
+	if !ok {
+		return selected, nil, nil
 	}
 
-	// Get all available versions
-	versionIDs, err := provider.GetModVersionIDs(modID, 100, "")
+	constraints, err := constraintsOn(selected, target)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list versions for dependency %s: %w", modID, err)
+		return nil, nil, err
+	}
+	nextRequiredBy := maps.Clone(requiredBy)
+	nextRequiredBy[target] = edge
+	conflict := &ResolutionError{
+		ModID:       target,
+		Chain:       dependencyChain(nextRequiredBy, target),
+		Constraints: constraints,
 	}
 
-	// Filter versions that satisfy ALL constraints
-	matchingVersionIDs := make([]string, 0)
-	for _, versionID := range versionIDs {
-		satisfiesAll := true
-		for _, constraint := range constraints {
-			constraintStr := strings.TrimSpace(constraint.VersionID)
-
-			// Check if this version satisfies this constraint
-			if constraintStr == "" || strings.EqualFold(constraintStr, "any") {
-				continue // "any" is always satisfied
-			}
-
-			if strings.EqualFold(constraintStr, "latest") {
-				// For "latest", we'll check later
-				continue
-			}
-
-			if isExactVersionConstraint(constraintStr) {
-				if versionID != constraintStr {
-					satisfiesAll = false
-					break
-				}
-			} else {
-				group := version.NewConstrainGroupFromString(constraintStr)
-				if !group.Match(versionID) {
-					satisfiesAll = false
-					break
-				}
-			}
+	tried := make(map[string]bool)
+	try := func(versionID string) (map[string]ModVersion, error) {
+		if tried[versionID] {
+			return nil, nil
 		}
-
-		if satisfiesAll {
-			matchingVersionIDs = append(matchingVersionIDs, versionID)
+		tried[versionID] = true
+		s.attempts++
+		if s.attempts > maxResolveAttempts {
+			return nil, fmt.Errorf("dependency resolution gave up after trying %d versions", maxResolveAttempts)
 		}
-	}
 
-	// Handle "latest" constraints
-	hasLatestConstraint := false
-	for _, constraint := range constraints {
-		if strings.EqualFold(strings.TrimSpace(constraint.VersionID), "latest") {
-			hasLatestConstraint = true
-			break
+		candidate, err := s.version(target, versionID)
+		if err != nil {
+			return nil, err
+		}
+		if candidate == nil {
+			conflict.Rejected = append(conflict.Rejected, RejectedVersion{VersionID: versionID, NotFound: true})
+			return nil, nil
+		}
+		if candidate.IsYanked() && !pinsExactly(constraints, versionID) {
+			conflict.Rejected = append(conflict.Rejected, RejectedVersion{VersionID: versionID, Yanked: true})
+			return nil, nil
+		}
+		violation, err := s.checkCandidate(selected, constraints, *candidate)
+		if err != nil {
+			return nil, err
+		}
+		if violation != nil {
+			conflict.Rejected = append(conflict.Rejected, RejectedVersion{VersionID: versionID, Violation: violation})
+			return nil, nil
+		}
+		if inc := candidate.CheckCompatibility(s.config.binaryType, s.config.gameVersion); inc != nil {
+			conflict.Rejected = append(conflict.Rejected, RejectedVersion{VersionID: versionID, Incompatible: inc})
+			return nil, nil
 		}
-	}
 
-	if hasLatestConstraint && len(matchingVersionIDs) > 0 {
-		// If there's a "latest" constraint, only return the latest matching version
-		latest, err := provider.GetLatestModVersion(modID)
+		next := maps.Clone(selected)
+		next[target] = *candidate
+		resolved, cause, err := s.solve(next, nextRequiredBy)
 		if err != nil {
 			return nil, err
 		}
-		if latest != nil {
-			// Check if latest is in our matching list
-			if slices.Contains(matchingVersionIDs, latest.VersionID) {
-				return []*ModVersion{latest}, nil
-			}
+		if cause != nil {
+			conflict.Rejected = append(conflict.Rejected, RejectedVersion{VersionID: versionID, Cause: cause})
+			return nil, nil
 		}
-		// Latest doesn't satisfy other constraints
-		return nil, nil
+		return resolved, nil
 	}
 
-	// Sort matching versions (newest first)
-	for i := 0; i < len(matchingVersionIDs)-1; i++ {
-		for j := i + 1; j < len(matchingVersionIDs); j++ {
-			if compareVersionID(matchingVersionIDs[i], matchingVersionIDs[j]) < 0 {
-				matchingVersionIDs[i], matchingVersionIDs[j] = matchingVersionIDs[j], matchingVersionIDs[i]
+	preferred, exhaustive, err := s.preferredCandidates(target, constraints)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, versionID := range preferred {
+		if resolved, err := try(versionID); err != nil || resolved != nil {
+			return resolved, nil, err
+		}
+	}
+	if !exhaustive {
+		versionIDs, err := s.listVersionIDs(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, versionID := range versionIDs {
+			if !matchesRangeConstraints(versionID, constraints) {
+				continue
+			}
+			if resolved, err := try(versionID); err != nil || resolved != nil {
+				return resolved, nil, err
 			}
 		}
 	}
+	return nil, conflict, nil
+}
 
-	// Fetch version objects
-	candidates := make([]*ModVersion, 0, len(matchingVersionIDs))
-	for _, versionID := range matchingVersionIDs {
-		depVersion, err := provider.GetModVersion(modID, versionID)
-		if err != nil {
+// preferredCandidates returns the versions to try first. When exhaustive is true no other version can satisfy
+// the constraints, e.g. because one of them pins an exact version.
+func (s *dependencySolver) preferredCandidates(target string, constraints []DependencyEdge) ([]string, bool, error) {
+	for _, c := range constraints {
+		if c.Type != ModDependencyTypeRequired {
 			continue
 		}
-		if depVersion != nil {
-			candidates = append(candidates, depVersion)
+		constraint := strings.TrimSpace(c.Constraint)
+		if constraint != "" && isExactVersionConstraint(constraint) {
+			return []string{constraint}, true, nil
 		}
 	}
 
-	return candidates, nil
+	latest, err := s.usableLatestVersion(target)
+	if err != nil {
+		return nil, false, err
+	}
+	pinnedToLatest := slices.ContainsFunc(constraints, func(c DependencyEdge) bool {
+		return c.Type == ModDependencyTypeRequired && strings.EqualFold(strings.TrimSpace(c.Constraint), "latest")
+	})
+	if latest == nil {
+		return nil, pinnedToLatest, nil
+	}
+	return []string{latest.VersionID}, pinnedToLatest, nil
 }
 
-// getCandidateVersions returns a list of candidate versions in priority order (best to worst)
-func getCandidateVersions(provider VersionProvider, dep model.ModVersionDependency) ([]*ModVersion, error) {
-	constraint := strings.TrimSpace(dep.VersionID)
-
-	// Handle "any", "latest", or empty constraint
-	if constraint == "" || strings.EqualFold(constraint, "any") || strings.EqualFold(constraint, "latest") {
-		depVersion, err := provider.GetLatestModVersion(dep.ModID)
+// checkSelected verifies that the mods fixed by the caller do not clash with each other.
+func (s *dependencySolver) checkSelected(selected map[string]ModVersion) (*ResolutionError, error) {
+	for _, modID := range slices.Sorted(maps.Keys(selected)) {
+		constraints, err := constraintsOn(selected, modID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch dependency %s (version: %s): %w", dep.ModID, dep.VersionID, err)
+			return nil, err
 		}
-		if depVersion == nil {
-			return nil, fmt.Errorf("failed to fetch dependency %s (version: %s): version not found", dep.ModID, dep.VersionID)
+		for _, c := range constraints {
+			violation, err := s.checkEdge(c, selected[modID])
+			if err != nil {
+				return nil, err
+			}
+			if violation != nil {
+				return &ResolutionError{ModID: modID, Constraints: constraints, Violation: violation}, nil
+			}
 		}
-		return []*ModVersion{depVersion}, nil
 	}
+	return nil, nil
+}
 
-	// Handle exact version constraint
-	if isExactVersionConstraint(constraint) {
-		depVersion, err := provider.GetModVersion(dep.ModID, constraint)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch dependency %s (version: %s): %w", dep.ModID, dep.VersionID, err)
-		}
-		if depVersion == nil {
-			return nil, fmt.Errorf("failed to fetch dependency %s (version: %s): version not found", dep.ModID, dep.VersionID)
+// checkCandidate checks the candidate against the edges of the selected mods and its own edges against the selected mods.
+func (s *dependencySolver) checkCandidate(selected map[string]ModVersion, constraints []DependencyEdge, candidate ModVersion) (*ConstraintViolation, error) {
+	for _, c := range constraints {
+		if violation, err := s.checkEdge(c, candidate); err != nil || violation != nil {
+			return violation, err
 		}
-		return []*ModVersion{depVersion}, nil
 	}
-
-	// Handle range constraint - get all matching versions in descending order
-	versionIDs, err := provider.GetModVersionIDs(dep.ModID, 100, "")
+	edges, err := dependencyEdges(candidate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list versions for dependency %s: %w", dep.ModID, err)
+		return nil, err
+	}
+	for _, edge := range edges {
+		target, ok := selected[edge.To]
+		if !ok {
+			continue
+		}
+		if violation, err := s.checkEdge(edge, target); err != nil || violation != nil {
+			return violation, err
+		}
 	}
+	return nil, nil
+}
 
-	matchingVersionIDs := getAllMatchingVersionIDs(versionIDs, constraint)
-	if len(matchingVersionIDs) == 0 {
+// checkEdge reports whether target, a selected version of edge.To, violates the edge.
+func (s *dependencySolver) checkEdge(edge DependencyEdge, target ModVersion) (*ConstraintViolation, error) {
+	if edge.Type == ModDependencyTypeEmbedded {
+		// Embedded means the dependency is bundled by the mod. It can coexist when selected explicitly.
 		return nil, nil
 	}
+	matched, expected, err := s.matches(edge, target.VersionID)
+	if err != nil {
+		return nil, err
+	}
+	if matched == (edge.Type == ModDependencyTypeConflict) {
+		return &ConstraintViolation{Edge: edge, VersionID: target.VersionID, Expected: expected}, nil
+	}
+	return nil, nil
+}
 
-	// Fetch all matching versions
-	candidates := make([]*ModVersion, 0, len(matchingVersionIDs))
-	for _, versionID := range matchingVersionIDs {
-		depVersion, err := provider.GetModVersion(dep.ModID, versionID)
+func (s *dependencySolver) matches(edge DependencyEdge, versionID string) (bool, string, error) {
+	constraint := strings.TrimSpace(edge.Constraint)
+	switch {
+	case constraint == "" || strings.EqualFold(constraint, "any"):
+		return true, "any", nil
+	case strings.EqualFold(constraint, "latest"):
+		latest, err := s.latestVersion(edge.To)
 		if err != nil {
-			continue // Skip versions that fail to fetch
+			return false, "", err
+		}
+		if latest == nil {
+			return false, "", fmt.Errorf("failed to fetch latest dependency %s: version not found", edge.To)
 		}
-		if depVersion != nil {
-			candidates = append(candidates, depVersion)
+		usable, err := s.usableLatestVersion(edge.To)
+		if err != nil {
+			return false, "", err
 		}
+		if usable.VersionID == latest.VersionID {
+			return versionID == latest.VersionID, fmt.Sprintf("latest (%s)", latest.VersionID), nil
+		}
+		// A yanked latest version still matches when the profile selected it itself
+		return versionID == usable.VersionID || versionID == latest.VersionID, fmt.Sprintf("latest (%s)", usable.VersionID), nil
+	default:
+		return MatchesConstraint(constraint, versionID, ""), constraint, nil
 	}
-
-	return candidates, nil
 }
 
-func validateResolvedDependencies(resolved map[string]ModVersion, provider VersionProvider, failedRequired map[string]error) error {
-	embeddedProviders, err := collectEmbeddedProviders(resolved)
-	if err != nil {
-		return err
+// prefetch fetches in one batch the versions that the edges of the selected mods point at and that are not cached yet:
+// the exact version or else the latest version of every open requirement, and the latest version of every mod
+// constrained to "latest".
+func (s *dependencySolver) prefetch(selected map[string]ModVersion) error {
+	batch, ok := s.provider.(BatchVersionProvider)
+	if !ok {
+		return nil
 	}
 
-	for _, current := range resolved {
-		for _, dep := range current.Dependencies {
-			depType, err := normalizeDependencyType(dep.DependencyType)
-			if err != nil {
-				return fmt.Errorf("invalid dependency type for %s@%s -> %s: %w", current.ModID, current.VersionID, dep.ModID, err)
+	embedded := make(map[string]bool)
+	var edges []DependencyEdge
+	for _, modID := range slices.Sorted(maps.Keys(selected)) {
+		modEdges, err := dependencyEdges(selected[modID])
+		if err != nil {
+			return err
+		}
+		for _, edge := range modEdges {
+			if edge.Type == ModDependencyTypeEmbedded {
+				embedded[edge.To] = true
 			}
+		}
+		edges = append(edges, modEdges...)
+	}
 
-			resolvedDep, found := resolved[dep.ModID]
-			switch depType {
-			case ModDependencyTypeRequired:
-				if !found && len(embeddedProviders[dep.ModID]) == 0 {
-					if failedRequired != nil {
-						if resolveErr, ok := failedRequired[requiredFailureKey(current, dep)]; ok {
-							return resolveErr
-						}
-					}
-					return fmt.Errorf("required dependency not resolved for mod %s: %s", current.ModID, dep.ModID)
-				}
-				if found {
-					if err := checkDependencyConstraint(resolvedDep, dep, provider); err != nil {
-						return err
-					}
-				}
-			case ModDependencyTypeOptional:
-				if found {
-					if err := checkOptionalDependencyConstraint(resolvedDep, dep, provider); err != nil {
-						return err
-					}
-				}
-			case ModDependencyTypeConflict:
-				if found {
-					if err := checkConflictDependencyConstraint(resolvedDep, dep, provider); err != nil {
-						return err
-					}
-				}
-			case ModDependencyTypeEmbedded:
-				// Embedded means the dependency is bundled by the current mod.
-				// It can coexist in resolved set (e.g. explicitly selected), so no error here.
+	var refs []model.ModVersionRef
+	add := func(ref model.ModVersionRef) {
+		if ref.VersionID == "" {
+			if _, ok := s.latest[ref.ModID]; ok {
+				return
 			}
+		} else if _, ok := s.versions[ref.ModID+"@"+ref.VersionID]; ok {
+			return
+		}
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	for _, edge := range edges {
+		constraint := strings.TrimSpace(edge.Constraint)
+		_, isSelected := selected[edge.To]
+		switch {
+		case edge.Type == ModDependencyTypeEmbedded:
+		case strings.EqualFold(constraint, "latest"):
+			add(model.ModVersionRef{ModID: edge.To})
+		case edge.Type != ModDependencyTypeRequired || isSelected || embedded[edge.To]:
+		case constraint != "" && isExactVersionConstraint(constraint):
+			add(model.ModVersionRef{ModID: edge.To, VersionID: constraint})
+		default:
+			add(model.ModVersionRef{ModID: edge.To})
 		}
 	}
+	if len(refs) == 0 {
+		return nil
+	}
 
+	versions, err := batch.GetModVersions(refs)
+	if err != nil {
+		return fmt.Errorf("failed to fetch dependencies: %w", err)
+	}
+	if len(versions) != len(refs) {
+		return fmt.Errorf("failed to fetch dependencies: got %d versions for %d requests", len(versions), len(refs))
+	}
+	for i, ref := range refs {
+		v := versions[i]
+		if ref.VersionID != "" {
+			s.versions[ref.ModID+"@"+ref.VersionID] = v
+			continue
+		}
+		s.latest[ref.ModID] = v
+		if v != nil {
+			s.versions[ref.ModID+"@"+v.VersionID] = v
+		}
+	}
 	return nil
 }
 
-func collectEmbeddedProviders(mods map[string]ModVersion) (map[string][]string, error) {
-	embeddedProviders := make(map[string][]string)
-	for _, current := range mods {
-		for _, dep := range current.Dependencies {
-			depType, err := normalizeDependencyType(dep.DependencyType)
-			if err != nil {
-				return nil, fmt.Errorf("invalid dependency type for %s@%s -> %s: %w", current.ModID, current.VersionID, dep.ModID, err)
-			}
-			if depType == ModDependencyTypeEmbedded {
-				embeddedProviders[dep.ModID] = append(embeddedProviders[dep.ModID], current.ModID)
-			}
-		}
+func (s *dependencySolver) version(modID, versionID string) (*ModVersion, error) {
+	key := modID + "@" + versionID
+	if v, ok := s.versions[key]; ok {
+		return v, nil
+	}
+	v, err := s.provider.GetModVersion(modID, versionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dependency %s (version: %s): %w", modID, versionID, err)
 	}
-	return embeddedProviders, nil
+	s.versions[key] = v
+	return v, nil
 }
 
-func checkDependencyConstraint(resolvedDep ModVersion, dep model.ModVersionDependency, provider VersionProvider) error {
-	matched, requiredVersion, err := matchesDependencyConstraint(resolvedDep, dep, provider)
+func (s *dependencySolver) latestVersion(modID string) (*ModVersion, error) {
+	if v, ok := s.latest[modID]; ok {
+		return v, nil
+	}
+	v, err := s.provider.GetLatestModVersion(modID)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to fetch latest dependency %s: %w", modID, err)
 	}
-	if !matched {
-		return fmt.Errorf("version conflict for mod %s: required %s but resolved %s", dep.ModID, requiredVersion, resolvedDep.VersionID)
+	s.latest[modID] = v
+	if v != nil {
+		s.versions[modID+"@"+v.VersionID] = v
 	}
-	return nil
+	return v, nil
 }
 
-func checkOptionalDependencyConstraint(resolvedDep ModVersion, dep model.ModVersionDependency, provider VersionProvider) error {
-	matched, requiredVersion, err := matchesDependencyConstraint(resolvedDep, dep, provider)
+// usableLatestVersion returns the latest version of the mod, or the newest version that is not yanked when the latest
+// one is, e.g. until the registry points its latest version elsewhere. When every version is yanked the latest one is
+// returned, to be rejected as such.
+func (s *dependencySolver) usableLatestVersion(modID string) (*ModVersion, error) {
+	latest, err := s.latestVersion(modID)
+	if err != nil || latest == nil || !latest.IsYanked() {
+		return latest, err
+	}
+	versionIDs, err := s.listVersionIDs(modID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !matched {
-		return fmt.Errorf("optional dependency version conflict for mod %s: optional %s but resolved %s", dep.ModID, requiredVersion, resolvedDep.VersionID)
+	for _, versionID := range versionIDs {
+		v, err := s.version(modID, versionID)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil && !v.IsYanked() {
+			return v, nil
+		}
 	}
-	return nil
+	return latest, nil
 }
 
-func checkConflictDependencyConstraint(resolvedDep ModVersion, dep model.ModVersionDependency, provider VersionProvider) error {
-	matched, conflictVersion, err := matchesDependencyConstraint(resolvedDep, dep, provider)
+// listVersionIDs returns the known versions of the mod, newest first.
+func (s *dependencySolver) listVersionIDs(modID string) ([]string, error) {
+	if ids, ok := s.versionIDs[modID]; ok {
+		return ids, nil
+	}
+	ids, err := s.provider.GetModVersionIDs(modID, 100, "")
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to list versions for dependency %s: %w", modID, err)
 	}
-	if matched {
-		return fmt.Errorf("dependency conflict for mod %s: conflicted %s and resolved %s", dep.ModID, conflictVersion, resolvedDep.VersionID)
+	ids = slices.Clone(ids)
+	slices.SortStableFunc(ids, func(a, b string) int {
+		return compareVersionID(b, a)
+	})
+	s.versionIDs[modID] = ids
+	return ids, nil
+}
+
+// matchesRangeConstraints filters listed versions cheaply before they are fetched; every other edge is checked by checkCandidate.
+func matchesRangeConstraints(versionID string, constraints []DependencyEdge) bool {
+	for _, c := range constraints {
+		constraint := strings.TrimSpace(c.Constraint)
+		if c.Type != ModDependencyTypeRequired || constraint == "" || strings.EqualFold(constraint, "any") || strings.EqualFold(constraint, "latest") || isExactVersionConstraint(constraint) {
+			continue
+		}
+		if !version.NewConstrainGroupFromString(constraint).Match(versionID) {
+			return false
+		}
 	}
-	return nil
+	return true
 }
 
-func matchesDependencyConstraint(resolvedDep ModVersion, dep model.ModVersionDependency, provider VersionProvider) (bool, string, error) {
-	constraint := strings.TrimSpace(dep.VersionID)
-	if constraint == "" || strings.EqualFold(constraint, "any") {
-		return true, "any", nil
+// pinsExactly reports whether a required constraint asks for exactly versionID.
+func pinsExactly(constraints []DependencyEdge, versionID string) bool {
+	return slices.ContainsFunc(constraints, func(c DependencyEdge) bool {
+		return c.Type == ModDependencyTypeRequired && strings.TrimSpace(c.Constraint) == versionID && isExactVersionConstraint(versionID)
+	})
+}
+
+// nextOpenRequirement returns the smallest mod ID that a selected mod requires but that is neither selected nor embedded,
+// together with the first edge requiring it.
+func nextOpenRequirement(selected map[string]ModVersion) (string, DependencyEdge, bool, error) {
+	embedded := make(map[string]bool)
+	var open []DependencyEdge
+	for _, modID := range slices.Sorted(maps.Keys(selected)) {
+		edges, err := dependencyEdges(selected[modID])
+		if err != nil {
+			return "", DependencyEdge{}, false, err
+		}
+		for _, edge := range edges {
+			switch edge.Type {
+			case ModDependencyTypeEmbedded:
+				embedded[edge.To] = true
+			case ModDependencyTypeRequired:
+				if _, ok := selected[edge.To]; !ok {
+					open = append(open, edge)
+				}
+			}
+		}
+	}
+
+	var (
+		next  DependencyEdge
+		found bool
+	)
+	for _, edge := range open {
+		if embedded[edge.To] {
+			continue
+		}
+		if !found || edge.To < next.To {
+			next, found = edge, true
+		}
 	}
+	return next.To, next, found, nil
+}
 
-	if isExactVersionConstraint(constraint) {
-		return resolvedDep.VersionID == constraint, dep.VersionID, nil
+// constraintsOn returns the edges of the selected mods pointing at modID, ordered by the declaring mod.
+func constraintsOn(selected map[string]ModVersion, modID string) ([]DependencyEdge, error) {
+	var constraints []DependencyEdge
+	for _, from := range slices.Sorted(maps.Keys(selected)) {
+		edges, err := dependencyEdges(selected[from])
+		if err != nil {
+			return nil, err
+		}
+		for _, edge := range edges {
+			if edge.To == modID && edge.Type != ModDependencyTypeEmbedded {
+				constraints = append(constraints, edge)
+			}
+		}
 	}
+	return constraints, nil
+}
 
-	if strings.EqualFold(constraint, "latest") {
-		latest, err := provider.GetLatestModVersion(dep.ModID)
+func dependencyEdges(mod ModVersion) ([]DependencyEdge, error) {
+	edges := make([]DependencyEdge, 0, len(mod.Dependencies))
+	for _, dep := range mod.Dependencies {
+		depType, err := normalizeDependencyType(dep.DependencyType)
 		if err != nil {
-			return false, "", fmt.Errorf("failed to fetch latest dependency %s: %w", dep.ModID, err)
+			return nil, fmt.Errorf("invalid dependency type for %s@%s -> %s: %w", mod.ModID, mod.VersionID, dep.ModID, err)
 		}
-		if latest == nil {
-			return false, "", fmt.Errorf("failed to fetch latest dependency %s: version not found", dep.ModID)
+		edges = append(edges, DependencyEdge{
+			From:        mod.ModID,
+			FromVersion: mod.VersionID,
+			To:          dep.ModID,
+			Constraint:  dep.VersionID,
+			Type:        depType,
+		})
+	}
+	return edges, nil
+}
+
+// dependencyChain follows the edges that pulled in each mod back to a mod selected by the caller.
+func dependencyChain(requiredBy map[string]DependencyEdge, modID string) []DependencyEdge {
+	var chain []DependencyEdge
+	seen := make(map[string]bool)
+	for {
+		edge, ok := requiredBy[modID]
+		if !ok || seen[modID] {
+			break
 		}
-		return resolvedDep.VersionID == latest.VersionID, fmt.Sprintf("latest (%s)", latest.VersionID), nil
+		seen[modID] = true
+		chain = append(chain, edge)
+		modID = edge.From
 	}
+	slices.Reverse(chain)
+	return chain
+}
 
-	return version.NewConstrainGroupFromString(constraint).Match(resolvedDep.VersionID), dep.VersionID, nil
+func constraintString(constraint string) string {
+	if constraint = strings.TrimSpace(constraint); constraint == "" {
+		return "any"
+	}
+	return constraint
 }
 
 func normalizeDependencyType(depType model.DependencyType) (ModDependencyType, error) {
@@ -566,34 +739,50 @@ func normalizeDependencyType(depType model.DependencyType) (ModDependencyType, e
 	}
 }
 
-func requiredFailureKey(current ModVersion, dep model.ModVersionDependency) string {
-	return fmt.Sprintf("%s@%s->%s@%s", current.ModID, current.VersionID, dep.ModID, dep.VersionID)
-}
-
 func isExactVersionConstraint(constraint string) bool {
-	return !strings.ContainsAny(constraint, "<>!=~*xX,^@,") && !strings.EqualFold(constraint, "latest") && !strings.EqualFold(constraint, "any")
+	return !strings.ContainsAny(constraint, "<>!=~*xX,^@") && !strings.EqualFold(constraint, "latest") && !strings.EqualFold(constraint, "any")
 }
 
-// getAllMatchingVersionIDs returns all versions that match the constraint, sorted from newest to oldest
-func getAllMatchingVersionIDs(versionIDs []string, constraint string) []string {
-	group := version.NewConstrainGroupFromString(constraint)
-	matching := make([]string, 0)
-	for _, versionID := range versionIDs {
-		if group.Match(versionID) {
-			matching = append(matching, versionID)
+var (
+	rangeConstraintPattern    = regexp.MustCompile(`^(?:[<>]=?|!=|==?|~|\^)?\s*v?\d+(?:\.(?:\d+|[xX*]))*(?:[-+.]?[0-9A-Za-z][0-9A-Za-z.-]*)?(?:@[A-Za-z]+)?$`)
+	wildcardConstraintPattern = regexp.MustCompile(`^[xX*](?:\.[xX*])*$`)
+	constraintSeparator       = regexp.MustCompile(`\s*,\s*`)
+)
+
+// ValidateConstraint checks that the resolver understands a dependency constraint: empty, "any", "latest", an exact
+// version ID, or comma separated version ranges such as ">=v1.0.0, <v2.0.0".
+func ValidateConstraint(constraint string) error {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" || strings.EqualFold(constraint, "any") || strings.EqualFold(constraint, "latest") || isExactVersionConstraint(constraint) {
+		return nil
+	}
+	for _, part := range constraintSeparator.Split(constraint, -1) {
+		if !rangeConstraintPattern.MatchString(part) && !wildcardConstraintPattern.MatchString(part) {
+			return fmt.Errorf("invalid version constraint %q", constraint)
 		}
 	}
+	return nil
+}
 
-	// Sort in descending order (newest first)
-	for i := 0; i < len(matching)-1; i++ {
-		for j := i + 1; j < len(matching); j++ {
-			if compareVersionID(matching[i], matching[j]) < 0 {
-				matching[i], matching[j] = matching[j], matching[i]
-			}
-		}
+// MatchesConstraint reports whether versionID satisfies constraint. "latest" only matches latestID, the latest version
+// of the mod, if there is one.
+func MatchesConstraint(constraint, versionID, latestID string) bool {
+	constraint = strings.TrimSpace(constraint)
+	switch {
+	case constraint == "" || strings.EqualFold(constraint, "any"):
+		return true
+	case strings.EqualFold(constraint, "latest"):
+		return latestID != "" && versionID == latestID
+	case isExactVersionConstraint(constraint):
+		return versionID == constraint
+	default:
+		return version.NewConstrainGroupFromString(constraint).Match(versionID)
 	}
+}
 
-	return matching
+// CompareVersionIDs orders version IDs the way the resolver does, returning -1, 0 or 1.
+func CompareVersionIDs(a, b string) int {
+	return compareVersionID(a, b)
 }
 
 func compareVersionID(a, b string) int {
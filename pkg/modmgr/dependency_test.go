@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
 )
 
 type mockVersionProvider struct {
@@ -631,3 +632,248 @@ func modVersion(modID, versionID string, deps ...model.ModVersionDependency) Mod
 		Dependencies: deps,
 	}
 }
+
+func TestResolveDependencies_ExplainsConflictChain(t *testing.T) {
+	// A requires B, B requires C >=v2.0.0, while D requires C <v2.0.0
+	b := modVersion("b", "v1.0.0", model.ModVersionDependency{
+		ModID:          "c",
+		VersionID:      ">=v2.0.0",
+		DependencyType: model.DependencyTypeRequired,
+	})
+	initial := []ModVersion{
+		modVersion("a", "v1.0.0", model.ModVersionDependency{
+			ModID:          "b",
+			VersionID:      "any",
+			DependencyType: model.DependencyTypeRequired,
+		}),
+		modVersion("d", "v1.0.0", model.ModVersionDependency{
+			ModID:          "c",
+			VersionID:      "<v2.0.0",
+			DependencyType: model.DependencyTypeRequired,
+		}),
+	}
+	provider := &mockVersionProvider{
+		versions: map[string]map[string]ModVersion{
+			"b": {"v1.0.0": b},
+			"c": {
+				"v1.0.0": modVersion("c", "v1.0.0"),
+				"v2.0.0": modVersion("c", "v2.0.0"),
+			},
+		},
+		ids: map[string][]string{
+			"b": {"v1.0.0"},
+			"c": {"v1.0.0", "v2.0.0"},
+		},
+		latest: map[string]string{"b": "v1.0.0", "c": "v2.0.0"},
+	}
+
+	_, err := ResolveDependencies(initial, provider)
+	var resolutionErr *ResolutionError
+	require.ErrorAs(t, err, &resolutionErr)
+	require.Equal(t, "b", resolutionErr.ModID)
+	require.Len(t, resolutionErr.Rejected, 1)
+
+	cause := resolutionErr.Rejected[0].Cause
+	require.NotNil(t, cause)
+	require.Equal(t, "c", cause.ModID)
+	require.Equal(t, []DependencyEdge{
+		{From: "a", FromVersion: "v1.0.0", To: "b", Constraint: "any", Type: ModDependencyTypeRequired},
+		{From: "b", FromVersion: "v1.0.0", To: "c", Constraint: ">=v2.0.0", Type: ModDependencyTypeRequired},
+	}, cause.Chain)
+	require.Len(t, cause.Constraints, 2)
+	for _, rejected := range cause.Rejected {
+		require.NotNil(t, rejected.Violation)
+	}
+	require.Contains(t, err.Error(), "required by: a@v1.0.0 -> b@v1.0.0 -> c")
+	require.Contains(t, err.Error(), "failed to find version for c satisfying all constraints")
+}
+
+func TestResolveDependencies_Deterministic(t *testing.T) {
+	// Only some combinations of x and y are compatible; the solver must always pick the same one.
+	initial := []ModVersion{
+		modVersion("a", "v1.0.0",
+			model.ModVersionDependency{ModID: "y", VersionID: "any", DependencyType: model.DependencyTypeRequired},
+			model.ModVersionDependency{ModID: "x", VersionID: "any", DependencyType: model.DependencyTypeRequired},
+		),
+	}
+	provider := &mockVersionProvider{
+		versions: map[string]map[string]ModVersion{
+			"x": {
+				"v1.0.0": modVersion("x", "v1.0.0"),
+				"v2.0.0": modVersion("x", "v2.0.0", model.ModVersionDependency{ModID: "y", VersionID: "v1.0.0", DependencyType: model.DependencyTypeConflict}),
+			},
+			"y": {
+				"v1.0.0": modVersion("y", "v1.0.0"),
+				"v2.0.0": modVersion("y", "v2.0.0", model.ModVersionDependency{ModID: "x", VersionID: "v2.0.0", DependencyType: model.DependencyTypeConflict}),
+			},
+		},
+		ids: map[string][]string{
+			"x": {"v1.0.0", "v2.0.0"},
+			"y": {"v1.0.0", "v2.0.0"},
+		},
+		latest: map[string]string{"x": "v2.0.0", "y": "v2.0.0"},
+	}
+
+	for range 20 {
+		resolved, err := ResolveDependencies(initial, provider)
+		require.NoError(t, err)
+		// x@v2.0.0 rules out every version of y, so x falls back while y keeps its latest version
+		require.Equal(t, "v1.0.0", resolved["x"].VersionID)
+		require.Equal(t, "v2.0.0", resolved["y"].VersionID)
+	}
+}
+
+func TestResolveDependencies_SkipsIncompatibleVersions(t *testing.T) {
+	initial := []ModVersion{modVersion("a", "v1.0.0", model.ModVersionDependency{ModID: "lib", VersionID: "any", DependencyType: model.DependencyTypeRequired})}
+	lib := func(versionID string, platform model.TargetPlatform, gameVersions ...string) ModVersion {
+		v := modVersion("lib", versionID)
+		v.GameVersions = gameVersions
+		v.Files = []model.ModVersionFile{{ID: versionID, TargetPlatform: platform}}
+		return v
+	}
+	provider := &mockVersionProvider{
+		versions: map[string]map[string]ModVersion{
+			"lib": {
+				"v1.0.0": lib("v1.0.0", model.TargetPlatformAny, "2024.6.18"),
+				"v2.0.0": lib("v2.0.0", model.TargetPlatformX86, "2024.6.18", "2025.3.25"),
+				"v3.0.0": lib("v3.0.0", model.TargetPlatformAny, "2025.3.25"),
+			},
+		},
+		ids:    map[string][]string{"lib": {"v1.0.0", "v2.0.0", "v3.0.0"}},
+		latest: map[string]string{"lib": "v3.0.0"},
+	}
+
+	resolved, err := ResolveDependencies(initial, provider, WithBinaryType(aumgr.BinaryType32Bit), WithGameVersion("2024.6.18"))
+	require.NoError(t, err)
+	require.Equal(t, "v2.0.0", resolved["lib"].VersionID)
+
+	resolved, err = ResolveDependencies(initial, provider, WithBinaryType(aumgr.BinaryType64Bit), WithGameVersion("2024.6.18"))
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", resolved["lib"].VersionID)
+
+	_, err = ResolveDependencies(initial, provider, WithBinaryType(aumgr.BinaryType64Bit), WithGameVersion("2023.10.24"))
+	var resolutionErr *ResolutionError
+	require.ErrorAs(t, err, &resolutionErr)
+	require.Equal(t, "lib", resolutionErr.ModID)
+	require.Len(t, resolutionErr.Rejected, 3)
+	require.Equal(t, &Incompatibility{GameVersion: "2023.10.24", SupportedGameVersions: []string{"2024.6.18", "2025.3.25"}, BinaryType: aumgr.BinaryType64Bit}, resolutionErr.Rejected[1].Incompatible)
+	require.Contains(t, err.Error(), "no version of lib satisfying all constraints is compatible with the installed game")
+	require.Contains(t, err.Error(), "lib@v2.0.0: incompatible: supports game versions 2024.6.18, 2025.3.25 but the game is 2023.10.24 and has no files for x64")
+}
+
+func TestResolveDependencies_SkipsYankedVersions(t *testing.T) {
+	yanked := modVersion("lib", "v2.0.0")
+	yanked.Status = model.VersionStatusYanked
+	yanked.StatusReason = "crashes on start"
+	deprecated := modVersion("lib", "v1.5.0")
+	deprecated.Status = model.VersionStatusDeprecated
+	provider := &mockVersionProvider{
+		versions: map[string]map[string]ModVersion{
+			"lib": {
+				"v1.0.0": modVersion("lib", "v1.0.0"),
+				"v1.5.0": deprecated,
+				"v2.0.0": yanked,
+			},
+		},
+		ids:    map[string][]string{"lib": {"v1.0.0", "v1.5.0", "v2.0.0"}},
+		latest: map[string]string{"lib": "v2.0.0"},
+	}
+	requires := func(constraint string) []ModVersion {
+		return []ModVersion{modVersion("a", "v1.0.0", model.ModVersionDependency{ModID: "lib", VersionID: constraint, DependencyType: model.DependencyTypeRequired})}
+	}
+
+	resolved, err := ResolveDependencies(requires("any"), provider)
+	require.NoError(t, err)
+	require.Equal(t, "v1.5.0", resolved["lib"].VersionID)
+
+	resolved, err = ResolveDependencies(requires(">=v1.0.0"), provider)
+	require.NoError(t, err)
+	require.Equal(t, "v1.5.0", resolved["lib"].VersionID)
+
+	resolved, err = ResolveDependencies(requires("v2.0.0"), provider)
+	require.NoError(t, err)
+	require.Equal(t, "v2.0.0", resolved["lib"].VersionID)
+
+	// "latest" falls back to the newest version that is not yanked
+	resolved, err = ResolveDependencies(requires("latest"), provider)
+	require.NoError(t, err)
+	require.Equal(t, "v1.5.0", resolved["lib"].VersionID)
+
+	// and fails as yanked when no version is left
+	onlyYanked := &mockVersionProvider{
+		versions: map[string]map[string]ModVersion{"lib": {"v2.0.0": yanked}},
+		ids:      map[string][]string{"lib": {"v2.0.0"}},
+		latest:   map[string]string{"lib": "v2.0.0"},
+	}
+	_, err = ResolveDependencies(requires("latest"), onlyYanked)
+	var resolutionErr *ResolutionError
+	require.ErrorAs(t, err, &resolutionErr)
+	require.Equal(t, []RejectedVersion{{VersionID: "v2.0.0", Yanked: true}}, resolutionErr.Rejected)
+	require.Contains(t, err.Error(), "lib@v2.0.0: version was yanked")
+
+	// A yanked version selected by the profile itself is kept
+	resolved, err = ResolveDependencies([]ModVersion{yanked}, provider)
+	require.NoError(t, err)
+	require.Equal(t, "v2.0.0", resolved["lib"].VersionID)
+}
+
+type batchVersionProvider struct {
+	*mockVersionProvider
+	batches [][]model.ModVersionRef
+	single  int
+}
+
+func (p *batchVersionProvider) GetModVersion(modID string, versionID string) (*ModVersion, error) {
+	p.single++
+	return p.mockVersionProvider.GetModVersion(modID, versionID)
+}
+
+func (p *batchVersionProvider) GetLatestModVersion(modID string) (*ModVersion, error) {
+	p.single++
+	return p.mockVersionProvider.GetLatestModVersion(modID)
+}
+
+func (p *batchVersionProvider) GetModVersions(refs []model.ModVersionRef) ([]*ModVersion, error) {
+	p.batches = append(p.batches, refs)
+	versions := make([]*ModVersion, len(refs))
+	for i, ref := range refs {
+		if ref.VersionID == "" {
+			versions[i], _ = p.mockVersionProvider.GetLatestModVersion(ref.ModID)
+		} else {
+			versions[i], _ = p.mockVersionProvider.GetModVersion(ref.ModID, ref.VersionID)
+		}
+	}
+	return versions, nil
+}
+
+func TestResolveDependencies_PrefetchesBatches(t *testing.T) {
+	required := func(modID, constraint string) model.ModVersionDependency {
+		return model.ModVersionDependency{ModID: modID, VersionID: constraint, DependencyType: model.DependencyTypeRequired}
+	}
+	provider := &batchVersionProvider{mockVersionProvider: &mockVersionProvider{
+		versions: map[string]map[string]ModVersion{
+			"b": {"v1.0.0": modVersion("b", "v1.0.0", required("d", "any"))},
+			"c": {"v1.2.0": modVersion("c", "v1.2.0", required("d", ">=v1.0.0"))},
+			"d": {"v1.0.0": modVersion("d", "v1.0.0")},
+		},
+		latest: map[string]string{"b": "v1.0.0", "c": "v2.0.0", "d": "v1.0.0"},
+	}}
+
+	resolved, err := ResolveDependencies([]ModVersion{modVersion("a", "v1.0.0", required("b", "any"), required("c", "v1.2.0"))}, provider)
+	require.NoError(t, err)
+	require.Len(t, resolved, 4)
+	require.Equal(t, [][]model.ModVersionRef{
+		{{ModID: "b"}, {ModID: "c", VersionID: "v1.2.0"}},
+		{{ModID: "d"}},
+	}, provider.batches)
+	require.Zero(t, provider.single)
+}
+
+func TestValidateConstraint(t *testing.T) {
+	for _, constraint := range []string{"", "any", "latest", "v1.2.0", ">=v1.0.0, <v2.0.0", "~1.2", "1.0.*", "!=v1.0.0-beta"} {
+		require.NoError(t, ValidateConstraint(constraint), constraint)
+	}
+	for _, constraint := range []string{">=", ">= foo", "<<v2", ">=v1.0.0 || <v0.5.0", ">v1,"} {
+		require.Error(t, ValidateConstraint(constraint), constraint)
+	}
+}
@@ -0,0 +1,496 @@
+package modmgr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/pkg/progress"
+)
+
+const (
+	DefaultDownloadConcurrency  = 4
+	DefaultDownloadAttempts     = 3
+	DefaultDownloadRetryBackoff = 1 * time.Second
+
+	// partialFileSuffix is appended to blob store files that are still being downloaded.
+	// Partial files are kept across runs so the next download can resume with an HTTP Range request.
+	partialFileSuffix = ".part"
+)
+
+type downloadConfig struct {
+	concurrency  int
+	attempts     int
+	retryBackoff time.Duration
+	httpClient   *http.Client
+	localMods    *LocalModStore
+}
+
+type DownloadOption func(*downloadConfig)
+
+// WithDownloadConcurrency sets the maximum number of files downloaded at the same time.
+func WithDownloadConcurrency(n int) DownloadOption {
+	return func(c *downloadConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithDownloadAttempts sets how many times each mirror is tried before falling back to the next one.
+func WithDownloadAttempts(n int) DownloadOption {
+	return func(c *downloadConfig) {
+		c.attempts = n
+	}
+}
+
+// WithDownloadRetryBackoff sets the initial delay between attempts. The delay doubles after every failed attempt.
+func WithDownloadRetryBackoff(d time.Duration) DownloadOption {
+	return func(c *downloadConfig) {
+		c.retryBackoff = d
+	}
+}
+
+func WithDownloadHTTPClient(client *http.Client) DownloadOption {
+	return func(c *downloadConfig) {
+		c.httpClient = client
+	}
+}
+
+// WithLocalModStore sets where the files of local mods are copied from.
+func WithLocalModStore(store *LocalModStore) DownloadOption {
+	return func(c *downloadConfig) {
+		c.localMods = store
+	}
+}
+
+func newDownloadConfig(opts []DownloadOption) *downloadConfig {
+	c := &downloadConfig{
+		concurrency:  DefaultDownloadConcurrency,
+		attempts:     DefaultDownloadAttempts,
+		retryBackoff: DefaultDownloadRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.concurrency <= 0 {
+		c.concurrency = 1
+	}
+	if c.attempts <= 0 {
+		c.attempts = 1
+	}
+	if c.httpClient == nil {
+		c.httpClient = newDownloadHTTPClient()
+	}
+	return c
+}
+
+// newDownloadHTTPClient returns a client with connection level timeouts.
+// No overall timeout is set because large archives can legitimately take minutes on slow links.
+func newDownloadHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   15 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConnsPerHost:   DefaultDownloadConcurrency,
+		},
+	}
+}
+
+// downloadJob is a single file of a mod version that has to be fetched into the blob store.
+type downloadJob struct {
+	index       int
+	mod         *ModVersion
+	file        model.ModVersionFile
+	store       *BlobStore
+	modCacheDir string
+	force       bool
+
+	// blob is set to the sha256 of the stored content once the job succeeds.
+	blob string
+	// duplicateOf is the job downloading the same content, which this job takes its blob from.
+	duplicateOf *downloadJob
+}
+
+// downloadProgress combines the progress of concurrently running downloads into a single value.
+// Every file owns an equal share of the total, matching the behaviour of the sequential downloader.
+type downloadProgress struct {
+	mu        sync.Mutex
+	listener  progress.Progress
+	fractions []float64
+}
+
+func newDownloadProgress(listener progress.Progress, n int) *downloadProgress {
+	return &downloadProgress{
+		listener:  listener,
+		fractions: make([]float64, n),
+	}
+}
+
+func (p *downloadProgress) set(index int, fraction float64) {
+	if p.listener == nil || len(p.fractions) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fractions[index] = min(max(fraction, 0.0), 1.0)
+	var sum float64
+	for _, f := range p.fractions {
+		sum += f
+	}
+	p.listener.SetValue(sum / float64(len(p.fractions)))
+}
+
+// fileProgressWriter reports the fraction of a single file written so far.
+type fileProgressWriter struct {
+	progress *downloadProgress
+	index    int
+	goal     int64
+	written  int64
+}
+
+func (w *fileProgressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if w.goal > 0 {
+		w.progress.set(w.index, float64(w.written)/float64(w.goal))
+	}
+	return len(p), nil
+}
+
+// errDownloadNotRetryable marks failures that will not go away by asking the same mirror again.
+var errDownloadNotRetryable = errors.New("not retryable")
+
+type downloadStatusError struct {
+	url    string
+	status string
+	code   int
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("unexpected status from %s: %s", e.url, e.status)
+}
+
+func (e *downloadStatusError) Is(target error) bool {
+	if target != errDownloadNotRetryable {
+		return false
+	}
+	if e.code == http.StatusRequestTimeout || e.code == http.StatusTooManyRequests {
+		return false
+	}
+	return e.code >= 400 && e.code < 500
+}
+
+// runDownloadJobs downloads all jobs using a bounded pool of workers.
+// The first failure cancels the remaining jobs; partial files are left in place so they can be resumed later.
+func runDownloadJobs(ctx context.Context, cfg *downloadConfig, jobs []*downloadJob, prog *downloadProgress) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	queue := make(chan *downloadJob)
+	var wg sync.WaitGroup
+	for range min(cfg.concurrency, len(jobs)) {
+		wg.Go(func() {
+			for job := range queue {
+				if err := downloadFileWithRetry(ctx, cfg, job, prog); err != nil {
+					cancel(fmt.Errorf("failed to download mod file %s@%s (%s): %w", job.mod.ModID, job.mod.VersionID, job.file.ID, err))
+				}
+			}
+		})
+	}
+
+enqueue:
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			break enqueue
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// downloadFileWithRetry tries every mirror of the file in order, retrying each one with exponential backoff.
+func downloadFileWithRetry(ctx context.Context, cfg *downloadConfig, job *downloadJob, prog *downloadProgress) error {
+	if len(job.file.Downloads) == 0 {
+		return fmt.Errorf("no download sources")
+	}
+	var lastErr error
+	for _, uri := range job.file.Downloads {
+		backoff := cfg.retryBackoff
+		for attempt := 1; attempt <= cfg.attempts; attempt++ {
+			var err error
+			if isLocalModURI(uri) {
+				err = copyLocalModFile(cfg.localMods, uri, job, prog)
+			} else {
+				err = downloadFile(ctx, cfg.httpClient, uri, job, prog)
+			}
+			if err == nil {
+				return nil
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			lastErr = err
+			slog.Warn("Failed to download mod file", "modId", job.mod.ModID, "versionId", job.mod.VersionID, "url", uri, "attempt", attempt, "error", err)
+			if errors.Is(err, errDownloadNotRetryable) || attempt == cfg.attempts {
+				break
+			}
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+		}
+	}
+	return fmt.Errorf("all download sources failed: %w", lastErr)
+}
+
+// downloadFile fetches a single file into the blob store, resuming from an existing partial file when possible.
+// The file is only moved into the store after its hashes have been verified.
+func downloadFile(ctx context.Context, hClient *http.Client, uri string, job *downloadJob, prog *downloadProgress) error {
+	if job.file.ContentType != model.ContentTypeArchive && job.file.ContentType != model.ContentTypeBinary && job.file.ContentType != model.ContentTypePluginDll {
+		return fmt.Errorf("unknown file type %s: %w", job.file.ContentType, errDownloadNotRetryable)
+	}
+	destPath := fileDestinationPath(job.file)
+	if destPath == "" || !filepath.IsLocal(destPath) {
+		return fmt.Errorf("invalid file path %q: %w", destPath, errDownloadNotRetryable)
+	}
+
+	if !job.force {
+		// Another mod version may already have stored the same content.
+		if sum := job.file.Hashes[blobAlgorithm]; job.store.Has(sum) {
+			if err := job.store.Verify(sum, job.file.Hashes); err == nil {
+				slog.Info("Mod file already in blob store", "modId", job.mod.ModID, "versionId", job.mod.VersionID, "file", destPath, "blob", sum)
+				job.blob = sum
+				prog.set(job.index, 1.0)
+				return nil
+			}
+		}
+		// Caches written before the blob store kept a plain copy next to the metadata.
+		if sum, ok := importLegacyCacheFile(job.store, filepath.Join(job.modCacheDir, destPath), job.file.Hashes); ok {
+			slog.Info("Moved legacy cached mod file into blob store", "modId", job.mod.ModID, "versionId", job.mod.VersionID, "file", destPath, "blob", sum)
+			job.blob = sum
+			prog.set(job.index, 1.0)
+			return nil
+		}
+	}
+
+	partPath := job.store.partialPath(partialDownloadKey(job.mod, job.file))
+	if err := os.MkdirAll(filepath.Dir(partPath), 0755); err != nil {
+		return fmt.Errorf("failed to create partial download directory: %w", err)
+	}
+	partFile, err := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open partial file: %w", err)
+	}
+	defer partFile.Close()
+
+	// Feed the bytes we already have into the hashers before appending more.
+	hasher := NewBlobHasher(job.file.Hashes)
+	offset, err := io.Copy(hasher, partFile)
+	if err != nil {
+		return fmt.Errorf("failed to read partial file: %w", err)
+	}
+
+	if job.file.Size > 0 && offset >= job.file.Size {
+		if _, err := hasher.Sum(); err == nil {
+			slog.Info("Partial mod file already complete", "modId", job.mod.ModID, "versionId", job.mod.VersionID, "file", destPath)
+			return finishPartialFile(job, partFile, hasher, prog)
+		}
+		slog.Warn("Partial mod file is corrupted, restarting download", "modId", job.mod.ModID, "versionId", job.mod.VersionID, "file", partPath)
+		if offset, hasher, err = resetPartialFile(partFile, job.file.Hashes); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w: %w", err, errDownloadNotRetryable)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := hClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			return fmt.Errorf("unexpected content range %q for offset %d", resp.Header.Get("Content-Range"), offset)
+		}
+		slog.Info("Resuming mod file download", "url", uri, "offset", offset)
+	case http.StatusOK:
+		if offset > 0 {
+			slog.Info("Server does not support resuming, restarting download", "url", uri)
+			if offset, hasher, err = resetPartialFile(partFile, job.file.Hashes); err != nil {
+				return err
+			}
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file may already hold the whole content when the size is not known up front.
+		if _, err := hasher.Sum(); err == nil {
+			return finishPartialFile(job, partFile, hasher, prog)
+		}
+		// Otherwise it does not match what the server has; start over on the next attempt.
+		if _, _, err := resetPartialFile(partFile, job.file.Hashes); err != nil {
+			return err
+		}
+		return fmt.Errorf("range not satisfiable for offset %d", offset)
+	default:
+		return &downloadStatusError{url: uri, status: resp.Status, code: resp.StatusCode}
+	}
+
+	if _, err := partFile.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek partial file: %w", err)
+	}
+	goal := job.file.Size
+	if goal <= 0 && resp.ContentLength > 0 {
+		goal = offset + resp.ContentLength
+	}
+	slog.Info("Downloading mod file", "url", uri, "contentLength", resp.ContentLength, "offset", offset)
+	pw := &fileProgressWriter{progress: prog, index: job.index, goal: goal, written: offset}
+	if _, err := io.Copy(io.MultiWriter(partFile, hasher, pw), resp.Body); err != nil {
+		return err
+	}
+
+	if _, err := hasher.Sum(); err != nil {
+		slog.Warn("File hash mismatch for downloaded file, deleting partial file", "modId", job.mod.ModID, "versionId", job.mod.VersionID, "file", partPath, "error", err)
+		if _, _, resetErr := resetPartialFile(partFile, job.file.Hashes); resetErr != nil {
+			slog.Warn("Failed to reset partial file after hash mismatch", "file", partPath, "error", resetErr)
+		}
+		return fmt.Errorf("downloaded file hash mismatch: %w", err)
+	}
+	return finishPartialFile(job, partFile, hasher, prog)
+}
+
+func finishPartialFile(job *downloadJob, partFile *os.File, hasher *BlobHasher, prog *downloadProgress) error {
+	sum, err := hasher.Sum()
+	if err != nil {
+		return err
+	}
+	slog.Info("File hash verified", "modId", job.mod.ModID, "versionId", job.mod.VersionID, "blob", sum)
+	if err := partFile.Close(); err != nil {
+		return fmt.Errorf("failed to close partial file: %w", err)
+	}
+	if err := job.store.Put(partFile.Name(), sum); err != nil {
+		return err
+	}
+	job.blob = sum
+	prog.set(job.index, 1.0)
+	return nil
+}
+
+func resetPartialFile(partFile *os.File, hashes map[string]string) (int64, *BlobHasher, error) {
+	if err := partFile.Truncate(0); err != nil {
+		return 0, nil, fmt.Errorf("failed to truncate partial file: %w", err)
+	}
+	if _, err := partFile.Seek(0, io.SeekStart); err != nil {
+		return 0, nil, fmt.Errorf("failed to seek partial file: %w", err)
+	}
+	return 0, NewBlobHasher(hashes), nil
+}
+
+// importLegacyCacheFile moves a verified file from the old per-version cache layout into the blob store.
+func importLegacyCacheFile(store *BlobStore, path string, hashes map[string]string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	hasher := NewBlobHasher(hashes)
+	_, err = io.Copy(hasher, f)
+	_ = f.Close()
+	if err != nil {
+		return "", false
+	}
+	sum, err := hasher.Sum()
+	if err != nil {
+		return "", false
+	}
+	if err := store.Put(path, sum); err != nil {
+		slog.Warn("Failed to move legacy cached file into blob store", "file", path, "error", err)
+		return "", false
+	}
+	return sum, true
+}
+
+// partialDownloadKey names the partial file of a download so that it survives metadata-only changes to the version.
+func partialDownloadKey(mod *ModVersion, file model.ModVersionFile) string {
+	if sum := file.Hashes[blobAlgorithm]; isValidBlobSum(sum) {
+		return sum
+	}
+	key := sha256.Sum256([]byte(mod.ModID + "\x00" + mod.VersionID + "\x00" + file.ID))
+	return hex.EncodeToString(key[:])
+}
+
+// BlobHasher verifies the declared hashes of a file while computing the sha256 used as its blob name.
+type BlobHasher struct {
+	checker HashCheckingWriter
+	sha     hash.Hash
+}
+
+func NewBlobHasher(hashes map[string]string) *BlobHasher {
+	return &BlobHasher{
+		checker: newHashWriters(hashes),
+		sha:     sha256.New(),
+	}
+}
+
+func (h *BlobHasher) Write(p []byte) (int, error) {
+	if _, err := h.checker.Write(p); err != nil {
+		return 0, err
+	}
+	return h.sha.Write(p)
+}
+
+// Sum returns the sha256 of the written content if it matches the declared hashes.
+func (h *BlobHasher) Sum() (string, error) {
+	if _, err := h.checker.Sum(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.sha.Sum(nil)), nil
+}
+
+// contentRangeStart parses the first byte position of a "bytes start-end/size" Content-Range header.
+func contentRangeStart(header string) (int64, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes ")
+	if !ok {
+		return 0, false
+	}
+	startStr, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(startStr), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
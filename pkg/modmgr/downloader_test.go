@@ -0,0 +1,121 @@
+package modmgr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
+)
+
+func downloadTestVersion(modID string, content []byte, downloads ...string) ModVersion {
+	sum := sha256.Sum256(content)
+	return ModVersion{ModVersionDetails: model.ModVersionDetails{
+		ModID:     modID,
+		VersionID: "v1.0.0",
+		Files: []model.ModVersionFile{{
+			ID:             modID + "-file",
+			Filename:       modID + ".dll",
+			ContentType:    model.ContentTypePluginDll,
+			Size:           int64(len(content)),
+			TargetPlatform: model.TargetPlatformAny,
+			Hashes:         map[string]string{"sha256": hex.EncodeToString(sum[:])},
+			Downloads:      downloads,
+		}},
+	}}
+}
+
+func TestDownloadMods_ResumesPartialFile(t *testing.T) {
+	content := bytes.Repeat([]byte("bepinex"), 1024)
+	var rangeHeader atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader.Store(r.Header.Get("Range"))
+		http.ServeContent(w, r, "a.dll", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	mod := downloadTestVersion("a", content, server.URL+"/a.dll")
+	store := NewBlobStore(cacheDir)
+	partPath := store.partialPath(partialDownloadKey(&mod, mod.Files[0]))
+	require.NoError(t, os.MkdirAll(filepath.Dir(partPath), 0755))
+	require.NoError(t, os.WriteFile(partPath, content[:1000], 0644))
+
+	require.NoError(t, DownloadMods(cacheDir, []ModVersion{mod}, aumgr.BinaryType64Bit, nil, false))
+
+	require.Equal(t, "bytes=1000-", rangeHeader.Load())
+	blobPath, err := store.Path(mod.Files[0].Hashes["sha256"])
+	require.NoError(t, err)
+	got, err := os.ReadFile(blobPath)
+	require.NoError(t, err)
+	require.Equal(t, content, got)
+	require.NoFileExists(t, partPath)
+
+	metadata, err := loadCacheMetadata(modCacheDirForTest(t, cacheDir, mod))
+	require.NoError(t, err)
+	require.Equal(t, mod.Files[0].Hashes["sha256"], metadata.Blobs[mod.Files[0].ID])
+}
+
+func TestDownloadMods_FallsBackToNextMirror(t *testing.T) {
+	content := []byte("plugin")
+	var brokenHits atomic.Int32
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		brokenHits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(append(content, r.URL.Path...))
+	}))
+	defer mirror.Close()
+
+	cacheDir := t.TempDir()
+	mods := []ModVersion{
+		downloadTestVersion("a", append(content, "/a.dll"...), broken.URL+"/a.dll", mirror.URL+"/a.dll"),
+		downloadTestVersion("b", append(content, "/b.dll"...), mirror.URL+"/b.dll"),
+	}
+	err := DownloadMods(cacheDir, mods, aumgr.BinaryType64Bit, nil, false,
+		WithDownloadAttempts(2),
+		WithDownloadRetryBackoff(time.Millisecond),
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, brokenHits.Load())
+
+	for _, mod := range mods {
+		require.True(t, isModVersionCached(NewBlobStore(cacheDir), modCacheDirForTest(t, cacheDir, mod), mod, aumgr.BinaryType64Bit))
+	}
+}
+
+func modCacheDirForTest(t *testing.T, cacheDir string, mod ModVersion) string {
+	t.Helper()
+	hashStr, err := hashModVersion(mod)
+	require.NoError(t, err)
+	return filepath.Join(cacheDir, string(aumgr.BinaryType64Bit), mod.ModID, hashStr)
+}
+
+func TestDownloadMods_NotFoundIsNotRetried(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	mod := downloadTestVersion("a", []byte("plugin"), server.URL+"/a.dll")
+	err := DownloadMods(t.TempDir(), []ModVersion{mod}, aumgr.BinaryType64Bit, nil, false,
+		WithDownloadAttempts(3),
+		WithDownloadRetryBackoff(time.Millisecond),
+	)
+	require.Error(t, err)
+	require.EqualValues(t, 1, hits.Load())
+}
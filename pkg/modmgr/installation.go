@@ -2,24 +2,29 @@ package modmgr
 
 import (
 	"archive/zip"
+	"context"
 	"encoding/json/v2"
 	"fmt"
 	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"path/filepath"
 
-	"github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
 	"github.com/ikafly144/au_mod_installer/pkg/progress"
 )
 
+// CacheMetadata is the manifest stored in the cache directory of a mod version.
 type CacheMetadata struct {
 	ModVersion ModVersion `json:"mod_version"`
+	// Blobs maps each downloaded file ID to the sha256 of its content in the BlobStore.
+	Blobs map[string]string `json:"blobs,omitempty"`
 }
 
-func DownloadMods(cacheDir string, modVersions []ModVersion, binaryType aumgr.BinaryType, progressListener progress.Progress, force bool) error {
+// DownloadMods fetches every compatible file of the given mod versions into the cache directory.
+// Files are downloaded concurrently, interrupted downloads are resumed from the partial files kept in the cache,
+// and every mirror in ModVersionFile.Downloads is retried with backoff before giving up.
+func DownloadMods(cacheDir string, modVersions []ModVersion, binaryType aumgr.BinaryType, progressListener progress.Progress, force bool, opts ...DownloadOption) error {
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return fmt.Errorf("failed to create cache directory: %w", err)
 	}
@@ -41,197 +46,140 @@ func DownloadMods(cacheDir string, modVersions []ModVersion, binaryType aumgr.Bi
 		defer progressListener.Done()
 	}
 
-	hClient := http.DefaultClient
+	cfg := newDownloadConfig(opts)
+	prog := newDownloadProgress(progressListener, totalDownloadCount)
+	store := NewBlobStore(cacheDir)
+
+	type pendingMod struct {
+		mod  *ModVersion
+		dir  string
+		jobs []*downloadJob
+	}
+	var (
+		pending []pendingMod
+		jobs    []*downloadJob
+		index   int
+		// Identical files share a partial download and a blob, so each content is fetched once
+		byContent = make(map[string]*downloadJob)
+	)
 	for i := range modVersions {
 		hashStr, err := hashModVersion(modVersions[i])
 		if err != nil {
 			return fmt.Errorf("failed to hash mod version: %w", err)
 		}
 		modCacheDir := filepath.Join(cacheDir, string(binaryType), modVersions[i].ModID, hashStr)
-		if _, err := os.Stat(modCacheDir); err == nil {
-			if !force {
-				// Load metadata and check if it matches the mod version
-				metaFile, err := os.Open(filepath.Join(modCacheDir, "metadata.json"))
-				if err != nil {
-					slog.Warn("Failed to open mod cache metadata, will re-download", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID, "error", err)
-					goto download
-				}
-				var metadata CacheMetadata
-				if err := json.UnmarshalRead(metaFile, &metadata); err != nil {
-					slog.Warn("Failed to decode mod cache metadata, will re-download", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID, "error", err)
-					goto download
-				} else if metadata.ModVersion.VersionID != modVersions[i].VersionID {
-					slog.Warn("Mod cache metadata version mismatch, will re-download", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID, "cachedVersionId", metadata.ModVersion.VersionID)
-					goto download
-				}
-
-				// Check if all files exist in cache
-				for file := range modVersions[i].Downloads(binaryType) {
-					cachedFilePath := filepath.Join(modCacheDir, fileDestinationPath(file))
-					if _, err := os.Stat(cachedFilePath); os.IsNotExist(err) {
-						slog.Info("Cached mod file not found, need to re-download", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID, "file", cachedFilePath)
-						goto download
-					}
-					hashChecker := newHashWriters(file.Hashes)
-					hashFile, err := os.Open(cachedFilePath)
-					if err != nil {
-						slog.Error("Failed to open cached mod file for hashing", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID, "file", cachedFilePath, "error", err)
-						goto download
-					}
-					if _, err := io.Copy(hashChecker, hashFile); err != nil {
-						slog.Error("Failed to hash cached mod file", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID, "file", cachedFilePath, "error", err)
-						hashFile.Close()
-						goto download
-					}
-					if _, err := hashChecker.Sum(); err != nil {
-						slog.Warn("Cached mod file hash mismatch, will re-download", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID, "file", cachedFilePath, "error", err)
-						hashFile.Close()
-						goto download
-					}
-					hashFile.Close()
-				}
-
-				slog.Info("Mod already cached", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID)
-				if progressListener != nil {
-					progressListener.SetValue(progressListener.GetValue() + (float64(modVersions[i].CompatibleFilesCount(binaryType)) / float64(totalDownloadCount)))
-				}
-				continue
-			} else {
-				slog.Info("Force re-downloading mod, clearing cache", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID)
-				if err := os.RemoveAll(modCacheDir); err != nil {
-					return fmt.Errorf("failed to clear mod cache: %w", err)
-				}
+		if !force && isModVersionCached(store, modCacheDir, modVersions[i], binaryType) {
+			slog.Info("Mod already cached", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID)
+			touchCacheEntry(modCacheDir)
+			for range modVersions[i].CompatibleFilesCount(binaryType) {
+				prog.set(index, 1.0)
+				index++
 			}
+			continue
+		}
+		if force {
+			slog.Info("Force re-downloading mod", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID)
 		}
-	download:
 
 		if err := os.MkdirAll(modCacheDir, 0755); err != nil {
 			return fmt.Errorf("failed to create mod cache directory: %w", err)
 		}
 
-		modCacheRoot, err := os.OpenRoot(modCacheDir)
-		if err != nil {
-			return fmt.Errorf("failed to open mod cache root: %w", err)
-		}
-		defer modCacheRoot.Close()
-
 		slog.Info("Downloading mod", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID)
+		p := pendingMod{mod: &modVersions[i], dir: modCacheDir}
 		for file := range modVersions[i].Downloads(binaryType) {
-			var response *http.Response
-			for _, uri := range file.Downloads {
-				req, err := http.NewRequest(http.MethodGet, uri, nil)
-				if err != nil {
-					slog.Error("Failed to create HTTP request for mod file", "url", uri, "error", err)
-					continue
-				}
-				resp, err := hClient.Do(req)
-				if err != nil {
-					slog.Error("Failed to download mod file", "url", uri, "error", err)
-					continue
-				}
-				defer resp.Body.Close()
-				if resp.StatusCode != http.StatusOK {
-					slog.Error("Failed to download mod file, non-OK status", "url", uri, "status", resp.Status)
-					continue
-				}
-				response = resp
-				break
+			job := &downloadJob{
+				index:       index,
+				mod:         &modVersions[i],
+				file:        file,
+				store:       store,
+				modCacheDir: modCacheDir,
+				force:       force,
 			}
-			if response == nil {
-				return fmt.Errorf("failed to download mod file from all sources: %s@%s (%s)", modVersions[i].ModID, modVersions[i].VersionID, file.ID)
-			}
-			contentLength := response.ContentLength
-			slog.Info("Downloading mod file", "url", response.Request.URL, "contentLength", contentLength)
-
-			hashChecker := newHashWriters(file.Hashes)
-
-			body := io.TeeReader(response.Body, hashChecker)
-			var extractPath string
-
-			switch file.ContentType {
-			case model.ContentTypeArchive:
-				fallthrough
-			case model.ContentTypeBinary, model.ContentTypePluginDll:
-				extractPath = fileDestinationPath(file)
-				if extractPath == "" {
-					return fmt.Errorf("file path is empty")
-				}
-				_ = modCacheRoot.MkdirAll(filepath.Dir(extractPath), 0755)
-				slog.Info("Saving mod file to cache", "path", extractPath)
-				destFile, err := modCacheRoot.OpenFile(extractPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-				if err != nil {
-					return err
-				}
-				defer destFile.Close()
-				startVal := 0.0
-				if progressListener != nil {
-					startVal = progressListener.GetValue()
-				}
-				buf := progress.NewProgressWriter(startVal, (1.0 / float64(totalDownloadCount)), contentLength, progressListener, destFile)
-				if _, err := io.Copy(buf, body); err != nil {
-					return err
-				}
-				buf.Complete()
-			default:
-				return fmt.Errorf("unknown file type: %s", file.ContentType)
-			}
-
-			if computedHash, err := hashChecker.Sum(); err != nil {
-				if extractPath != "" {
-					slog.Warn("File hash mismatch for extracted file, deleting cached file", "modId", modVersions[i].ModID, "versionId", modVersions[i].VersionID, "file", extractPath, "error", err)
-					if err := modCacheRoot.RemoveAll(extractPath); err != nil {
-						slog.Warn("Failed to remove cached file after hash mismatch", "file", extractPath, "error", err)
-					}
-				}
-				return fmt.Errorf("downloaded file hash mismatch: %w", err)
-			} else {
-				slog.Info("File hash verified", "hash", computedHash)
+			p.jobs = append(p.jobs, job)
+			index++
+			key := partialDownloadKey(&modVersions[i], file)
+			if first, ok := byContent[key]; ok {
+				job.duplicateOf = first
+				continue
 			}
+			byContent[key] = job
+			jobs = append(jobs, job)
 		}
+		pending = append(pending, p)
+	}
+
+	if err := runDownloadJobs(context.Background(), cfg, jobs, prog); err != nil {
+		return err
+	}
 
-		// Write metadata.json to cache directory
+	// Write the manifest of each mod version once all of its blobs are in place
+	for _, p := range pending {
 		metadata := CacheMetadata{
-			ModVersion: modVersions[i],
+			ModVersion: *p.mod,
+			Blobs:      make(map[string]string, len(p.jobs)),
 		}
-		metaFile, err := modCacheRoot.OpenFile("metadata.json", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
-			return err
+		for _, job := range p.jobs {
+			if job.duplicateOf != nil {
+				job.blob = job.duplicateOf.blob
+				prog.set(job.index, 1.0)
+			}
+			metadata.Blobs[job.file.ID] = job.blob
 		}
-		defer metaFile.Close()
-		if err := json.MarshalWrite(metaFile, metadata); err != nil {
-			return err
+		if err := saveCacheMetadata(p.dir, &metadata); err != nil {
+			return fmt.Errorf("failed to save mod cache metadata: %w", err)
 		}
 	}
 	return nil
 }
 
-func removeEmptyDirs(root *os.Root, dir string) error {
-	dirInfo, err := root.Stat(dir)
+func loadCacheMetadata(modCacheDir string) (*CacheMetadata, error) {
+	metaFile, err := os.Open(filepath.Join(modCacheDir, "metadata.json"))
 	if err != nil {
-		slog.Warn("Failed to stat directory during cleanup", "dir", dir, "error", err)
-		return err
+		return nil, err
 	}
-	if !dirInfo.IsDir() {
-		return nil
+	defer metaFile.Close()
+	var metadata CacheMetadata
+	if err := json.UnmarshalRead(metaFile, &metadata); err != nil {
+		return nil, err
 	}
-	d, err := root.Open(dir)
+	return &metadata, nil
+}
+
+func saveCacheMetadata(modCacheDir string, metadata *CacheMetadata) error {
+	data, err := json.Marshal(metadata)
 	if err != nil {
-		slog.Warn("Failed to open directory during cleanup", "dir", dir, "error", err)
 		return err
 	}
-	defer d.Close()
-	entries, err := d.Readdirnames(-1)
+	return os.WriteFile(filepath.Join(modCacheDir, "metadata.json"), data, 0644)
+}
+
+// isModVersionCached reports whether the manifest of the mod version references a complete, hash-verified set of blobs.
+func isModVersionCached(store *BlobStore, modCacheDir string, mod ModVersion, binaryType aumgr.BinaryType) bool {
+	metadata, err := loadCacheMetadata(modCacheDir)
 	if err != nil {
-		slog.Warn("Failed to read directory entries during cleanup", "dir", dir, "error", err)
-		return err
+		if !os.IsNotExist(err) {
+			slog.Warn("Failed to load mod cache metadata, will re-download", "modId", mod.ModID, "versionId", mod.VersionID, "error", err)
+		}
+		return false
+	} else if metadata.ModVersion.VersionID != mod.VersionID {
+		slog.Warn("Mod cache metadata version mismatch, will re-download", "modId", mod.ModID, "versionId", mod.VersionID, "cachedVersionId", metadata.ModVersion.VersionID)
+		return false
 	}
-	if len(entries) == 0 {
-		if err := root.Remove(dir); err != nil {
-			slog.Warn("Failed to remove empty directory during cleanup", "dir", dir, "error", err)
+
+	// Check if all files exist in the blob store
+	for file := range mod.Downloads(binaryType) {
+		sum, ok := metadata.Blobs[file.ID]
+		if !ok {
+			slog.Info("Cached mod file not found in manifest, need to re-download", "modId", mod.ModID, "versionId", mod.VersionID, "fileId", file.ID)
+			return false
+		}
+		if err := store.Verify(sum, file.Hashes); err != nil {
+			slog.Warn("Cached mod file is missing or corrupted, will re-download", "modId", mod.ModID, "versionId", mod.VersionID, "fileId", file.ID, "blob", sum, "error", err)
+			return false
 		}
-		return removeEmptyDirs(root, filepath.Dir(dir))
 	}
-	return nil
+	return true
 }
 
 func extractZip(reader io.ReaderAt, contentLength int64, destRoot *os.Root, progressListener progress.Progress, n int) ([]string, error) {
@@ -303,6 +251,10 @@ func extractFile(f *zip.File, destRoot *os.Root, progressWriter *progress.Progre
 	}
 
 	_ = destRoot.MkdirAll(filepath.Dir(f.Name), 0755)
+	// Never write through an existing file: it may be a hard link into the blob store.
+	if err := destRoot.Remove(f.Name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	destFile, err := destRoot.OpenFile(f.Name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode())
 	if err != nil {
 		return err
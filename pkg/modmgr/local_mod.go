@@ -0,0 +1,295 @@
+package modmgr
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
+)
+
+// FeatureLocalSource marks a mod version created from a local file rather than fetched from a server.
+// The value is the path the file or folder was added from.
+const FeatureLocalSource = "local_source"
+
+// LocalModIDPrefix starts the ID of every local mod, so it never collides with a mod on the server.
+const LocalModIDPrefix = "local-"
+
+// localModScheme is the download URI scheme of local mod files, e.g. "local:<sha256>".
+const localModScheme = "local"
+
+// IsLocal reports whether the mod version was added from a local file and cannot be fetched from a server.
+func (m ModVersion) IsLocal() bool {
+	_, ok := m.Features[FeatureLocalSource]
+	return ok
+}
+
+// LocalSource returns the path a local mod was added from.
+func (m ModVersion) LocalSource() string {
+	source, _ := m.Features[FeatureLocalSource].(string)
+	return source
+}
+
+// LocalModStore keeps the content of local mods by sha256, outside the mod cache, so clearing the cache does not lose them.
+// DownloadMods copies local files into the cache from the store given by WithLocalModStore.
+type LocalModStore struct {
+	dir string
+}
+
+func NewLocalModStore(dir string) *LocalModStore {
+	return &LocalModStore{dir: dir}
+}
+
+func (s *LocalModStore) Dir() string {
+	return s.dir
+}
+
+// Open opens the stored content with the given sha256.
+func (s *LocalModStore) Open(sum string) (*os.File, error) {
+	if !isValidBlobSum(sum) {
+		return nil, fmt.Errorf("invalid local mod hash %q", sum)
+	}
+	return os.Open(filepath.Join(s.dir, sum))
+}
+
+// Import stores a plugin DLL, a zip archive or a folder and returns a mod version installing it.
+//
+// A DLL is installed into BepInEx/plugins and an archive is extracted into the profile root, like files on the server.
+// A folder is archived first; it is extracted into the profile root if it has a BepInEx directory at the top,
+// and into BepInEx/plugins/<folder name> otherwise.
+// The mod ID is derived from the file name, so importing a new build of the same file replaces the old one in a profile.
+func (s *LocalModStore) Import(source string) (*ModVersion, error) {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve local mod path: %w", err)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat local mod: %w", err)
+	}
+
+	name := filepath.Base(source)
+	file := model.ModVersionFile{
+		Filename:       name,
+		TargetPlatform: model.TargetPlatformAny,
+	}
+	var sum string
+	var size int64
+	switch ext := strings.ToLower(filepath.Ext(name)); {
+	case info.IsDir():
+		file.Filename = name + ".zip"
+		file.ContentType = model.ContentTypeArchive
+		if fi, err := os.Stat(filepath.Join(source, "BepInEx")); err != nil || !fi.IsDir() {
+			file.ExtractPath = path.Join("BepInEx", "plugins", name, file.Filename)
+		}
+		sum, size, err = s.store(func(w io.Writer) error {
+			return zipDirectory(w, source)
+		})
+	case ext == ".dll":
+		file.ContentType = model.ContentTypePluginDll
+		sum, size, err = s.storeFile(source)
+	case ext == ".zip":
+		file.ContentType = model.ContentTypeArchive
+		sum, size, err = s.storeFile(source)
+	default:
+		return nil, fmt.Errorf("unsupported local mod file %s: only plugin DLLs, zip archives and folders can be added", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	file.ID = sum[:16]
+	file.Size = size
+	file.Hashes = map[string]string{blobAlgorithm: sum}
+	file.Downloads = []string{localModScheme + ":" + sum}
+	now := time.Now().UTC().Truncate(time.Second)
+	file.CreatedAt = now
+
+	return &ModVersion{ModVersionDetails: model.ModVersionDetails{
+		ModID:     LocalModIDPrefix + localModSlug(strings.TrimSuffix(name, filepath.Ext(name))),
+		VersionID: LocalModIDPrefix + sum[:12],
+		Files:     []model.ModVersionFile{file},
+		Features:  map[string]any{FeatureLocalSource: source},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}}, nil
+}
+
+func (s *LocalModStore) storeFile(source string) (string, int64, error) {
+	f, err := os.Open(source)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open local mod: %w", err)
+	}
+	defer f.Close()
+	return s.store(func(w io.Writer) error {
+		_, err := io.Copy(w, f)
+		return err
+	})
+}
+
+// store writes the content produced by write into the store and returns its sha256 and size.
+func (s *LocalModStore) store(write func(io.Writer) error) (string, int64, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create local mod directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, "import-*"+partialFileSuffix)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create local mod file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	counter := &countingWriter{}
+	if err := write(io.MultiWriter(tmp, hasher, counter)); err != nil {
+		_ = tmp.Close()
+		return "", 0, fmt.Errorf("failed to copy local mod: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to close local mod file: %w", err)
+	}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, sum)); err != nil {
+		return "", 0, fmt.Errorf("failed to store local mod: %w", err)
+	}
+	return sum, counter.n, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// zipDirectory archives the regular files under dir with fixed timestamps, so the same folder always gives the same hash.
+func zipDirectory(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		header := &zip.FileHeader{Name: filepath.ToSlash(rel), Method: zip.Deflate}
+		header.Modified = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(entry, f)
+		_ = f.Close()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func localModSlug(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	if slug := strings.Trim(b.String(), "-."); slug != "" {
+		return slug
+	}
+	return "mod"
+}
+
+// copyLocalModFile stores a local mod file from the local mod store in the blob store, verifying its hashes.
+func copyLocalModFile(localMods *LocalModStore, uri string, job *downloadJob, prog *downloadProgress) error {
+	if localMods == nil {
+		return fmt.Errorf("local mod store is not configured: %w", errDownloadNotRetryable)
+	}
+	sum := strings.TrimPrefix(uri, localModScheme+":")
+	src, err := localMods.Open(sum)
+	if err != nil {
+		return fmt.Errorf("local mod file is missing, add it to the profile again: %w: %w", err, errDownloadNotRetryable)
+	}
+	defer src.Close()
+
+	partPath := job.store.partialPath(partialDownloadKey(job.mod, job.file))
+	if err := os.MkdirAll(filepath.Dir(partPath), 0755); err != nil {
+		return fmt.Errorf("failed to create partial download directory: %w", err)
+	}
+	partFile, err := os.Create(partPath)
+	if err != nil {
+		return fmt.Errorf("failed to open partial file: %w", err)
+	}
+	defer partFile.Close()
+
+	hasher := NewBlobHasher(job.file.Hashes)
+	if _, err := io.Copy(io.MultiWriter(partFile, hasher), src); err != nil {
+		return fmt.Errorf("failed to copy local mod file: %w", err)
+	}
+	if _, err := hasher.Sum(); err != nil {
+		_ = partFile.Close()
+		_ = os.Remove(partPath)
+		return fmt.Errorf("local mod file hash mismatch: %w: %w", err, errDownloadNotRetryable)
+	}
+	return finishPartialFile(job, partFile, hasher, prog)
+}
+
+func isLocalModURI(uri string) bool {
+	return strings.HasPrefix(uri, localModScheme+":")
+}
+
+// Prune removes stored files that none of the referenced mod versions use. Files stored within the GC grace period are
+// kept, as they may belong to a mod that was just added to an unsaved profile. It returns the number of bytes freed.
+func (s *LocalModStore) Prune(refs []CacheReference) (int64, error) {
+	used := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.Unknown {
+			return 0, nil
+		}
+		for _, mod := range ref.ModVersions {
+			if !mod.IsLocal() {
+				continue
+			}
+			for _, file := range mod.Files {
+				used[file.Hashes[blobAlgorithm]] = true
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read local mod directory: %w", err)
+	}
+	var freed int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || used[entry.Name()] || time.Since(info.ModTime()) < cacheGCGracePeriod {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err != nil {
+			return freed, fmt.Errorf("failed to remove local mod file: %w", err)
+		}
+		freed += info.Size()
+	}
+	return freed, nil
+}
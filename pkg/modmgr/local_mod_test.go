@@ -0,0 +1,58 @@
+package modmgr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
+)
+
+func TestLocalModStore_ImportAndInstall(t *testing.T) {
+	sourceDir := t.TempDir()
+	dllPath := filepath.Join(sourceDir, "My Plugin.dll")
+	require.NoError(t, os.WriteFile(dllPath, []byte("plugin"), 0644))
+	folder := filepath.Join(sourceDir, "Extras")
+	require.NoError(t, os.MkdirAll(filepath.Join(folder, "data"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(folder, "Extras.dll"), []byte("extras"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(folder, "data", "config.txt"), []byte("config"), 0644))
+
+	store := NewLocalModStore(filepath.Join(t.TempDir(), "local_mods"))
+	dll, err := store.Import(dllPath)
+	require.NoError(t, err)
+	require.True(t, dll.IsLocal())
+	require.Equal(t, "local-my-plugin", dll.ModID)
+	require.Equal(t, dllPath, dll.LocalSource())
+	require.Equal(t, model.ContentTypePluginDll, dll.Files[0].ContentType)
+	require.EqualValues(t, 6, dll.Files[0].Size)
+
+	dir, err := store.Import(folder)
+	require.NoError(t, err)
+	require.Equal(t, "local-extras", dir.ModID)
+	require.Equal(t, "BepInEx/plugins/Extras/Extras.zip", dir.Files[0].ExtractPath)
+
+	// Archiving a folder is deterministic
+	again, err := store.Import(folder)
+	require.NoError(t, err)
+	require.Equal(t, dir.VersionID, again.VersionID)
+
+	_, err = store.Import(filepath.Join(folder, "data", "config.txt"))
+	require.Error(t, err)
+
+	// The source files may be gone by the time the mods are installed
+	require.NoError(t, os.RemoveAll(sourceDir))
+
+	cacheDir := t.TempDir()
+	profileDir := filepath.Join(t.TempDir(), "profile")
+	mods := []ModVersion{*dll, *dir}
+	require.Error(t, DownloadMods(cacheDir, mods, aumgr.BinaryType64Bit, nil, false, WithDownloadAttempts(1)))
+	require.NoError(t, DownloadMods(cacheDir, mods, aumgr.BinaryType64Bit, nil, false, WithLocalModStore(store)))
+	require.NoError(t, PrepareProfileDirectory(profileDir, "", cacheDir, mods, aumgr.BinaryType64Bit, "2025.1.1", false, nil))
+	require.FileExists(t, filepath.Join(profileDir, "BepInEx", "plugins", "My Plugin.dll"))
+	data, err := os.ReadFile(filepath.Join(profileDir, "BepInEx", "plugins", "Extras", "data", "config.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "config", string(data))
+}
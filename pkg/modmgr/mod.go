@@ -1,9 +1,12 @@
 package modmgr
 
 import (
+	"fmt"
 	"iter"
 	"log/slog"
+	"path/filepath"
 	"slices"
+	"strings"
 
 	"github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
@@ -13,26 +16,21 @@ type Mod struct {
 	model.ModDetails
 }
 
-type ModType string
+type ModType = model.ModType
 
 const (
-	ModTypeMod     ModType = "mod"
-	ModTypeLibrary ModType = "library"
+	ModTypeMod     = model.ModTypeMod
+	ModTypeLibrary = model.ModTypeLibrary
 	// Deprecated: should not be used anymore
 	// ModPack will be represented as profile.Profile now
-	ModTypeModPack ModType = "modpack"
+	ModTypeModPack = model.ModTypeModPack
 )
 
 const FeatureDirectJoin = "direct_join"
 
-func (mt ModType) IsVisible() bool {
-	switch mt {
-	case ModTypeMod, ModTypeModPack:
-		return true
-	default:
-		return false
-	}
-}
+// FeaturePreservePaths lists profile-relative paths the mod stores user data in, e.g. "BepInEx/config/MyMod".
+// The value is either a list of paths or a comma separated string.
+const FeaturePreservePaths = "preserve_paths"
 
 type ModVersion struct {
 	model.ModVersionDetails
@@ -79,6 +77,43 @@ func (m ModVersion) CompatibleFilesCount(binaryType aumgr.BinaryType) int {
 	return count
 }
 
+// Incompatibility explains why a mod version cannot be used with the installed game.
+type Incompatibility struct {
+	// GameVersion is set when the mod version does not support the installed game version.
+	GameVersion           string   `json:"game_version,omitempty"`
+	SupportedGameVersions []string `json:"supported_game_versions,omitempty"`
+	// BinaryType is set when the mod version has no files for the installed binary type.
+	BinaryType aumgr.BinaryType `json:"binary_type,omitempty"`
+}
+
+func (i *Incompatibility) Error() string {
+	var reasons []string
+	if i.GameVersion != "" {
+		reasons = append(reasons, fmt.Sprintf("supports game versions %s but the game is %s", strings.Join(i.SupportedGameVersions, ", "), i.GameVersion))
+	}
+	if i.BinaryType != "" {
+		reasons = append(reasons, fmt.Sprintf("has no files for %s", i.BinaryType))
+	}
+	return "incompatible: " + strings.Join(reasons, " and ")
+}
+
+// CheckCompatibility returns why the mod version cannot be used with the installed game, or nil if it can.
+// An empty binary type or game version is not checked.
+func (m ModVersion) CheckCompatibility(binaryType aumgr.BinaryType, gameVersion string) *Incompatibility {
+	var inc Incompatibility
+	if gameVersion != "" && len(m.GameVersions) > 0 && !slices.Contains(m.GameVersions, gameVersion) {
+		inc.GameVersion = gameVersion
+		inc.SupportedGameVersions = slices.Clone(m.GameVersions)
+	}
+	if binaryType != "" && len(m.Files) > 0 && m.CompatibleFilesCount(binaryType) == 0 {
+		inc.BinaryType = binaryType
+	}
+	if inc.GameVersion == "" && inc.BinaryType == "" {
+		return nil
+	}
+	return &inc
+}
+
 func (m ModVersion) Downloads(binaryType aumgr.BinaryType) iter.Seq[model.ModVersionFile] {
 	return func(yield func(model.ModVersionFile) bool) {
 		for _, file := range m.Files {
@@ -110,3 +145,27 @@ func (m ModVersion) HasFeature(feature string) bool {
 		return true
 	}
 }
+
+// PreservePaths returns the paths declared by FeaturePreservePaths, in the platform's path format.
+func (m ModVersion) PreservePaths() []string {
+	var raw []string
+	switch v := m.Features[FeaturePreservePaths].(type) {
+	case string:
+		raw = strings.Split(v, ",")
+	case []string:
+		raw = v
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+	var paths []string
+	for _, path := range raw {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, filepath.FromSlash(path))
+		}
+	}
+	return paths
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json/v2"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -34,6 +35,27 @@ type ProfileMetadata struct {
 	BinaryType  aumgr.BinaryType `json:"binary_type"`
 	ModVersions []ModVersion     `json:"mod_versions"`
 	ModFiles    []string         `json:"mod_files,omitempty"`
+	// PreservePaths are the paths kept across the install, declared by the user and the installed mod versions.
+	PreservePaths []string `json:"preserve_paths,omitempty"`
+}
+
+// DefaultPreservePaths are kept across every install of a profile.
+var DefaultPreservePaths = []string{filepath.Join("BepInEx", "config")}
+
+type InstallOption func(*installConfig)
+
+type installConfig struct {
+	preservePaths []string
+}
+
+// WithPreservePaths keeps the given profile-relative paths across reinstalls in addition to DefaultPreservePaths
+// and the paths declared by mods.
+func WithPreservePaths(paths ...string) InstallOption {
+	return func(c *installConfig) {
+		for _, path := range paths {
+			c.preservePaths = append(c.preservePaths, filepath.FromSlash(path))
+		}
+	}
 }
 
 func getProfileMetadataPath(profileDir string) string {
@@ -83,7 +105,17 @@ func modVersionsEqual(a, b []ModVersion) bool {
 }
 
 // PrepareProfileDirectory installs mods from cache to the profile directory and generates doorstop_config.ini.
-func PrepareProfileDirectory(profileDir string, gamePath string, cacheDir string, modVersions []ModVersion, binaryType aumgr.BinaryType, gameVersion string, force bool, progressListener progress.Progress) error {
+// Installs are staged in a sibling directory and swapped in once every file is verified,
+// so a failed install keeps the last working profile.
+func PrepareProfileDirectory(profileDir string, gamePath string, cacheDir string, modVersions []ModVersion, binaryType aumgr.BinaryType, gameVersion string, force bool, progressListener progress.Progress, opts ...InstallOption) error {
+	var cfg installConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if err := recoverProfileDirectory(profileDir); err != nil {
+		return fmt.Errorf("failed to recover profile directory: %w", err)
+	}
 	if err := os.MkdirAll(profileDir, 0755); err != nil {
 		return fmt.Errorf("failed to create profile directory: %w", err)
 	}
@@ -92,217 +124,23 @@ func PrepareProfileDirectory(profileDir string, gamePath string, cacheDir string
 	if err != nil {
 		return fmt.Errorf("failed to load profile metadata: %w", err)
 	}
-	profileRoot, err := os.OpenRoot(profileDir)
-	if err != nil {
-		return fmt.Errorf("failed to open profile directory: %w", err)
-	}
-	defer profileRoot.Close()
-	cacheRoot, err := os.OpenRoot(cacheDir)
-	if err != nil {
-		return fmt.Errorf("failed to open cache directory: %w", err)
-	}
-	defer cacheRoot.Close()
 
 	shouldInstall := force || meta == nil || !modVersionsEqual(meta.ModVersions, modVersions) || meta.GameVersion != gameVersion || meta.BinaryType != binaryType
 
 	if shouldInstall {
-		if meta != nil {
-			// Delete existing mod files in profile directory
-			var files []string
-			copy(files, meta.ModFiles)
-			// sort files by length desc to delete nested files before their parents
-			slices.SortFunc(files, func(a, b string) int {
-				return len(filepath.Dir(b)) - len(filepath.Dir(a))
-			})
-
-			for _, path := range meta.ModFiles {
-				if err := profileRoot.Remove(path); err != nil && !os.IsNotExist(err) {
-					slog.Warn("Failed to remove existing mod file, will attempt to overwrite", "file", path, "error", err)
-				}
-				if err := removeEmptyDirs(profileRoot, filepath.Dir(path)); err != nil {
-					slog.Warn("Failed to remove empty directories after deleting mod file", "file", path, "error", err)
-				}
-			}
-		}
-
 		if progressListener != nil {
 			progressListener.SetValue(0)
 			progressListener.Start()
 			defer progressListener.Done()
 		}
-		// Clear BepInEx folder
-		bepInExDir := filepath.Join(profileDir, "BepInEx")
-		if _, err := os.Stat(bepInExDir); err == nil {
-			if err := os.RemoveAll(bepInExDir); err != nil {
-				return fmt.Errorf("failed to clear BepInEx directory: %w", err)
-			}
-		}
-		// Also clear dotnet folder if it exists (for IL2CPP)
-		dotnetDir := filepath.Join(profileDir, "dotnet")
-		if _, err := os.Stat(dotnetDir); err == nil {
-			if err := os.RemoveAll(dotnetDir); err != nil {
-				return fmt.Errorf("failed to clear dotnet directory: %w", err)
-			}
-		}
-
-		var totalFiles int
-		for _, mod := range modVersions {
-			totalFiles += mod.CompatibleFilesCount(binaryType)
-		}
-
-		completedCopies := 0
-		var modPaths []string
-		for _, mod := range modVersions {
-			hashStr, err := hashModVersion(mod)
-			if err != nil {
-				return fmt.Errorf("failed to hash mod version: %w", err)
-			}
-			modCacheDir := filepath.Join(string(binaryType), mod.ModID, hashStr)
-			cacheRoot, err := cacheRoot.OpenRoot(modCacheDir)
-			if err != nil {
-				return fmt.Errorf("failed to open mod cache directory for %s: %w", mod.ModID, err)
-			}
-
-			var metadata CacheMetadata
-			if metaFile, err := cacheRoot.Open("metadata.json"); err != nil {
-				slog.Warn("Failed to open mod cache metadata, will re-download", "modId", mod.ModID, "versionId", mod.VersionID, "error", err)
-				return fmt.Errorf("mod cache metadata not found for %s: %w", mod.ModID, err)
-			} else if err := json.UnmarshalRead(metaFile, &metadata); err != nil {
-				_ = metaFile.Close()
-				slog.Warn("Failed to decode mod cache metadata, will re-download", "modId", mod.ModID, "versionId", mod.VersionID, "error", err)
-				return fmt.Errorf("failed to decode mod cache metadata for %s: %w", mod.ModID, err)
-			} else if metadata.ModVersion.VersionID != mod.VersionID {
-				_ = metaFile.Close()
-				slog.Warn("Mod cache metadata version mismatch, will re-download", "modId", mod.ModID, "versionId", mod.VersionID, "cachedVersionId", metadata.ModVersion.VersionID)
-				return fmt.Errorf("mod cache metadata version mismatch for %s: cached %s but expected %s", mod.ModID, metadata.ModVersion.VersionID, mod.VersionID)
-			} else {
-				_ = metaFile.Close()
-			}
-
-			for _, file := range mod.Files {
-				if !binaryType.IsCompatibleWith(file.TargetPlatform) {
-					slog.Info("Skipping incompatible file in cache", "modId", mod.ModID, "versionId", mod.VersionID, "file", file, "binaryType", binaryType)
-					continue
-				}
-
-				path := fileDestinationPath(file)
-				if path == "" {
-					slog.Warn("File has no valid path, skipping", "modId", mod.ModID, "versionId", mod.VersionID, "file", file)
-					return fmt.Errorf("file has no valid path for mod %s version %s: %s", mod.ModID, mod.VersionID, file.Filename)
-				}
-				srcFile, err := cacheRoot.Open(path)
-				if err != nil {
-					return fmt.Errorf("failed to open cached file for %s: %w", path, err)
-				}
-				srcInfo, err := srcFile.Stat()
-				if err != nil {
-					_ = srcFile.Close()
-					return fmt.Errorf("failed to stat cached file for %s: %w", path, err)
-				}
-				if err := profileRoot.MkdirAll(filepath.Dir(path), 0755); err != nil {
-					_ = srcFile.Close()
-					return fmt.Errorf("failed to create directories for %s: %w", path, err)
-				}
-
-				if file.ContentType == model.ContentTypeArchive {
-					// Check zip hash
-					newHashChecker := newHashWriters(file.Hashes)
-					if _, err := io.Copy(io.Discard, io.TeeReader(srcFile, newHashChecker)); err != nil {
-						_ = srcFile.Close()
-						return fmt.Errorf("failed to read zip file for hashing: %w", err)
-					}
-					if computedHash, err := newHashChecker.Sum(); err != nil {
-						_ = srcFile.Close()
-						return fmt.Errorf("failed to compute hash for zip file: %w", err)
-					} else {
-						slog.Info("Zip file hash verified for cached file", "modId", mod.ModID, "versionId", mod.VersionID, "file", path, "hashes", computedHash)
-					}
-
-					_, _ = srcFile.Seek(0, io.SeekStart)
-
-					destRoot, err := profileRoot.OpenRoot(filepath.Dir(path))
-					if err != nil {
-						_ = srcFile.Close()
-						return fmt.Errorf("failed to open destination directory for %s: %w", path, err)
-					}
-
-					zipPaths, err := extractZip(srcFile, srcInfo.Size(), destRoot, progressListener, totalFiles)
-					if err != nil {
-						_ = srcFile.Close()
-						return fmt.Errorf("failed to extract zip file: %w", err)
-					}
-					for i, zipPath := range zipPaths {
-						zipPaths[i] = filepath.Clean(filepath.Join(filepath.Dir(path), zipPath))
-					}
-					modPaths = append(modPaths, zipPaths...)
-					completedCopies++
-					continue
-				}
-
-				destFile, err := profileRoot.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-				if err != nil {
-					_ = srcFile.Close()
-					return fmt.Errorf("failed to create destination file for %s: %w", path, err)
-				}
-
-				hashChecker := newHashWriters(file.Hashes)
-
-				writer := io.MultiWriter(destFile, hashChecker)
-				if progressListener != nil && totalFiles > 0 {
-					scale := 1.0 / float64(totalFiles)
-					start := float64(completedCopies) * scale
-					pw := progress.NewProgressWriter(start, scale, srcInfo.Size(), progressListener, writer)
-					writer = pw
-					if _, err := io.Copy(writer, srcFile); err != nil {
-						_ = destFile.Close()
-						_ = srcFile.Close()
-						return fmt.Errorf("failed to copy file: %w", err)
-					}
-					pw.Complete()
-				} else {
-					if _, err := io.Copy(writer, srcFile); err != nil {
-						_ = destFile.Close()
-						_ = srcFile.Close()
-						return fmt.Errorf("failed to copy file: %w", err)
-					}
-				}
-				if err := destFile.Close(); err != nil {
-					_ = srcFile.Close()
-					return fmt.Errorf("failed to close destination file: %w", err)
-				}
-				if err := srcFile.Close(); err != nil {
-					return fmt.Errorf("failed to close source file: %w", err)
-				}
-				computedHash, err := hashChecker.Sum()
-				if err != nil {
-					return fmt.Errorf("failed to compute hash for %s: %w", path, err)
-				}
-				for hashType, hashStr := range file.Hashes {
-					if computedHash[hashType] != hashStr {
-						slog.Warn("File hash mismatch for copied file, deleting profile file", "modId", mod.ModID, "versionId", mod.VersionID, "file", path, "hashType", hashType, "expectedHash", hashStr, "computedHash", computedHash[hashType])
-						return fmt.Errorf("file hash mismatch for %s: expected %s but got %s", path, hashStr, computedHash[hashType])
-					}
-					slog.Info("File hash verified for copied file", "modId", mod.ModID, "versionId", mod.VersionID, "file", path, "hashType", hashType, "hash", hashStr)
-				}
-				modPaths = append(modPaths, filepath.Clean(path))
-				completedCopies++
-			}
-		}
-
-		// sort modPaths for consistent metadata (not strictly necessary but cleaner)
-		slices.SortStableFunc(modPaths, func(a, b string) int {
-			return len(filepath.Dir(b)) - len(filepath.Dir(a))
-		})
-
-		// Save metadata
 		newMeta := &ProfileMetadata{
-			ModVersions: modVersions,
-			GameVersion: gameVersion,
-			BinaryType:  binaryType,
-			ModFiles:    modPaths,
+			ModVersions:   modVersions,
+			GameVersion:   gameVersion,
+			BinaryType:    binaryType,
+			PreservePaths: collectPreservePaths(&cfg, modVersions),
 		}
-		if err := saveProfileMetadata(profileDir, newMeta); err != nil {
-			return fmt.Errorf("failed to save profile metadata: %w", err)
+		if err := installProfile(profileDir, cacheDir, meta, newMeta, progressListener); err != nil {
+			return err
 		}
 	}
 
@@ -370,3 +208,376 @@ coreclr_path = %s
 corlib_dir = %s
 `, targetAssembly, coreClrPath, corlibDir)
 }
+
+// installProfile builds the profile described by newMeta in a staging directory and swaps it in for profileDir.
+// Files that do not belong to mods are carried over; mod files of the previous install, BepInEx and dotnet are not,
+// except for the preserved paths, which are copied over the freshly installed files.
+func installProfile(profileDir, cacheDir string, oldMeta, newMeta *ProfileMetadata, progressListener progress.Progress) (err error) {
+	stagingDir := stagingProfileDir(profileDir)
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return fmt.Errorf("failed to remove stale staging directory: %w", err)
+	}
+	if err := os.Mkdir(stagingDir, 0755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rmErr := os.RemoveAll(stagingDir); rmErr != nil {
+				slog.Warn("Failed to remove staging directory", "dir", stagingDir, "error", rmErr)
+			}
+		}
+	}()
+
+	if err := carryOverProfileFiles(profileDir, stagingDir, oldMeta); err != nil {
+		return fmt.Errorf("failed to carry over profile files: %w", err)
+	}
+
+	stagingRoot, err := os.OpenRoot(stagingDir)
+	if err != nil {
+		return fmt.Errorf("failed to open staging directory: %w", err)
+	}
+	modPaths, err := installModFiles(stagingRoot, stagingDir, cacheDir, newMeta.ModVersions, newMeta.BinaryType, progressListener)
+	_ = stagingRoot.Close()
+	if err != nil {
+		return err
+	}
+	newMeta.ModFiles = modPaths
+	// Mod files inside preserved paths come from the new install, not the previous tree
+	modFiles := make(map[string]struct{})
+	for _, path := range modPaths {
+		modFiles[filepath.Clean(path)] = struct{}{}
+	}
+	if oldMeta != nil {
+		for _, path := range oldMeta.ModFiles {
+			modFiles[filepath.Clean(path)] = struct{}{}
+		}
+	}
+	if err := restorePreservedFiles(profileDir, stagingDir, newMeta.PreservePaths, modFiles); err != nil {
+		return fmt.Errorf("failed to restore preserved files: %w", err)
+	}
+	if err := saveProfileMetadata(stagingDir, newMeta); err != nil {
+		return fmt.Errorf("failed to save profile metadata: %w", err)
+	}
+
+	return swapProfileDirectory(profileDir, stagingDir)
+}
+
+// installModFiles installs the files of modVersions from the cache into the staging directory and returns their paths.
+func installModFiles(profileRoot *os.Root, stagingDir, cacheDir string, modVersions []ModVersion, binaryType aumgr.BinaryType, progressListener progress.Progress) ([]string, error) {
+	store := NewBlobStore(cacheDir)
+	var totalFiles int
+	for _, mod := range modVersions {
+		totalFiles += mod.CompatibleFilesCount(binaryType)
+	}
+
+	completedCopies := 0
+	var modPaths []string
+	for _, mod := range modVersions {
+		hashStr, err := hashModVersion(mod)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash mod version: %w", err)
+		}
+		modCacheDir := filepath.Join(cacheDir, string(binaryType), mod.ModID, hashStr)
+
+		metadata, err := loadCacheMetadata(modCacheDir)
+		if err != nil {
+			slog.Warn("Failed to load mod cache metadata", "modId", mod.ModID, "versionId", mod.VersionID, "error", err)
+			return nil, fmt.Errorf("mod cache metadata not found for %s: %w", mod.ModID, err)
+		} else if metadata.ModVersion.VersionID != mod.VersionID {
+			slog.Warn("Mod cache metadata version mismatch", "modId", mod.ModID, "versionId", mod.VersionID, "cachedVersionId", metadata.ModVersion.VersionID)
+			return nil, fmt.Errorf("mod cache metadata version mismatch for %s: cached %s but expected %s", mod.ModID, metadata.ModVersion.VersionID, mod.VersionID)
+		}
+		touchCacheEntry(modCacheDir)
+
+		for _, file := range mod.Files {
+			if !binaryType.IsCompatibleWith(file.TargetPlatform) {
+				slog.Info("Skipping incompatible file in cache", "modId", mod.ModID, "versionId", mod.VersionID, "file", file, "binaryType", binaryType)
+				continue
+			}
+
+			path := fileDestinationPath(file)
+			if path == "" || !filepath.IsLocal(path) {
+				slog.Warn("File has no valid path, skipping", "modId", mod.ModID, "versionId", mod.VersionID, "file", file)
+				return nil, fmt.Errorf("file has no valid path for mod %s version %s: %s", mod.ModID, mod.VersionID, file.Filename)
+			}
+			sum, ok := metadata.Blobs[file.ID]
+			if !ok {
+				return nil, fmt.Errorf("mod cache manifest of %s version %s does not reference file %s", mod.ModID, mod.VersionID, file.ID)
+			}
+			if err := profileRoot.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directories for %s: %w", path, err)
+			}
+
+			if file.ContentType == model.ContentTypeArchive {
+				srcFile, err := store.Open(sum)
+				if err != nil {
+					return nil, fmt.Errorf("failed to open cached file for %s: %w", path, err)
+				}
+				srcInfo, err := srcFile.Stat()
+				if err != nil {
+					_ = srcFile.Close()
+					return nil, fmt.Errorf("failed to stat cached file for %s: %w", path, err)
+				}
+
+				// Check zip hash
+				newHashChecker := newHashWriters(file.Hashes)
+				if _, err := io.Copy(io.Discard, io.TeeReader(srcFile, newHashChecker)); err != nil {
+					_ = srcFile.Close()
+					return nil, fmt.Errorf("failed to read zip file for hashing: %w", err)
+				}
+				if computedHash, err := newHashChecker.Sum(); err != nil {
+					_ = srcFile.Close()
+					return nil, fmt.Errorf("failed to compute hash for zip file: %w", err)
+				} else {
+					slog.Info("Zip file hash verified for cached file", "modId", mod.ModID, "versionId", mod.VersionID, "file", path, "hashes", computedHash)
+				}
+
+				_, _ = srcFile.Seek(0, io.SeekStart)
+
+				destRoot, err := profileRoot.OpenRoot(filepath.Dir(path))
+				if err != nil {
+					_ = srcFile.Close()
+					return nil, fmt.Errorf("failed to open destination directory for %s: %w", path, err)
+				}
+
+				zipPaths, err := extractZip(srcFile, srcInfo.Size(), destRoot, progressListener, totalFiles)
+				_ = destRoot.Close()
+				_ = srcFile.Close()
+				if err != nil {
+					return nil, fmt.Errorf("failed to extract zip file: %w", err)
+				}
+				for i, zipPath := range zipPaths {
+					zipPaths[i] = filepath.Clean(filepath.Join(filepath.Dir(path), zipPath))
+				}
+				modPaths = append(modPaths, zipPaths...)
+				completedCopies++
+				continue
+			}
+
+			// Link the blob into the profile instead of copying it where the filesystem allows.
+			if err := profileRoot.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to replace existing file %s: %w", path, err)
+			}
+			if err := store.Link(sum, filepath.Join(stagingDir, path)); err != nil {
+				return nil, fmt.Errorf("failed to install %s from cache: %w", path, err)
+			}
+
+			installedFile, err := profileRoot.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open installed file %s: %w", path, err)
+			}
+			installedInfo, err := installedFile.Stat()
+			if err != nil {
+				_ = installedFile.Close()
+				return nil, fmt.Errorf("failed to stat installed file %s: %w", path, err)
+			}
+
+			hashChecker := newHashWriters(file.Hashes)
+			writer := io.Writer(hashChecker)
+			if progressListener != nil && totalFiles > 0 {
+				scale := 1.0 / float64(totalFiles)
+				start := float64(completedCopies) * scale
+				pw := progress.NewProgressWriter(start, scale, installedInfo.Size(), progressListener, writer)
+				writer = pw
+				if _, err := io.Copy(writer, installedFile); err != nil {
+					_ = installedFile.Close()
+					return nil, fmt.Errorf("failed to verify file: %w", err)
+				}
+				pw.Complete()
+			} else {
+				if _, err := io.Copy(writer, installedFile); err != nil {
+					_ = installedFile.Close()
+					return nil, fmt.Errorf("failed to verify file: %w", err)
+				}
+			}
+			if err := installedFile.Close(); err != nil {
+				return nil, fmt.Errorf("failed to close installed file: %w", err)
+			}
+			computedHash, err := hashChecker.Sum()
+			if err != nil {
+				return nil, fmt.Errorf("failed to compute hash for %s: %w", path, err)
+			}
+			for hashType, hashStr := range file.Hashes {
+				if computedHash[hashType] != hashStr {
+					slog.Warn("File hash mismatch for installed file", "modId", mod.ModID, "versionId", mod.VersionID, "file", path, "hashType", hashType, "expectedHash", hashStr, "computedHash", computedHash[hashType])
+					return nil, fmt.Errorf("file hash mismatch for %s: expected %s but got %s", path, hashStr, computedHash[hashType])
+				}
+				slog.Info("File hash verified for installed file", "modId", mod.ModID, "versionId", mod.VersionID, "file", path, "hashType", hashType, "hash", hashStr)
+			}
+			modPaths = append(modPaths, filepath.Clean(path))
+			completedCopies++
+		}
+	}
+	// sort modPaths for consistent metadata (not strictly necessary but cleaner)
+	slices.SortStableFunc(modPaths, func(a, b string) int {
+		return len(filepath.Dir(b)) - len(filepath.Dir(a))
+	})
+	return modPaths, nil
+}
+
+// collectPreservePaths merges the preserved paths of the user and the mod versions being installed. The list is
+// rebuilt on every install, so the paths of a removed mod are no longer kept.
+func collectPreservePaths(cfg *installConfig, modVersions []ModVersion) []string {
+	paths := slices.Clone(DefaultPreservePaths)
+	paths = append(paths, cfg.preservePaths...)
+	for _, mod := range modVersions {
+		paths = append(paths, mod.PreservePaths()...)
+	}
+
+	valid := paths[:0]
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if !filepath.IsLocal(path) {
+			slog.Warn("Ignoring preserved path outside of the profile", "path", path)
+			continue
+		}
+		valid = append(valid, path)
+	}
+	slices.Sort(valid)
+	return slices.Compact(valid)
+}
+
+// restorePreservedFiles copies the preserved paths of the previous tree into dst, skipping modFiles so only data
+// created at runtime is restored. They are copied rather than linked because mods rewrite them in place.
+func restorePreservedFiles(profileDir, dst string, paths []string, modFiles map[string]struct{}) error {
+	for _, preserved := range paths {
+		src := filepath.Join(profileDir, preserved)
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(profileDir, path)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(dst, rel)
+			switch {
+			case d.IsDir():
+				return os.MkdirAll(target, 0755)
+			case d.Type().IsRegular():
+				if _, ok := modFiles[rel]; ok {
+					return nil
+				}
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					return err
+				}
+				if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				return copyFile(path, target)
+			default:
+				slog.Warn("Skipping non-regular preserved file", "file", path)
+				return nil
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", preserved, err)
+		}
+		slog.Info("Preserved profile data across install", "path", preserved)
+	}
+	return nil
+}
+
+func stagingProfileDir(profileDir string) string {
+	return filepath.Join(filepath.Dir(profileDir), "."+filepath.Base(profileDir)+".staging")
+}
+
+func previousProfileDir(profileDir string) string {
+	return filepath.Join(filepath.Dir(profileDir), "."+filepath.Base(profileDir)+".previous")
+}
+
+// carryOverProfileFiles copies everything in profileDir that was not installed from mods into dst,
+// e.g. the profile icon. Files are hard linked where possible; the previous tree is never modified.
+func carryOverProfileFiles(profileDir, dst string, meta *ProfileMetadata) error {
+	modFiles := make(map[string]struct{})
+	if meta != nil {
+		for _, path := range meta.ModFiles {
+			modFiles[filepath.Clean(path)] = struct{}{}
+		}
+	}
+	return filepath.WalkDir(profileDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(profileDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		switch rel {
+		case "BepInEx", "dotnet":
+			return filepath.SkipDir
+		case "profile_meta.json":
+			return nil
+		}
+		if _, ok := modFiles[rel]; ok {
+			return nil
+		}
+		switch {
+		case d.IsDir():
+			return os.Mkdir(filepath.Join(dst, rel), 0755)
+		case d.Type().IsRegular():
+			if err := os.Link(path, filepath.Join(dst, rel)); err == nil {
+				return nil
+			}
+			return copyFile(path, filepath.Join(dst, rel))
+		default:
+			slog.Warn("Skipping non-regular file in profile directory", "file", path)
+			return nil
+		}
+	})
+}
+
+// swapProfileDirectory replaces profileDir with stagingDir. The previous tree is moved aside first and
+// restored if the staging directory cannot be moved into place.
+func swapProfileDirectory(profileDir, stagingDir string) error {
+	previousDir := previousProfileDir(profileDir)
+	if err := os.RemoveAll(previousDir); err != nil {
+		return fmt.Errorf("failed to remove stale previous profile directory: %w", err)
+	}
+	hasPrevious := true
+	if err := os.Rename(profileDir, previousDir); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to move previous profile directory aside: %w", err)
+		}
+		hasPrevious = false
+	}
+	if err := os.Rename(stagingDir, profileDir); err != nil {
+		if hasPrevious {
+			if restoreErr := os.Rename(previousDir, profileDir); restoreErr != nil {
+				return fmt.Errorf("failed to commit profile directory: %w (restoring the previous profile also failed: %v)", err, restoreErr)
+			}
+		}
+		return fmt.Errorf("failed to commit profile directory: %w", err)
+	}
+	if hasPrevious {
+		if err := os.RemoveAll(previousDir); err != nil {
+			slog.Warn("Failed to remove previous profile directory", "dir", previousDir, "error", err)
+		}
+	}
+	return nil
+}
+
+// recoverProfileDirectory finishes or rolls back a swap interrupted by a crash.
+func recoverProfileDirectory(profileDir string) error {
+	previousDir := previousProfileDir(profileDir)
+	if _, err := os.Stat(previousDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if _, err := os.Stat(profileDir); err == nil {
+		// The new tree was committed; only the cleanup is missing
+		return os.RemoveAll(previousDir)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	slog.Warn("Restoring previous profile directory after an interrupted install", "dir", profileDir)
+	return os.Rename(previousDir, profileDir)
+}
@@ -0,0 +1,129 @@
+package modmgr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
+)
+
+func TestPrepareProfileDirectory_FailedInstallKeepsPreviousProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	profileDir := filepath.Join(t.TempDir(), "profile")
+	a := downloadTestVersion("a", []byte("/a.dll"), server.URL+"/a.dll")
+	b := downloadTestVersion("b", []byte("/b.dll"), server.URL+"/b.dll")
+	require.NoError(t, DownloadMods(cacheDir, []ModVersion{a}, aumgr.BinaryType64Bit, nil, false))
+	require.NoError(t, PrepareProfileDirectory(profileDir, "", cacheDir, []ModVersion{a}, aumgr.BinaryType64Bit, "2025.1.1", false, nil))
+	require.NoError(t, os.WriteFile(filepath.Join(profileDir, "icon.png"), []byte("icon"), 0644))
+
+	// b is not cached, so the install fails after a has already been staged
+	err := PrepareProfileDirectory(profileDir, "", cacheDir, []ModVersion{a, b}, aumgr.BinaryType64Bit, "2025.1.1", false, nil)
+	require.Error(t, err)
+
+	meta, err := GetProfileMetadata(profileDir)
+	require.NoError(t, err)
+	require.Len(t, meta.ModVersions, 1)
+	require.FileExists(t, filepath.Join(profileDir, fileDestinationPath(a.Files[0])))
+	require.FileExists(t, filepath.Join(profileDir, "icon.png"))
+	require.NoDirExists(t, stagingProfileDir(profileDir))
+	require.NoDirExists(t, previousProfileDir(profileDir))
+
+	// Once b is cached the new tree replaces the old one, keeping files that do not belong to mods
+	require.NoError(t, DownloadMods(cacheDir, []ModVersion{b}, aumgr.BinaryType64Bit, nil, false))
+	require.NoError(t, PrepareProfileDirectory(profileDir, "", cacheDir, []ModVersion{b}, aumgr.BinaryType64Bit, "2025.1.1", false, nil))
+	require.NoFileExists(t, filepath.Join(profileDir, fileDestinationPath(a.Files[0])))
+	require.FileExists(t, filepath.Join(profileDir, fileDestinationPath(b.Files[0])))
+	require.FileExists(t, filepath.Join(profileDir, "icon.png"))
+	meta, err = GetProfileMetadata(profileDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{fileDestinationPath(b.Files[0])}, meta.ModFiles)
+}
+
+func TestPrepareProfileDirectory_RecoversInterruptedSwap(t *testing.T) {
+	profileDir := filepath.Join(t.TempDir(), "profile")
+	require.NoError(t, os.MkdirAll(previousProfileDir(profileDir), 0755))
+	require.NoError(t, saveProfileMetadata(previousProfileDir(profileDir), &ProfileMetadata{GameVersion: "2025.1.1", BinaryType: aumgr.BinaryType64Bit}))
+
+	// The crash happened after the previous tree was moved aside but before the new one was moved in
+	require.NoError(t, PrepareProfileDirectory(profileDir, "", t.TempDir(), nil, aumgr.BinaryType64Bit, "2025.1.1", false, nil))
+	require.NoDirExists(t, previousProfileDir(profileDir))
+	meta, err := GetProfileMetadata(profileDir)
+	require.NoError(t, err)
+	require.Equal(t, "2025.1.1", meta.GameVersion)
+}
+
+func TestPrepareProfileDirectory_PreservesUserData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	profileDir := filepath.Join(t.TempDir(), "profile")
+	v1 := downloadTestVersion("a", []byte("/v1/a.dll"), server.URL+"/v1/a.dll")
+	v1.Features = map[string]any{FeaturePreservePaths: []any{"BepInEx/plugins/a-data"}}
+	v2 := downloadTestVersion("a", []byte("/v2/a.dll"), server.URL+"/v2/a.dll")
+	v2.VersionID = "v2.0.0"
+	v2.Features = v1.Features
+	require.NoError(t, DownloadMods(cacheDir, []ModVersion{v1, v2}, aumgr.BinaryType64Bit, nil, false))
+	require.NoError(t, PrepareProfileDirectory(profileDir, "", cacheDir, []ModVersion{v1}, aumgr.BinaryType64Bit, "2025.1.1", false, nil))
+
+	userFiles := map[string]string{
+		filepath.Join("BepInEx", "config", "a.cfg"):           "roles=on",
+		filepath.Join("BepInEx", "plugins", "a-data", "save"): "save",
+		filepath.Join("BepInEx", "plugins", "user", "notes"):  "user",
+		filepath.Join("BepInEx", "LogOutput.log"):             "log",
+	}
+	for path, content := range userFiles {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(profileDir, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(profileDir, path), []byte(content), 0644))
+	}
+
+	// The user preserves the whole plugins directory, but the mod file in it still comes from v2
+	require.NoError(t, PrepareProfileDirectory(profileDir, "", cacheDir, []ModVersion{v2}, aumgr.BinaryType64Bit, "2025.1.1", false, nil, WithPreservePaths("BepInEx/plugins")))
+	for path, content := range userFiles {
+		if filepath.Base(path) == "LogOutput.log" {
+			require.NoFileExists(t, filepath.Join(profileDir, path))
+			continue
+		}
+		got, err := os.ReadFile(filepath.Join(profileDir, path))
+		require.NoError(t, err)
+		require.Equal(t, content, string(got))
+	}
+	got, err := os.ReadFile(filepath.Join(profileDir, fileDestinationPath(v2.Files[0])))
+	require.NoError(t, err)
+	require.Equal(t, "/v2/a.dll", string(got))
+}
+
+func TestPrepareProfileDirectory_DropsPreservePathsOfRemovedMods(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	profileDir := filepath.Join(t.TempDir(), "profile")
+	a := downloadTestVersion("a", []byte("/a.dll"), server.URL+"/a.dll")
+	a.Features = map[string]any{FeaturePreservePaths: []any{"BepInEx/a-data"}}
+	require.NoError(t, DownloadMods(cacheDir, []ModVersion{a}, aumgr.BinaryType64Bit, nil, false))
+	require.NoError(t, PrepareProfileDirectory(profileDir, "", cacheDir, []ModVersion{a}, aumgr.BinaryType64Bit, "2025.1.1", false, nil))
+	save := filepath.Join(profileDir, "BepInEx", "a-data", "save")
+	require.NoError(t, os.MkdirAll(filepath.Dir(save), 0755))
+	require.NoError(t, os.WriteFile(save, []byte("save"), 0644))
+
+	require.NoError(t, PrepareProfileDirectory(profileDir, "", cacheDir, nil, aumgr.BinaryType64Bit, "2025.1.1", false, nil))
+	meta, err := GetProfileMetadata(profileDir)
+	require.NoError(t, err)
+	require.Equal(t, DefaultPreservePaths, meta.PreservePaths)
+	require.NoFileExists(t, save)
+}
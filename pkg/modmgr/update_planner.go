@@ -0,0 +1,360 @@
+package modmgr
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
+)
+
+// ModUpdate is a newer version of a selected mod that is compatible with the installed game.
+type ModUpdate struct {
+	ModID   string      `json:"mod_id"`
+	Current ModVersion  `json:"current"`
+	Latest  ModVersion  `json:"latest"`
+	Diff    VersionDiff `json:"diff"`
+}
+
+// UpdatePlan lists the available updates of a set of selected mods.
+type UpdatePlan struct {
+	Current []ModVersion `json:"current"`
+	// Updates are sorted by mod ID.
+	Updates []ModUpdate `json:"updates,omitempty"`
+	// Errors holds the mods whose updates could not be checked, e.g. because they were removed from the server.
+	Errors map[string]error `json:"-"`
+	// Resolved is the dependency graph with every update applied. It is nil when Conflict is set.
+	Resolved map[string]ModVersion `json:"resolved,omitempty"`
+	// Conflict explains why the updates cannot all be applied together; a subset may still resolve.
+	Conflict *ResolutionError `json:"conflict,omitempty"`
+
+	provider VersionProvider
+	opts     []ResolveOption
+}
+
+// UpdateChecker is a VersionProvider that can check many mods for updates in one request.
+// PlanUpdates uses it to look up the latest versions of the selected mods together instead of one at a time.
+type UpdateChecker interface {
+	VersionProvider
+	// CheckForUpdates maps each installed mod whose latest version differs from the installed one to that version,
+	// and each mod that does not exist or has no versions to nil. Mods that are up to date are left out.
+	CheckForUpdates(installedVersions map[string]string) (map[string]*ModVersion, error)
+}
+
+// PlanUpdates finds the newest version of each selected mod that is compatible with the game configured by opts,
+// and resolves the dependencies of the set with every update applied.
+// Local mods are skipped, and a mod that cannot be checked is recorded in Errors instead of failing the whole plan.
+// If the provider is an UpdateChecker, the latest versions are checked in one batch and other versions are only
+// looked up for mods whose latest version is yanked or incompatible.
+func PlanUpdates(selected []ModVersion, provider VersionProvider, opts ...ResolveOption) (*UpdatePlan, error) {
+	var config resolveConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	plan := &UpdatePlan{
+		Current:  slices.Clone(selected),
+		Errors:   make(map[string]error),
+		provider: provider,
+		opts:     opts,
+	}
+	slices.SortFunc(plan.Current, func(a, b ModVersion) int {
+		return strings.Compare(a.ModID, b.ModID)
+	})
+	latestVersions := checkLatestVersions(provider, plan.Current)
+	for _, current := range plan.Current {
+		if current.IsLocal() {
+			continue
+		}
+		latest, ok := latestVersions[current.ModID]
+		if !ok {
+			var err error
+			if latest, err = provider.GetLatestModVersion(current.ModID); err != nil {
+				plan.Errors[current.ModID] = fmt.Errorf("failed to fetch latest version of %s: %w", current.ModID, err)
+				continue
+			}
+		} else if latest == nil {
+			plan.Errors[current.ModID] = fmt.Errorf("failed to fetch latest version of %s: mod not found", current.ModID)
+			continue
+		}
+		latest, err := findUpdate(provider, current, latest, config)
+		if err != nil {
+			plan.Errors[current.ModID] = err
+			continue
+		}
+		if latest == nil {
+			continue
+		}
+		plan.Updates = append(plan.Updates, ModUpdate{
+			ModID:   current.ModID,
+			Current: current,
+			Latest:  *latest,
+			Diff:    DiffVersions(current, *latest),
+		})
+	}
+
+	if len(plan.Updates) == 0 {
+		return plan, nil
+	}
+	_, resolved, err := plan.apply(nil)
+	var conflict *ResolutionError
+	switch {
+	case errors.As(err, &conflict):
+		plan.Conflict = conflict
+	case err != nil:
+		return nil, err
+	default:
+		plan.Resolved = resolved
+	}
+	return plan, nil
+}
+
+// Apply returns the selected mods with the updates of modIDs applied, or every update if modIDs is empty,
+// together with their resolved dependency graph.
+func (p *UpdatePlan) Apply(modIDs ...string) ([]ModVersion, map[string]ModVersion, error) {
+	for _, modID := range modIDs {
+		if !slices.ContainsFunc(p.Updates, func(u ModUpdate) bool { return u.ModID == modID }) {
+			return nil, nil, fmt.Errorf("no update available for mod %s", modID)
+		}
+	}
+	return p.apply(modIDs)
+}
+
+func (p *UpdatePlan) apply(modIDs []string) ([]ModVersion, map[string]ModVersion, error) {
+	updates := make(map[string]ModVersion, len(p.Updates))
+	for _, u := range p.Updates {
+		if len(modIDs) == 0 || slices.Contains(modIDs, u.ModID) {
+			updates[u.ModID] = u.Latest
+		}
+	}
+	selected := make([]ModVersion, len(p.Current))
+	for i, v := range p.Current {
+		if latest, ok := updates[v.ModID]; ok {
+			v = latest
+		}
+		selected[i] = v
+	}
+	resolved, err := ResolveDependencies(selected, p.provider, p.opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return selected, resolved, nil
+}
+
+// checkLatestVersions looks up the latest versions of the selected mods in one batch if the provider is an
+// UpdateChecker. Mods that are up to date map to their current version, and mods that do not exist to nil.
+// Mods left out, or every mod if the batch fails, are looked up one at a time.
+func checkLatestVersions(provider VersionProvider, selected []ModVersion) map[string]*ModVersion {
+	checker, ok := provider.(UpdateChecker)
+	if !ok {
+		return nil
+	}
+	installed := make(map[string]string, len(selected))
+	for _, current := range selected {
+		if !current.IsLocal() {
+			installed[current.ModID] = current.VersionID
+		}
+	}
+	if len(installed) == 0 {
+		return nil
+	}
+	updates, err := checker.CheckForUpdates(installed)
+	if err != nil {
+		slog.Debug("Failed to check for updates in one batch, checking mods one at a time", "error", err)
+		return nil
+	}
+	latest := make(map[string]*ModVersion, len(selected))
+	for _, current := range selected {
+		if _, ok := installed[current.ModID]; !ok {
+			continue
+		}
+		if update, ok := updates[current.ModID]; ok {
+			latest[current.ModID] = update
+		} else {
+			latest[current.ModID] = &current
+		}
+	}
+	return latest
+}
+
+// findUpdate returns the newest compatible version newer than current, or nil if there is none. latest is the
+// latest version of the mod, if it has one.
+func findUpdate(provider VersionProvider, current ModVersion, latest *ModVersion, config resolveConfig) (*ModVersion, error) {
+	if latest == nil || compareVersionID(latest.VersionID, current.VersionID) <= 0 {
+		return nil, nil
+	}
+	if !latest.IsYanked() && latest.CheckCompatibility(config.binaryType, config.gameVersion) == nil {
+		return latest, nil
+	}
+
+	ids, err := provider.GetModVersionIDs(current.ModID, 100, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of %s: %w", current.ModID, err)
+	}
+	ids = slices.Clone(ids)
+	slices.SortStableFunc(ids, func(a, b string) int {
+		return compareVersionID(b, a)
+	})
+	for _, versionID := range ids {
+		if compareVersionID(versionID, current.VersionID) <= 0 {
+			break
+		}
+		if versionID == latest.VersionID {
+			continue
+		}
+		v, err := provider.GetModVersion(current.ModID, versionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s (version: %s): %w", current.ModID, versionID, err)
+		}
+		if v != nil && !v.IsYanked() && v.CheckCompatibility(config.binaryType, config.gameVersion) == nil {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// VersionDiff describes what changed between two versions of a mod. Files are named by their install path.
+type VersionDiff struct {
+	AddedFiles   []string `json:"added_files,omitempty"`
+	RemovedFiles []string `json:"removed_files,omitempty"`
+	ChangedFiles []string `json:"changed_files,omitempty"`
+
+	AddedDependencies   []model.ModVersionDependency `json:"added_dependencies,omitempty"`
+	RemovedDependencies []model.ModVersionDependency `json:"removed_dependencies,omitempty"`
+	ChangedDependencies []DependencyChange           `json:"changed_dependencies,omitempty"`
+
+	AddedFeatures   []string `json:"added_features,omitempty"`
+	RemovedFeatures []string `json:"removed_features,omitempty"`
+	ChangedFeatures []string `json:"changed_features,omitempty"`
+}
+
+// DependencyChange is a dependency declared by both versions with a different constraint or type.
+type DependencyChange struct {
+	ModID string                     `json:"mod_id"`
+	From  model.ModVersionDependency `json:"from"`
+	To    model.ModVersionDependency `json:"to"`
+}
+
+// DiffVersions compares the files, dependencies and feature flags of two versions of a mod.
+func DiffVersions(from, to ModVersion) VersionDiff {
+	var diff VersionDiff
+
+	fromFiles, toFiles := filesByPath(from), filesByPath(to)
+	for _, p := range slices.Sorted(maps.Keys(toFiles)) {
+		prev, ok := fromFiles[p]
+		switch {
+		case !ok:
+			diff.AddedFiles = append(diff.AddedFiles, p)
+		case prev.Size != toFiles[p].Size || !maps.Equal(prev.Hashes, toFiles[p].Hashes):
+			diff.ChangedFiles = append(diff.ChangedFiles, p)
+		}
+	}
+	for _, p := range slices.Sorted(maps.Keys(fromFiles)) {
+		if _, ok := toFiles[p]; !ok {
+			diff.RemovedFiles = append(diff.RemovedFiles, p)
+		}
+	}
+
+	fromDeps, toDeps := dependenciesByMod(from), dependenciesByMod(to)
+	for _, modID := range slices.Sorted(maps.Keys(toDeps)) {
+		prev, ok := fromDeps[modID]
+		switch {
+		case !ok:
+			diff.AddedDependencies = append(diff.AddedDependencies, toDeps[modID])
+		case constraintString(prev.VersionID) != constraintString(toDeps[modID].VersionID) || prev.DependencyType != toDeps[modID].DependencyType:
+			diff.ChangedDependencies = append(diff.ChangedDependencies, DependencyChange{ModID: modID, From: prev, To: toDeps[modID]})
+		}
+	}
+	for _, modID := range slices.Sorted(maps.Keys(fromDeps)) {
+		if _, ok := toDeps[modID]; !ok {
+			diff.RemovedDependencies = append(diff.RemovedDependencies, fromDeps[modID])
+		}
+	}
+
+	for _, feature := range slices.Sorted(maps.Keys(to.Features)) {
+		prev, ok := from.Features[feature]
+		switch {
+		case !ok:
+			diff.AddedFeatures = append(diff.AddedFeatures, feature)
+		case !reflect.DeepEqual(prev, to.Features[feature]):
+			diff.ChangedFeatures = append(diff.ChangedFeatures, feature)
+		}
+	}
+	for _, feature := range slices.Sorted(maps.Keys(from.Features)) {
+		if _, ok := to.Features[feature]; !ok {
+			diff.RemovedFeatures = append(diff.RemovedFeatures, feature)
+		}
+	}
+	return diff
+}
+
+// IsEmpty reports whether the versions have the same files, dependencies and feature flags.
+func (d VersionDiff) IsEmpty() bool {
+	return reflect.DeepEqual(d, VersionDiff{})
+}
+
+// String renders the diff one change per line, prefixed with +, - or ~.
+func (d VersionDiff) String() string {
+	var b strings.Builder
+	for _, f := range d.AddedFiles {
+		fmt.Fprintf(&b, "+ file %s\n", f)
+	}
+	for _, f := range d.RemovedFiles {
+		fmt.Fprintf(&b, "- file %s\n", f)
+	}
+	for _, f := range d.ChangedFiles {
+		fmt.Fprintf(&b, "~ file %s\n", f)
+	}
+	for _, dep := range d.AddedDependencies {
+		fmt.Fprintf(&b, "+ %s dependency %s %s\n", dependencyTypeString(dep.DependencyType), dep.ModID, constraintString(dep.VersionID))
+	}
+	for _, dep := range d.RemovedDependencies {
+		fmt.Fprintf(&b, "- %s dependency %s %s\n", dependencyTypeString(dep.DependencyType), dep.ModID, constraintString(dep.VersionID))
+	}
+	for _, c := range d.ChangedDependencies {
+		fmt.Fprintf(&b, "~ dependency %s: %s %s -> %s %s\n", c.ModID, dependencyTypeString(c.From.DependencyType), constraintString(c.From.VersionID), dependencyTypeString(c.To.DependencyType), constraintString(c.To.VersionID))
+	}
+	for _, f := range d.AddedFeatures {
+		fmt.Fprintf(&b, "+ feature %s\n", f)
+	}
+	for _, f := range d.RemovedFeatures {
+		fmt.Fprintf(&b, "- feature %s\n", f)
+	}
+	for _, f := range d.ChangedFeatures {
+		fmt.Fprintf(&b, "~ feature %s\n", f)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// filesByPath keys files by where they are installed, so a re-uploaded file with a new ID is reported as changed.
+func filesByPath(mod ModVersion) map[string]model.ModVersionFile {
+	files := make(map[string]model.ModVersionFile, len(mod.Files))
+	for _, f := range mod.Files {
+		key := path.Join(f.ExtractPath, f.Filename)
+		if f.TargetPlatform != "" && f.TargetPlatform != model.TargetPlatformAny {
+			key += " (" + string(f.TargetPlatform) + ")"
+		}
+		files[key] = f
+	}
+	return files
+}
+
+func dependenciesByMod(mod ModVersion) map[string]model.ModVersionDependency {
+	deps := make(map[string]model.ModVersionDependency, len(mod.Dependencies))
+	for _, dep := range mod.Dependencies {
+		deps[dep.ModID] = dep
+	}
+	return deps
+}
+
+func dependencyTypeString(depType model.DependencyType) string {
+	if t, err := normalizeDependencyType(depType); err == nil {
+		return string(t)
+	}
+	return string(depType)
+}
@@ -0,0 +1,172 @@
+package modmgr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
+)
+
+type removedModProvider struct {
+	*mockVersionProvider
+	removed string
+}
+
+func (p removedModProvider) GetLatestModVersion(modID string) (*ModVersion, error) {
+	if modID == p.removed {
+		return nil, fmt.Errorf("mod %s not found", modID)
+	}
+	return p.mockVersionProvider.GetLatestModVersion(modID)
+}
+
+// updateChecker answers update checks in one batch and counts the lookups made one mod at a time.
+type updateChecker struct {
+	*mockVersionProvider
+	checks  []map[string]string
+	lookups []string
+}
+
+func (p *updateChecker) CheckForUpdates(installedVersions map[string]string) (map[string]*ModVersion, error) {
+	p.checks = append(p.checks, installedVersions)
+	updates := make(map[string]*ModVersion)
+	for modID, versionID := range installedVersions {
+		latest, _ := p.mockVersionProvider.GetLatestModVersion(modID)
+		if latest == nil || latest.VersionID != versionID {
+			updates[modID] = latest
+		}
+	}
+	return updates, nil
+}
+
+func (p *updateChecker) GetLatestModVersion(modID string) (*ModVersion, error) {
+	p.lookups = append(p.lookups, "latest "+modID)
+	return p.mockVersionProvider.GetLatestModVersion(modID)
+}
+
+func (p *updateChecker) GetModVersion(modID string, versionID string) (*ModVersion, error) {
+	p.lookups = append(p.lookups, modID+"@"+versionID)
+	return p.mockVersionProvider.GetModVersion(modID, versionID)
+}
+
+func TestPlanUpdates(t *testing.T) {
+	a1 := modVersion("a", "v1.0.0")
+	a1.Files = []model.ModVersionFile{
+		{ID: "1", Filename: "a.dll", TargetPlatform: model.TargetPlatformAny, Hashes: map[string]string{"sha256": "a1"}},
+		{ID: "2", Filename: "old.dll", TargetPlatform: model.TargetPlatformAny},
+	}
+	a1.Features = map[string]any{FeaturePreservePaths: "BepInEx/a", "direct_join": true}
+	a2 := modVersion("a", "v2.0.0",
+		model.ModVersionDependency{ModID: "lib", VersionID: ">=v2.0.0", DependencyType: model.DependencyTypeRequired},
+	)
+	a2.GameVersions = []string{"2025.3.25"}
+	a2.Files = []model.ModVersionFile{
+		{ID: "3", Filename: "a.dll", TargetPlatform: model.TargetPlatformAny, Hashes: map[string]string{"sha256": "a2"}},
+		{ID: "4", Filename: "new.dll", ExtractPath: "BepInEx/patchers", TargetPlatform: model.TargetPlatformAny},
+	}
+	a2.Features = map[string]any{FeaturePreservePaths: "BepInEx/a,BepInEx/b"}
+	// a@v3.0.0 does not support the installed game
+	a3 := modVersion("a", "v3.0.0")
+	a3.GameVersions = []string{"2026.1.1"}
+	b1 := modVersion("b", "v1.0.0", model.ModVersionDependency{ModID: "lib", VersionID: "<v2.0.0", DependencyType: model.DependencyTypeRequired})
+	b2 := modVersion("b", "v2.0.0", model.ModVersionDependency{ModID: "lib", VersionID: "any", DependencyType: model.DependencyTypeRequired})
+	provider := &mockVersionProvider{
+		versions: map[string]map[string]ModVersion{
+			"a": {"v1.0.0": a1, "v2.0.0": a2, "v3.0.0": a3},
+			"b": {"v1.0.0": b1, "v2.0.0": b2},
+			"lib": {
+				"v1.0.0": modVersion("lib", "v1.0.0"),
+				"v2.0.0": modVersion("lib", "v2.0.0"),
+			},
+		},
+		ids: map[string][]string{
+			"a":   {"v1.0.0", "v2.0.0", "v3.0.0"},
+			"b":   {"v1.0.0", "v2.0.0"},
+			"lib": {"v1.0.0", "v2.0.0"},
+		},
+		latest: map[string]string{"a": "v3.0.0", "b": "v2.0.0", "lib": "v2.0.0"},
+	}
+
+	plan, err := PlanUpdates([]ModVersion{b1, a1, modVersion("gone", "v1.0.0")}, removedModProvider{provider, "gone"}, WithGameVersion("2025.3.25"))
+	require.NoError(t, err)
+	require.Contains(t, plan.Errors, "gone")
+	require.Len(t, plan.Updates, 2)
+	require.Nil(t, plan.Conflict)
+	require.Equal(t, "v2.0.0", plan.Resolved["lib"].VersionID)
+
+	update := plan.Updates[0]
+	require.Equal(t, "a", update.ModID)
+	require.Equal(t, "v2.0.0", update.Latest.VersionID)
+	require.Equal(t, VersionDiff{
+		AddedFiles:        []string{"BepInEx/patchers/new.dll"},
+		RemovedFiles:      []string{"old.dll"},
+		ChangedFiles:      []string{"a.dll"},
+		AddedDependencies: []model.ModVersionDependency{{ModID: "lib", VersionID: ">=v2.0.0", DependencyType: model.DependencyTypeRequired}},
+		RemovedFeatures:   []string{"direct_join"},
+		ChangedFeatures:   []string{FeaturePreservePaths},
+	}, update.Diff)
+	require.Equal(t, "v2.0.0", plan.Updates[1].Latest.VersionID)
+	require.Equal(t, []DependencyChange{{
+		ModID: "lib",
+		From:  model.ModVersionDependency{ModID: "lib", VersionID: "<v2.0.0", DependencyType: model.DependencyTypeRequired},
+		To:    model.ModVersionDependency{ModID: "lib", VersionID: "any", DependencyType: model.DependencyTypeRequired},
+	}}, plan.Updates[1].Diff.ChangedDependencies)
+
+	// Updating only a leaves b requiring lib <v2.0.0
+	_, _, err = plan.Apply("a")
+	var resolutionErr *ResolutionError
+	require.ErrorAs(t, err, &resolutionErr)
+
+	selected, resolved, err := plan.Apply("b")
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", selected[0].VersionID)
+	require.Equal(t, "v2.0.0", selected[1].VersionID)
+	require.Equal(t, "v2.0.0", resolved["lib"].VersionID)
+
+	_, _, err = plan.Apply("lib")
+	require.Error(t, err)
+}
+
+func TestPlanUpdates_ChecksLatestVersionsInOneBatch(t *testing.T) {
+	// a@v3.0.0 does not support the installed game, and b@v2.0.0 was yanked
+	a3 := modVersion("a", "v3.0.0")
+	a3.GameVersions = []string{"2026.1.1"}
+	b2 := modVersion("b", "v2.0.0")
+	b2.Status = model.VersionStatusYanked
+	provider := &updateChecker{mockVersionProvider: &mockVersionProvider{
+		versions: map[string]map[string]ModVersion{
+			"a": {"v1.0.0": modVersion("a", "v1.0.0"), "v2.0.0": modVersion("a", "v2.0.0"), "v3.0.0": a3},
+			"b": {"v1.0.0": modVersion("b", "v1.0.0"), "v2.0.0": b2},
+			"c": {"v1.0.0": modVersion("c", "v1.0.0"), "v1.1.0": modVersion("c", "v1.1.0")},
+			"d": {"v1.0.0": modVersion("d", "v1.0.0")},
+		},
+		ids: map[string][]string{
+			"a": {"v1.0.0", "v2.0.0", "v3.0.0"},
+			"b": {"v1.0.0", "v2.0.0"},
+			"c": {"v1.0.0", "v1.1.0"},
+			"d": {"v1.0.0"},
+		},
+		latest: map[string]string{"a": "v3.0.0", "b": "v2.0.0", "c": "v1.1.0", "d": "v1.0.0"},
+	}}
+	local := modVersion(LocalModIDPrefix+"mine", "v1.0.0")
+	local.Features = map[string]any{FeatureLocalSource: "mine.dll"}
+
+	selected := []ModVersion{
+		modVersion("a", "v1.0.0"), modVersion("b", "v1.0.0"), modVersion("c", "v1.0.0"), modVersion("d", "v1.0.0"),
+		modVersion("gone", "v1.0.0"), local,
+	}
+	plan, err := PlanUpdates(selected, provider, WithGameVersion("2025.3.25"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{{"a": "v1.0.0", "b": "v1.0.0", "c": "v1.0.0", "d": "v1.0.0", "gone": "v1.0.0"}}, provider.checks)
+	require.Contains(t, plan.Errors, "gone")
+	require.Len(t, plan.Updates, 2)
+	require.Equal(t, "a", plan.Updates[0].ModID)
+	require.Equal(t, "v2.0.0", plan.Updates[0].Latest.VersionID)
+	require.Equal(t, "c", plan.Updates[1].ModID)
+	require.Equal(t, "v1.1.0", plan.Updates[1].Latest.VersionID)
+
+	// No latest version is looked up one mod at a time, and only a, whose latest version does not support the game,
+	// has an older version looked up; b has none newer than the installed one besides the yanked latest
+	require.Equal(t, []string{"a@v2.0.0"}, provider.lookups)
+}
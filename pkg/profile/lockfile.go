@@ -0,0 +1,233 @@
+package profile
+
+import (
+	"encoding/json/v2"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
+	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
+)
+
+// LockFileName is the name of the dependency lock stored in each profile directory.
+const LockFileName = "mods.lock.json"
+
+const lockFileVersion = 1
+
+// Lock pins the dependency graph resolved for a profile, so launches install the same mod versions and files
+// until the lock is explicitly updated.
+type Lock struct {
+	Version    int       `json:"version"`
+	ResolvedAt time.Time `json:"resolved_at"`
+	// Requested maps each mod picked in the profile to its version ID at resolution time.
+	Requested map[string]string `json:"requested"`
+	// ModVersions is the resolved set sorted by mod ID, including the file hashes of every version.
+	ModVersions []modmgr.ModVersion `json:"mod_versions"`
+}
+
+// NewLock creates a lock of the resolved versions for the profile's current mods.
+func NewLock(p Profile, resolved []modmgr.ModVersion) *Lock {
+	requested := make(map[string]string, len(p.ModVersions))
+	for modID, v := range p.ModVersions {
+		requested[modID] = v.VersionID
+	}
+	versions := slices.Clone(resolved)
+	slices.SortFunc(versions, func(a, b modmgr.ModVersion) int {
+		return strings.Compare(a.ModID, b.ModID)
+	})
+	return &Lock{
+		Version:     lockFileVersion,
+		ResolvedAt:  time.Now(),
+		Requested:   requested,
+		ModVersions: versions,
+	}
+}
+
+// Matches reports whether the lock was resolved for the mods currently picked in the profile.
+func (l *Lock) Matches(p Profile) bool {
+	if l == nil || l.Version != lockFileVersion || len(l.Requested) != len(p.ModVersions) {
+		return false
+	}
+	for modID, v := range p.ModVersions {
+		if versionID, ok := l.Requested[modID]; !ok || versionID != v.VersionID {
+			return false
+		}
+	}
+	return true
+}
+
+// CompatibleWith reports whether every locked dependency can be used with the installed game.
+// Mods picked in the profile are not checked, as resolving again would not change them.
+func (l *Lock) CompatibleWith(binaryType aumgr.BinaryType, gameVersion string) bool {
+	if l == nil {
+		return false
+	}
+	for _, v := range l.ModVersions {
+		if _, requested := l.Requested[v.ModID]; requested {
+			continue
+		}
+		if v.CheckCompatibility(binaryType, gameVersion) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Versions returns a copy of the locked mod versions.
+func (l *Lock) Versions() []modmgr.ModVersion {
+	if l == nil {
+		return nil
+	}
+	return slices.Clone(l.ModVersions)
+}
+
+func (m *Manager) lockPath(id uuid.UUID) string {
+	return filepath.Join(m.profileDir(id), LockFileName)
+}
+
+// LoadLock reads the dependency lock of the profile. It returns nil if the profile has no lock yet.
+func (m *Manager) LoadLock(id uuid.UUID) (*Lock, error) {
+	if id == uuid.Nil {
+		return nil, fmt.Errorf("profile ID cannot be nil")
+	}
+	data, err := os.ReadFile(m.lockPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read profile lock: %w", err)
+	}
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profile lock: %w", err)
+	}
+	return &lock, nil
+}
+
+// SaveLock replaces the dependency lock of the profile.
+func (m *Manager) SaveLock(id uuid.UUID, lock *Lock) error {
+	if id == uuid.Nil {
+		return fmt.Errorf("profile ID cannot be nil")
+	}
+	if lock == nil {
+		return fmt.Errorf("profile lock cannot be nil")
+	}
+	data, err := json.Marshal(lock, json.Deterministic(true))
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile lock: %w", err)
+	}
+
+	path := m.lockPath(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	// Replace the file so a crash never leaves a truncated lock, and links made by profile installs are not written through
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile lock: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write profile lock: %w", err)
+	}
+	return nil
+}
+
+type LockChangeType string
+
+const (
+	LockChangeAdded      LockChangeType = "added"
+	LockChangeRemoved    LockChangeType = "removed"
+	LockChangeUpgraded   LockChangeType = "upgraded"
+	LockChangeDowngraded LockChangeType = "downgraded"
+	// LockChangeFiles means the version is unchanged but its files or their hashes differ.
+	LockChangeFiles LockChangeType = "files_changed"
+)
+
+// LockChange describes how one mod differs between two locks.
+type LockChange struct {
+	ModID       string         `json:"mod_id"`
+	Type        LockChangeType `json:"type"`
+	FromVersion string         `json:"from_version,omitempty"`
+	ToVersion   string         `json:"to_version,omitempty"`
+}
+
+func (c LockChange) String() string {
+	switch c.Type {
+	case LockChangeAdded:
+		return fmt.Sprintf("+ %s %s", c.ModID, c.ToVersion)
+	case LockChangeRemoved:
+		return fmt.Sprintf("- %s %s", c.ModID, c.FromVersion)
+	case LockChangeFiles:
+		return fmt.Sprintf("~ %s %s (files changed)", c.ModID, c.ToVersion)
+	default:
+		return fmt.Sprintf("~ %s %s -> %s", c.ModID, c.FromVersion, c.ToVersion)
+	}
+}
+
+// DiffLocks lists the changes from current to proposed, sorted by mod ID. A nil lock is treated as empty.
+func DiffLocks(current, proposed *Lock) []LockChange {
+	from := lockVersionsByMod(current)
+	to := lockVersionsByMod(proposed)
+
+	var changes []LockChange
+	for modID, prev := range from {
+		if _, ok := to[modID]; !ok {
+			changes = append(changes, LockChange{ModID: modID, Type: LockChangeRemoved, FromVersion: prev.VersionID})
+		}
+	}
+	for modID, next := range to {
+		prev, ok := from[modID]
+		switch {
+		case !ok:
+			changes = append(changes, LockChange{ModID: modID, Type: LockChangeAdded, ToVersion: next.VersionID})
+		case prev.VersionID != next.VersionID:
+			changeType := LockChangeUpgraded
+			if modmgr.CompareVersionIDs(next.VersionID, prev.VersionID) < 0 {
+				changeType = LockChangeDowngraded
+			}
+			changes = append(changes, LockChange{ModID: modID, Type: changeType, FromVersion: prev.VersionID, ToVersion: next.VersionID})
+		case !sameLockedFiles(prev, next):
+			changes = append(changes, LockChange{ModID: modID, Type: LockChangeFiles, FromVersion: prev.VersionID, ToVersion: next.VersionID})
+		}
+	}
+	slices.SortFunc(changes, func(a, b LockChange) int {
+		return strings.Compare(a.ModID, b.ModID)
+	})
+	return changes
+}
+
+func lockVersionsByMod(lock *Lock) map[string]modmgr.ModVersion {
+	if lock == nil {
+		return nil
+	}
+	versions := make(map[string]modmgr.ModVersion, len(lock.ModVersions))
+	for _, v := range lock.ModVersions {
+		versions[v.ModID] = v
+	}
+	return versions
+}
+
+func sameLockedFiles(a, b modmgr.ModVersion) bool {
+	if len(a.Files) != len(b.Files) {
+		return false
+	}
+	files := make(map[string]map[string]string, len(a.Files))
+	for _, f := range a.Files {
+		files[f.ID] = f.Hashes
+	}
+	for _, f := range b.Files {
+		hashes, ok := files[f.ID]
+		if !ok || !maps.Equal(hashes, f.Hashes) {
+			return false
+		}
+	}
+	return true
+}
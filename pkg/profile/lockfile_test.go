@@ -0,0 +1,103 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/pkg/aumgr"
+	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
+)
+
+func lockTestVersion(modID, versionID, sha256 string) modmgr.ModVersion {
+	return modmgr.ModVersion{ModVersionDetails: model.ModVersionDetails{
+		ModID:     modID,
+		VersionID: versionID,
+		Files: []model.ModVersionFile{{
+			ID:     modID + "-file",
+			Hashes: map[string]string{"sha256": sha256},
+		}},
+	}}
+}
+
+func TestManager_SaveAndLoadLock(t *testing.T) {
+	manager, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+
+	p := Profile{ID: uuid.New(), Name: "Locked"}
+	p.AddModVersion(lockTestVersion("a", "v1.0.0", "aa"))
+	require.NoError(t, manager.Add(p))
+
+	lock, err := manager.LoadLock(p.ID)
+	require.NoError(t, err)
+	assert.Nil(t, lock)
+	assert.False(t, lock.Matches(p))
+
+	resolved := []modmgr.ModVersion{lockTestVersion("lib", "v2.0.0", "bb"), lockTestVersion("a", "v1.0.0", "aa")}
+	require.NoError(t, manager.SaveLock(p.ID, NewLock(p, resolved)))
+
+	lock, err = manager.LoadLock(p.ID)
+	require.NoError(t, err)
+	require.NotNil(t, lock)
+	assert.True(t, lock.Matches(p))
+	require.Len(t, lock.Versions(), 2)
+	assert.Equal(t, "a", lock.Versions()[0].ModID)
+	assert.Equal(t, "bb", lock.Versions()[1].Files[0].Hashes["sha256"])
+
+	// Picking another version invalidates the lock
+	p.AddModVersion(lockTestVersion("a", "v1.1.0", "cc"))
+	assert.False(t, lock.Matches(p))
+
+	refs, err := manager.CacheReferences()
+	require.NoError(t, err)
+	require.Len(t, refs, 2)
+	assert.Len(t, refs[1].ModVersions, 2)
+}
+
+func TestDiffLocks(t *testing.T) {
+	p := Profile{ID: uuid.New()}
+	p.AddModVersion(lockTestVersion("a", "v1.0.0", "aa"))
+
+	current := NewLock(p, []modmgr.ModVersion{
+		lockTestVersion("a", "v1.0.0", "aa"),
+		lockTestVersion("lib", "v1.0.0", "l1"),
+		lockTestVersion("old", "v1.0.0", "o1"),
+		lockTestVersion("pinned", "v1.0.0", "p1"),
+		lockTestVersion("rollback", "v2.0.0", "r2"),
+	})
+	proposed := NewLock(p, []modmgr.ModVersion{
+		lockTestVersion("a", "v1.0.0", "aa"),
+		lockTestVersion("lib", "v1.2.0", "l2"),
+		lockTestVersion("new", "v0.1.0", "n1"),
+		lockTestVersion("pinned", "v1.0.0", "p2"),
+		lockTestVersion("rollback", "v1.9.0", "r1"),
+	})
+
+	assert.Equal(t, []LockChange{
+		{ModID: "lib", Type: LockChangeUpgraded, FromVersion: "v1.0.0", ToVersion: "v1.2.0"},
+		{ModID: "new", Type: LockChangeAdded, ToVersion: "v0.1.0"},
+		{ModID: "old", Type: LockChangeRemoved, FromVersion: "v1.0.0"},
+		{ModID: "pinned", Type: LockChangeFiles, FromVersion: "v1.0.0", ToVersion: "v1.0.0"},
+		{ModID: "rollback", Type: LockChangeDowngraded, FromVersion: "v2.0.0", ToVersion: "v1.9.0"},
+	}, DiffLocks(current, proposed))
+	assert.Empty(t, DiffLocks(proposed, proposed))
+	assert.Len(t, DiffLocks(nil, proposed), 5)
+}
+
+func TestLock_CompatibleWith(t *testing.T) {
+	p := Profile{ID: uuid.New()}
+	picked := lockTestVersion("a", "v1.0.0", "aa")
+	picked.GameVersions = []string{"2024.6.18"}
+	p.AddModVersion(picked)
+	lib := lockTestVersion("lib", "v1.0.0", "bb")
+	lib.Files[0].TargetPlatform = model.TargetPlatformX86
+	lock := NewLock(p, []modmgr.ModVersion{picked, lib})
+
+	assert.True(t, lock.CompatibleWith(aumgr.BinaryType32Bit, "2024.6.18"))
+	// The picked mod is not re-resolved, so only the dependency matters
+	assert.True(t, lock.CompatibleWith(aumgr.BinaryType32Bit, "2025.3.25"))
+	assert.False(t, lock.CompatibleWith(aumgr.BinaryType64Bit, "2024.6.18"))
+}
@@ -3,11 +3,15 @@ package profile
 import (
 	"encoding/json/v2"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
+
+	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
 )
 
 type Manager struct {
@@ -140,6 +144,68 @@ func (m *Manager) profileDir(id uuid.UUID) string {
 	return filepath.Join(m.storageDir, "profiles", id.String())
 }
 
+// CacheReferences returns the mod versions every profile needs in the mod cache.
+// Each profile contributes the versions selected in profiles.json and pinned by its lock, for any binary type, and the
+// resolved versions installed in its directory according to profile_meta.json. Profile directories missing from profiles.json are included too.
+// A profile whose lock or metadata cannot be read is logged and referenced as Unknown, which keeps the whole cache.
+func (m *Manager) CacheReferences() ([]modmgr.CacheReference, error) {
+	m.mu.RLock()
+	profiles := make([]Profile, len(m.profiles))
+	copy(profiles, m.profiles)
+	m.mu.RUnlock()
+
+	names := make(map[string]string, len(profiles))
+	refs := make([]modmgr.CacheReference, 0, len(profiles)*2)
+	for _, p := range profiles {
+		names[p.ID.String()] = p.Name
+		refs = append(refs, modmgr.CacheReference{
+			ProfileID:   p.ID.String(),
+			ProfileName: p.Name,
+			ModVersions: p.Versions(),
+		})
+		lock, err := m.LoadLock(p.ID)
+		if err != nil {
+			slog.Warn("Keeping the whole mod cache, as a profile lock could not be read", "profileId", p.ID, "error", err)
+			refs = append(refs, modmgr.CacheReference{ProfileID: p.ID.String(), ProfileName: p.Name, Unknown: true})
+			continue
+		}
+		if lock != nil {
+			refs = append(refs, modmgr.CacheReference{
+				ProfileID:   p.ID.String(),
+				ProfileName: p.Name,
+				ModVersions: lock.Versions(),
+			})
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(m.storageDir, "profiles"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read profiles directory: %w", err)
+	}
+	for _, entry := range entries {
+		// Hidden directories are staging areas of profile installs
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		meta, err := modmgr.GetProfileMetadata(filepath.Join(m.storageDir, "profiles", entry.Name()))
+		if err != nil {
+			slog.Warn("Keeping the whole mod cache, as profile metadata could not be read", "profileId", entry.Name(), "error", err)
+			refs = append(refs, modmgr.CacheReference{ProfileID: entry.Name(), ProfileName: names[entry.Name()], Unknown: true})
+			continue
+		}
+		if meta == nil {
+			continue
+		}
+		refs = append(refs, modmgr.CacheReference{
+			ProfileID:   entry.Name(),
+			ProfileName: names[entry.Name()],
+			BinaryType:  meta.BinaryType,
+			ModVersions: meta.ModVersions,
+		})
+	}
+	return refs, nil
+}
+
 func (m *Manager) profileIconPath(id uuid.UUID) string {
 	return filepath.Join(m.profileDir(id), "icon.png")
 }
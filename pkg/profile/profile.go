@@ -1,6 +1,7 @@
 package profile
 
 import (
+	"fmt"
 	"maps"
 	"slices"
 	"strings"
@@ -20,6 +21,7 @@ type Profile struct {
 	UpdatedAt      time.Time                    `json:"updated_at"`
 	PlayDurationNS int64                        `json:"play_duration_ns,omitzero"`
 	LastLaunchedAt time.Time                    `json:"last_launched_at"`
+	PreservePaths  []string                     `json:"preserve_paths,omitempty"` // profile-relative paths kept across reinstalls
 }
 
 const SharedProfileVersion = "1"
@@ -55,6 +57,25 @@ func (p *Profile) RemoveModVersion(modID string) {
 	delete(p.ModVersions, modID)
 }
 
+// ApplyUpdates applies the planned updates of modIDs, or every update if none are given, to the mods still in the profile.
+// The updated set is resolved first, so a subset that breaks dependencies is refused.
+func (p *Profile) ApplyUpdates(plan *modmgr.UpdatePlan, modIDs ...string) error {
+	if _, _, err := plan.Apply(modIDs...); err != nil {
+		return fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+	for _, update := range plan.Updates {
+		if len(modIDs) > 0 && !slices.Contains(modIDs, update.ModID) {
+			continue
+		}
+		if _, ok := p.ModVersions[update.ModID]; ok {
+			p.AddModVersion(update.Latest)
+		}
+	}
+	return nil
+}
+
+// MakeShared converts the profile into its shareable form. Local mods cannot be fetched by others, so they are left out;
+// check LocalMods to warn the user first.
 func (p *Profile) MakeShared() SharedProfile {
 	shared := SharedProfile{
 		ID:          p.ID,
@@ -66,18 +87,33 @@ func (p *Profile) MakeShared() SharedProfile {
 	}
 
 	for modID, version := range p.ModVersions {
+		if version.IsLocal() {
+			continue
+		}
 		shared.ModVersions[modID] = version.VersionID
 	}
 
 	return shared
 }
 
+// LocalMods returns the mods added from local files, sorted by mod ID.
+func (p *Profile) LocalMods() []modmgr.ModVersion {
+	var local []modmgr.ModVersion
+	for _, v := range p.Versions() {
+		if v.IsLocal() {
+			local = append(local, v)
+		}
+	}
+	return local
+}
+
 // MatchesSharedModVersions checks if the profile's mod versions match the shared profile's mod versions.
+// Local mods are ignored, as they are never shared.
 func (p *Profile) MatchesSharedModVersions(shared SharedProfile) bool {
 	if p == nil {
 		return false
 	}
-	if len(p.ModVersions) != len(shared.ModVersions) {
+	if len(p.ModVersions)-len(p.LocalMods()) != len(shared.ModVersions) {
 		return false
 	}
 	for modID, versionID := range shared.ModVersions {
@@ -2,6 +2,7 @@ package profile
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -52,6 +53,27 @@ func TestProfileManager_AddAndGet(t *testing.T) {
 	assert.Equal(t, versionID, persistedProfile.ModVersions[modID].VersionID)
 }
 
+func TestProfileManager_CacheReferences_UnreadableMetadata(t *testing.T) {
+	manager, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+
+	p := Profile{ID: uuid.New(), Name: "Broken"}
+	p.AddModVersion(lockTestVersion("a", "v1.0.0", "aa"))
+	require.NoError(t, manager.Add(p))
+	profileDir, err := manager.ProfileDir(p.ID)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(profileDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(profileDir, "profile_meta.json"), []byte("{"), 0644))
+
+	// The profile is kept as unknown rather than failing the collection
+	refs, err := manager.CacheReferences()
+	require.NoError(t, err)
+	require.Len(t, refs, 2)
+	assert.False(t, refs[0].Unknown)
+	assert.True(t, refs[1].Unknown)
+	assert.Equal(t, "Broken", refs[1].ProfileName)
+}
+
 func TestProfile_VersionTracking(t *testing.T) {
 	p := Profile{
 		ID:   uuid.New(),
@@ -178,4 +200,16 @@ func TestProfile_MatchesShared(t *testing.T) {
 		assert.True(t, emptyP.MatchesShared(shared))
 		assert.True(t, emptyP.MatchesSharedModVersions(shared))
 	})
+	t.Run("local mods are not shared", func(t *testing.T) {
+		withLocal := p.Clone()
+		withLocal.AddModVersion(modmgr.ModVersion{ModVersionDetails: model.ModVersionDetails{
+			ModID:     "local-plugin",
+			VersionID: "local-0123456789ab",
+			Features:  map[string]any{modmgr.FeatureLocalSource: "/mods/plugin.dll"},
+		}})
+		shared := withLocal.MakeShared()
+		assert.NotContains(t, shared.ModVersions, "local-plugin")
+		assert.Len(t, withLocal.LocalMods(), 1)
+		assert.True(t, withLocal.MatchesShared(shared))
+	})
 }
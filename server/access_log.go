@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ikafly144/au_mod_installer/server/metrics"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+var (
+	httpRequests = metrics.NewCounter("mus_http_requests_total",
+		"HTTP requests handled, by endpoint and status.", "method", "route", "status")
+	httpRequestDuration = metrics.NewHistogram("mus_http_request_duration_seconds",
+		"Time to handle HTTP requests, by endpoint.", metrics.DefBuckets, "method", "route")
+)
+
+// requestIDKey stores the request ID in the request context, for the logs of the handlers.
+type requestIDKey struct{}
+
+// observeRequests assigns every request an ID, logs it once handled and records it in the request metrics. The
+// route is the rest.Endpoint route that matched, so metrics do not grow with mod IDs.
+func observeRequests(basePath string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		requestID := ctx.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		ctx.Header(requestIDHeader, requestID)
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), requestIDKey{}, requestID))
+
+		ctx.Next()
+
+		route := "unmatched"
+		if fullPath := ctx.FullPath(); fullPath != "" {
+			route = strings.TrimPrefix(fullPath, strings.TrimSuffix(basePath, "/"))
+		}
+		status := ctx.Writer.Status()
+		duration := time.Since(start)
+		httpRequests.Inc(ctx.Request.Method, route, strconv.Itoa(status))
+		httpRequestDuration.Observe(duration.Seconds(), ctx.Request.Method, route)
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		slog.Log(ctx.Request.Context(), level, "Request handled",
+			"method", ctx.Request.Method,
+			"route", route,
+			"path", ctx.Request.URL.Path,
+			"status", status,
+			"duration_ms", float64(duration.Microseconds())/1000,
+			"bytes", ctx.Writer.Size(),
+			"client_ip", clientIP(ctx),
+			"user_agent", ctx.Request.UserAgent(),
+		)
+	}
+}
+
+// requestIDHandler adds the request ID of the context to every record logged with one.
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := requestIDFrom(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
+
+func requestIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	// Handlers log with the gin context, which only finds the request context values through the request
+	if ginCtx, ok := ctx.(*gin.Context); ok && ginCtx.Request != nil {
+		ctx = ginCtx.Request.Context()
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// validRequestID accepts the IDs of proxies in front of the server, as long as they are short and printable.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
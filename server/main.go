@@ -12,9 +12,11 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 
+	"github.com/ikafly144/au_mod_installer/server/metrics"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+	"github.com/ikafly144/au_mod_installer/server/repository/cache"
+	fsrepo "github.com/ikafly144/au_mod_installer/server/repository/filesystem"
 	gormrepo "github.com/ikafly144/au_mod_installer/server/repository/gorm"
 	"github.com/ikafly144/au_mod_installer/server/service"
 )
@@ -22,7 +24,7 @@ import (
 func main() {
 	zl := log.Logger
 	handler := zerolog.NewSlogHandler(zl)
-	slog.SetDefault(slog.New(handler))
+	slog.SetDefault(slog.New(requestIDHandler{handler}))
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
@@ -36,6 +38,8 @@ func realMain(ctx context.Context) error {
 	var addr = flag.String("addr", ":8080", "Address to listen on")
 	var pathPrefix = flag.String("path-prefix", "/api", "Path prefix for API endpoints")
 	var basePath = flag.String("base-path", "/v1", "Base path for API endpoints")
+	var migrate = flag.Bool("migrate", false, "Migrate the database schema before serving")
+	var metricsAddr = flag.String("metrics-addr", "", "Address to serve metrics on, such as 127.0.0.1:9090; empty disables metrics")
 	flag.Parse()
 
 	// read from environment variables
@@ -48,12 +52,75 @@ func realMain(ctx context.Context) error {
 	if envBasePath := os.Getenv("BASE_PATH"); envBasePath != "" {
 		*basePath = envBasePath
 	}
+	if envMigrate := os.Getenv("AUTO_MIGRATE"); envMigrate != "" {
+		*migrate = envMigrate == "true" || envMigrate == "1"
+	}
+	if envMetricsAddr, ok := os.LookupEnv("METRICS_ADDR"); ok {
+		*metricsAddr = envMetricsAddr
+	}
 
-	db, err := gorm.Open(postgres.Open(os.Getenv("DATABASE_URL")))
+	db, err := gormrepo.Open(os.Getenv("DATABASE_URL"))
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	modSrv := service.NewModService(gormrepo.NewGormRepository(db))
+	repo := gormrepo.NewGormRepository(db)
+	// Lets a development server start from an empty SQLite file without running mus-mgr migrate first
+	if *migrate {
+		if err := repo.Migrate(); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+	// The models need every migration of this release, and a newer release may have changed the schema in ways this
+	// one would corrupt
+	if err := repo.CheckSchema(); err != nil {
+		return err
+	}
+	if err := repo.ObserveQueries(); err != nil {
+		return fmt.Errorf("failed to observe queries: %w", err)
+	}
+	// Image and mirror fetches follow URLs set by publishers, so they share a client that only reaches public hosts
+	outbound := service.NewPublicHTTPClient()
+	// Thumbnails and galleries are resized once and served from the database, instead of sending clients to the
+	// original images
+	modSrvOpts := []service.ModServiceOption{
+		service.WithTokenRepository(repo),
+		service.WithImageRepository(repo, outbound),
+	}
+	// Shared games can live in the database so every replica sees the same sessions and rate limits
+	switch store := os.Getenv("SHARE_GAME_STORE"); store {
+	case "", "memory":
+	case "database":
+		modSrvOpts = append(modSrvOpts, service.WithSharedGameRepository(repo), service.WithAupackRepository(repo))
+	default:
+		return fmt.Errorf("unknown SHARE_GAME_STORE %q", store)
+	}
+	// Shared aupacks can be kept on disk instead, out of the database
+	if aupackDir := os.Getenv("AUPACK_DIR"); aupackDir != "" {
+		packs, err := fsrepo.NewAupackRepository(aupackDir)
+		if err != nil {
+			return err
+		}
+		modSrvOpts = append(modSrvOpts, service.WithAupackRepository(packs))
+	}
+	// Files of published versions can be mirrored for clients that cannot reach their upstream URLs
+	if mirrorDir := os.Getenv("MIRROR_DIR"); mirrorDir != "" {
+		modSrvOpts = append(modSrvOpts, service.WithFileMirror(mirrorDir, outbound))
+	}
+	// Registry reads are cached in process; writes through this server drop the cache, others are seen after the TTL
+	modCacheTTL := 30 * time.Second
+	if rawTTL := os.Getenv("MOD_CACHE_TTL"); rawTTL != "" {
+		parsedTTL, err := time.ParseDuration(rawTTL)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid MOD_CACHE_TTL; using default", "value", rawTTL, "error", err)
+		} else {
+			modCacheTTL = parsedTTL
+		}
+	}
+	var modRepo repository.ModRepository = repo
+	if modCacheTTL > 0 {
+		modRepo = cache.NewModRepository(repo, modCacheTTL)
+	}
+	modSrv := service.NewModService(modRepo, modSrvOpts...)
 	versionInfoTTL := time.Duration(0)
 	if rawTTL := os.Getenv("VERSION_INFO_TTL"); rawTTL != "" {
 		parsedTTL, err := time.ParseDuration(rawTTL)
@@ -80,6 +147,22 @@ func realMain(ctx context.Context) error {
 		}
 	}()
 
+	var metricsSrv *http.Server
+	if *metricsAddr != "" {
+		metrics.NewGaugeFunc("mus_share_game_sessions", "Shared games that have not expired.", func() (float64, error) {
+			count, err := modSrv.CountSharedGames()
+			return float64(count), err
+		})
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsSrv = &http.Server{Addr: *metricsAddr, Handler: mux}
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.ErrorContext(ctx, "metrics listen", "err", err)
+			}
+		}()
+	}
+
 	slog.InfoContext(ctx, "Server started")
 
 	<-ctx.Done()
@@ -88,6 +171,11 @@ func realMain(ctx context.Context) error {
 	if err := srv.Shutdown(context.Background()); err != nil {
 		slog.ErrorContext(ctx, "Server forced to shutdown: %s\n", "err", err)
 	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(context.Background()); err != nil {
+			slog.ErrorContext(ctx, "Metrics server forced to shutdown", "err", err)
+		}
+	}
 
 	slog.InfoContext(ctx, "Server exiting")
 
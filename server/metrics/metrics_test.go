@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Requests served.", "route", "status")
+	latency := r.NewHistogram("latency_seconds", "Request latency.", []float64{0.1, 1}, "route")
+	r.NewGaugeFunc("sessions", "Live sessions.", func() (float64, error) { return 3, nil })
+	r.NewGaugeFunc("broken", "Fails to collect.", func() (float64, error) { return 0, errors.New("down") })
+
+	requests.Inc("/mod/:mod_id", "200")
+	requests.Inc("/mod/:mod_id", "200")
+	requests.Inc(`/say "hi"`, "404")
+	latency.Observe(0.05, "/mods")
+	latency.Observe(0.1, "/mods")
+	latency.Observe(3, "/mods")
+
+	var out strings.Builder
+	_, err := r.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="/mod/:mod_id",status="200"} 2
+requests_total{route="/say \"hi\"",status="404"} 1
+# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/mods",le="0.1"} 2
+latency_seconds_bucket{route="/mods",le="1"} 2
+latency_seconds_bucket{route="/mods",le="+Inf"} 3
+latency_seconds_sum{route="/mods"} 3.15
+latency_seconds_count{route="/mods"} 3
+# HELP sessions Live sessions.
+# TYPE sessions gauge
+sessions 3
+`, out.String())
+
+	assert.Panics(t, func() { requests.Inc("/mods") })
+	assert.Panics(t, func() { r.NewCounter("sessions", "Duplicate.") })
+}
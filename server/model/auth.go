@@ -0,0 +1,47 @@
+package model
+
+import (
+	"path"
+	"time"
+)
+
+// APIToken authorizes the write API for the mods whose IDs match one of its ModIDs patterns, e.g. "my-mod" or "author-*".
+// Only the sha256 of the token is stored.
+type APIToken struct {
+	ID        string      `gorm:"primaryKey" json:"id"`
+	Name      string      `gorm:"not null" json:"name"`
+	TokenHash string      `gorm:"not null;uniqueIndex" json:"-"`
+	ModIDs    StringArray `gorm:"type:json" json:"mod_ids"`
+
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastUsedAt *time.Time `gorm:"default:null" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"default:null" json:"revoked_at,omitempty"`
+}
+
+// Allows reports whether the token may create or change the mod.
+func (t APIToken) Allows(modID string) bool {
+	for _, pattern := range t.ModIDs {
+		if ok, _ := path.Match(pattern, modID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditLogEntry records a change made through the write API.
+type AuditLogEntry struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	TokenID   string `gorm:"index" json:"token_id"`
+	TokenName string `json:"token_name"`
+	RemoteIP  string `json:"remote_ip,omitempty"`
+
+	// Action is what was done, e.g. "mod.create" or "version.delete".
+	Action    string `gorm:"not null" json:"action"`
+	ModID     string `gorm:"index" json:"mod_id"`
+	VersionID string `json:"version_id,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+	// Detail is the JSON encoded request body, if any.
+	Detail string `json:"detail,omitempty"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+type ImageKind string
+
+const (
+	ImageKindThumbnail ImageKind = "thumbnail"
+	ImageKindGallery   ImageKind = "gallery"
+)
+
+// ModImage is an image hosted for a mod. Each mod has at most one thumbnail and a gallery ordered by Position.
+type ModImage struct {
+	ID    string     `gorm:"primaryKey"`
+	ModID string     `gorm:"not null;index"`
+	Mod   ModDetails `gorm:"foreignKey:ModID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Kind  ImageKind  `gorm:"not null"`
+
+	Position int    `gorm:"not null;default:0"`
+	Caption  string `gorm:"not null;default:''"`
+	// SourceURL is the URL the image was fetched from, empty for uploads. A thumbnail fetched from the ThumbnailURI of
+	// its mod is fetched again once the URI changes.
+	SourceURL string `gorm:"not null;default:''"`
+	// SHA256 is the hex sha256 of the original image, which is not kept.
+	SHA256 string `gorm:"not null"`
+	// Width and Height are of the detail variant.
+	Width  int `gorm:"not null;default:0"`
+	Height int `gorm:"not null;default:0"`
+
+	Variants []ModImageVariant `gorm:"foreignKey:ImageID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// ModImageVariant is a resized copy of an image, encoded for serving.
+type ModImageVariant struct {
+	ImageID     string `gorm:"primaryKey"`
+	Size        string `gorm:"primaryKey"`
+	ContentType string `gorm:"not null"`
+	Width       int    `gorm:"not null"`
+	Height      int    `gorm:"not null"`
+	Data        []byte `gorm:"not null"`
+}
@@ -56,7 +56,7 @@ func (a *StringArray) Scan(value any) error {
 		b = []byte(s)
 	}
 
-	// Postgres native array or invalid old JSON might appear as {} instead of []
+	// Databases not yet migrated past schema version 2 may still hold {} instead of []
 	if len(b) > 0 && b[0] == '{' {
 		*a = StringArray{}
 		return nil
@@ -70,8 +70,14 @@ type ModDetails struct {
 	Name         string  `gorm:"not null" json:"name"`
 	Description  string  `gorm:"not null" json:"description"`
 	Author       string  `gorm:"not null" json:"author"`
+	Type         ModType `gorm:"not null;default:'mod';index" json:"type"`
 	ThumbnailURI *string `gorm:"default:null" json:"-"`
 
+	Tags      StringArray `gorm:"type:json" json:"tags,omitempty"`
+	Links     ModLinks    `gorm:"embedded;embeddedPrefix:link_" json:"links,omitzero"`
+	License   string      `gorm:"not null;default:''" json:"license,omitempty"`
+	Languages StringArray `gorm:"type:json" json:"languages,omitempty"`
+
 	LatestVersionID       *string `gorm:"index;default:null;" json:"-"`
 	LatestVersionExternal string  `gorm:"-" json:"latest_version"`
 
@@ -87,14 +93,45 @@ type ModVersionDetails struct {
 	VersionID string `gorm:"index:idx_mod_version_details" json:"version_id"`
 	ModID     string `gorm:"index:idx_mod_version_details" json:"mod_id"`
 
+	GameVersions StringArray `gorm:"type:json" json:"game_versions,omitempty"`
+
 	Files        []ModVersionFile `gorm:"foreignKey:VersionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"files,omitempty"`
 	Dependencies DependencyArray  `gorm:"type:json" json:"dependencies,omitempty"`
 	Features     Features         `gorm:"type:json" json:"features,omitempty"`
 
+	Status       VersionStatus `gorm:"not null;default:''" json:"status,omitempty"`
+	StatusReason string        `gorm:"not null;default:''" json:"status_reason,omitempty"`
+
+	Changelog  string     `gorm:"not null;default:''" json:"changelog,omitempty"`
+	ReleasedAt *time.Time `gorm:"default:null" json:"released_at,omitempty"`
+
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
+// ModLinks are stored as the link_* columns of a mod.
+type ModLinks struct {
+	Homepage string `gorm:"not null;default:''" json:"homepage,omitempty"`
+	Source   string `gorm:"not null;default:''" json:"source,omitempty"`
+	Issues   string `gorm:"not null;default:''" json:"issues,omitempty"`
+	Discord  string `gorm:"not null;default:''" json:"discord,omitempty"`
+}
+
+type VersionStatus string
+
+const (
+	VersionStatusActive     VersionStatus = ""
+	VersionStatusDeprecated VersionStatus = "deprecated"
+	VersionStatusYanked     VersionStatus = "yanked"
+)
+
+type ModType string
+
+const (
+	ModTypeMod     ModType = "mod"
+	ModTypeLibrary ModType = "library"
+)
+
 type ModVersionFile struct {
 	ID        string  `gorm:"primaryKey" json:"id"`
 	ModID     *string `gorm:"index:idx_mod_version_file;not null" json:"-"`
@@ -153,7 +190,7 @@ func (a *DependencyArray) Scan(value any) error {
 		b = []byte(s)
 	}
 
-	// Postgres or old data might have stored an empty object "{}" instead of "[]"
+	// Databases not yet migrated past schema version 2 may still hold "{}" instead of "[]"
 	if len(b) > 0 && b[0] == '{' {
 		*a = DependencyArray{}
 		return nil
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// SharedGame is a room shared through /share_game. Each IP shares at most one game at a time.
+type SharedGame struct {
+	SessionID string `gorm:"primaryKey"`
+	HostKey   string `gorm:"not null"`
+	IP        string `gorm:"not null;uniqueIndex"`
+	// RoomKey identifies the shared room and aupack, so sharing the same room again returns the existing session.
+	RoomKey string `gorm:"not null"`
+	// AupackSHA256 is the hex sha256 of the shared aupack, which is stored once as a SharedAupack.
+	AupackSHA256 string `gorm:"not null;index"`
+	AupackSize   int64  `gorm:"not null"`
+
+	LobbyCode      string `gorm:"not null;default:''"`
+	ServerIP       string `gorm:"not null;default:''"`
+	ServerPort     uint16 `gorm:"not null;default:0"`
+	MatchMakerIP   string `gorm:"not null;default:''"`
+	MatchMakerPort uint16 `gorm:"not null;default:0"`
+	GameVersion    string `gorm:"not null;default:''"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// ShareGameRate counts the games shared from an IP in the rate window starting at WindowStart.
+type ShareGameRate struct {
+	IP          string    `gorm:"primaryKey"`
+	WindowStart time.Time `gorm:"not null;index"`
+	Count       int       `gorm:"not null"`
+}
+
+// SharedAupack is an aupack shared by one or more games, stored by the hex sha256 of its content.
+type SharedAupack struct {
+	SHA256 string `gorm:"primaryKey"`
+	Data   []byte `gorm:"not null"`
+	// StoredAt is refreshed whenever the pack is shared again, so packs in use are not collected.
+	StoredAt time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ikafly144/au_mod_installer/common/rest"
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/server/service"
+)
+
+const publisherContextKey = "publisher"
+
+// registerPublishRoutes adds the write API. Every route requires an API token covering the mod it changes.
+func registerPublishRoutes(api *gin.RouterGroup, srv *service.ModService) {
+	write := api.Group("", requireAPIToken(srv))
+
+	write.POST(rest.EndpointCreateMod.Route, func(ctx *gin.Context) {
+		var req restmodel.ModCreateRequest
+		if !bindJSONRequest(ctx, &req) {
+			return
+		}
+		mod, err := srv.CreateMod(publisher(ctx), req)
+		if err != nil {
+			respondPublishError(ctx, "Failed to create mod", err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, mod)
+	})
+	write.PATCH(rest.EndpointUpdateMod.Route, func(ctx *gin.Context) {
+		var req restmodel.ModUpdateRequest
+		if !bindJSONRequest(ctx, &req) {
+			return
+		}
+		mod, err := srv.UpdateMod(publisher(ctx), ctx.Param("mod_id"), req)
+		if err != nil {
+			respondPublishError(ctx, "Failed to update mod", err)
+			return
+		}
+		ctx.JSON(http.StatusOK, mod)
+	})
+	write.DELETE(rest.EndpointDeleteMod.Route, func(ctx *gin.Context) {
+		if err := srv.DeleteMod(publisher(ctx), ctx.Param("mod_id")); err != nil {
+			respondPublishError(ctx, "Failed to delete mod", err)
+			return
+		}
+		ctx.Status(http.StatusNoContent)
+	})
+	write.POST(rest.EndpointCreateModVersion.Route, func(ctx *gin.Context) {
+		var req restmodel.ModVersionCreateRequest
+		if !bindJSONRequest(ctx, &req) {
+			return
+		}
+		version, err := srv.CreateModVersion(publisher(ctx), ctx.Param("mod_id"), req)
+		if err != nil {
+			respondPublishError(ctx, "Failed to create mod version", err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, version)
+	})
+	write.PATCH(rest.EndpointUpdateModVersion.Route, func(ctx *gin.Context) {
+		var req restmodel.ModVersionUpdateRequest
+		if !bindJSONRequest(ctx, &req) {
+			return
+		}
+		version, err := srv.UpdateModVersion(publisher(ctx), ctx.Param("mod_id"), ctx.Param("version_id"), req)
+		if err != nil {
+			respondPublishError(ctx, "Failed to update mod version", err)
+			return
+		}
+		ctx.JSON(http.StatusOK, version)
+	})
+	write.DELETE(rest.EndpointDeleteModVersion.Route, func(ctx *gin.Context) {
+		if err := srv.DeleteModVersion(publisher(ctx), ctx.Param("mod_id"), ctx.Param("version_id")); err != nil {
+			respondPublishError(ctx, "Failed to delete mod version", err)
+			return
+		}
+		ctx.Status(http.StatusNoContent)
+	})
+	write.PUT(rest.EndpointSetModVersionStatus.Route, func(ctx *gin.Context) {
+		var req restmodel.ModVersionStatusRequest
+		if !bindJSONRequest(ctx, &req) {
+			return
+		}
+		version, err := srv.SetModVersionStatus(publisher(ctx), ctx.Param("mod_id"), ctx.Param("version_id"), req)
+		if err != nil {
+			respondPublishError(ctx, "Failed to set mod version status", err)
+			return
+		}
+		ctx.JSON(http.StatusOK, version)
+	})
+	write.POST(rest.EndpointCreateModFile.Route, func(ctx *gin.Context) {
+		var req publishedFile
+		if !bindJSONRequest(ctx, &req) {
+			return
+		}
+		file, err := srv.CreateModVersionFile(publisher(ctx), ctx.Param("mod_id"), ctx.Param("version_id"), req.ModVersionFile)
+		if err != nil {
+			respondPublishError(ctx, "Failed to create mod file", err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, file)
+	})
+	write.DELETE(rest.EndpointDeleteModFile.Route, func(ctx *gin.Context) {
+		if err := srv.DeleteModVersionFile(publisher(ctx), ctx.Param("mod_id"), ctx.Param("version_id"), ctx.Param("file_id")); err != nil {
+			respondPublishError(ctx, "Failed to delete mod file", err)
+			return
+		}
+		ctx.Status(http.StatusNoContent)
+	})
+	write.PUT(rest.EndpointSetModThumbnail.Route, func(ctx *gin.Context) {
+		src, _, ok := bindImageSource(ctx)
+		if !ok {
+			return
+		}
+		image, err := srv.SetModThumbnail(ctx.Request.Context(), publisher(ctx), ctx.Param("mod_id"), src)
+		if err != nil {
+			respondPublishError(ctx, "Failed to set mod thumbnail", err)
+			return
+		}
+		ctx.JSON(http.StatusOK, image)
+	})
+	write.POST(rest.EndpointAddModImage.Route, func(ctx *gin.Context) {
+		src, caption, ok := bindImageSource(ctx)
+		if !ok {
+			return
+		}
+		image, err := srv.AddModImage(ctx.Request.Context(), publisher(ctx), ctx.Param("mod_id"), src, caption)
+		if err != nil {
+			respondPublishError(ctx, "Failed to add mod image", err)
+			return
+		}
+		ctx.JSON(http.StatusCreated, image)
+	})
+	write.DELETE(rest.EndpointDeleteModImage.Route, func(ctx *gin.Context) {
+		if err := srv.DeleteModImage(publisher(ctx), ctx.Param("mod_id"), ctx.Param("image_id")); err != nil {
+			respondPublishError(ctx, "Failed to delete mod image", err)
+			return
+		}
+		ctx.Status(http.StatusNoContent)
+	})
+}
+
+// bindImageSource reads an image sent to the write API: a JSON ModImageRequest naming the URL to fetch it from, or
+// the image itself as the body with its caption in the caption query.
+func bindImageSource(ctx *gin.Context) (service.ImageSource, string, bool) {
+	if ctx.ContentType() == "application/json" {
+		var req restmodel.ModImageRequest
+		if !bindJSONRequest(ctx, &req) {
+			return service.ImageSource{}, "", false
+		}
+		return service.ImageSource{URL: req.URL}, req.Caption, true
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, service.MaxImageSize))
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrImageTooLarge.Error()})
+		return service.ImageSource{}, "", false
+	case err != nil:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read image"})
+		return service.ImageSource{}, "", false
+	case len(data) == 0:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "image is required"})
+		return service.ImageSource{}, "", false
+	}
+	caption := ctx.Query("caption")
+	if err := restmodel.ValidateImageCaption(caption); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return service.ImageSource{}, "", false
+	}
+	return service.ImageSource{Data: data}, caption, true
+}
+
+// publishedFile validates a file sent on its own to the write API.
+type publishedFile struct {
+	restmodel.ModVersionFile
+}
+
+func (f *publishedFile) Validate() error {
+	return restmodel.ValidatePublishedFile(f.ModVersionFile)
+}
+
+// requireAPIToken authenticates the bearer token of the request and stores the caller for the handlers.
+func requireAPIToken(srv *service.ModService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		secret, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(secret) == "" {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API token required"})
+			return
+		}
+		token, err := srv.Authenticate(strings.TrimSpace(secret))
+		if err != nil {
+			respondPublishError(ctx, "Failed to authenticate API token", err)
+			ctx.Abort()
+			return
+		}
+		ctx.Set(publisherContextKey, service.Publisher{Token: token, RemoteIP: clientIP(ctx)})
+		ctx.Next()
+	}
+}
+
+func publisher(ctx *gin.Context) service.Publisher {
+	return ctx.MustGet(publisherContextKey).(service.Publisher)
+}
+
+func respondPublishError(ctx *gin.Context, msg string, err error) {
+	var depErr *service.DependencyError
+	switch {
+	case errors.As(err, &depErr):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "problems": depErr.Problems})
+	case errors.Is(err, service.ErrPublishDisabled), errors.Is(err, service.ErrImagesDisabled):
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidAPIToken):
+		ctx.Header("WWW-Authenticate", "Bearer")
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTokenScope):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrModNotFound), errors.Is(err, service.ErrVersionNotFound), errors.Is(err, service.ErrFileNotFound), errors.Is(err, service.ErrImageNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAlreadyExists), errors.Is(err, service.ErrVersionYanked), errors.Is(err, service.ErrGalleryFull):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidImage):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrImageTooLarge):
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrImageFetchFailed):
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		slog.ErrorContext(ctx, msg, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
@@ -0,0 +1,248 @@
+// Package cache wraps repositories with in-process read-through caches.
+package cache
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+// ModRepository caches the mods and versions read from a repository.ModRepository for ttl. Writes are rare, so
+// every write through the cache drops all of it. Writes made elsewhere, e.g. by another replica or mus-mgr, are seen
+// once the entries expire. Listing and searching mods are passed through, as their queries rarely repeat.
+type ModRepository struct {
+	repository.ModRepository
+	ttl time.Duration
+
+	mu sync.Mutex
+	// generation counts the writes, so a read that raced a write does not store what it read
+	generation uint64
+	mods       map[string]entry[model.ModDetails]
+	versionIDs map[string]entry[[]string]
+	versions   map[repository.ModVersionKey]entry[model.ModVersionDetails]
+}
+
+type entry[T any] struct {
+	value   T
+	expires time.Time
+}
+
+var _ repository.ModRepository = (*ModRepository)(nil)
+
+func NewModRepository(repo repository.ModRepository, ttl time.Duration) *ModRepository {
+	return &ModRepository{
+		ModRepository: repo,
+		ttl:           ttl,
+		mods:          make(map[string]entry[model.ModDetails]),
+		versionIDs:    make(map[string]entry[[]string]),
+		versions:      make(map[repository.ModVersionKey]entry[model.ModVersionDetails]),
+	}
+}
+
+func (r *ModRepository) GetModDetails(modID string) (*model.ModDetails, error) {
+	gen, cached, ok := lookup(r, r.mods, modID)
+	if ok {
+		return copyMod(cached), nil
+	}
+	mod, err := r.ModRepository.GetModDetails(modID)
+	if err != nil {
+		return nil, err
+	}
+	store(r, gen, r.mods, modID, *mod)
+	return copyMod(*mod), nil
+}
+
+func (r *ModRepository) GetModVersionIds(modID string) ([]string, error) {
+	gen, cached, ok := lookup(r, r.versionIDs, modID)
+	if ok {
+		return slices.Clone(cached), nil
+	}
+	ids, err := r.ModRepository.GetModVersionIds(modID)
+	if err != nil {
+		return nil, err
+	}
+	store(r, gen, r.versionIDs, modID, slices.Clone(ids))
+	return ids, nil
+}
+
+func (r *ModRepository) GetModVersionDetails(modID, versionID string) (*model.ModVersionDetails, error) {
+	key := repository.ModVersionKey{ModID: modID, VersionID: versionID}
+	gen, cached, ok := lookup(r, r.versions, key)
+	if ok {
+		return copyVersion(cached), nil
+	}
+	version, err := r.ModRepository.GetModVersionDetails(modID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	store(r, gen, r.versions, key, *copyVersion(*version))
+	return version, nil
+}
+
+func (r *ModRepository) GetModDetailsBatch(modIDs []string) ([]model.ModDetails, error) {
+	var mods []model.ModDetails
+	var missing []string
+	r.mu.Lock()
+	gen, now := r.generation, time.Now()
+	for _, modID := range modIDs {
+		if e, ok := r.mods[modID]; ok && now.Before(e.expires) {
+			mods = append(mods, *copyMod(e.value))
+		} else {
+			missing = append(missing, modID)
+		}
+	}
+	r.mu.Unlock()
+	if len(missing) == 0 {
+		return mods, nil
+	}
+
+	fetched, err := r.ModRepository.GetModDetailsBatch(missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, mod := range fetched {
+		store(r, gen, r.mods, mod.ID, *copyMod(mod))
+	}
+	return append(mods, fetched...), nil
+}
+
+func (r *ModRepository) GetModVersionDetailsBatch(keys []repository.ModVersionKey) ([]model.ModVersionDetails, error) {
+	var versions []model.ModVersionDetails
+	var missing []repository.ModVersionKey
+	r.mu.Lock()
+	gen, now := r.generation, time.Now()
+	for _, key := range keys {
+		if e, ok := r.versions[key]; ok && now.Before(e.expires) {
+			versions = append(versions, *copyVersion(e.value))
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	r.mu.Unlock()
+	if len(missing) == 0 {
+		return versions, nil
+	}
+
+	fetched, err := r.ModRepository.GetModVersionDetailsBatch(missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, version := range fetched {
+		for _, key := range missing {
+			if key.Matches(version) {
+				store(r, gen, r.versions, key, *copyVersion(version))
+			}
+		}
+	}
+	return append(versions, fetched...), nil
+}
+
+func (r *ModRepository) CreateMod(details *model.ModDetails) (string, error) {
+	defer r.invalidate()
+	return r.ModRepository.CreateMod(details)
+}
+
+func (r *ModRepository) CreateModVersion(modID string, details *model.ModVersionDetails) (string, error) {
+	defer r.invalidate()
+	return r.ModRepository.CreateModVersion(modID, details)
+}
+
+func (r *ModRepository) CreateModVersionFile(file *model.ModVersionFile) error {
+	defer r.invalidate()
+	return r.ModRepository.CreateModVersionFile(file)
+}
+
+func (r *ModRepository) ReplaceModVersion(modID string, details *model.ModVersionDetails, files []model.ModVersionFile) error {
+	defer r.invalidate()
+	return r.ModRepository.ReplaceModVersion(modID, details, files)
+}
+
+func (r *ModRepository) UpdateMod(modID string, details *model.ModDetails) error {
+	defer r.invalidate()
+	return r.ModRepository.UpdateMod(modID, details)
+}
+
+func (r *ModRepository) UpdateModVersion(modID, versionID string, details *model.ModVersionDetails) error {
+	defer r.invalidate()
+	return r.ModRepository.UpdateModVersion(modID, versionID, details)
+}
+
+func (r *ModRepository) UpdateModFields(modID string, updates map[string]any) error {
+	defer r.invalidate()
+	return r.ModRepository.UpdateModFields(modID, updates)
+}
+
+func (r *ModRepository) UpdateModVersionFields(modID, versionID string, updates map[string]any) error {
+	defer r.invalidate()
+	return r.ModRepository.UpdateModVersionFields(modID, versionID, updates)
+}
+
+func (r *ModRepository) SetModVersionStatus(modID, versionID string, status model.VersionStatus, reason string) error {
+	defer r.invalidate()
+	return r.ModRepository.SetModVersionStatus(modID, versionID, status, reason)
+}
+
+func (r *ModRepository) DeleteMod(modID string) error {
+	defer r.invalidate()
+	return r.ModRepository.DeleteMod(modID)
+}
+
+func (r *ModRepository) DeleteModVersion(modID, versionID string) error {
+	defer r.invalidate()
+	return r.ModRepository.DeleteModVersion(modID, versionID)
+}
+
+func (r *ModRepository) DeleteModVersionFile(fileID string) error {
+	defer r.invalidate()
+	return r.ModRepository.DeleteModVersionFile(fileID)
+}
+
+// invalidate drops the whole cache. It runs after the write, so a read between the write and invalidate is the only
+// one that may see the old value.
+func (r *ModRepository) invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	clear(r.mods)
+	clear(r.versionIDs)
+	clear(r.versions)
+}
+
+func lookup[K comparable, T any](r *ModRepository, entries map[K]entry[T], key K) (uint64, T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := entries[key]
+	if !ok || !time.Now().Before(e.expires) {
+		var zero T
+		return r.generation, zero, false
+	}
+	return r.generation, e.value, true
+}
+
+func store[K comparable, T any](r *ModRepository, gen uint64, entries map[K]entry[T], key K, value T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if gen != r.generation {
+		return
+	}
+	entries[key] = entry[T]{value: value, expires: time.Now().Add(r.ttl)}
+}
+
+// copyMod and copyVersion keep callers from changing the cached values through the slices they share.
+func copyMod(mod model.ModDetails) *model.ModDetails {
+	mod.Versions = slices.Clone(mod.Versions)
+	mod.Files = slices.Clone(mod.Files)
+	return &mod
+}
+
+func copyVersion(version model.ModVersionDetails) *model.ModVersionDetails {
+	version.GameVersions = slices.Clone(version.GameVersions)
+	version.Files = slices.Clone(version.Files)
+	version.Dependencies = slices.Clone(version.Dependencies)
+	version.Features = maps.Clone(version.Features)
+	return &version
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+type countingRepository struct {
+	repository.ModRepository
+	names map[string]string
+	reads int
+}
+
+func (r *countingRepository) GetModDetails(modID string) (*model.ModDetails, error) {
+	r.reads++
+	name, ok := r.names[modID]
+	if !ok {
+		return nil, fmt.Errorf("mod %s: %w", modID, repository.ErrNotFound)
+	}
+	return &model.ModDetails{ID: modID, Name: name}, nil
+}
+
+func (r *countingRepository) GetModDetailsBatch(modIDs []string) ([]model.ModDetails, error) {
+	r.reads++
+	var mods []model.ModDetails
+	for _, modID := range modIDs {
+		if name, ok := r.names[modID]; ok {
+			mods = append(mods, model.ModDetails{ID: modID, Name: name})
+		}
+	}
+	return mods, nil
+}
+
+func (r *countingRepository) UpdateMod(modID string, details *model.ModDetails) error {
+	r.names[modID] = details.Name
+	return nil
+}
+
+func TestModRepository(t *testing.T) {
+	backend := &countingRepository{names: map[string]string{"a": "A", "b": "B"}}
+	repo := NewModRepository(backend, time.Minute)
+
+	mod, err := repo.GetModDetails("a")
+	require.NoError(t, err)
+	mod.Name = "changed by caller"
+	mod, err = repo.GetModDetails("a")
+	require.NoError(t, err)
+	assert.Equal(t, "A", mod.Name)
+	assert.Equal(t, 1, backend.reads)
+
+	// Only the mods missing from the cache are fetched
+	mods, err := repo.GetModDetailsBatch([]string{"a", "b", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, mods, 2)
+	assert.Equal(t, 2, backend.reads)
+	_, err = repo.GetModDetails("b")
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.reads)
+
+	// Errors are not cached
+	_, err = repo.GetModDetails("missing")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetModDetails("missing")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.Equal(t, 4, backend.reads)
+
+	require.NoError(t, repo.UpdateMod("a", &model.ModDetails{Name: "A2"}))
+	mod, err = repo.GetModDetails("a")
+	require.NoError(t, err)
+	assert.Equal(t, "A2", mod.Name)
+	assert.Equal(t, 5, backend.reads)
+}
+
+func TestModRepository_Expires(t *testing.T) {
+	backend := &countingRepository{names: map[string]string{"a": "A"}}
+	repo := NewModRepository(backend, time.Millisecond)
+
+	_, err := repo.GetModDetails("a")
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = repo.GetModDetails("a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.reads)
+}
@@ -0,0 +1,117 @@
+// Package filesystem implements repositories on a local directory.
+package filesystem
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+const aupackExt = ".aupack"
+
+// AupackRepository stores each shared aupack as a file named by its hash, under a directory named by the first two
+// characters of the hash. The modification time of the file is its stored time.
+type AupackRepository struct {
+	dir string
+}
+
+var _ repository.AupackRepository = (*AupackRepository)(nil)
+
+func NewAupackRepository(dir string) (*AupackRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create aupack directory: %w", err)
+	}
+	return &AupackRepository{dir: dir}, nil
+}
+
+func (r *AupackRepository) PutAupack(hash string, data []byte, now time.Time) error {
+	path, err := r.path(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Chtimes(path, now, now); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to touch aupack: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create aupack directory: %w", err)
+	}
+	// Write next to the final path and rename, so readers never see a partial pack
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create aupack file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write aupack: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write aupack: %w", err)
+	}
+	if err := os.Chtimes(tmp.Name(), now, now); err != nil {
+		return fmt.Errorf("failed to touch aupack: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store aupack: %w", err)
+	}
+	return nil
+}
+
+func (r *AupackRepository) GetAupack(hash string) ([]byte, error) {
+	path, err := r.path(hash)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("aupack %s: %w", hash, repository.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aupack: %w", err)
+	}
+	return data, nil
+}
+
+func (r *AupackRepository) DeleteUnusedAupacks(keep []string, storedBefore time.Time) error {
+	return filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		hash, ok := strings.CutSuffix(d.Name(), aupackExt)
+		if d.IsDir() || !ok || slices.Contains(keep, hash) {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(storedBefore) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to delete aupack: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *AupackRepository) path(hash string) (string, error) {
+	// The hash names the file, so anything but a sha256 in hex could escape the directory
+	if sum, err := hex.DecodeString(hash); err != nil || len(sum) != 32 || hash != strings.ToLower(hash) {
+		return "", fmt.Errorf("invalid aupack hash %q", hash)
+	}
+	return filepath.Join(r.dir, hash[:2], hash+aupackExt), nil
+}
@@ -0,0 +1,45 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+func TestAupackRepository(t *testing.T) {
+	repo, err := NewAupackRepository(t.TempDir())
+	require.NoError(t, err)
+
+	hashOf := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	old, used, fresh := []byte("old"), []byte("used"), []byte("fresh")
+	start := time.Now().Add(-time.Hour)
+	require.NoError(t, repo.PutAupack(hashOf(old), old, start))
+	require.NoError(t, repo.PutAupack(hashOf(used), used, start))
+	require.NoError(t, repo.PutAupack(hashOf(fresh), fresh, start))
+	// Sharing a pack again refreshes it
+	require.NoError(t, repo.PutAupack(hashOf(fresh), fresh, time.Now()))
+
+	require.NoError(t, repo.DeleteUnusedAupacks([]string{hashOf(used)}, start.Add(time.Minute)))
+
+	_, err = repo.GetAupack(hashOf(old))
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	data, err := repo.GetAupack(hashOf(used))
+	require.NoError(t, err)
+	assert.Equal(t, used, data)
+	data, err = repo.GetAupack(hashOf(fresh))
+	require.NoError(t, err)
+	assert.Equal(t, fresh, data)
+
+	_, err = repo.GetAupack("../../etc/passwd")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, repository.ErrNotFound)
+}
@@ -0,0 +1,59 @@
+package gorm
+
+import (
+	"slices"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+func (r *GormRepository) GetModDetailsBatch(modIDs []string) ([]model.ModDetails, error) {
+	var mods []model.ModDetails
+	if err := r.db.Find(&mods, "id IN ?", modIDs).Error; err != nil {
+		return nil, err
+	}
+
+	var latestIDs []string
+	for _, mod := range mods {
+		if mod.LatestVersionID != nil {
+			latestIDs = append(latestIDs, *mod.LatestVersionID)
+		}
+	}
+	if len(latestIDs) == 0 {
+		return mods, nil
+	}
+	var latest []model.ModVersionDetails
+	if err := r.db.Select("id", "version_id").Find(&latest, "id IN ?", latestIDs).Error; err != nil {
+		return nil, err
+	}
+	versionIDs := make(map[string]string, len(latest))
+	for _, v := range latest {
+		versionIDs[v.ID] = v.VersionID
+	}
+	for i, mod := range mods {
+		if mod.LatestVersionID != nil {
+			mods[i].LatestVersionExternal = versionIDs[*mod.LatestVersionID]
+		}
+	}
+	return mods, nil
+}
+
+func (r *GormRepository) GetModVersionDetailsBatch(keys []repository.ModVersionKey) ([]model.ModVersionDetails, error) {
+	var modIDs, versionIDs []string
+	for _, key := range keys {
+		modIDs = append(modIDs, key.ModID)
+		versionIDs = append(versionIDs, key.VersionID)
+	}
+
+	// The query matches every combination of the mod and version IDs, so rows that were not asked for are dropped below.
+	var versions []model.ModVersionDetails
+	err := r.db.Preload("Files").
+		Where("mod_id IN ? AND (version_id IN ? OR id IN ?)", modIDs, versionIDs, versionIDs).
+		Find(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(versions, func(v model.ModVersionDetails) bool {
+		return !slices.ContainsFunc(keys, func(key repository.ModVersionKey) bool { return key.Matches(v) })
+	}), nil
+}
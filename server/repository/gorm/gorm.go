@@ -2,10 +2,12 @@ package gorm
 
 import (
 	"errors"
+	"fmt"
 
 	"gorm.io/gorm"
 
 	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
 )
 
 type GormRepository struct {
@@ -16,13 +18,6 @@ func NewGormRepository(db *gorm.DB) *GormRepository {
 	return &GormRepository{db: db}
 }
 
-func (r *GormRepository) Migrate() error {
-	if err := r.db.AutoMigrate(&model.ModDetails{}, &model.ModVersionFile{}, &model.ModVersionDetails{}); err != nil {
-		return err
-	}
-	return nil
-}
-
 func (r *GormRepository) CreateMod(details *model.ModDetails) (string, error) {
 	result := r.db.Create(details)
 	if result.Error != nil {
@@ -71,7 +66,7 @@ func (r *GormRepository) GetModDetails(modID string) (*model.ModDetails, error)
 	var mod model.ModDetails
 	result := r.db.First(&mod, "id = ?", modID)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, notFound(result.Error)
 	}
 	// Load LatestVersionExternal from LatestVersionID
 	if mod.LatestVersionID != nil {
@@ -92,6 +87,31 @@ func (r *GormRepository) CreateModVersion(modID string, details *model.ModVersio
 	return details.VersionID, nil
 }
 
+func (r *GormRepository) CreateModVersionFile(file *model.ModVersionFile) error {
+	return r.db.Create(file).Error
+}
+
+func (r *GormRepository) ReplaceModVersion(modID string, details *model.ModVersionDetails, files []model.ModVersionFile) error {
+	details.ModID = modID
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.ModVersionFile{}, "version_id = ?", details.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.ModVersionDetails{}, "id = ?", details.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Files").Create(details).Error; err != nil {
+			return err
+		}
+		for i := range files {
+			if err := tx.Create(&files[i]).Error; err != nil {
+				return fmt.Errorf("failed to create file %s: %w", files[i].Filename, err)
+			}
+		}
+		return nil
+	})
+}
+
 func (r *GormRepository) GetModVersionIds(modID string) ([]string, error) {
 	var ids []string
 	result := r.db.Model(&model.ModVersionDetails{}).Where("mod_id = ?", modID).Order("created_at ASC").Pluck("version_id", &ids)
@@ -105,11 +125,19 @@ func (r *GormRepository) GetModVersionDetails(modID, versionID string) (*model.M
 	var version model.ModVersionDetails
 	result := r.db.Preload("Files").First(&version, "mod_id = ? AND (version_id = ? OR id = ?)", modID, versionID, versionID)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, notFound(result.Error)
 	}
 	return &version, nil
 }
 
+func (r *GormRepository) GetModVersionFile(fileID string) (*model.ModVersionFile, error) {
+	var file model.ModVersionFile
+	if err := r.db.First(&file, "id = ?", fileID).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &file, nil
+}
+
 func (r *GormRepository) UpdateMod(modID string, details *model.ModDetails) error {
 	result := r.db.Model(&model.ModDetails{}).Where("id = ?", modID).Updates(details)
 	return result.Error
@@ -146,18 +174,74 @@ func (r *GormRepository) DeleteModVersion(modID, versionID string) error {
 	}
 
 	// If deleting the latest version, update or clear latest_version_id on mod
+	r.replaceLatestVersion(modID, version.ID)
+
+	result := r.db.Select("Files").Delete(&version)
+	return result.Error
+}
+
+func (r *GormRepository) SetModVersionStatus(modID, versionID string, status model.VersionStatus, reason string) error {
+	version, err := r.GetModVersionDetails(modID, versionID)
+	if err != nil {
+		return err
+	}
+	if status == model.VersionStatusActive {
+		reason = ""
+	}
+	updates := map[string]any{"status": status, "status_reason": reason}
+	if err := r.db.Model(&model.ModVersionDetails{}).Where("id = ?", version.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	if status == model.VersionStatusYanked {
+		r.replaceLatestVersion(modID, version.ID)
+	} else if version.Status == model.VersionStatusYanked {
+		r.restoreLatestVersion(modID, version)
+	}
+	return nil
+}
+
+// restoreLatestVersion points the latest version of the mod back at a restored version, if it is newer than the
+// latest one or the mod has none.
+func (r *GormRepository) restoreLatestVersion(modID string, version *model.ModVersionDetails) {
 	var mod model.ModDetails
-	if err := r.db.First(&mod, "id = ?", modID).Error; err == nil {
-		if mod.LatestVersionID != nil && *mod.LatestVersionID == version.ID {
-			var remainingLatest model.ModVersionDetails
-			if err := r.db.Where("mod_id = ? AND id != ?", modID, version.ID).Order("created_at DESC").First(&remainingLatest).Error; err == nil {
-				_ = r.db.Model(&model.ModDetails{}).Where("id = ?", modID).Update("latest_version_id", remainingLatest.ID).Error
-			} else {
-				_ = r.db.Model(&model.ModDetails{}).Where("id = ?", modID).Update("latest_version_id", nil).Error
-			}
+	if err := r.db.First(&mod, "id = ?", modID).Error; err != nil {
+		return
+	}
+	if mod.LatestVersionID != nil {
+		var latest model.ModVersionDetails
+		if err := r.db.First(&latest, "id = ?", *mod.LatestVersionID).Error; err == nil && !latest.CreatedAt.Before(version.CreatedAt) {
+			return
 		}
 	}
+	_ = r.db.Model(&model.ModDetails{}).Where("id = ?", modID).Update("latest_version_id", version.ID).Error
+}
 
-	result := r.db.Select("Files").Delete(&version)
-	return result.Error
+// replaceLatestVersion points the latest version of the mod away from versionID, to the newest version that is not
+// yanked, or to nothing.
+func (r *GormRepository) replaceLatestVersion(modID, versionID string) {
+	var mod model.ModDetails
+	if err := r.db.First(&mod, "id = ?", modID).Error; err != nil {
+		return
+	}
+	if mod.LatestVersionID == nil || *mod.LatestVersionID != versionID {
+		return
+	}
+	var remainingLatest model.ModVersionDetails
+	if err := r.db.Where("mod_id = ? AND id != ? AND status != ?", modID, versionID, model.VersionStatusYanked).Order("created_at DESC").First(&remainingLatest).Error; err == nil {
+		_ = r.db.Model(&model.ModDetails{}).Where("id = ?", modID).Update("latest_version_id", remainingLatest.ID).Error
+	} else {
+		_ = r.db.Model(&model.ModDetails{}).Where("id = ?", modID).Update("latest_version_id", nil).Error
+	}
+}
+
+func (r *GormRepository) DeleteModVersionFile(fileID string) error {
+	return r.db.Delete(&model.ModVersionFile{}, "id = ?", fileID).Error
+}
+
+// notFound wraps gorm.ErrRecordNotFound with repository.ErrNotFound, so callers need not depend on gorm.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %w", repository.ErrNotFound, err)
+	}
+	return err
 }
@@ -0,0 +1,84 @@
+package gorm
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+)
+
+func (r *GormRepository) SaveModImage(image *model.ModImage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if image.Kind == model.ImageKindThumbnail {
+			var previous []string
+			if err := tx.Model(&model.ModImage{}).Where("mod_id = ? AND kind = ?", image.ModID, model.ImageKindThumbnail).Pluck("id", &previous).Error; err != nil {
+				return err
+			}
+			if err := deleteImages(tx, previous); err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Mod", "Variants").Create(image).Error; err != nil {
+			return err
+		}
+		if len(image.Variants) == 0 {
+			return nil
+		}
+		for i := range image.Variants {
+			image.Variants[i].ImageID = image.ID
+		}
+		return tx.Create(&image.Variants).Error
+	})
+}
+
+func (r *GormRepository) GetModThumbnail(modID string) (*model.ModImage, error) {
+	var image model.ModImage
+	if err := r.db.First(&image, "mod_id = ? AND kind = ?", modID, model.ImageKindThumbnail).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &image, nil
+}
+
+func (r *GormRepository) ListModGallery(modID string) ([]model.ModImage, error) {
+	var images []model.ModImage
+	if err := r.db.Where("mod_id = ? AND kind = ?", modID, model.ImageKindGallery).Order("position").Order("created_at").Find(&images).Error; err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *GormRepository) GetModImage(modID, imageID string) (*model.ModImage, error) {
+	var image model.ModImage
+	if err := r.db.First(&image, "mod_id = ? AND id = ?", modID, imageID).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &image, nil
+}
+
+func (r *GormRepository) GetModImageVariant(imageID, size string) (*model.ModImageVariant, error) {
+	var variant model.ModImageVariant
+	if err := r.db.First(&variant, "image_id = ? AND size = ?", imageID, size).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &variant, nil
+}
+
+func (r *GormRepository) DeleteModImage(modID, imageID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var image model.ModImage
+		if err := tx.First(&image, "mod_id = ? AND id = ?", modID, imageID).Error; err != nil {
+			return notFound(err)
+		}
+		return deleteImages(tx, []string{imageID})
+	})
+}
+
+// deleteImages deletes images with their variants, without relying on the foreign keys cascading.
+func deleteImages(tx *gorm.DB, imageIDs []string) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("image_id IN ?", imageIDs).Delete(&model.ModImageVariant{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", imageIDs).Delete(&model.ModImage{}).Error
+}
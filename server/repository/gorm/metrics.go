@@ -0,0 +1,52 @@
+package gorm
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ikafly144/au_mod_installer/server/metrics"
+)
+
+const queryStartKey = "mus:query_start"
+
+var queryDuration = metrics.NewHistogram("mus_db_query_duration_seconds", "Database query latency by operation.", metrics.DefBuckets, "operation")
+
+// ObserveQueries records the latency of every query run through the repository.
+func (r *GormRepository) ObserveQueries() error {
+	type register func(name string, fn func(*gorm.DB)) error
+	callbacks := r.db.Callback()
+	hooks := []struct {
+		operation     string
+		before, after register
+	}{
+		{"create", callbacks.Create().Before("*").Register, callbacks.Create().After("*").Register},
+		{"query", callbacks.Query().Before("*").Register, callbacks.Query().After("*").Register},
+		{"update", callbacks.Update().Before("*").Register, callbacks.Update().After("*").Register},
+		{"delete", callbacks.Delete().Before("*").Register, callbacks.Delete().After("*").Register},
+		{"row", callbacks.Row().Before("*").Register, callbacks.Row().After("*").Register},
+		{"raw", callbacks.Raw().Before("*").Register, callbacks.Raw().After("*").Register},
+	}
+	for _, hook := range hooks {
+		if err := hook.before("mus:start_"+hook.operation, startQueryTimer); err != nil {
+			return fmt.Errorf("failed to register %s callback: %w", hook.operation, err)
+		}
+		if err := hook.after("mus:observe_"+hook.operation, observeQuery(hook.operation)); err != nil {
+			return fmt.Errorf("failed to register %s callback: %w", hook.operation, err)
+		}
+	}
+	return nil
+}
+
+func startQueryTimer(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if start, ok := db.InstanceGet(queryStartKey); ok {
+			queryDuration.Observe(time.Since(start.(time.Time)).Seconds(), operation)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package gorm
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer release than this one.
+var ErrSchemaTooNew = errors.New("database schema is newer than this release supports")
+
+// ErrSchemaOutOfDate is returned when the database has migrations pending that this release relies on.
+var ErrSchemaOutOfDate = errors.New("database schema is out of date; run mus-mgr migrate")
+
+// migration is one step of the schema. Steps run in order, each in a transaction of its own, and are recorded in the
+// schema_version table. New steps go at the end of migrations; released steps must not change. Steps migrate with
+// snapshots of the models taken when they were written, so a later change to a model needs a step of its own.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *gorm.DB) error
+	down    func(tx *gorm.DB) error
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create tables",
+		// Databases created by AutoMigrate before versioned migrations already have these tables, so the first step
+		// brings them up to date as well.
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(v1Tables()...)
+		},
+		down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(v1Tables()...)
+		},
+	},
+	{
+		version: 2,
+		name:    "replace {} in JSON array columns",
+		// Rows written by older releases, or converted from native Postgres arrays, hold an empty object where an
+		// array is expected
+		up: func(tx *gorm.DB) error {
+			for _, column := range []struct{ table, name string }{
+				{"mod_version_details", "game_versions"},
+				{"mod_version_details", "dependencies"},
+				{"mod_version_files", "downloads"},
+				{"api_tokens", "mod_ids"},
+			} {
+				if err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = '[]' WHERE CAST(%s AS TEXT) = '{}'", column.table, column.name, column.name)).Error; err != nil {
+					return fmt.Errorf("failed to fix %s.%s: %w", column.table, column.name, err)
+				}
+			}
+			return nil
+		},
+		// Empty arrays are valid either way
+		down: func(tx *gorm.DB) error { return nil },
+	},
+	{
+		version: 3,
+		name:    "create mod image tables",
+		up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v3ModImage{}, &v3ModImageVariant{})
+		},
+		down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v3ModImageVariant{}, &v3ModImage{})
+		},
+	},
+	{
+		version: 4,
+		name:    "add mod metadata and version changelogs",
+		up: func(tx *gorm.DB) error {
+			return forV4Columns(func(table any, field string) error {
+				if tx.Migrator().HasColumn(table, field) {
+					return nil
+				}
+				return tx.Migrator().AddColumn(table, field)
+			})
+		},
+		down: func(tx *gorm.DB) error {
+			return forV4Columns(func(table any, field string) error {
+				if !tx.Migrator().HasColumn(table, field) {
+					return nil
+				}
+				return tx.Migrator().DropColumn(table, field)
+			})
+		},
+	},
+}
+
+func v1Tables() []any {
+	return []any{&v1ModDetails{}, &v1ModVersionFile{}, &v1ModVersionDetails{}, &v1APIToken{}, &v1AuditLogEntry{}, &v1SharedGame{}, &v1ShareGameRate{}, &v1SharedAupack{}}
+}
+
+// forV4Columns calls fn with each column added by migration 4.
+func forV4Columns(fn func(table any, field string) error) error {
+	for _, column := range []struct {
+		table any
+		field string
+	}{
+		{&v4ModDetails{}, "Tags"},
+		{&v4ModDetails{}, "LinkHomepage"},
+		{&v4ModDetails{}, "LinkSource"},
+		{&v4ModDetails{}, "LinkIssues"},
+		{&v4ModDetails{}, "LinkDiscord"},
+		{&v4ModDetails{}, "License"},
+		{&v4ModDetails{}, "Languages"},
+		{&v4ModVersionDetails{}, "Changelog"},
+		{&v4ModVersionDetails{}, "ReleasedAt"},
+	} {
+		if err := fn(column.table, column.field); err != nil {
+			return fmt.Errorf("failed to migrate column %s: %w", column.field, err)
+		}
+	}
+	return nil
+}
+
+// schemaVersion records an applied migration.
+type schemaVersion struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	AppliedAt time.Time
+}
+
+func (schemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// MigrationStatus is a migration known to this release, or one recorded in the database that is not.
+type MigrationStatus struct {
+	Version int
+	Name    string
+	// AppliedAt is nil for migrations that have not run.
+	AppliedAt *time.Time
+	// Unknown is set for migrations recorded by a newer release.
+	Unknown bool
+}
+
+// LatestSchemaVersion is the schema version this release migrates to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func (r *GormRepository) appliedMigrations() ([]schemaVersion, error) {
+	if !r.db.Migrator().HasTable(&schemaVersion{}) {
+		return nil, nil
+	}
+	var applied []schemaVersion
+	if err := r.db.Order("version").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return applied, nil
+}
+
+// SchemaVersion returns the version of the newest migration applied to the database, or 0 for a new database.
+func (r *GormRepository) SchemaVersion() (int, error) {
+	applied, err := r.appliedMigrations()
+	if err != nil || len(applied) == 0 {
+		return 0, err
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// CheckSchema fails with ErrSchemaTooNew when the database has migrations this release does not know, and with
+// ErrSchemaOutOfDate when migrations are pending.
+func (r *GormRepository) CheckSchema() error {
+	current, err := r.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	latest := LatestSchemaVersion()
+	switch {
+	case current > latest:
+		return fmt.Errorf("%w: database is at version %d, this release knows up to %d", ErrSchemaTooNew, current, latest)
+	case current < latest:
+		return fmt.Errorf("%w: database is at version %d, this release needs %d", ErrSchemaOutOfDate, current, latest)
+	}
+	return nil
+}
+
+// MigrationStatus lists every migration of this release and whether it has been applied, followed by the migrations
+// recorded by newer releases.
+func (r *GormRepository) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := r.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[int]time.Time, len(applied))
+	for _, a := range applied {
+		appliedAt[a.Version] = a.AppliedAt
+	}
+	var status []MigrationStatus
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.version, Name: m.name}
+		if at, ok := appliedAt[m.version]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	for _, a := range applied {
+		if a.Version > LatestSchemaVersion() {
+			status = append(status, MigrationStatus{Version: a.Version, Name: a.Name, AppliedAt: &a.AppliedAt, Unknown: true})
+		}
+	}
+	return status, nil
+}
+
+// Migrate applies every pending migration.
+func (r *GormRepository) Migrate() error {
+	return r.MigrateTo(LatestSchemaVersion())
+}
+
+// MigrateTo applies or reverts migrations until the schema is at the target version. 0 reverts every migration.
+func (r *GormRepository) MigrateTo(target int) error {
+	if target < 0 || target > LatestSchemaVersion() {
+		return fmt.Errorf("unknown schema version %d", target)
+	}
+	if err := r.db.AutoMigrate(&schemaVersion{}); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+	current, err := r.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > LatestSchemaVersion() {
+		return fmt.Errorf("%w: database is at version %d", ErrSchemaTooNew, current)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current || m.version > target {
+			continue
+		}
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaVersion{Version: m.version, Name: m.name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+		slog.Info("Applied migration", "version", m.version, "name", m.name)
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version > current || m.version <= target {
+			continue
+		}
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := m.down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaVersion{}, "version = ?", m.version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to revert migration %d (%s): %w", m.version, m.name, err)
+		}
+		slog.Info("Reverted migration", "version", m.version, "name", m.name)
+	}
+	return nil
+}
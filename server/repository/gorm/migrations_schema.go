@@ -0,0 +1,192 @@
+package gorm
+
+import "time"
+
+// The structs below are snapshots of the models as the migration using them was written. Steps migrate with these
+// rather than the models in server/model, so a later change to a model cannot change what a released step does.
+// They must not change; a change to a model gets a step and, if needed, snapshots of its own.
+
+// Tables created by migration 1.
+
+type v1ModDetails struct {
+	ID           string  `gorm:"primaryKey"`
+	Name         string  `gorm:"not null"`
+	Description  string  `gorm:"not null"`
+	Author       string  `gorm:"not null"`
+	Type         string  `gorm:"not null;default:'mod';index"`
+	ThumbnailURI *string `gorm:"default:null"`
+
+	LatestVersionID *string `gorm:"index;default:null;"`
+
+	Versions []v1ModVersionDetails `gorm:"foreignKey:ModID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Files    []v1ModVersionFile    `gorm:"foreignKey:ModID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (v1ModDetails) TableName() string { return "mod_details" }
+
+type v1ModVersionDetails struct {
+	ID        string `gorm:"primaryKey"`
+	VersionID string `gorm:"index:idx_mod_version_details"`
+	ModID     string `gorm:"index:idx_mod_version_details"`
+
+	GameVersions string `gorm:"type:json"`
+
+	Files        []v1ModVersionFile `gorm:"foreignKey:VersionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Dependencies string             `gorm:"type:json"`
+	Features     string             `gorm:"type:json"`
+
+	Status       string `gorm:"not null;default:''"`
+	StatusReason string `gorm:"not null;default:''"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (v1ModVersionDetails) TableName() string { return "mod_version_details" }
+
+type v1ModVersionFile struct {
+	ID        string  `gorm:"primaryKey"`
+	ModID     *string `gorm:"index:idx_mod_version_file;not null"`
+	VersionID *string `gorm:"index:idx_mod_version_file;not null"`
+
+	Filename    string `gorm:"not null"`
+	ContentType string `gorm:"not null"`
+	Size        int64  `gorm:"not null"`
+
+	ExtractPath    *string `gorm:"default:null"`
+	TargetPlatform string  `gorm:"not null;default:'any'"`
+
+	Hashes    string `gorm:"type:json"`
+	Downloads string `gorm:"type:json"`
+
+	CreatedAt time.Time
+}
+
+func (v1ModVersionFile) TableName() string { return "mod_version_files" }
+
+type v1APIToken struct {
+	ID        string `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	TokenHash string `gorm:"not null;uniqueIndex"`
+	ModIDs    string `gorm:"type:json"`
+
+	CreatedAt  time.Time
+	LastUsedAt *time.Time `gorm:"default:null"`
+	RevokedAt  *time.Time `gorm:"default:null"`
+}
+
+func (v1APIToken) TableName() string { return "api_tokens" }
+
+type v1AuditLogEntry struct {
+	ID        uint   `gorm:"primaryKey"`
+	TokenID   string `gorm:"index"`
+	TokenName string
+	RemoteIP  string
+
+	Action    string `gorm:"not null"`
+	ModID     string `gorm:"index"`
+	VersionID string
+	FileID    string
+
+	Detail string
+
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (v1AuditLogEntry) TableName() string { return "audit_log_entries" }
+
+type v1SharedGame struct {
+	SessionID string `gorm:"primaryKey"`
+	HostKey   string `gorm:"not null"`
+	IP        string `gorm:"not null;uniqueIndex"`
+
+	RoomKey string `gorm:"not null"`
+
+	AupackSHA256 string `gorm:"not null;index"`
+	AupackSize   int64  `gorm:"not null"`
+
+	LobbyCode      string `gorm:"not null;default:''"`
+	ServerIP       string `gorm:"not null;default:''"`
+	ServerPort     uint16 `gorm:"not null;default:0"`
+	MatchMakerIP   string `gorm:"not null;default:''"`
+	MatchMakerPort uint16 `gorm:"not null;default:0"`
+	GameVersion    string `gorm:"not null;default:''"`
+
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (v1SharedGame) TableName() string { return "shared_games" }
+
+type v1ShareGameRate struct {
+	IP          string    `gorm:"primaryKey"`
+	WindowStart time.Time `gorm:"not null;index"`
+	Count       int       `gorm:"not null"`
+}
+
+func (v1ShareGameRate) TableName() string { return "share_game_rates" }
+
+type v1SharedAupack struct {
+	SHA256   string    `gorm:"primaryKey"`
+	Data     []byte    `gorm:"not null"`
+	StoredAt time.Time `gorm:"not null;index"`
+}
+
+func (v1SharedAupack) TableName() string { return "shared_aupacks" }
+
+// Tables created by migration 3.
+
+type v3ModImage struct {
+	ID    string       `gorm:"primaryKey"`
+	ModID string       `gorm:"not null;index"`
+	Mod   v1ModDetails `gorm:"foreignKey:ModID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Kind  string       `gorm:"not null"`
+
+	Position  int    `gorm:"not null;default:0"`
+	Caption   string `gorm:"not null;default:''"`
+	SourceURL string `gorm:"not null;default:''"`
+	SHA256    string `gorm:"not null"`
+	Width     int    `gorm:"not null;default:0"`
+	Height    int    `gorm:"not null;default:0"`
+
+	Variants []v3ModImageVariant `gorm:"foreignKey:ImageID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+
+	CreatedAt time.Time
+}
+
+func (v3ModImage) TableName() string { return "mod_images" }
+
+type v3ModImageVariant struct {
+	ImageID     string `gorm:"primaryKey"`
+	Size        string `gorm:"primaryKey"`
+	ContentType string `gorm:"not null"`
+	Width       int    `gorm:"not null"`
+	Height      int    `gorm:"not null"`
+	Data        []byte `gorm:"not null"`
+}
+
+func (v3ModImageVariant) TableName() string { return "mod_image_variants" }
+
+// Columns added by migration 4.
+
+type v4ModDetails struct {
+	Tags         string `gorm:"type:json"`
+	LinkHomepage string `gorm:"not null;default:''"`
+	LinkSource   string `gorm:"not null;default:''"`
+	LinkIssues   string `gorm:"not null;default:''"`
+	LinkDiscord  string `gorm:"not null;default:''"`
+	License      string `gorm:"not null;default:''"`
+	Languages    string `gorm:"type:json"`
+}
+
+func (v4ModDetails) TableName() string { return "mod_details" }
+
+type v4ModVersionDetails struct {
+	Changelog  string     `gorm:"not null;default:''"`
+	ReleasedAt *time.Time `gorm:"default:null"`
+}
+
+func (v4ModVersionDetails) TableName() string { return "mod_version_details" }
@@ -0,0 +1,69 @@
+package gorm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Open connects to the database named by dsn, choosing the driver by its scheme. postgres:// and postgresql:// URLs and
+// key=value connection strings use PostgreSQL. sqlite: URLs use SQLite, with the rest of the DSN as the database file:
+// sqlite://registry.db, sqlite:///var/lib/mus/registry.db or sqlite::memory:.
+func Open(dsn string, opts ...gorm.Option) (*gorm.DB, error) {
+	dialector, err := openDialector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if dialector.Name() == "sqlite" {
+		// SQLite allows one writer at a time, and every connection to :memory: opens a database of its own
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	return db, nil
+}
+
+func openDialector(dsn string) (gorm.Dialector, error) {
+	scheme, rest, ok := strings.Cut(dsn, ":")
+	if !ok || strings.ContainsAny(scheme, " =") {
+		// key=value connection strings
+		return postgres.Open(dsn), nil
+	}
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return postgres.Open(dsn), nil
+	case "sqlite", "sqlite3":
+		path := strings.TrimPrefix(rest, "//")
+		if path == "" {
+			return nil, fmt.Errorf("sqlite DSN %q has no database file", dsn)
+		}
+		return sqlite.Open(withSQLiteDefaults(path)), nil
+	}
+	return nil, fmt.Errorf("unsupported database scheme %q", scheme)
+}
+
+// withSQLiteDefaults enables the foreign keys the cascading deletes rely on, and waits for the lock instead of failing
+// when mus-mgr writes to the database of a running server.
+func withSQLiteDefaults(path string) string {
+	for _, pragma := range []string{"foreign_keys(1)", "busy_timeout(5000)"} {
+		name, _, _ := strings.Cut(pragma, "(")
+		if strings.Contains(path, "_pragma="+name+"(") {
+			continue
+		}
+		if strings.Contains(path, "?") {
+			path += "&_pragma=" + pragma
+		} else {
+			path += "?_pragma=" + pragma
+		}
+	}
+	return path
+}
@@ -0,0 +1,145 @@
+package gorm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/server/model"
+)
+
+// searchBatchSize is how many mods are read at a time when the version filters are evaluated.
+const searchBatchSize = 100
+
+// SearchMods lists the IDs of mods matching the query in its sort order, starting after the mod with the given ID.
+// The text and type filters run in the database. The version filters need the JSON columns of the latest versions,
+// which cannot be queried portably, so they are evaluated on batches of candidates instead.
+func (r *GormRepository) SearchMods(query restmodel.ModSearchQuery, after string, limit int) ([]string, string, error) {
+	column, desc := modSortColumn(query.Sort)
+	direction, cmp := "ASC", ">"
+	if desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	db := r.db.Model(&model.ModDetails{}).
+		Order(column + " " + direction).
+		Order("id " + direction)
+	for _, term := range query.Terms() {
+		pattern := "%" + escapeLike(term) + "%"
+		db = db.Where(`(LOWER(name) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\' OR LOWER(author) LIKE ? ESCAPE '\')`, pattern, pattern, pattern)
+	}
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+	if after != "" {
+		var cursor model.ModDetails
+		if err := r.db.Select("id", column).First(&cursor, "id = ?", after).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return []string{}, "", nil
+			}
+			return nil, "", err
+		}
+		value := modSortValue(cursor, query.Sort)
+		db = db.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, cmp, column, cmp), value, value, cursor.ID)
+	}
+
+	if !query.HasVersionFilters() {
+		var ids []string
+		if err := db.Limit(limit).Pluck("id", &ids).Error; err != nil {
+			return nil, "", err
+		}
+		return ids, lastID(ids), nil
+	}
+
+	db = db.Session(&gorm.Session{})
+	ids := []string{}
+	for offset := 0; len(ids) < limit; offset += searchBatchSize {
+		var mods []model.ModDetails
+		if err := db.Select("id", "latest_version_id").Offset(offset).Limit(searchBatchSize).Find(&mods).Error; err != nil {
+			return nil, "", err
+		}
+		var versionIDs []string
+		for _, mod := range mods {
+			if mod.LatestVersionID != nil {
+				versionIDs = append(versionIDs, *mod.LatestVersionID)
+			}
+		}
+		latest := make(map[string]model.ModVersionDetails, len(versionIDs))
+		if len(versionIDs) > 0 {
+			var versions []model.ModVersionDetails
+			if err := r.db.Preload("Files").Where("id IN ?", versionIDs).Find(&versions).Error; err != nil {
+				return nil, "", err
+			}
+			for _, v := range versions {
+				latest[v.ID] = v
+			}
+		}
+		for _, mod := range mods {
+			if mod.LatestVersionID == nil {
+				continue
+			}
+			v, ok := latest[*mod.LatestVersionID]
+			if !ok || !query.MatchesVersion(searchableVersion(v)) {
+				continue
+			}
+			ids = append(ids, mod.ID)
+			if len(ids) == limit {
+				break
+			}
+		}
+		if len(mods) < searchBatchSize {
+			break
+		}
+	}
+	return ids, lastID(ids), nil
+}
+
+func modSortColumn(sort restmodel.ModSort) (column string, desc bool) {
+	switch sort {
+	case restmodel.ModSortUpdatedAt:
+		return "updated_at", true
+	case restmodel.ModSortName:
+		return "name", false
+	default:
+		return "created_at", true
+	}
+}
+
+func modSortValue(mod model.ModDetails, sort restmodel.ModSort) any {
+	switch sort {
+	case restmodel.ModSortUpdatedAt:
+		return mod.UpdatedAt
+	case restmodel.ModSortName:
+		return mod.Name
+	default:
+		return mod.CreatedAt
+	}
+}
+
+// searchableVersion converts the fields of a version the search filters look at.
+func searchableVersion(v model.ModVersionDetails) restmodel.ModVersionDetails {
+	version := restmodel.ModVersionDetails{
+		GameVersions: v.GameVersions,
+		Features:     v.Features,
+	}
+	for _, f := range v.Files {
+		version.Files = append(version.Files, restmodel.ModVersionFile{TargetPlatform: restmodel.TargetPlatform(f.TargetPlatform)})
+	}
+	return version
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func lastID(ids []string) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[len(ids)-1]
+}
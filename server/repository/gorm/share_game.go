@@ -0,0 +1,120 @@
+package gorm
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+)
+
+func (r *GormRepository) CountSharedGame(ip string, now time.Time, window time.Duration) (int, error) {
+	// One upsert, so replicas counting the same IP at once cannot lose an increment
+	windowOpen := now.Add(-window)
+	rate := model.ShareGameRate{IP: ip, WindowStart: now, Count: 1}
+	err := r.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "ip"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"count":        gorm.Expr("CASE WHEN share_game_rates.window_start <= ? THEN 1 ELSE share_game_rates.count + 1 END", windowOpen),
+				"window_start": gorm.Expr("CASE WHEN share_game_rates.window_start <= ? THEN ? ELSE share_game_rates.window_start END", windowOpen, now),
+			}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "count"}}},
+	).Create(&rate).Error
+	if err != nil {
+		return 0, err
+	}
+	return rate.Count, nil
+}
+
+func (r *GormRepository) ReplaceSharedGame(game *model.SharedGame) error {
+	// One upsert on the IP, so shares from the same IP at once cannot both delete the previous game and then collide on
+	// insert
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "ip"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"session_id", "host_key", "room_key", "aupack_sha256", "aupack_size",
+			"lobby_code", "server_ip", "server_port", "match_maker_ip", "match_maker_port", "game_version",
+			"created_at", "expires_at",
+		}),
+	}).Create(game).Error
+}
+
+func (r *GormRepository) GetSharedGame(sessionID string) (*model.SharedGame, error) {
+	var game model.SharedGame
+	if err := r.db.First(&game, "session_id = ?", sessionID).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &game, nil
+}
+
+func (r *GormRepository) GetSharedGameByIP(ip string) (*model.SharedGame, error) {
+	var game model.SharedGame
+	if err := r.db.First(&game, "ip = ?", ip).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &game, nil
+}
+
+func (r *GormRepository) UpdateSharedGameExpiration(sessionID string, expiresAt time.Time) error {
+	result := r.db.Model(&model.SharedGame{}).Where("session_id = ?", sessionID).Update("expires_at", expiresAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return notFound(gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (r *GormRepository) DeleteSharedGame(sessionID string) error {
+	return r.db.Delete(&model.SharedGame{}, "session_id = ?", sessionID).Error
+}
+
+func (r *GormRepository) DeleteExpiredSharedGames(now time.Time, window time.Duration) error {
+	if err := r.db.Delete(&model.SharedGame{}, "expires_at < ?", now).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&model.ShareGameRate{}, "window_start <= ?", now.Add(-window)).Error
+}
+
+func (r *GormRepository) CountSharedGames(now time.Time) (int, error) {
+	var count int64
+	if err := r.db.Model(&model.SharedGame{}).Where("expires_at >= ?", now).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (r *GormRepository) ListSharedGameAupacks() ([]string, error) {
+	var hashes []string
+	if err := r.db.Model(&model.SharedGame{}).Distinct().Pluck("aupack_sha256", &hashes).Error; err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+func (r *GormRepository) PutAupack(hash string, data []byte, now time.Time) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sha256"}},
+		DoUpdates: clause.Assignments(map[string]any{"stored_at": now}),
+	}).Create(&model.SharedAupack{SHA256: hash, Data: data, StoredAt: now}).Error
+}
+
+func (r *GormRepository) GetAupack(hash string) ([]byte, error) {
+	var aupack model.SharedAupack
+	if err := r.db.First(&aupack, "sha256 = ?", hash).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return aupack.Data, nil
+}
+
+func (r *GormRepository) DeleteUnusedAupacks(keep []string, storedBefore time.Time) error {
+	query := r.db.Where("stored_at < ?", storedBefore)
+	if len(keep) > 0 {
+		query = query.Where("sha256 NOT IN ?", keep)
+	}
+	return query.Delete(&model.SharedAupack{}).Error
+}
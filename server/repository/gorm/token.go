@@ -0,0 +1,60 @@
+package gorm
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+)
+
+func (r *GormRepository) CreateAPIToken(token *model.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *GormRepository) GetAPITokenByHash(hash string) (*model.APIToken, error) {
+	var token model.APIToken
+	if err := r.db.First(&token, "token_hash = ?", hash).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &token, nil
+}
+
+func (r *GormRepository) ListAPITokens() ([]model.APIToken, error) {
+	var tokens []model.APIToken
+	if err := r.db.Order("created_at ASC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *GormRepository) RevokeAPIToken(id string, revokedAt time.Time) error {
+	result := r.db.Model(&model.APIToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return notFound(gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (r *GormRepository) TouchAPIToken(id string, usedAt time.Time) error {
+	return r.db.Model(&model.APIToken{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
+
+func (r *GormRepository) CreateAuditLogEntry(entry *model.AuditLogEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *GormRepository) ListAuditLogEntries(modID string, limit int) ([]model.AuditLogEntry, error) {
+	query := r.db.Order("created_at DESC").Order("id DESC").Limit(limit)
+	if modID != "" {
+		query = query.Where("mod_id = ?", modID)
+	}
+	var entries []model.AuditLogEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
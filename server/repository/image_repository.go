@@ -0,0 +1,15 @@
+package repository
+
+import "github.com/ikafly144/au_mod_installer/server/model"
+
+// ImageRepository stores the thumbnails and galleries of mods together with their resized variants.
+type ImageRepository interface {
+	// SaveModImage stores the image and its variants. Saving a thumbnail replaces the previous thumbnail of the mod.
+	SaveModImage(image *model.ModImage) error
+	GetModThumbnail(modID string) (*model.ModImage, error)
+	// ListModGallery returns the gallery images of the mod ordered by position, without their variants.
+	ListModGallery(modID string) ([]model.ModImage, error)
+	GetModImage(modID, imageID string) (*model.ModImage, error)
+	GetModImageVariant(imageID, size string) (*model.ModImageVariant, error)
+	DeleteModImage(modID, imageID string) error
+}
@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+// AupackRepository keeps shared aupacks in memory.
+type AupackRepository struct {
+	mu       sync.Mutex
+	packs    map[string][]byte
+	storedAt map[string]time.Time
+}
+
+var _ repository.AupackRepository = (*AupackRepository)(nil)
+
+func NewAupackRepository() *AupackRepository {
+	return &AupackRepository{
+		packs:    make(map[string][]byte),
+		storedAt: make(map[string]time.Time),
+	}
+}
+
+func (r *AupackRepository) PutAupack(hash string, data []byte, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.packs[hash]; !ok {
+		r.packs[hash] = slices.Clone(data)
+	}
+	r.storedAt[hash] = now
+	return nil
+}
+
+func (r *AupackRepository) GetAupack(hash string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, ok := r.packs[hash]
+	if !ok {
+		return nil, fmt.Errorf("aupack %s: %w", hash, repository.ErrNotFound)
+	}
+	return slices.Clone(data), nil
+}
+
+func (r *AupackRepository) DeleteUnusedAupacks(keep []string, storedBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, storedAt := range r.storedAt {
+		if storedAt.Before(storedBefore) && !slices.Contains(keep, hash) {
+			delete(r.packs, hash)
+			delete(r.storedAt, hash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+// ImageRepository keeps mod images in memory.
+type ImageRepository struct {
+	mu     sync.Mutex
+	images map[string]model.ModImage
+}
+
+var _ repository.ImageRepository = (*ImageRepository)(nil)
+
+func NewImageRepository() *ImageRepository {
+	return &ImageRepository{images: make(map[string]model.ModImage)}
+}
+
+func (r *ImageRepository) SaveModImage(image *model.ModImage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if image.Kind == model.ImageKindThumbnail {
+		for id, stored := range r.images {
+			if stored.ModID == image.ModID && stored.Kind == model.ImageKindThumbnail {
+				delete(r.images, id)
+			}
+		}
+	}
+	stored := *image
+	stored.Variants = slices.Clone(image.Variants)
+	for i := range stored.Variants {
+		stored.Variants[i].ImageID = image.ID
+	}
+	r.images[image.ID] = stored
+	return nil
+}
+
+func (r *ImageRepository) GetModThumbnail(modID string) (*model.ModImage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, image := range r.images {
+		if image.ModID == modID && image.Kind == model.ImageKindThumbnail {
+			image.Variants = nil
+			return &image, nil
+		}
+	}
+	return nil, fmt.Errorf("thumbnail of %s: %w", modID, repository.ErrNotFound)
+}
+
+func (r *ImageRepository) ListModGallery(modID string) ([]model.ModImage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var gallery []model.ModImage
+	for _, image := range r.images {
+		if image.ModID == modID && image.Kind == model.ImageKindGallery {
+			image.Variants = nil
+			gallery = append(gallery, image)
+		}
+	}
+	slices.SortFunc(gallery, func(a, b model.ModImage) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), a.CreatedAt.Compare(b.CreatedAt))
+	})
+	return gallery, nil
+}
+
+func (r *ImageRepository) GetModImage(modID, imageID string) (*model.ModImage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	image, ok := r.images[imageID]
+	if !ok || image.ModID != modID {
+		return nil, fmt.Errorf("image %s: %w", imageID, repository.ErrNotFound)
+	}
+	image.Variants = nil
+	return &image, nil
+}
+
+func (r *ImageRepository) GetModImageVariant(imageID, size string) (*model.ModImageVariant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, variant := range r.images[imageID].Variants {
+		if variant.Size == size {
+			return &variant, nil
+		}
+	}
+	return nil, fmt.Errorf("%s variant of image %s: %w", size, imageID, repository.ErrNotFound)
+}
+
+func (r *ImageRepository) DeleteModImage(modID, imageID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if image, ok := r.images[imageID]; !ok || image.ModID != modID {
+		return fmt.Errorf("image %s: %w", imageID, repository.ErrNotFound)
+	}
+	delete(r.images, imageID)
+	return nil
+}
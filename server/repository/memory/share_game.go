@@ -0,0 +1,143 @@
+// Package memory implements repositories in process memory, for a single server instance and for tests.
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+// SharedGameRepository keeps shared games in memory. They are lost on restart and not seen by other replicas.
+type SharedGameRepository struct {
+	mu    sync.Mutex
+	games map[string]model.SharedGame
+	byIP  map[string]string
+	rates map[string]model.ShareGameRate
+}
+
+var _ repository.SharedGameRepository = (*SharedGameRepository)(nil)
+
+func NewSharedGameRepository() *SharedGameRepository {
+	return &SharedGameRepository{
+		games: make(map[string]model.SharedGame),
+		byIP:  make(map[string]string),
+		rates: make(map[string]model.ShareGameRate),
+	}
+}
+
+func (r *SharedGameRepository) CountSharedGame(ip string, now time.Time, window time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rate, ok := r.rates[ip]
+	if !ok || now.Sub(rate.WindowStart) >= window {
+		rate = model.ShareGameRate{IP: ip, WindowStart: now}
+	}
+	rate.Count++
+	r.rates[ip] = rate
+	return rate.Count, nil
+}
+
+func (r *SharedGameRepository) ReplaceSharedGame(game *model.SharedGame) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if previous, ok := r.byIP[game.IP]; ok {
+		delete(r.games, previous)
+	}
+	r.games[game.SessionID] = *game
+	r.byIP[game.IP] = game.SessionID
+	return nil
+}
+
+func (r *SharedGameRepository) GetSharedGame(sessionID string) (*model.SharedGame, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getLocked(sessionID)
+}
+
+func (r *SharedGameRepository) GetSharedGameByIP(ip string) (*model.SharedGame, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getLocked(r.byIP[ip])
+}
+
+func (r *SharedGameRepository) UpdateSharedGameExpiration(sessionID string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	game, ok := r.games[sessionID]
+	if !ok {
+		return fmt.Errorf("shared game %s: %w", sessionID, repository.ErrNotFound)
+	}
+	game.ExpiresAt = expiresAt
+	r.games[sessionID] = game
+	return nil
+}
+
+func (r *SharedGameRepository) DeleteSharedGame(sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleteLocked(sessionID)
+	return nil
+}
+
+func (r *SharedGameRepository) DeleteExpiredSharedGames(now time.Time, window time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, game := range r.games {
+		if now.After(game.ExpiresAt) {
+			r.deleteLocked(id)
+		}
+	}
+	for ip, rate := range r.rates {
+		if now.Sub(rate.WindowStart) >= window {
+			delete(r.rates, ip)
+		}
+	}
+	return nil
+}
+
+func (r *SharedGameRepository) CountSharedGames(now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, game := range r.games {
+		if !now.After(game.ExpiresAt) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *SharedGameRepository) ListSharedGameAupacks() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var hashes []string
+	for _, game := range r.games {
+		if !slices.Contains(hashes, game.AupackSHA256) {
+			hashes = append(hashes, game.AupackSHA256)
+		}
+	}
+	return hashes, nil
+}
+
+func (r *SharedGameRepository) getLocked(sessionID string) (*model.SharedGame, error) {
+	game, ok := r.games[sessionID]
+	if !ok {
+		return nil, fmt.Errorf("shared game %s: %w", sessionID, repository.ErrNotFound)
+	}
+	return &game, nil
+}
+
+func (r *SharedGameRepository) deleteLocked(sessionID string) {
+	game, ok := r.games[sessionID]
+	if !ok {
+		return
+	}
+	delete(r.games, sessionID)
+	if r.byIP[game.IP] == sessionID {
+		delete(r.byIP, game.IP)
+	}
+}
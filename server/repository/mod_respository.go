@@ -1,21 +1,57 @@
 package repository
 
 import (
+	"errors"
+
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/server/model"
 )
 
+// ErrNotFound is wrapped by the errors of lookups that match nothing.
+var ErrNotFound = errors.New("not found")
+
 type ModRepository interface {
 	CreateMod(details *model.ModDetails) (string, error)
 	CreateModVersion(modID string, details *model.ModVersionDetails) (string, error)
+	// CreateModVersionFile adds a file to an existing version. file.VersionID is the internal ID of the version.
+	CreateModVersionFile(file *model.ModVersionFile) error
+	// ReplaceModVersion creates the version with the internal ID details.ID and its files, in place of the version and
+	// files stored under that ID if any. Either all of it is written or nothing is.
+	ReplaceModVersion(modID string, details *model.ModVersionDetails, files []model.ModVersionFile) error
 
 	GetModIds(next string, limit int) (ids []string, nextID string, err error)
+	SearchMods(query restmodel.ModSearchQuery, next string, limit int) (ids []string, nextID string, err error)
 	GetModDetails(modID string) (*model.ModDetails, error)
 	GetModVersionIds(modID string) ([]string, error)
 	GetModVersionDetails(modID, versionID string) (*model.ModVersionDetails, error)
+	GetModVersionFile(fileID string) (*model.ModVersionFile, error)
+	// GetModDetailsBatch returns the mods that exist among modIDs, in no particular order.
+	GetModDetailsBatch(modIDs []string) ([]model.ModDetails, error)
+	// GetModVersionDetailsBatch returns the versions that exist among keys, in no particular order.
+	GetModVersionDetailsBatch(keys []ModVersionKey) ([]model.ModVersionDetails, error)
 
+	// UpdateMod and UpdateModVersion change the non-zero fields of details.
 	UpdateMod(modID string, details *model.ModDetails) error
 	UpdateModVersion(modID, versionID string, details *model.ModVersionDetails) error
+	// UpdateModFields and UpdateModVersionFields set columns to the given values, including zero values.
+	UpdateModFields(modID string, updates map[string]any) error
+	UpdateModVersionFields(modID, versionID string, updates map[string]any) error
+	// SetModVersionStatus yanks, deprecates or restores a version. Yanking the latest version moves the mod's latest
+	// version to the newest version that is not yanked.
+	SetModVersionStatus(modID, versionID string, status model.VersionStatus, reason string) error
 
 	DeleteMod(modID string) error
 	DeleteModVersion(modID, versionID string) error
+	DeleteModVersionFile(fileID string) error
+}
+
+// ModVersionKey names a version of a mod by its version ID or internal ID, like GetModVersionDetails.
+type ModVersionKey struct {
+	ModID     string
+	VersionID string
+}
+
+// Matches reports whether the version is the one named by the key.
+func (k ModVersionKey) Matches(version model.ModVersionDetails) bool {
+	return version.ModID == k.ModID && (version.VersionID == k.VersionID || version.ID == k.VersionID)
 }
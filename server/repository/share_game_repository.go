@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+)
+
+// SharedGameRepository stores the games shared through /share_game, so join links survive restarts and work on every
+// replica of the server.
+type SharedGameRepository interface {
+	// CountSharedGame counts a share from ip and returns the number of shares in the current rate window. A window
+	// older than window is restarted.
+	CountSharedGame(ip string, now time.Time, window time.Duration) (int, error)
+	// ReplaceSharedGame stores the game, deleting the game previously shared from the same IP.
+	ReplaceSharedGame(game *model.SharedGame) error
+	GetSharedGame(sessionID string) (*model.SharedGame, error)
+	// GetSharedGameByIP returns the game currently shared from ip.
+	GetSharedGameByIP(ip string) (*model.SharedGame, error)
+	UpdateSharedGameExpiration(sessionID string, expiresAt time.Time) error
+	DeleteSharedGame(sessionID string) error
+	// DeleteExpiredSharedGames deletes the games expired at now and forgets rate windows older than window.
+	DeleteExpiredSharedGames(now time.Time, window time.Duration) error
+	// CountSharedGames counts the games that have not expired at now.
+	CountSharedGames(now time.Time) (int, error)
+	// ListSharedGameAupacks returns the hashes of the aupacks referenced by stored games.
+	ListSharedGameAupacks() ([]string, error)
+}
+
+// AupackRepository stores shared aupacks by the hex sha256 of their content, so a pack shared by many hosts is kept
+// once.
+type AupackRepository interface {
+	// PutAupack stores data under hash. Storing a pack that already exists only refreshes its stored time.
+	PutAupack(hash string, data []byte, now time.Time) error
+	GetAupack(hash string) ([]byte, error)
+	// DeleteUnusedAupacks deletes the packs last stored before storedBefore whose hash is not in keep.
+	DeleteUnusedAupacks(keep []string, storedBefore time.Time) error
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/server/model"
+)
+
+// TokenRepository stores the API tokens of the write API and its audit log.
+type TokenRepository interface {
+	CreateAPIToken(token *model.APIToken) error
+	GetAPITokenByHash(hash string) (*model.APIToken, error)
+	ListAPITokens() ([]model.APIToken, error)
+	RevokeAPIToken(id string, revokedAt time.Time) error
+	TouchAPIToken(id string, usedAt time.Time) error
+
+	CreateAuditLogEntry(entry *model.AuditLogEntry) error
+	// ListAuditLogEntries returns the newest entries first, optionally only those of one mod.
+	ListAuditLogEntries(modID string, limit int) ([]model.AuditLogEntry, error)
+}
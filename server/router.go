@@ -2,28 +2,41 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"embed"
+	"encoding/base64"
+	"encoding/json/v2"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	ginjson "github.com/gin-gonic/gin/codec/json"
 
 	"github.com/ikafly144/au_mod_installer/common/rest"
 	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/server/model"
 	"github.com/ikafly144/au_mod_installer/server/service"
 )
 
 func router(srv *service.ModService, versionProvider service.VersionInfoProvider, pathPrefix string, basePath string) http.Handler {
-	r := gin.Default()
+	r := gin.New()
+	r.Use(observeRequests(basePath), gin.Recovery())
 
 	api := r.Group(basePath)
+	// mirrorURL points clients at the mirror of a file on this server, as seen by the request
+	mirrorURL := func(ctx *gin.Context) func(fileID string) string {
+		return func(fileID string) string {
+			return absoluteURL(ctx, combinePath(pathPrefix, basePath, rest.EndpointGetMirroredFile.Compile(nil, fileID).URL))
+		}
+	}
 	api.GET(rest.EndpointGetModList.Route, func(ctx *gin.Context) {
 		after := ctx.Query("after")
 		limitStr := ctx.Query("limit")
@@ -37,14 +50,20 @@ func router(srv *service.ModService, versionProvider service.VersionInfoProvider
 			}
 		}
 
-		modIDs, nextID, err := srv.GetModIds(after, limit)
+		query, err := restmodel.ParseModSearchQuery(ctx.Request.URL.Query())
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		modIDs, nextID, err := srv.SearchMods(query, after, limit)
 		if err != nil {
 			slog.ErrorContext(ctx, "Failed to get mod IDs", "error", err)
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get mod IDs"})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, restmodel.ModListResult{
+		respondCacheableJSON(ctx, restmodel.ModListResult{
 			IDs:    modIDs,
 			NextID: nextID,
 		})
@@ -59,7 +78,7 @@ func router(srv *service.ModService, versionProvider service.VersionInfoProvider
 			return
 		}
 
-		ctx.JSON(http.StatusOK, details)
+		respondCacheableJSON(ctx, details)
 	})
 	api.GET(rest.EndpointGetModVersionList.Route, func(ctx *gin.Context) {
 		modID := ctx.Param("mod_id")
@@ -71,7 +90,7 @@ func router(srv *service.ModService, versionProvider service.VersionInfoProvider
 			return
 		}
 
-		ctx.JSON(http.StatusOK, restmodel.ModVersionListResult{
+		respondCacheableJSON(ctx, restmodel.ModVersionListResult{
 			IDs: versionIDs,
 		})
 	})
@@ -86,27 +105,152 @@ func router(srv *service.ModService, versionProvider service.VersionInfoProvider
 			return
 		}
 
-		ctx.JSON(http.StatusOK, details)
+		respondCacheableJSON(ctx, srv.WithMirrorURLs(details, mirrorURL(ctx)))
 	})
-	api.GET(rest.EndpointGetModThumbnail.Route, func(ctx *gin.Context) {
-		modID := ctx.Param("mod_id")
+	api.POST(rest.EndpointGetModsBatch.Route, func(ctx *gin.Context) {
+		var req restmodel.ModBatchRequest
+		if !bindJSONRequest(ctx, &req) {
+			return
+		}
 
-		modDetails, err := srv.GetModDetails(modID)
+		mods, err := srv.GetModDetailsBatch(req.IDs)
 		if err != nil {
-			slog.ErrorContext(ctx, "Failed to get mod details", "mod_id", modID, "error", err)
+			slog.ErrorContext(ctx, "Failed to get mod details batch", "count", len(req.IDs), "error", err)
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get mod details"})
 			return
 		}
 
-		thumbnailURI := modDetails.ThumbnailURI
+		ctx.JSON(http.StatusOK, gin.H{"mods": mods})
+	})
+	api.POST(rest.EndpointGetModVersionsBatch.Route, func(ctx *gin.Context) {
+		var req restmodel.ModVersionBatchRequest
+		if !bindJSONRequest(ctx, &req) {
+			return
+		}
+
+		versions, err := srv.GetModVersionDetailsBatch(req.Versions)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get mod version details batch", "count", len(req.Versions), "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get mod version details"})
+			return
+		}
+		for i, version := range versions {
+			versions[i] = srv.WithMirrorURLs(version, mirrorURL(ctx))
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"versions": versions})
+	})
+	api.POST(rest.EndpointCheckModUpdates.Route, func(ctx *gin.Context) {
+		var req restmodel.ModUpdateCheckRequest
+		if !bindJSONRequest(ctx, &req) {
+			return
+		}
+
+		updates, missing, err := srv.CheckModUpdates(req.Installed)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to check mod updates", "count", len(req.Installed), "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check mod updates"})
+			return
+		}
+		for modID, version := range updates {
+			updates[modID] = srv.WithMirrorURLs(version, mirrorURL(ctx))
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"updates": updates, "missing": missing})
+	})
+	api.GET(rest.EndpointGetModThumbnail.Route, func(ctx *gin.Context) {
+		modID := ctx.Param("mod_id")
+		size, err := restmodel.ParseImageSize(ctx.Query("size"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		if thumbnailURI == nil {
+		variant, err := srv.ModThumbnail(ctx.Request.Context(), modID, size)
+		switch {
+		case errors.Is(err, service.ErrModNotFound), errors.Is(err, service.ErrImageNotFound):
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
 			return
+		case errors.Is(err, service.ErrImagesDisabled), errors.Is(err, service.ErrImageFetchFailed):
+			// Without a stored copy the client can still fetch the original and resize it itself
+			modDetails, err := srv.GetModDetails(modID)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to get mod details", "mod_id", modID, "error", err)
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get mod details"})
+				return
+			}
+			if modDetails.ThumbnailURI == nil {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+				return
+			}
+			ctx.Redirect(http.StatusFound, *modDetails.ThumbnailURI)
+			return
+		case err != nil:
+			slog.ErrorContext(ctx, "Failed to get mod thumbnail", "mod_id", modID, "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get mod thumbnail"})
+			return
 		}
+		// The thumbnail of a mod can be replaced, so clients revalidate it by the ETag
+		serveImageVariant(ctx, variant, "no-cache")
+	})
+	api.GET(rest.EndpointGetModGallery.Route, func(ctx *gin.Context) {
+		modID := ctx.Param("mod_id")
+		gallery, err := srv.ModGallery(modID)
+		switch {
+		case errors.Is(err, service.ErrImagesDisabled):
+			ctx.JSON(http.StatusOK, restmodel.ModGallery{Images: []restmodel.ModImage{}})
+			return
+		case errors.Is(err, service.ErrModNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			slog.ErrorContext(ctx, "Failed to get mod gallery", "mod_id", modID, "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get mod gallery"})
+			return
+		}
+		ctx.JSON(http.StatusOK, restmodel.ModGallery{Images: gallery})
+	})
+	api.GET(rest.EndpointGetModImage.Route, func(ctx *gin.Context) {
+		modID, imageID := ctx.Param("mod_id"), ctx.Param("image_id")
+		size, err := restmodel.ParseImageSize(ctx.Query("size"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		variant, err := srv.ModImage(modID, imageID, size)
+		switch {
+		case errors.Is(err, service.ErrImagesDisabled), errors.Is(err, service.ErrImageNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		case err != nil:
+			slog.ErrorContext(ctx, "Failed to get mod image", "mod_id", modID, "image_id", imageID, "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get mod image"})
+			return
+		}
+		// Images are never changed in place; a replaced image gets a new ID
+		serveImageVariant(ctx, variant, "public, max-age=31536000, immutable")
+	})
+	api.GET(rest.EndpointGetMirroredFile.Route, func(ctx *gin.Context) {
+		fileID := ctx.Param("file_id")
+		f, file, err := srv.OpenMirroredFile(ctx.Request.Context(), fileID)
+		switch {
+		case errors.Is(err, service.ErrMirrorDisabled), errors.Is(err, service.ErrFileNotFound), errors.Is(err, service.ErrFileNotMirrorable):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			slog.ErrorContext(ctx, "Failed to mirror mod file", "file_id", fileID, "error", err)
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch file"})
+			return
+		}
+		defer f.Close()
 
-		ctx.Redirect(http.StatusFound, *thumbnailURI)
+		ctx.Header("ETag", `"`+file.Hashes["sha256"]+`"`)
+		ctx.Header("Cache-Control", "public, max-age=86400")
+		ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}))
+		// ServeContent answers range requests, so clients can resume downloads from the mirror
+		http.ServeContent(ctx.Writer, ctx.Request, file.Filename, file.CreatedAt, f)
 	})
+	registerPublishRoutes(api, srv)
 	api.GET(rest.EndpointHealth.Route, func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
@@ -124,13 +268,23 @@ func router(srv *service.ModService, versionProvider service.VersionInfoProvider
 		ctx.JSON(http.StatusOK, info)
 	})
 	api.POST(rest.EndpointShareGame.Route, func(ctx *gin.Context) {
+		// Leave room for the room fields and the multipart framing around the pack
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, service.MaxAupackSize+64<<10)
 		fileHeader, err := ctx.FormFile("aupack")
 		if err != nil {
-			if errors.Is(err, http.ErrMissingFile) {
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.Is(err, http.ErrMissingFile):
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": "aupack is required"})
-				return
+			case errors.As(err, &maxBytesErr):
+				ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrAupackTooLarge.Error()})
+			default:
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart body"})
 			}
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart body"})
+			return
+		}
+		if fileHeader.Size > service.MaxAupackSize {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrAupackTooLarge.Error()})
 			return
 		}
 		file, err := fileHeader.Open()
@@ -183,9 +337,16 @@ func router(srv *service.ModService, versionProvider service.VersionInfoProvider
 		ip := clientIP(ctx)
 		rs, err := srv.CreateSharedGame(ip, req)
 		if err != nil {
-			if err == service.ErrShareGameRateLimited {
+			switch {
+			case errors.Is(err, service.ErrShareGameRateLimited):
 				ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limited"})
 				return
+			case errors.Is(err, service.ErrAupackTooLarge):
+				ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+				return
+			case errors.Is(err, service.ErrInvalidAupack):
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
 			}
 			slog.ErrorContext(ctx, "Failed to create shared game", "error", err, "ip", ip)
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create shared game"})
@@ -245,8 +406,9 @@ func router(srv *service.ModService, versionProvider service.VersionInfoProvider
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "session_id is required"})
 			return
 		}
-		if ctx.Query("download") != "" {
-			data, err := srv.GetJoinGameDownload(sessionID)
+		if download := ctx.Query("download"); download != "" {
+			// download=ref leaves the pack to the cacheable aupack endpoint; other values inline it for older clients
+			data, err := srv.GetJoinGameDownload(sessionID, download != "ref")
 			if err != nil {
 				switch err {
 				case service.ErrShareGameNotFound:
@@ -259,6 +421,7 @@ func router(srv *service.ModService, versionProvider service.VersionInfoProvider
 				}
 				return
 			}
+			data.AupackURL = absoluteURL(ctx, combinePath(pathPrefix, basePath, rest.EndpointGetSharedAupack.Compile(nil, data.AupackSHA256).URL))
 			ctx.JSON(http.StatusOK, data)
 			return
 		}
@@ -286,6 +449,31 @@ func router(srv *service.ModService, versionProvider service.VersionInfoProvider
 		ctx.String(http.StatusOK, joinGameHTML("", deepLink, true))
 	})
 
+	api.GET(rest.EndpointGetSharedAupack.Route, func(ctx *gin.Context) {
+		hash := ctx.Param("sha256")
+		// Looked up first, so only packs the server has are answered with a cacheable response
+		data, err := srv.GetSharedAupack(hash)
+		if err != nil {
+			if errors.Is(err, service.ErrAupackNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "aupack not found"})
+				return
+			}
+			slog.ErrorContext(ctx, "Failed to get shared aupack", "error", err, "sha256", hash)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get aupack"})
+			return
+		}
+		// Packs are addressed by their content, so the hash is a strong validator and the response never changes
+		etag := `"` + hash + `"`
+		ctx.Header("ETag", etag)
+		ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+		// A client only has the pack if it names its hash; "*" would be answered with an immutable 304 for anything
+		if etagListed(ctx.GetHeader("If-None-Match"), etag) {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+		ctx.Data(http.StatusOK, "application/zip", data)
+	})
+
 	if pathPrefix != "" && pathPrefix != "/" {
 		return http.StripPrefix(pathPrefix, r.Handler())
 	}
@@ -374,3 +562,70 @@ func joinGameHTML(message, deepLink string, success bool) string {
 	}
 	return buf.String()
 }
+
+type validatedRequest interface {
+	Validate() error
+}
+
+// bindJSONRequest decodes and validates a JSON request body, responding with 400 if it is invalid.
+func bindJSONRequest(ctx *gin.Context, req validatedRequest) bool {
+	if err := json.UnmarshalRead(ctx.Request.Body, req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return false
+	}
+	if err := req.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// respondCacheableJSON writes obj like ctx.JSON with an ETag of its content. Clients may keep the response but must
+// revalidate it, and get 304 Not Modified while it is unchanged.
+func respondCacheableJSON(ctx *gin.Context, obj any) {
+	body, err := ginjson.API.Marshal(obj)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encode response", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode response"})
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "no-cache")
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using the weak comparison of RFC 9110.
+func etagMatches(ifNoneMatch, etag string) bool {
+	return strings.TrimSpace(ifNoneMatch) == "*" || etagListed(ifNoneMatch, etag)
+}
+
+// etagListed reports whether an If-None-Match header lists etag itself, without matching "*".
+func etagListed(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// serveImageVariant writes an image variant with its content type, answering conditional requests by its ETag.
+func serveImageVariant(ctx *gin.Context, variant *model.ModImageVariant, cacheControl string) {
+	etag := `"` + variant.ImageID + "-" + variant.Size + `"`
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", cacheControl)
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(http.StatusOK, variant.ContentType, variant.Data)
+}
@@ -3,16 +3,36 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json/v2"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	restcommon "github.com/ikafly144/au_mod_installer/common/rest"
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
+	"github.com/ikafly144/au_mod_installer/pkg/profile"
+	"github.com/ikafly144/au_mod_installer/server/metrics"
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+	gormrepo "github.com/ikafly144/au_mod_installer/server/repository/gorm"
+	"github.com/ikafly144/au_mod_installer/server/repository/memory"
 	"github.com/ikafly144/au_mod_installer/server/service"
 )
 
@@ -22,6 +42,681 @@ func (staticVersionInfoProvider) GetVersionInfo(ctx context.Context) (*restcommo
 	return &restcommon.VersionInfo{}, nil
 }
 
+type searchModRepository struct {
+	repository.ModRepository
+	query restmodel.ModSearchQuery
+	after string
+	limit int
+}
+
+func (r *searchModRepository) SearchMods(query restmodel.ModSearchQuery, after string, limit int) ([]string, string, error) {
+	r.query, r.after, r.limit = query, after, limit
+	return []string{"mod-b", "mod-c"}, "mod-c", nil
+}
+
+func TestRouter_GetModList_Search(t *testing.T) {
+	repo := &searchModRepository{}
+	handler := router(service.NewModService(repo), staticVersionInfoProvider{}, "", "")
+
+	req := httptest.NewRequest(http.MethodGet, "/mods?q=town&sort=name&game_version=2025.3.25&target_platform=x64&feature=direct_join&type=mod&after=mod-a&limit=500", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var rs restmodel.ModListResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rs))
+	assert.Equal(t, restmodel.ModListResult{IDs: []string{"mod-b", "mod-c"}, NextID: "mod-c"}, rs)
+	assert.Equal(t, restmodel.ModSearchQuery{
+		Query:          "town",
+		Type:           restmodel.ModTypeMod,
+		GameVersion:    "2025.3.25",
+		TargetPlatform: restmodel.TargetPlatformX64,
+		Features:       []string{"direct_join"},
+		Sort:           restmodel.ModSortName,
+	}, repo.query)
+	assert.Equal(t, "mod-a", repo.after)
+	assert.Equal(t, 100, repo.limit)
+
+	req = httptest.NewRequest(http.MethodGet, "/mods?sort=downloads", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRouter_GetModDetail_ETag(t *testing.T) {
+	repo := &memoryPublishRepository{mods: map[string]*model.ModDetails{"my-mod": {ID: "my-mod", Name: "My Mod"}}}
+	handler := router(service.NewModService(repo), staticVersionInfoProvider{}, "", "")
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/mod/my-mod", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Body.String(), `"name":"My Mod"`)
+
+	rec = get(`"other", ` + etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	repo.mods["my-mod"].Name = "Renamed"
+	rec = get(etag)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	assert.Contains(t, rec.Body.String(), `"name":"Renamed"`)
+}
+
+func TestRouter_RequestIDAndMetrics(t *testing.T) {
+	repo := &memoryPublishRepository{mods: map[string]*model.ModDetails{"my-mod": {ID: "my-mod", Name: "My Mod"}}}
+	handler := router(service.NewModService(repo), staticVersionInfoProvider{}, "", "/v1")
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/mod/my-mod", nil)
+	req.Header.Set("X-Request-ID", "proxy-id-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "proxy-id-1", rec.Header().Get("X-Request-ID"))
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/mod/my-mod", nil)
+	req.Header.Set("X-Request-ID", "bad id")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Len(t, rec.Header().Get("X-Request-ID"), 24)
+
+	var out strings.Builder
+	_, err := metrics.Default.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), `mus_http_requests_total{method="GET",route="/mod/:mod_id",status="200"}`)
+	assert.Contains(t, out.String(), `mus_http_request_duration_seconds_count{method="GET",route="/mod/:mod_id"}`)
+}
+
+type batchModRepository struct {
+	repository.ModRepository
+	mods     []model.ModDetails
+	versions []model.ModVersionDetails
+}
+
+func (r *batchModRepository) GetModDetailsBatch(modIDs []string) ([]model.ModDetails, error) {
+	return slices.DeleteFunc(slices.Clone(r.mods), func(m model.ModDetails) bool { return !slices.Contains(modIDs, m.ID) }), nil
+}
+
+func (r *batchModRepository) GetModVersionDetailsBatch(keys []repository.ModVersionKey) ([]model.ModVersionDetails, error) {
+	return slices.DeleteFunc(slices.Clone(r.versions), func(v model.ModVersionDetails) bool {
+		return !slices.ContainsFunc(keys, func(key repository.ModVersionKey) bool { return key.Matches(v) })
+	}), nil
+}
+
+func TestRouter_Batch(t *testing.T) {
+	latestA, latestB := "row-a2", "row-b1"
+	repo := &batchModRepository{
+		mods: []model.ModDetails{
+			{ID: "a", Name: "A", LatestVersionID: &latestA, LatestVersionExternal: "v2.0.0"},
+			{ID: "b", Name: "B", LatestVersionID: &latestB, LatestVersionExternal: "v1.0.0"},
+		},
+		versions: []model.ModVersionDetails{
+			{ID: "row-a1", ModID: "a", VersionID: "v1.0.0"},
+			{ID: "row-a2", ModID: "a", VersionID: "v2.0.0"},
+			{ID: "row-b1", ModID: "b", VersionID: "v1.0.0"},
+		},
+	}
+	handler := router(service.NewModService(repo), staticVersionInfoProvider{}, "", "")
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		raw, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("/mods/batch", restmodel.ModBatchRequest{IDs: []string{"b", "missing", "a"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var mods restmodel.ModBatchResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &mods))
+	require.Len(t, mods.Mods, 3)
+	assert.Equal(t, "b", mods.Mods[0].ID)
+	assert.Nil(t, mods.Mods[1])
+	assert.Equal(t, "v2.0.0", mods.Mods[2].LatestVersionID)
+
+	rec = post("/mods/versions/batch", restmodel.ModVersionBatchRequest{Versions: []restmodel.ModVersionRef{
+		{ModID: "a", VersionID: "v1.0.0"},
+		{ModID: "a"},
+		{ModID: "b", VersionID: "v9.0.0"},
+		{ModID: "missing"},
+	}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var versions restmodel.ModVersionBatchResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &versions))
+	require.Len(t, versions.Versions, 4)
+	assert.Equal(t, "v1.0.0", versions.Versions[0].VersionID)
+	assert.Equal(t, "v2.0.0", versions.Versions[1].VersionID)
+	assert.Nil(t, versions.Versions[2])
+	assert.Nil(t, versions.Versions[3])
+
+	rec = post("/mods/updates", restmodel.ModUpdateCheckRequest{Installed: map[string]string{"a": "v1.0.0", "b": "v1.0.0", "missing": "v1.0.0"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var updates restmodel.ModUpdateCheckResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updates))
+	require.Len(t, updates.Updates, 1)
+	assert.Equal(t, "v2.0.0", updates.Updates["a"].VersionID)
+	assert.Equal(t, []string{"missing"}, updates.Missing)
+
+	ids := make([]string, restmodel.MaxBatchSize+1)
+	for i := range ids {
+		ids[i] = fmt.Sprint("mod-", i)
+	}
+	assert.Equal(t, http.StatusBadRequest, post("/mods/batch", restmodel.ModBatchRequest{IDs: ids}).Code)
+	assert.Equal(t, http.StatusBadRequest, post("/mods/versions/batch", restmodel.ModVersionBatchRequest{}).Code)
+}
+
+// memoryPublishRepository keeps mods, versions, tokens and the audit log in memory for the write API tests.
+type memoryPublishRepository struct {
+	repository.ModRepository
+	repository.TokenRepository
+	mods     map[string]*model.ModDetails
+	versions []*model.ModVersionDetails
+	tokens   []model.APIToken
+	audit    []model.AuditLogEntry
+}
+
+func (r *memoryPublishRepository) CreateMod(details *model.ModDetails) (string, error) {
+	r.mods[details.ID] = details
+	return details.ID, nil
+}
+
+func (r *memoryPublishRepository) GetModDetails(modID string) (*model.ModDetails, error) {
+	mod, ok := r.mods[modID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return mod, nil
+}
+
+func (r *memoryPublishRepository) UpdateMod(modID string, details *model.ModDetails) error {
+	if details.Name != "" {
+		r.mods[modID].Name = details.Name
+	}
+	if details.LatestVersionID != nil {
+		r.mods[modID].LatestVersionID = details.LatestVersionID
+	}
+	if details.ThumbnailURI != nil {
+		r.mods[modID].ThumbnailURI = details.ThumbnailURI
+	}
+	return nil
+}
+
+func (r *memoryPublishRepository) UpdateModFields(modID string, updates map[string]any) error {
+	mod := r.mods[modID]
+	for column, value := range updates {
+		switch column {
+		case "name":
+			mod.Name = value.(string)
+		case "license":
+			mod.License = value.(string)
+		case "tags":
+			mod.Tags = value.(model.StringArray)
+		case "thumbnail_uri":
+			uri := value.(string)
+			mod.ThumbnailURI = &uri
+		case "latest_version_id":
+			id := value.(string)
+			mod.LatestVersionID = &id
+		}
+	}
+	return nil
+}
+
+func (r *memoryPublishRepository) CreateModVersion(modID string, details *model.ModVersionDetails) (string, error) {
+	r.versions = append(r.versions, details)
+	return details.VersionID, nil
+}
+
+func (r *memoryPublishRepository) GetModVersionDetails(modID, versionID string) (*model.ModVersionDetails, error) {
+	for _, v := range r.versions {
+		if (repository.ModVersionKey{ModID: modID, VersionID: versionID}).Matches(*v) {
+			return v, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memoryPublishRepository) GetModVersionIds(modID string) ([]string, error) {
+	var ids []string
+	for _, v := range r.versions {
+		if v.ModID == modID {
+			ids = append(ids, v.VersionID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *memoryPublishRepository) CreateModVersionFile(file *model.ModVersionFile) error {
+	for _, v := range r.versions {
+		if v.ID == *file.VersionID {
+			v.Files = append(v.Files, *file)
+		}
+	}
+	return nil
+}
+
+func (r *memoryPublishRepository) SetModVersionStatus(modID, versionID string, status model.VersionStatus, reason string) error {
+	version, err := r.GetModVersionDetails(modID, versionID)
+	if err != nil {
+		return err
+	}
+	version.Status = status
+	version.StatusReason = reason
+	return nil
+}
+
+func (r *memoryPublishRepository) GetAPITokenByHash(hash string) (*model.APIToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memoryPublishRepository) TouchAPIToken(id string, usedAt time.Time) error {
+	return nil
+}
+
+func (r *memoryPublishRepository) CreateAuditLogEntry(entry *model.AuditLogEntry) error {
+	r.audit = append(r.audit, *entry)
+	return nil
+}
+
+type mirrorModRepository struct {
+	repository.ModRepository
+	version model.ModVersionDetails
+}
+
+func (r *mirrorModRepository) GetModVersionDetails(modID, versionID string) (*model.ModVersionDetails, error) {
+	version := r.version
+	return &version, nil
+}
+
+func (r *mirrorModRepository) GetModVersionFile(fileID string) (*model.ModVersionFile, error) {
+	for _, file := range r.version.Files {
+		if file.ID == fileID {
+			return &file, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func TestRouter_MirroredFile(t *testing.T) {
+	content := []byte("mod-file-content")
+	sum := sha256.Sum256(content)
+	var upstreamHits int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHits++
+		if r.URL.Path == "/corrupted.zip" {
+			_, _ = w.Write([]byte("corrupted-content"))
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer upstream.Close()
+
+	file := func(id, path string) model.ModVersionFile {
+		return model.ModVersionFile{
+			ID:        id,
+			Filename:  id + ".zip",
+			Size:      int64(len(content)),
+			Hashes:    model.StringMap{"sha256": hex.EncodeToString(sum[:])},
+			Downloads: model.StringArray{upstream.URL + path},
+		}
+	}
+	corrupted := file("corrupted", "/corrupted.zip")
+	otherSum := sha256.Sum256([]byte("other-content"))
+	corrupted.Hashes = model.StringMap{"sha256": hex.EncodeToString(otherSum[:])}
+	repo := &mirrorModRepository{version: model.ModVersionDetails{
+		ModID:     "my-mod",
+		VersionID: "v1.0.0",
+		Files: []model.ModVersionFile{
+			file("good", "/good.zip"),
+			corrupted,
+			{ID: "unhashed", Filename: "unhashed.zip", Downloads: model.StringArray{upstream.URL + "/good.zip"}},
+		},
+	}}
+	mirrorDir := t.TempDir()
+	handler := router(service.NewModService(repo, service.WithFileMirror(mirrorDir, upstream.Client())), staticVersionInfoProvider{}, "", "")
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/mod/my-mod/version/v1.0.0")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var version restmodel.ModVersionDetails
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &version))
+	assert.Equal(t, []string{upstream.URL + "/good.zip", "http://example.com/file/good"}, version.Files[0].Downloads)
+	assert.Equal(t, []string{upstream.URL + "/good.zip"}, version.Files[2].Downloads)
+	assert.Len(t, repo.version.Files[0].Downloads, 1, "the stored version is left alone")
+
+	for range 2 {
+		rec = get("/file/good")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, content, rec.Body.Bytes())
+	}
+	assert.Equal(t, 1, upstreamHits)
+
+	req := httptest.NewRequest(http.MethodGet, "/file/good", nil)
+	req.Header.Set("Range", "bytes=4-")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, content[4:], rec.Body.Bytes())
+
+	assert.Equal(t, http.StatusBadGateway, get("/file/corrupted").Code)
+	assert.False(t, modmgr.NewBlobStore(mirrorDir).Has(hex.EncodeToString(otherSum[:])), "unverified downloads never enter the store")
+	assert.Equal(t, http.StatusNotFound, get("/file/unhashed").Code)
+	assert.Equal(t, http.StatusNotFound, get("/file/missing").Code)
+}
+
+func TestRouter_PublishMod(t *testing.T) {
+	secret, token, err := service.NewAPIToken("ci", []string{"my-*"})
+	require.NoError(t, err)
+	revokedSecret, revoked, err := service.NewAPIToken("old", []string{"*"})
+	require.NoError(t, err)
+	_, _, err = service.NewAPIToken("bad", []string{"my-*", "my-["})
+	require.ErrorIs(t, err, path.ErrBadPattern)
+	revokedAt := time.Now()
+	revoked.RevokedAt = &revokedAt
+	repo := &memoryPublishRepository{
+		mods:   map[string]*model.ModDetails{"other": {ID: "other", Name: "Other"}},
+		tokens: []model.APIToken{*token, *revoked},
+	}
+	handler := router(service.NewModService(repo, service.WithTokenRepository(repo)), staticVersionInfoProvider{}, "", "")
+	send := func(method, path, secret string, body any) *httptest.ResponseRecorder {
+		raw, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	createMod := restmodel.ModCreateRequest{ID: "my-mod", Name: "My Mod", Author: "me", Tags: []string{"roles"}, License: "GPL-3.0-only"}
+	name := "Mine"
+
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/mods", "", createMod).Code)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/mods", "mus_wrong", createMod).Code)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/mods", revokedSecret, createMod).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/mods", secret, restmodel.ModCreateRequest{ID: "their-mod", Name: "x", Author: "x"}).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPatch, "/mod/other", secret, restmodel.ModUpdateRequest{Name: &name}).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/mods", secret, restmodel.ModCreateRequest{ID: "my-mod"}).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/mods", secret, restmodel.ModCreateRequest{ID: "my-mod", Name: "x", Author: "x", License: "MIT OR"}).Code)
+
+	rec := send(http.MethodPost, "/mods", secret, createMod)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, model.ModTypeMod, repo.mods["my-mod"].Type)
+	assert.Equal(t, "GPL-3.0-only", repo.mods["my-mod"].License)
+	tags := []string{"roles", "cosmetics"}
+	rec = send(http.MethodPatch, "/mod/my-mod", secret, restmodel.ModUpdateRequest{Tags: &tags})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"tags":["roles","cosmetics"]`)
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/mods", secret, createMod).Code)
+
+	file := restmodel.ModVersionFile{
+		Filename:    "MyMod.dll",
+		ContentType: restmodel.ContentTypePluginDll,
+		Size:        4,
+		Hashes:      map[string]string{"sha256": strings.Repeat("ab", 32)},
+		Downloads:   []string{"https://example.com/MyMod.dll"},
+	}
+	noHash := file
+	noHash.Hashes = nil
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/mod/my-mod/versions", secret, restmodel.ModVersionCreateRequest{VersionID: "v1.0.0", Files: []restmodel.ModVersionFile{noHash}}).Code)
+
+	rec = send(http.MethodPost, "/mod/my-mod/versions", secret, restmodel.ModVersionCreateRequest{VersionID: "v1.0.0", Files: []restmodel.ModVersionFile{file}, Changelog: "- First release", SetLatest: true})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Len(t, repo.versions, 1)
+	assert.Equal(t, "- First release", repo.versions[0].Changelog)
+	assert.NotNil(t, repo.versions[0].ReleasedAt)
+	assert.Equal(t, repo.versions[0].ID, *repo.mods["my-mod"].LatestVersionID)
+	assert.Equal(t, model.TargetPlatformAny, repo.versions[0].Files[0].TargetPlatform)
+
+	rec = send(http.MethodPost, "/mod/my-mod/version/v1.0.0/files", secret, file)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Len(t, repo.versions[0].Files, 2)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/mod/my-mod/version/v9.9.9/files", secret, file).Code)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/mod/my-mod/version/v1.0.0/status", secret, restmodel.ModVersionStatusRequest{Status: restmodel.VersionStatusYanked}).Code)
+	rec = send(http.MethodPut, "/mod/my-mod/version/v1.0.0/status", secret, restmodel.ModVersionStatusRequest{Status: restmodel.VersionStatusYanked, Reason: "broken build"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"status":"yanked"`)
+	assert.Equal(t, "broken build", repo.versions[0].StatusReason)
+	latest := "v1.0.0"
+	assert.Equal(t, http.StatusConflict, send(http.MethodPatch, "/mod/my-mod", secret, restmodel.ModUpdateRequest{LatestVersion: &latest}).Code)
+
+	dependsOn := func(modID, constraint string) restmodel.ModVersionCreateRequest {
+		return restmodel.ModVersionCreateRequest{VersionID: "v1.1.0", Dependencies: []restmodel.ModVersionDependency{{ModID: modID, VersionID: constraint, DependencyType: restmodel.DependencyTypeRequired}}}
+	}
+	rec = send(http.MethodPost, "/mod/my-mod/versions", secret, dependsOn("missing", "any"))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "mod missing does not exist")
+	rec = send(http.MethodPost, "/mod/my-mod/versions", secret, dependsOn("other", ">=v1.0.0"))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), `no version of other satisfies`)
+	otherID := "other-1"
+	repo.versions = append(repo.versions, &model.ModVersionDetails{ID: otherID, ModID: "other", VersionID: "v1.0.0", Dependencies: model.DependencyArray{{ModID: "my-mod", VersionID: "any", DependencyType: model.DependencyTypeRequired}}})
+	repo.mods["other"].LatestVersionID = &otherID
+	assert.Equal(t, http.StatusUnprocessableEntity, send(http.MethodPost, "/mod/my-mod/versions", secret, dependsOn("other", ">=v1.0.0, <<v2")).Code)
+	rec = send(http.MethodPost, "/mod/my-mod/versions", secret, dependsOn("other", "latest"))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	var depErr service.DependencyError
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &depErr))
+	assert.Equal(t, []string{"dependency cycle: my-mod -> other -> my-mod"}, depErr.Problems)
+	repo.versions[len(repo.versions)-1].Dependencies = nil
+	rec = send(http.MethodPost, "/mod/my-mod/versions", secret, dependsOn("other", "latest"))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	actions := make([]string, len(repo.audit))
+	for i, entry := range repo.audit {
+		actions[i] = entry.Action
+		assert.Equal(t, token.ID, entry.TokenID)
+		assert.Equal(t, "my-mod", entry.ModID)
+	}
+	assert.Equal(t, []string{"mod.create", "mod.update", "version.create", "file.create", "version.status", "version.create"}, actions)
+	assert.Contains(t, repo.audit[2].Detail, `"version_id":"v1.0.0"`)
+}
+
+func TestRouter_PublishMod_ClearsMetadata(t *testing.T) {
+	db, err := gormrepo.Open("sqlite::memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	repo := gormrepo.NewGormRepository(db)
+	require.NoError(t, repo.Migrate())
+	secret, token, err := service.NewAPIToken("ci", []string{"my-*"})
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateAPIToken(token))
+	handler := router(service.NewModService(repo, service.WithTokenRepository(repo)), staticVersionInfoProvider{}, "", "")
+	send := func(method, path string, body any) {
+		t.Helper()
+		raw, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+secret)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Less(t, rec.Code, 300, rec.Body.String())
+	}
+
+	send(http.MethodPost, "/mods", restmodel.ModCreateRequest{
+		ID:          "my-mod",
+		Name:        "My Mod",
+		Description: "Roles",
+		Author:      "me",
+		Tags:        []string{"roles"},
+		Links:       restmodel.ModLinks{Homepage: "https://example.com", Discord: "https://discord.gg/mymod"},
+		License:     "MIT",
+	})
+	send(http.MethodPost, "/mod/my-mod/versions", restmodel.ModVersionCreateRequest{VersionID: "v1.0.0", Changelog: "- First release"})
+
+	empty := ""
+	send(http.MethodPatch, "/mod/my-mod", restmodel.ModUpdateRequest{Description: &empty, License: &empty, Links: &restmodel.ModLinks{Discord: "https://discord.gg/mymod"}, Tags: &[]string{}})
+	send(http.MethodPatch, "/mod/my-mod/version/v1.0.0", restmodel.ModVersionUpdateRequest{Changelog: &empty})
+
+	mod, err := repo.GetModDetails("my-mod")
+	require.NoError(t, err)
+	assert.Empty(t, mod.Description)
+	assert.Empty(t, mod.License)
+	assert.Empty(t, mod.Tags)
+	assert.Equal(t, model.ModLinks{Discord: "https://discord.gg/mymod"}, mod.Links)
+	version, err := repo.GetModVersionDetails("my-mod", "v1.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, version.Changelog)
+}
+
+func TestRouter_ModImages(t *testing.T) {
+	photo := testImage(t, 400, 200, true)
+	var upstreamHits int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHits++
+		if r.URL.Path != "/photo.png" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(photo)
+	}))
+	defer upstream.Close()
+
+	secret, token, err := service.NewAPIToken("ci", []string{"my-mod"})
+	require.NoError(t, err)
+	thumbnailURL, brokenURL := upstream.URL+"/photo.png", upstream.URL+"/missing.png"
+	repo := &memoryPublishRepository{
+		mods: map[string]*model.ModDetails{
+			"my-mod": {ID: "my-mod", Name: "My Mod", ThumbnailURI: &thumbnailURL},
+			"broken": {ID: "broken", Name: "Broken", ThumbnailURI: &brokenURL},
+		},
+		tokens: []model.APIToken{*token},
+	}
+	handler := router(service.NewModService(repo, service.WithTokenRepository(repo), service.WithImageRepository(memory.NewImageRepository(), upstream.Client())), staticVersionInfoProvider{}, "", "")
+	send := func(method, path, contentType string, body []byte, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		return send(http.MethodGet, path, "", nil, header...)
+	}
+	decode := func(rec *httptest.ResponseRecorder) (string, int, int) {
+		t.Helper()
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		config, format, err := image.DecodeConfig(bytes.NewReader(rec.Body.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, "image/"+format, rec.Header().Get("Content-Type"))
+		return format, config.Width, config.Height
+	}
+
+	// A thumbnail set by URL is fetched once and resized
+	format, width, height := decode(get("/mod/my-mod/thumbnail?size=icon"))
+	assert.Equal(t, []any{"jpeg", 64, 64}, []any{format, width, height})
+	_, width, height = decode(get("/mod/my-mod/thumbnail?size=list"))
+	assert.Equal(t, []int{256, 256}, []int{width, height})
+	rec := get("/mod/my-mod/thumbnail")
+	_, width, height = decode(rec)
+	assert.Equal(t, []int{400, 200}, []int{width, height}, "detail images are not scaled up")
+	assert.Equal(t, 1, upstreamHits)
+	assert.Equal(t, http.StatusNotModified, get("/mod/my-mod/thumbnail", "If-None-Match", rec.Header().Get("ETag")).Code)
+	assert.Equal(t, http.StatusBadRequest, get("/mod/my-mod/thumbnail?size=huge").Code)
+	assert.Equal(t, http.StatusNotFound, get("/mod/missing/thumbnail").Code)
+
+	// A thumbnail that cannot be fetched still sends the client to its URL
+	rec = get("/mod/broken/thumbnail")
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, brokenURL, rec.Header().Get("Location"))
+
+	rec = send(http.MethodPut, "/mod/my-mod/thumbnail", "image/png", testImage(t, 100, 300, false))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	format, width, height = decode(get("/mod/my-mod/thumbnail?size=list"))
+	assert.Equal(t, []any{"png", 256, 256}, []any{format, width, height}, "transparent images stay PNG")
+	assert.Equal(t, http.StatusUnprocessableEntity, send(http.MethodPut, "/mod/my-mod/thumbnail", "image/png", []byte("not an image")).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPut, "/mod/broken/thumbnail", "image/png", photo).Code)
+
+	rec = send(http.MethodPost, "/mod/my-mod/gallery?caption=Lobby", "image/png", testImage(t, 2560, 1440, true))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var uploaded restmodel.ModImage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &uploaded))
+	assert.Equal(t, []int{1280, 720}, []int{uploaded.Width, uploaded.Height})
+	body, err := json.Marshal(restmodel.ModImageRequest{URL: thumbnailURL, Caption: "Meeting"})
+	require.NoError(t, err)
+	rec = send(http.MethodPost, "/mod/my-mod/gallery", "application/json", body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = get("/mod/my-mod/gallery")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var gallery restmodel.ModGallery
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &gallery))
+	require.Len(t, gallery.Images, 2)
+	assert.Equal(t, []string{"Lobby", "Meeting"}, []string{gallery.Images[0].Caption, gallery.Images[1].Caption})
+
+	rec = get("/mod/my-mod/image/" + uploaded.ID + "?size=detail")
+	_, width, height = decode(rec)
+	assert.Equal(t, []int{1280, 720}, []int{width, height})
+	assert.Contains(t, rec.Header().Get("Cache-Control"), "immutable")
+	assert.Equal(t, http.StatusNotFound, get("/mod/broken/image/"+uploaded.ID).Code)
+
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/mod/my-mod/image/"+uploaded.ID, "application/json", nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/mod/my-mod/image/"+uploaded.ID, "application/json", nil).Code)
+	assert.Equal(t, http.StatusNotFound, get("/mod/my-mod/image/"+uploaded.ID).Code)
+
+	actions := make([]string, len(repo.audit))
+	for i, entry := range repo.audit {
+		actions[i] = entry.Action
+	}
+	assert.Equal(t, []string{"image.thumbnail", "image.add", "image.add", "image.delete"}, actions)
+
+	// Servers that do not host images keep redirecting to the thumbnail URL
+	plain := router(service.NewModService(repo), staticVersionInfoProvider{}, "", "")
+	rec = httptest.NewRecorder()
+	plain.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/mod/my-mod/thumbnail?size=icon", nil))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, thumbnailURL, rec.Header().Get("Location"))
+}
+
+// testImage encodes a PNG of the size, with a transparent pixel unless it is opaque.
+func testImage(t *testing.T, width, height int, opaque bool) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 200, G: 40, B: 40, A: 255}), image.Point{}, draw.Src)
+	if !opaque {
+		img.Set(0, 0, color.Transparent)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
 func TestRouter_ShareGame_AcceptsMultipartFormData(t *testing.T) {
 	srv := service.NewModService(nil)
 	handler := router(srv, staticVersionInfoProvider{}, "", "")
@@ -31,7 +726,7 @@ func TestRouter_ShareGame_AcceptsMultipartFormData(t *testing.T) {
 
 	part, err := writer.CreateFormFile("aupack", "test.aupack")
 	require.NoError(t, err)
-	_, err = part.Write([]byte("pack-data"))
+	_, err = part.Write(testAupack(t))
 	require.NoError(t, err)
 	require.NoError(t, writer.WriteField("lobby_code", "ABCD"))
 	require.NoError(t, writer.WriteField("server_ip", "127.0.0.1"))
@@ -64,6 +759,81 @@ func TestRouter_ShareGame_AcceptsMultipartFormData(t *testing.T) {
 	assert.Equal(t, "2024.3.5", downloadRs.Room.GameVersion)
 }
 
+func TestRouter_ShareGame_AupackByReference(t *testing.T) {
+	handler := router(service.NewModService(nil), staticVersionInfoProvider{}, "/api", "/v1")
+	share := func(aupack []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("aupack", "test.aupack")
+		require.NoError(t, err)
+		_, err = part.Write(aupack)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/share_game", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, share([]byte("not-a-zip")).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, share(make([]byte, service.MaxAupackSize+1)).Code)
+
+	aupack := testAupack(t)
+	rec := share(aupack)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var rs restcommon.ShareGameResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rs))
+
+	joinRec := httptest.NewRecorder()
+	handler.ServeHTTP(joinRec, httptest.NewRequest(http.MethodGet, "/api/v1/join_game?session_id="+rs.SessionID+"&download=ref", nil))
+	require.Equal(t, http.StatusOK, joinRec.Code)
+	var download restcommon.JoinGameDownloadResponse
+	require.NoError(t, json.Unmarshal(joinRec.Body.Bytes(), &download))
+	assert.Empty(t, download.Aupack)
+	assert.Equal(t, int64(len(aupack)), download.AupackSize)
+	assert.Equal(t, "http://example.com/api/v1/aupack/"+download.AupackSHA256, download.AupackURL)
+
+	aupackRec := httptest.NewRecorder()
+	handler.ServeHTTP(aupackRec, httptest.NewRequest(http.MethodGet, "/api/v1/aupack/"+download.AupackSHA256, nil))
+	require.Equal(t, http.StatusOK, aupackRec.Code)
+	assert.Equal(t, aupack, aupackRec.Body.Bytes())
+	etag := aupackRec.Header().Get("ETag")
+	assert.Equal(t, `"`+download.AupackSHA256+`"`, etag)
+
+	cachedReq := httptest.NewRequest(http.MethodGet, "/api/v1/aupack/"+download.AupackSHA256, nil)
+	cachedReq.Header.Set("If-None-Match", etag)
+	cachedRec := httptest.NewRecorder()
+	handler.ServeHTTP(cachedRec, cachedReq)
+	assert.Equal(t, http.StatusNotModified, cachedRec.Code)
+	assert.Empty(t, cachedRec.Body.Bytes())
+
+	anyReq := httptest.NewRequest(http.MethodGet, "/api/v1/aupack/"+download.AupackSHA256, nil)
+	anyReq.Header.Set("If-None-Match", "*")
+	anyRec := httptest.NewRecorder()
+	handler.ServeHTTP(anyRec, anyReq)
+	assert.Equal(t, http.StatusOK, anyRec.Code)
+	assert.Equal(t, aupack, anyRec.Body.Bytes())
+
+	// Packs the server does not have are never answered with a cacheable 304
+	for _, hash := range []string{strings.Repeat("0", 64), "not-a-hash"} {
+		missingReq := httptest.NewRequest(http.MethodGet, "/api/v1/aupack/"+hash, nil)
+		missingReq.Header.Set("If-None-Match", `"`+hash+`"`)
+		missingRec := httptest.NewRecorder()
+		handler.ServeHTTP(missingRec, missingReq)
+		assert.Equal(t, http.StatusNotFound, missingRec.Code, hash)
+		assert.Empty(t, missingRec.Header().Get("Cache-Control"), hash)
+	}
+
+	// Older clients still get the pack inline
+	inlineRec := httptest.NewRecorder()
+	handler.ServeHTTP(inlineRec, httptest.NewRequest(http.MethodGet, "/api/v1/join_game?session_id="+rs.SessionID+"&download=1", nil))
+	require.Equal(t, http.StatusOK, inlineRec.Code)
+	require.NoError(t, json.Unmarshal(inlineRec.Body.Bytes(), &download))
+	assert.Equal(t, aupack, download.Aupack)
+}
+
 func TestRouter_ShareGame_RejectsInvalidServerPort(t *testing.T) {
 	srv := service.NewModService(nil)
 	handler := router(srv, staticVersionInfoProvider{}, "", "")
@@ -137,7 +907,7 @@ func TestRouter_JoinGame_HTML(t *testing.T) {
 		writer := multipart.NewWriter(body)
 		part, err := writer.CreateFormFile("aupack", "test.aupack")
 		require.NoError(t, err)
-		_, err = part.Write([]byte("pack-data"))
+		_, err = part.Write(testAupack(t))
 		require.NoError(t, err)
 		require.NoError(t, writer.Close())
 
@@ -174,3 +944,64 @@ func TestRouter_JoinGame_HTML(t *testing.T) {
 		assert.Contains(t, joinRec.Body.String(), "error_type=session_not_found")
 	})
 }
+
+func TestRouter_ShareGame_SharedStore(t *testing.T) {
+	store, packs := memory.NewSharedGameRepository(), memory.NewAupackRepository()
+	first := router(service.NewModService(nil, service.WithSharedGameRepository(store), service.WithAupackRepository(packs)), staticVersionInfoProvider{}, "", "")
+	second := router(service.NewModService(nil, service.WithSharedGameRepository(store), service.WithAupackRepository(packs)), staticVersionInfoProvider{}, "", "")
+
+	share := func(handler http.Handler, lobbyCode string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("aupack", "test.aupack")
+		require.NoError(t, err)
+		_, err = part.Write(testAupack(t))
+		require.NoError(t, err)
+		require.NoError(t, writer.WriteField("lobby_code", lobbyCode))
+		require.NoError(t, writer.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/share_game", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := share(first, "ABCD")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var rs restcommon.ShareGameResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rs))
+
+	// The session created through one instance is served and guarded by the other
+	joinRec := httptest.NewRecorder()
+	second.ServeHTTP(joinRec, httptest.NewRequest(http.MethodGet, "/join_game?session_id="+rs.SessionID+"&download=1", nil))
+	assert.Equal(t, http.StatusOK, joinRec.Code)
+
+	deleteRec := httptest.NewRecorder()
+	second.ServeHTTP(deleteRec, httptest.NewRequest(http.MethodDelete, "/share_game?session_id="+rs.SessionID+"&host_key=wrong", nil))
+	assert.Equal(t, http.StatusForbidden, deleteRec.Code)
+
+	// Sharing the same room again returns the existing session
+	rec = share(second, "ABCD")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var again restcommon.ShareGameResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &again))
+	assert.Equal(t, rs.SessionID, again.SessionID)
+
+	// The rate limit counts requests made to either instance
+	for i := range 8 {
+		require.Equal(t, http.StatusOK, share(first, fmt.Sprintf("ROOM%d", i)).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, share(second, "LAST").Code)
+
+	deleteRec = httptest.NewRecorder()
+	first.ServeHTTP(deleteRec, httptest.NewRequest(http.MethodDelete, "/share_game?session_id="+rs.SessionID+"&host_key="+rs.HostKey, nil))
+	assert.Equal(t, http.StatusNotFound, deleteRec.Code, "the session was replaced by the later shares")
+}
+
+func testAupack(t *testing.T) []byte {
+	t.Helper()
+	aupack, err := profile.EncodeSharedArchive(profile.SharedProfile{Name: "Shared"}, nil)
+	require.NoError(t, err)
+	return aupack
+}
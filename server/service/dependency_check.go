@@ -0,0 +1,264 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+// DependencyError lists what is wrong with the dependencies of a version being published.
+type DependencyError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *DependencyError) Error() string {
+	return "invalid dependencies: " + strings.Join(e.Problems, "; ")
+}
+
+// ValidateDependencies checks the dependencies of a version before it is published. Every referenced mod must exist,
+// every constraint must parse and match an existing version, the edges on one mod must not contradict each other, and
+// the version must resolve against the registry without a dependency cycle. The problems are returned as a
+// *DependencyError.
+func (s *ModService) ValidateDependencies(modID, versionID string, deps model.DependencyArray) error {
+	var problems []string
+	byMod := make(map[string][]model.ModVersionDependency)
+	for _, dep := range deps {
+		switch {
+		case dep.ModID == modID:
+			problems = append(problems, fmt.Sprintf("%s depends on itself", modID))
+		case !knownDependencyType(dep.DependencyType):
+			problems = append(problems, fmt.Sprintf("%s: unknown dependency type %q", dep.ModID, dep.DependencyType))
+		default:
+			byMod[dep.ModID] = append(byMod[dep.ModID], dep)
+		}
+	}
+
+	for _, target := range slices.Sorted(maps.Keys(byMod)) {
+		targetProblems, err := s.checkDependencyTarget(target, byMod[target])
+		if err != nil {
+			return err
+		}
+		problems = append(problems, targetProblems...)
+	}
+	if len(problems) > 0 {
+		return &DependencyError{Problems: problems}
+	}
+
+	// Dry run of the resolver the client uses, against the registry itself
+	candidate := modmgr.ModVersion{ModVersionDetails: restmodel.ModVersionDetails{
+		ModID:        modID,
+		VersionID:    versionID,
+		Dependencies: toRestDependencies(deps),
+	}}
+	resolved, err := modmgr.ResolveDependencies([]modmgr.ModVersion{candidate}, repositoryVersionProvider{repo: s.repo})
+	var resolutionErr *modmgr.ResolutionError
+	switch {
+	case errors.As(err, &resolutionErr):
+		return &DependencyError{Problems: []string{"dependencies cannot be resolved: " + resolutionErr.Summary()}}
+	case err != nil:
+		return fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+	if cycle := dependencyCycle(modID, resolved); cycle != nil {
+		return &DependencyError{Problems: []string{"dependency cycle: " + strings.Join(cycle, " -> ")}}
+	}
+	return nil
+}
+
+// checkDependencyTarget checks the edges pointing at one mod against the versions it has.
+func (s *ModService) checkDependencyTarget(target string, edges []model.ModVersionDependency) ([]string, error) {
+	mod, err := s.repo.GetModDetails(target)
+	if errors.Is(err, repository.ErrNotFound) {
+		return []string{fmt.Sprintf("mod %s does not exist", target)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mod %s: %w", target, err)
+	}
+	versionIDs, err := s.repo.GetModVersionIds(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of %s: %w", target, err)
+	}
+	var latestID string
+	if mod.LatestVersionID != nil {
+		latest, err := s.repo.GetModVersionDetails(target, *mod.LatestVersionID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("failed to get latest version of %s: %w", target, err)
+		}
+		if latest != nil {
+			latestID = latest.VersionID
+		}
+	}
+	matching := func(constraint string) []string {
+		return slices.DeleteFunc(slices.Clone(versionIDs), func(id string) bool {
+			return !modmgr.MatchesConstraint(constraint, id, latestID)
+		})
+	}
+
+	var problems []string
+	types := make(map[model.DependencyType]bool)
+	allowed := slices.Clone(versionIDs)
+	var conflicting []string
+	for _, edge := range edges {
+		depType := edge.DependencyType
+		if depType == "" {
+			depType = model.DependencyTypeRequired
+		}
+		types[depType] = true
+		if err := modmgr.ValidateConstraint(edge.VersionID); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", target, err))
+			continue
+		}
+		switch depType {
+		case model.DependencyTypeRequired, model.DependencyTypeOptional:
+			matched := matching(edge.VersionID)
+			if len(matched) == 0 {
+				problems = append(problems, fmt.Sprintf("no version of %s satisfies %q", target, edge.VersionID))
+				continue
+			}
+			if depType == model.DependencyTypeRequired {
+				allowed = slices.DeleteFunc(allowed, func(id string) bool { return !slices.Contains(matched, id) })
+			}
+		case model.DependencyTypeConflict:
+			conflicting = append(conflicting, matching(edge.VersionID)...)
+		}
+	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	if types[model.DependencyTypeConflict] && types[model.DependencyTypeEmbedded] {
+		problems = append(problems, fmt.Sprintf("%s is both embedded and in conflict", target))
+	}
+	if types[model.DependencyTypeRequired] {
+		switch {
+		case len(allowed) == 0:
+			problems = append(problems, fmt.Sprintf("no version of %s satisfies every required constraint", target))
+		case !slices.ContainsFunc(allowed, func(id string) bool { return !slices.Contains(conflicting, id) }):
+			problems = append(problems, fmt.Sprintf("every version of %s allowed by the required constraints is in conflict", target))
+		}
+	}
+	return problems, nil
+}
+
+// dependencyCycle returns a path of required dependencies from modID back to itself among the resolved versions.
+func dependencyCycle(modID string, resolved map[string]modmgr.ModVersion) []string {
+	seen := make(map[string]bool)
+	var walk func(path []string) []string
+	walk = func(path []string) []string {
+		for _, dep := range resolved[path[len(path)-1]].Dependencies {
+			if dep.DependencyType != "" && dep.DependencyType != restmodel.DependencyTypeRequired {
+				continue
+			}
+			if dep.ModID == modID {
+				return append(path, modID)
+			}
+			if seen[dep.ModID] {
+				continue
+			}
+			seen[dep.ModID] = true
+			if cycle := walk(append(slices.Clone(path), dep.ModID)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return walk([]string{modID})
+}
+
+func knownDependencyType(t model.DependencyType) bool {
+	switch t {
+	case "", model.DependencyTypeRequired, model.DependencyTypeOptional, model.DependencyTypeConflict, model.DependencyTypeEmbedded:
+		return true
+	}
+	return false
+}
+
+// repositoryVersionProvider serves the resolver from the registry.
+type repositoryVersionProvider struct {
+	repo repository.ModRepository
+}
+
+func (p repositoryVersionProvider) GetModVersion(modID, versionID string) (*modmgr.ModVersion, error) {
+	version, err := p.repo.GetModVersionDetails(modID, versionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &modmgr.ModVersion{ModVersionDetails: toRestVersion(*version)}, nil
+}
+
+func (p repositoryVersionProvider) GetLatestModVersion(modID string) (*modmgr.ModVersion, error) {
+	mod, err := p.repo.GetModDetails(modID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mod.LatestVersionID == nil {
+		return nil, nil
+	}
+	return p.GetModVersion(modID, *mod.LatestVersionID)
+}
+
+func (p repositoryVersionProvider) GetModVersionIDs(modID string, limit int, after string) ([]string, error) {
+	return p.repo.GetModVersionIds(modID)
+}
+
+func toRestVersion(v model.ModVersionDetails) restmodel.ModVersionDetails {
+	version := restmodel.ModVersionDetails{
+		VersionID:    v.VersionID,
+		ModID:        v.ModID,
+		GameVersions: v.GameVersions,
+		Dependencies: toRestDependencies(v.Dependencies),
+		Features:     v.Features,
+		Status:       restmodel.VersionStatus(v.Status),
+		StatusReason: v.StatusReason,
+		Changelog:    v.Changelog,
+		CreatedAt:    v.CreatedAt,
+		UpdatedAt:    v.UpdatedAt,
+	}
+	if v.ReleasedAt != nil {
+		version.ReleasedAt = *v.ReleasedAt
+	}
+	for _, f := range v.Files {
+		file := restmodel.ModVersionFile{
+			ID:             f.ID,
+			Filename:       f.Filename,
+			ContentType:    restmodel.ContentType(f.ContentType),
+			Size:           f.Size,
+			TargetPlatform: restmodel.TargetPlatform(f.TargetPlatform),
+			Hashes:         f.Hashes,
+			Downloads:      f.Downloads,
+			CreatedAt:      f.CreatedAt,
+		}
+		if f.ExtractPath != nil {
+			file.ExtractPath = *f.ExtractPath
+		}
+		version.Files = append(version.Files, file)
+	}
+	return version
+}
+
+func toRestDependencies(deps model.DependencyArray) []restmodel.ModVersionDependency {
+	if deps == nil {
+		return nil
+	}
+	result := make([]restmodel.ModVersionDependency, len(deps))
+	for i, dep := range deps {
+		result[i] = restmodel.ModVersionDependency{
+			ModID:          dep.ModID,
+			VersionID:      dep.VersionID,
+			DependencyType: restmodel.DependencyType(dep.DependencyType),
+		}
+	}
+	return result
+}
@@ -0,0 +1,450 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+// MaxImageSize is the largest image accepted for a thumbnail or gallery.
+const MaxImageSize = 8 << 20 // 8 MiB
+
+const (
+	// maxImagePixels rejects images that are small on the wire but huge once decoded
+	maxImagePixels    = 40_000_000
+	imageFetchTimeout = 30 * time.Second
+	// thumbnailRetryAfter keeps a thumbnail URL that failed to fetch from being fetched again on every request
+	thumbnailRetryAfter = 5 * time.Minute
+	jpegQuality         = 85
+)
+
+var (
+	ErrImagesDisabled   = errors.New("image hosting is disabled")
+	ErrImageNotFound    = errors.New("image not found")
+	ErrInvalidImage     = errors.New("invalid image")
+	ErrImageTooLarge    = fmt.Errorf("image is larger than %d bytes", MaxImageSize)
+	ErrImageFetchFailed = errors.New("failed to fetch image")
+	ErrGalleryFull      = fmt.Errorf("gallery already has %d images", restmodel.MaxGalleryImages)
+)
+
+// imageVariants are generated for every hosted image.
+var imageVariants = []struct {
+	size          restmodel.ImageSize
+	width, height int
+	// crop fills the whole size, cutting the edges of the image off; otherwise the image is scaled to fit
+	crop bool
+}{
+	{restmodel.ImageSizeIcon, 64, 64, true},
+	{restmodel.ImageSizeList, 256, 256, true},
+	{restmodel.ImageSizeDetail, 1280, 720, false},
+}
+
+// WithImageRepository hosts the thumbnails and galleries of mods in images. Thumbnails of mods that only have a
+// thumbnail URL are fetched with client on first use. As those URLs are set by publishers, client should be one from
+// NewPublicHTTPClient.
+func WithImageRepository(images repository.ImageRepository, client *http.Client) ModServiceOption {
+	return func(s *ModService) {
+		s.images = &imageStore{
+			repo:     images,
+			client:   client,
+			fetching: make(map[string]*thumbnailFetch),
+			failed:   make(map[string]time.Time),
+		}
+	}
+}
+
+type imageStore struct {
+	repo   repository.ImageRepository
+	client *http.Client
+
+	mu       sync.Mutex
+	fetching map[string]*thumbnailFetch
+	failed   map[string]time.Time
+}
+
+type thumbnailFetch struct {
+	done  chan struct{}
+	image *model.ModImage
+	err   error
+}
+
+// ImageSource is an uploaded image, or the URL to fetch it from.
+type ImageSource struct {
+	Data []byte
+	URL  string
+}
+
+// ModThumbnail returns a variant of the thumbnail of a mod. A thumbnail set only by URL is fetched and stored first;
+// ErrImageFetchFailed is returned when that fails, so the caller can send the client to the URL instead.
+func (s *ModService) ModThumbnail(ctx context.Context, modID string, size restmodel.ImageSize) (*model.ModImageVariant, error) {
+	if s.images == nil {
+		return nil, ErrImagesDisabled
+	}
+	mod, err := s.lookupMod(modID)
+	if err != nil {
+		return nil, err
+	}
+	var source string
+	if mod.ThumbnailURI != nil {
+		source = *mod.ThumbnailURI
+	}
+	thumbnail, err := s.images.repo.GetModThumbnail(modID)
+	switch {
+	case err == nil && (thumbnail.SourceURL == "" || thumbnail.SourceURL == source):
+	case err != nil && !errors.Is(err, repository.ErrNotFound):
+		return nil, fmt.Errorf("failed to get thumbnail: %w", err)
+	case source == "":
+		return nil, ErrImageNotFound
+	default:
+		thumbnail, err = s.ensureThumbnail(ctx, modID, source)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.imageVariant(thumbnail.ID, size)
+}
+
+// ModGallery lists the gallery of a mod in display order.
+func (s *ModService) ModGallery(modID string) ([]restmodel.ModImage, error) {
+	if s.images == nil {
+		return nil, ErrImagesDisabled
+	}
+	if _, err := s.lookupMod(modID); err != nil {
+		return nil, err
+	}
+	images, err := s.images.repo.ListModGallery(modID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gallery: %w", err)
+	}
+	gallery := make([]restmodel.ModImage, len(images))
+	for i, image := range images {
+		gallery[i] = toRestImage(&image)
+	}
+	return gallery, nil
+}
+
+// ModImage returns a variant of an image of a mod, its thumbnail or one in its gallery.
+func (s *ModService) ModImage(modID, imageID string, size restmodel.ImageSize) (*model.ModImageVariant, error) {
+	if s.images == nil {
+		return nil, ErrImagesDisabled
+	}
+	image, err := s.images.repo.GetModImage(modID, imageID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+	return s.imageVariant(image.ID, size)
+}
+
+func (s *ModService) imageVariant(imageID string, size restmodel.ImageSize) (*model.ModImageVariant, error) {
+	variant, err := s.images.repo.GetModImageVariant(imageID, string(size))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image variant: %w", err)
+	}
+	return variant, nil
+}
+
+// StoreModThumbnail replaces the thumbnail of a mod. A thumbnail fetched from a URL also becomes the thumbnail URL of
+// the mod, while an uploaded one is served regardless of it until the URL changes.
+func (s *ModService) StoreModThumbnail(ctx context.Context, modID string, src ImageSource) (*model.ModImage, error) {
+	if s.images == nil {
+		return nil, ErrImagesDisabled
+	}
+	if _, err := s.lookupMod(modID); err != nil {
+		return nil, err
+	}
+	data, err := s.images.load(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	image, err := s.images.store(&model.ModImage{ModID: modID, Kind: model.ImageKindThumbnail, SourceURL: src.URL}, data)
+	if err != nil {
+		return nil, err
+	}
+	if src.URL != "" {
+		if err := s.repo.UpdateMod(modID, &model.ModDetails{ThumbnailURI: &src.URL}); err != nil {
+			return nil, fmt.Errorf("failed to update thumbnail URL: %w", err)
+		}
+	}
+	return image, nil
+}
+
+// AddModGalleryImage appends an image to the gallery of a mod.
+func (s *ModService) AddModGalleryImage(ctx context.Context, modID string, src ImageSource, caption string) (*model.ModImage, error) {
+	if s.images == nil {
+		return nil, ErrImagesDisabled
+	}
+	if _, err := s.lookupMod(modID); err != nil {
+		return nil, err
+	}
+	if err := restmodel.ValidateImageCaption(caption); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+	gallery, err := s.images.repo.ListModGallery(modID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gallery: %w", err)
+	}
+	if len(gallery) >= restmodel.MaxGalleryImages {
+		return nil, ErrGalleryFull
+	}
+	position := 0
+	if len(gallery) > 0 {
+		position = gallery[len(gallery)-1].Position + 1
+	}
+	data, err := s.images.load(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	return s.images.store(&model.ModImage{ModID: modID, Kind: model.ImageKindGallery, Position: position, Caption: caption, SourceURL: src.URL}, data)
+}
+
+// RemoveModImage deletes an image of a mod, its thumbnail or one in its gallery.
+func (s *ModService) RemoveModImage(modID, imageID string) error {
+	if s.images == nil {
+		return ErrImagesDisabled
+	}
+	err := s.images.repo.DeleteModImage(modID, imageID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrImageNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+	return nil
+}
+
+// dropUploadedThumbnail deletes an uploaded thumbnail of the mod, so a new thumbnail URL takes its place.
+func (s *ModService) dropUploadedThumbnail(modID string) error {
+	if s.images == nil {
+		return nil
+	}
+	thumbnail, err := s.images.repo.GetModThumbnail(modID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && thumbnail.SourceURL != "") {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get thumbnail: %w", err)
+	}
+	return s.RemoveModImage(modID, thumbnail.ID)
+}
+
+// ensureThumbnail fetches the thumbnail of a mod from source and stores it. Concurrent requests for the same
+// thumbnail wait for a single fetch.
+func (s *ModService) ensureThumbnail(ctx context.Context, modID, source string) (*model.ModImage, error) {
+	m := s.images
+	key := modID + "\x00" + source
+	m.mu.Lock()
+	fetch, ok := m.fetching[key]
+	if !ok {
+		if failedAt, ok := m.failed[source]; ok && time.Since(failedAt) < thumbnailRetryAfter {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("%w: %s failed recently", ErrImageFetchFailed, source)
+		}
+		fetch = &thumbnailFetch{done: make(chan struct{})}
+		m.fetching[key] = fetch
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), imageFetchTimeout)
+			defer cancel()
+			data, err := m.fetch(ctx, source)
+			if err == nil {
+				fetch.image, err = m.store(&model.ModImage{ModID: modID, Kind: model.ImageKindThumbnail, SourceURL: source}, data)
+			}
+			if err != nil {
+				slog.Warn("Failed to fetch mod thumbnail", "mod_id", modID, "url", source, "error", err)
+				fetch.err = fmt.Errorf("%w: %w", ErrImageFetchFailed, err)
+			} else {
+				slog.Info("Fetched mod thumbnail", "mod_id", modID, "url", source)
+			}
+			m.mu.Lock()
+			delete(m.fetching, key)
+			if err != nil {
+				m.failed[source] = time.Now()
+			} else {
+				delete(m.failed, source)
+			}
+			m.mu.Unlock()
+			close(fetch.done)
+		}()
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-fetch.done:
+		return fetch.image, fetch.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *imageStore) load(ctx context.Context, src ImageSource) ([]byte, error) {
+	if src.URL == "" {
+		if len(src.Data) > MaxImageSize {
+			return nil, ErrImageTooLarge
+		}
+		return src.Data, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, imageFetchTimeout)
+	defer cancel()
+	data, err := m.fetch(ctx, src.URL)
+	if errors.Is(err, ErrImageTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImageFetchFailed, err)
+	}
+	return data, nil
+}
+
+func (m *imageStore) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	if len(data) > MaxImageSize {
+		return nil, ErrImageTooLarge
+	}
+	return data, nil
+}
+
+// store decodes data, renders its variants and saves them as image.
+func (m *imageStore) store(image *model.ModImage, data []byte) (*model.ModImage, error) {
+	src, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := renderImageVariants(src)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	image.ID = uuid.New().String()
+	image.SHA256 = hex.EncodeToString(sum[:])
+	image.Variants = variants
+	image.CreatedAt = time.Now()
+	for _, variant := range variants {
+		if variant.Size == string(restmodel.ImageSizeDetail) {
+			image.Width, image.Height = variant.Width, variant.Height
+		}
+	}
+	if err := m.repo.SaveModImage(image); err != nil {
+		return nil, fmt.Errorf("failed to save image: %w", err)
+	}
+	return image, nil
+}
+
+func decodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrInvalidImage, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+	return img, nil
+}
+
+func renderImageVariants(src image.Image) ([]model.ModImageVariant, error) {
+	variants := make([]model.ModImageVariant, 0, len(imageVariants))
+	// Photos compress far better as JPEG; anything with transparency stays PNG in every size
+	opaque, ok := src.(interface{ Opaque() bool })
+	photo := ok && opaque.Opaque()
+	for _, spec := range imageVariants {
+		srcRect := src.Bounds()
+		width, height := spec.width, spec.height
+		if spec.crop {
+			srcRect = centerCrop(srcRect, spec.width, spec.height)
+		} else {
+			// Fit within the size, never scaling up
+			scale := min(1, float64(spec.width)/float64(srcRect.Dx()), float64(spec.height)/float64(srcRect.Dy()))
+			width = max(1, int(float64(srcRect.Dx())*scale+0.5))
+			height = max(1, int(float64(srcRect.Dy())*scale+0.5))
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Src, nil)
+
+		var buf bytes.Buffer
+		contentType := "image/png"
+		var err error
+		if photo {
+			contentType = "image/jpeg"
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			err = png.Encode(&buf, dst)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s image: %w", spec.size, err)
+		}
+		variants = append(variants, model.ModImageVariant{
+			Size:        string(spec.size),
+			ContentType: contentType,
+			Width:       width,
+			Height:      height,
+			Data:        buf.Bytes(),
+		})
+	}
+	return variants, nil
+}
+
+// centerCrop returns the largest rectangle at the center of bounds with the aspect ratio of width to height.
+func centerCrop(bounds image.Rectangle, width, height int) image.Rectangle {
+	w, h := bounds.Dx(), bounds.Dy()
+	if w*height > h*width {
+		cropped := h * width / height
+		x := bounds.Min.X + (w-cropped)/2
+		return image.Rect(x, bounds.Min.Y, x+cropped, bounds.Max.Y)
+	}
+	cropped := w * height / width
+	y := bounds.Min.Y + (h-cropped)/2
+	return image.Rect(bounds.Min.X, y, bounds.Max.X, y+cropped)
+}
+
+func toRestImage(image *model.ModImage) restmodel.ModImage {
+	return restmodel.ModImage{
+		ID:        image.ID,
+		Caption:   image.Caption,
+		Width:     image.Width,
+		Height:    image.Height,
+		CreatedAt: image.CreatedAt,
+	}
+}
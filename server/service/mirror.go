@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ikafly144/au_mod_installer/pkg/modmgr"
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+const (
+	// mirrorFetchTimeout bounds one fetch from upstream, which is shared by every request waiting for the file
+	mirrorFetchTimeout = 10 * time.Minute
+	// mirrorMaxUnsizedFile bounds the files published without a size
+	mirrorMaxUnsizedFile = 512 << 20 // 512 MiB
+)
+
+var (
+	ErrMirrorDisabled    = errors.New("file mirror is disabled")
+	ErrFileNotMirrorable = errors.New("file has no sha256 hash to mirror it by")
+)
+
+// WithFileMirror serves the files of published versions from dir, fetching each from its download URLs on first use.
+// As those URLs are set by publishers, client should be one from NewPublicHTTPClient.
+func WithFileMirror(dir string, client *http.Client) ModServiceOption {
+	return func(s *ModService) {
+		s.mirror = &fileMirror{
+			blobs:    modmgr.NewBlobStore(dir),
+			client:   client,
+			fetching: make(map[string]*mirrorFetch),
+		}
+	}
+}
+
+// fileMirror keeps verified copies of mod files in a blob store keyed by their sha256, like the client cache.
+type fileMirror struct {
+	blobs  *modmgr.BlobStore
+	client *http.Client
+
+	mu       sync.Mutex
+	fetching map[string]*mirrorFetch
+}
+
+type mirrorFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// MirrorEnabled reports whether the server mirrors the files of published versions.
+func (s *ModService) MirrorEnabled() bool {
+	return s.mirror != nil
+}
+
+// OpenMirroredFile returns the mirrored copy of a file, fetching and verifying it first if needed.
+func (s *ModService) OpenMirroredFile(ctx context.Context, fileID string) (*os.File, *model.ModVersionFile, error) {
+	if s.mirror == nil {
+		return nil, nil, ErrMirrorDisabled
+	}
+	file, err := s.repo.GetModVersionFile(fileID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	sum := file.Hashes["sha256"]
+	if _, err := s.mirror.blobs.Path(sum); err != nil {
+		return nil, nil, ErrFileNotMirrorable
+	}
+	if err := s.mirror.ensure(ctx, file); err != nil {
+		return nil, nil, err
+	}
+	f, err := s.mirror.blobs.Open(sum)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open mirrored file: %w", err)
+	}
+	return f, file, nil
+}
+
+// WithMirrorURLs returns a copy of version whose mirrorable files also list their mirror URL, after the upstream URLs
+// so clients only fall back to it.
+func (s *ModService) WithMirrorURLs(version *model.ModVersionDetails, fileURL func(fileID string) string) *model.ModVersionDetails {
+	if s.mirror == nil || version == nil {
+		return version
+	}
+	mirrored := *version
+	mirrored.Files = slices.Clone(version.Files)
+	for i, file := range mirrored.Files {
+		if _, err := s.mirror.blobs.Path(file.Hashes["sha256"]); err != nil {
+			continue
+		}
+		mirrored.Files[i].Downloads = slices.Concat(file.Downloads, model.StringArray{fileURL(file.ID)})
+	}
+	return &mirrored
+}
+
+// ensure fetches the file into the store unless it is there already. Concurrent requests for the same content wait
+// for a single fetch.
+func (m *fileMirror) ensure(ctx context.Context, file *model.ModVersionFile) error {
+	sum := file.Hashes["sha256"]
+	m.mu.Lock()
+	// Blobs are only put into the store once verified, so one that is present can be served
+	fetch, ok := m.fetching[sum]
+	if !ok {
+		if m.blobs.Has(sum) {
+			m.mu.Unlock()
+			return nil
+		}
+		fetch = &mirrorFetch{done: make(chan struct{})}
+		m.fetching[sum] = fetch
+		go func() {
+			fetch.err = m.fetch(file)
+			m.mu.Lock()
+			delete(m.fetching, sum)
+			m.mu.Unlock()
+			close(fetch.done)
+		}()
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-fetch.done:
+		return fetch.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *fileMirror) fetch(file *model.ModVersionFile) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorFetchTimeout)
+	defer cancel()
+	var errs []error
+	for _, download := range file.Downloads {
+		if u, err := url.Parse(download); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		err := m.fetchFrom(ctx, download, file)
+		if err == nil {
+			slog.Info("Mirrored mod file", "fileId", file.ID, "url", download)
+			return nil
+		}
+		slog.Warn("Failed to mirror mod file", "fileId", file.ID, "url", download, "error", err)
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("failed to mirror file %s: no http download URL", file.ID)
+	}
+	return fmt.Errorf("failed to mirror file %s: %w", file.ID, errors.Join(errs...))
+}
+
+func (m *fileMirror) fetchFrom(ctx context.Context, download string, file *model.ModVersionFile) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, download, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(m.blobs.Dir(), 0o755); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(m.blobs.Dir(), "fetch-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	limit := file.Size
+	if limit <= 0 {
+		limit = mirrorMaxUnsizedFile
+	}
+	hasher := modmgr.NewBlobHasher(file.Hashes)
+	written, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(resp.Body, limit+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	if written > limit || (file.Size > 0 && written != file.Size) {
+		return fmt.Errorf("downloaded %d bytes, expected %d", written, file.Size)
+	}
+
+	sum := file.Hashes["sha256"]
+	if computed, err := hasher.Sum(); err != nil {
+		return fmt.Errorf("downloaded file does not match its hashes: %w", err)
+	} else if computed != sum {
+		return fmt.Errorf("downloaded file does not match its hashes: sha256 is %s", computed)
+	}
+	return m.blobs.Put(tmp.Name(), sum)
+}
@@ -1,31 +1,53 @@
 package service
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
 	restcommon "github.com/ikafly144/au_mod_installer/common/rest"
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
 	"github.com/ikafly144/au_mod_installer/server/model"
 	"github.com/ikafly144/au_mod_installer/server/repository"
 )
 
 type ModService struct {
 	repo      repository.ModRepository
+	tokens    repository.TokenRepository
 	shareGame *shareGameManager
+	mirror    *fileMirror
+	images    *imageStore
 }
 
-func NewModService(repo repository.ModRepository) *ModService {
-	return &ModService{
+func NewModService(repo repository.ModRepository, opts ...ModServiceOption) *ModService {
+	s := &ModService{
 		repo:      repo,
 		shareGame: newShareGameManager(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *ModService) GetModIds(after string, limit int) ([]string, string, error) {
+	return s.repo.GetModIds(after, clampModListLimit(limit))
+}
+
+// SearchMods lists the IDs of mods matching the query, one page at a time.
+func (s *ModService) SearchMods(query restmodel.ModSearchQuery, after string, limit int) ([]string, string, error) {
+	return s.repo.SearchMods(query, after, clampModListLimit(limit))
+}
+
+func clampModListLimit(limit int) int {
 	switch {
 	case limit <= 0:
-		limit = 20
+		return 20
 	case limit > 100:
-		limit = 100
+		return 100
 	}
-	return s.repo.GetModIds(after, limit)
+	return limit
 }
 
 func (s *ModService) GetModDetails(modID string) (*model.ModDetails, error) {
@@ -40,6 +62,97 @@ func (s *ModService) GetModVersionDetails(modID, versionID string) (*model.ModVe
 	return s.repo.GetModVersionDetails(modID, versionID)
 }
 
+// GetModDetailsBatch returns the details of the mods in the order of modIDs, with nil for mods that do not exist.
+func (s *ModService) GetModDetailsBatch(modIDs []string) ([]*model.ModDetails, error) {
+	mods, err := s.repo.GetModDetailsBatch(modIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mod details: %w", err)
+	}
+	byID := make(map[string]*model.ModDetails, len(mods))
+	for i := range mods {
+		byID[mods[i].ID] = &mods[i]
+	}
+	result := make([]*model.ModDetails, len(modIDs))
+	for i, modID := range modIDs {
+		result[i] = byID[modID]
+	}
+	return result, nil
+}
+
+// GetModVersionDetailsBatch returns the versions in the order of refs, with nil for versions that do not exist.
+// A ref without a version ID resolves to the latest version of the mod.
+func (s *ModService) GetModVersionDetailsBatch(refs []restmodel.ModVersionRef) ([]*model.ModVersionDetails, error) {
+	var latestOf []string
+	for _, ref := range refs {
+		if ref.VersionID == "" && !slices.Contains(latestOf, ref.ModID) {
+			latestOf = append(latestOf, ref.ModID)
+		}
+	}
+	latest := make(map[string]string)
+	if len(latestOf) > 0 {
+		mods, err := s.repo.GetModDetailsBatch(latestOf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get mod details: %w", err)
+		}
+		for _, mod := range mods {
+			if mod.LatestVersionID != nil {
+				latest[mod.ID] = *mod.LatestVersionID
+			}
+		}
+	}
+
+	keys := make([]repository.ModVersionKey, len(refs))
+	for i, ref := range refs {
+		keys[i] = repository.ModVersionKey{ModID: ref.ModID, VersionID: ref.VersionID}
+		if ref.VersionID == "" {
+			keys[i].VersionID = latest[ref.ModID]
+		}
+	}
+	versions, err := s.repo.GetModVersionDetailsBatch(slices.DeleteFunc(slices.Clone(keys), func(key repository.ModVersionKey) bool {
+		return key.VersionID == ""
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mod versions: %w", err)
+	}
+
+	result := make([]*model.ModVersionDetails, len(refs))
+	for i, key := range keys {
+		if key.VersionID == "" {
+			continue
+		}
+		if j := slices.IndexFunc(versions, key.Matches); j >= 0 {
+			result[i] = &versions[j]
+		}
+	}
+	return result, nil
+}
+
+// CheckModUpdates returns the latest version of every installed mod whose latest version differs from the installed one,
+// and the installed mods that do not exist or have no versions.
+func (s *ModService) CheckModUpdates(installed map[string]string) (map[string]*model.ModVersionDetails, []string, error) {
+	modIDs := slices.Sorted(maps.Keys(installed))
+	refs := make([]restmodel.ModVersionRef, len(modIDs))
+	for i, modID := range modIDs {
+		refs[i] = restmodel.ModVersionRef{ModID: modID}
+	}
+	latest, err := s.GetModVersionDetailsBatch(refs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates := make(map[string]*model.ModVersionDetails)
+	var missing []string
+	for i, modID := range modIDs {
+		switch {
+		case latest[i] == nil:
+			missing = append(missing, modID)
+		case latest[i].VersionID != installed[modID]:
+			updates[modID] = latest[i]
+		}
+	}
+	return updates, missing, nil
+}
+
 func (s *ModService) CreateSharedGame(ip string, req restcommon.ShareGameRequest) (*restcommon.ShareGameResponse, error) {
 	return s.shareGame.create(ip, req)
 }
@@ -52,15 +165,22 @@ func (s *ModService) UpdateSharedGameExpiration(sessionID, hostKey string) (*res
 	return s.shareGame.updateExpiration(sessionID, hostKey)
 }
 
-func (s *ModService) GetJoinGameDownload(sessionID string) (*restcommon.JoinGameDownloadResponse, error) {
-	return s.shareGame.getDownload(sessionID)
+// GetJoinGameDownload returns the room of a shared game and a reference to its aupack. With inline, the pack itself
+// is included for clients that do not fetch it through GetSharedAupack.
+func (s *ModService) GetJoinGameDownload(sessionID string, inline bool) (*restcommon.JoinGameDownloadResponse, error) {
+	return s.shareGame.getDownload(sessionID, inline)
+}
+
+// GetSharedAupack returns a shared aupack by the hex sha256 of its content.
+func (s *ModService) GetSharedAupack(hash string) ([]byte, error) {
+	return s.shareGame.getAupack(hash)
+}
+
+// CountSharedGames counts the shared games that have not expired.
+func (s *ModService) CountSharedGames() (int, error) {
+	return s.shareGame.store.CountSharedGames(time.Now())
 }
 
 func (s *ModService) GetJoinGameMeta(sessionID string) (*restcommon.RoomInfo, error) {
-	session, err := s.shareGame.getSessionMeta(sessionID)
-	if err != nil {
-		return nil, err
-	}
-	room := session.Room
-	return &room, nil
+	return s.shareGame.getRoom(sessionID)
 }
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.215.14":        true,
+		"2606:2800:21f::1":     true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"fd00::1":              false,
+		"fe80::1":              false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.215.14": true,
+	} {
+		assert.Equal(t, want, isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestNewPublicHTTPClient_RefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewPublicHTTPClient().Get(server.URL)
+	assert.ErrorIs(t, err, ErrNonPublicAddress)
+}
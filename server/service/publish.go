@@ -0,0 +1,488 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+)
+
+// apiTokenPrefix starts every API token, so leaked tokens are easy to recognize.
+const apiTokenPrefix = "mus_"
+
+var (
+	ErrPublishDisabled = errors.New("publishing is not enabled")
+	ErrInvalidAPIToken = errors.New("invalid API token")
+	ErrTokenScope      = errors.New("API token does not cover the mod")
+	ErrModNotFound     = errors.New("mod not found")
+	ErrVersionNotFound = errors.New("version not found")
+	ErrFileNotFound    = errors.New("file not found")
+	ErrAlreadyExists   = errors.New("already exists")
+	ErrVersionYanked   = errors.New("version is yanked")
+)
+
+type ModServiceOption func(*ModService)
+
+// WithTokenRepository enables the write API, authenticating requests with the API tokens in tokens.
+func WithTokenRepository(tokens repository.TokenRepository) ModServiceOption {
+	return func(s *ModService) {
+		s.tokens = tokens
+	}
+}
+
+// WithSharedGameRepository stores shared games in store instead of process memory, e.g. to share them between replicas.
+func WithSharedGameRepository(store repository.SharedGameRepository) ModServiceOption {
+	return func(s *ModService) {
+		s.shareGame.store = store
+	}
+}
+
+// WithAupackRepository stores the aupacks of shared games in packs instead of process memory.
+func WithAupackRepository(packs repository.AupackRepository) ModServiceOption {
+	return func(s *ModService) {
+		s.shareGame.packs = packs
+	}
+}
+
+// Publisher is the authenticated caller of the write API.
+type Publisher struct {
+	Token    *model.APIToken
+	RemoteIP string
+}
+
+// NewAPIToken creates a token allowed to write the mods matching modIDs. The returned secret is shown once;
+// only its hash is stored. Malformed patterns fail with path.ErrBadPattern, as they would never match.
+func NewAPIToken(name string, modIDs []string) (string, *model.APIToken, error) {
+	for _, pattern := range modIDs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return "", nil, fmt.Errorf("invalid mod pattern %q: %w", pattern, err)
+		}
+	}
+	random, err := randomURLToken(32)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate API token: %w", err)
+	}
+	secret := apiTokenPrefix + random
+	return secret, &model.APIToken{
+		ID:        uuid.New().String(),
+		Name:      name,
+		TokenHash: HashAPIToken(secret),
+		ModIDs:    modIDs,
+	}, nil
+}
+
+func HashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate returns the unrevoked token with the given secret.
+func (s *ModService) Authenticate(secret string) (*model.APIToken, error) {
+	if s.tokens == nil {
+		return nil, ErrPublishDisabled
+	}
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+	token, err := s.tokens.GetAPITokenByHash(HashAPIToken(secret))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API token: %w", err)
+	}
+	if token.RevokedAt != nil {
+		return nil, ErrInvalidAPIToken
+	}
+	if err := s.tokens.TouchAPIToken(token.ID, time.Now()); err != nil {
+		slog.Warn("Failed to record API token use", "token_id", token.ID, "error", err)
+	}
+	return token, nil
+}
+
+func (s *ModService) CreateMod(p Publisher, req restmodel.ModCreateRequest) (*model.ModDetails, error) {
+	if !p.Token.Allows(req.ID) {
+		return nil, ErrTokenScope
+	}
+	if _, err := s.lookupMod(req.ID); err == nil {
+		return nil, fmt.Errorf("mod %s %w", req.ID, ErrAlreadyExists)
+	} else if !errors.Is(err, ErrModNotFound) {
+		return nil, err
+	}
+
+	mod := &model.ModDetails{
+		ID:          req.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Author:      req.Author,
+		Type:        model.ModType(req.Type),
+		Tags:        req.Tags,
+		Links:       model.ModLinks(req.Links),
+		License:     req.License,
+		Languages:   req.Languages,
+	}
+	if mod.Type == "" {
+		mod.Type = model.ModTypeMod
+	}
+	if req.ThumbnailURL != "" {
+		mod.ThumbnailURI = &req.ThumbnailURL
+	}
+	if _, err := s.repo.CreateMod(mod); err != nil {
+		return nil, fmt.Errorf("failed to create mod: %w", err)
+	}
+	s.audit(p, "mod.create", req.ID, "", "", req)
+	return mod, nil
+}
+
+func (s *ModService) UpdateMod(p Publisher, modID string, req restmodel.ModUpdateRequest) (*model.ModDetails, error) {
+	if err := s.authorize(p, modID); err != nil {
+		return nil, err
+	}
+
+	// A column map, unlike a struct, also writes the empty values that remove a license, link or description
+	updates := make(map[string]any)
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Author != nil {
+		updates["author"] = *req.Author
+	}
+	if req.Type != nil {
+		updates["type"] = model.ModType(*req.Type)
+	}
+	if req.Tags != nil {
+		updates["tags"] = model.StringArray(*req.Tags)
+	}
+	if req.Links != nil {
+		updates["link_homepage"] = req.Links.Homepage
+		updates["link_source"] = req.Links.Source
+		updates["link_issues"] = req.Links.Issues
+		updates["link_discord"] = req.Links.Discord
+	}
+	if req.License != nil {
+		updates["license"] = *req.License
+	}
+	if req.Languages != nil {
+		updates["languages"] = model.StringArray(*req.Languages)
+	}
+	if req.ThumbnailURL != nil {
+		updates["thumbnail_uri"] = *req.ThumbnailURL
+		if err := s.dropUploadedThumbnail(modID); err != nil {
+			return nil, err
+		}
+	}
+	if req.LatestVersion != nil {
+		version, err := s.lookupVersion(modID, *req.LatestVersion)
+		if err != nil {
+			return nil, err
+		}
+		if version.Status == model.VersionStatusYanked {
+			return nil, fmt.Errorf("%w: %s cannot be the latest version", ErrVersionYanked, version.VersionID)
+		}
+		updates["latest_version_id"] = version.ID
+	}
+	if err := s.repo.UpdateModFields(modID, updates); err != nil {
+		return nil, fmt.Errorf("failed to update mod: %w", err)
+	}
+	s.audit(p, "mod.update", modID, "", "", req)
+	return s.lookupMod(modID)
+}
+
+func (s *ModService) DeleteMod(p Publisher, modID string) error {
+	if err := s.authorize(p, modID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteMod(modID); err != nil {
+		return fmt.Errorf("failed to delete mod: %w", err)
+	}
+	s.audit(p, "mod.delete", modID, "", "", nil)
+	return nil
+}
+
+func (s *ModService) CreateModVersion(p Publisher, modID string, req restmodel.ModVersionCreateRequest) (*model.ModVersionDetails, error) {
+	if err := s.authorize(p, modID); err != nil {
+		return nil, err
+	}
+	if _, err := s.lookupVersion(modID, req.VersionID); err == nil {
+		return nil, fmt.Errorf("version %s of mod %s %w", req.VersionID, modID, ErrAlreadyExists)
+	} else if !errors.Is(err, ErrVersionNotFound) {
+		return nil, err
+	}
+
+	version := &model.ModVersionDetails{
+		ID:           uuid.New().String(),
+		VersionID:    req.VersionID,
+		ModID:        modID,
+		GameVersions: req.GameVersions,
+		Dependencies: toServerDependencies(req.Dependencies),
+		Features:     req.Features,
+		Changelog:    req.Changelog,
+		ReleasedAt:   &req.ReleasedAt,
+	}
+	if req.ReleasedAt.IsZero() {
+		now := time.Now()
+		version.ReleasedAt = &now
+	}
+	for _, file := range req.Files {
+		version.Files = append(version.Files, toServerFile(modID, version.ID, file))
+	}
+	if err := s.ValidateDependencies(modID, version.VersionID, version.Dependencies); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.CreateModVersion(modID, version); err != nil {
+		return nil, fmt.Errorf("failed to create version: %w", err)
+	}
+	if req.SetLatest {
+		if err := s.repo.UpdateMod(modID, &model.ModDetails{LatestVersionID: &version.ID}); err != nil {
+			return nil, fmt.Errorf("failed to update latest version: %w", err)
+		}
+	}
+	s.audit(p, "version.create", modID, req.VersionID, "", req)
+	return version, nil
+}
+
+func (s *ModService) UpdateModVersion(p Publisher, modID, versionID string, req restmodel.ModVersionUpdateRequest) (*model.ModVersionDetails, error) {
+	if err := s.authorize(p, modID); err != nil {
+		return nil, err
+	}
+	version, err := s.lookupVersion(modID, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]any)
+	if req.GameVersions != nil {
+		updates["game_versions"] = model.StringArray(*req.GameVersions)
+	}
+	if req.Dependencies != nil {
+		dependencies := toServerDependencies(*req.Dependencies)
+		if err := s.ValidateDependencies(modID, version.VersionID, dependencies); err != nil {
+			return nil, err
+		}
+		updates["dependencies"] = dependencies
+	}
+	if req.Features != nil {
+		updates["features"] = model.Features(req.Features)
+	}
+	if req.Changelog != nil {
+		updates["changelog"] = *req.Changelog
+	}
+	if req.ReleasedAt != nil {
+		updates["released_at"] = *req.ReleasedAt
+	}
+	if err := s.repo.UpdateModVersionFields(modID, version.ID, updates); err != nil {
+		return nil, fmt.Errorf("failed to update version: %w", err)
+	}
+	s.audit(p, "version.update", modID, version.VersionID, "", req)
+	return s.lookupVersion(modID, version.ID)
+}
+
+func (s *ModService) DeleteModVersion(p Publisher, modID, versionID string) error {
+	if err := s.authorize(p, modID); err != nil {
+		return err
+	}
+	version, err := s.lookupVersion(modID, versionID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteModVersion(modID, version.ID); err != nil {
+		return fmt.Errorf("failed to delete version: %w", err)
+	}
+	s.audit(p, "version.delete", modID, version.VersionID, "", nil)
+	return nil
+}
+
+// SetModVersionStatus yanks, deprecates or restores a version. Yanked versions stay available by their exact ID, so
+// profiles pinning them keep working.
+func (s *ModService) SetModVersionStatus(p Publisher, modID, versionID string, req restmodel.ModVersionStatusRequest) (*model.ModVersionDetails, error) {
+	if err := s.authorize(p, modID); err != nil {
+		return nil, err
+	}
+	version, err := s.lookupVersion(modID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetModVersionStatus(modID, version.ID, model.VersionStatus(req.Status), req.Reason); err != nil {
+		return nil, fmt.Errorf("failed to set version status: %w", err)
+	}
+	s.audit(p, "version.status", modID, version.VersionID, "", req)
+	return s.lookupVersion(modID, version.ID)
+}
+
+func (s *ModService) CreateModVersionFile(p Publisher, modID, versionID string, file restmodel.ModVersionFile) (*model.ModVersionFile, error) {
+	if err := s.authorize(p, modID); err != nil {
+		return nil, err
+	}
+	version, err := s.lookupVersion(modID, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	created := toServerFile(modID, version.ID, file)
+	if err := s.repo.CreateModVersionFile(&created); err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	s.audit(p, "file.create", modID, version.VersionID, created.ID, file)
+	return &created, nil
+}
+
+func (s *ModService) DeleteModVersionFile(p Publisher, modID, versionID, fileID string) error {
+	if err := s.authorize(p, modID); err != nil {
+		return err
+	}
+	version, err := s.lookupVersion(modID, versionID)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(version.Files, func(f model.ModVersionFile) bool { return f.ID == fileID }) {
+		return ErrFileNotFound
+	}
+	if err := s.repo.DeleteModVersionFile(fileID); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	s.audit(p, "file.delete", modID, version.VersionID, fileID, nil)
+	return nil
+}
+
+func (s *ModService) SetModThumbnail(ctx context.Context, p Publisher, modID string, src ImageSource) (*restmodel.ModImage, error) {
+	if err := s.authorize(p, modID); err != nil {
+		return nil, err
+	}
+	image, err := s.StoreModThumbnail(ctx, modID, src)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(p, "image.thumbnail", modID, "", "", imageAuditDetail(image))
+	rest := toRestImage(image)
+	return &rest, nil
+}
+
+func (s *ModService) AddModImage(ctx context.Context, p Publisher, modID string, src ImageSource, caption string) (*restmodel.ModImage, error) {
+	if err := s.authorize(p, modID); err != nil {
+		return nil, err
+	}
+	image, err := s.AddModGalleryImage(ctx, modID, src, caption)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(p, "image.add", modID, "", "", imageAuditDetail(image))
+	rest := toRestImage(image)
+	return &rest, nil
+}
+
+func (s *ModService) DeleteModImage(p Publisher, modID, imageID string) error {
+	if err := s.authorize(p, modID); err != nil {
+		return err
+	}
+	if err := s.RemoveModImage(modID, imageID); err != nil {
+		return err
+	}
+	s.audit(p, "image.delete", modID, "", "", map[string]string{"image_id": imageID})
+	return nil
+}
+
+func imageAuditDetail(image *model.ModImage) map[string]string {
+	return map[string]string{"image_id": image.ID, "sha256": image.SHA256, "source_url": image.SourceURL}
+}
+
+// authorize checks that the token covers the mod and that the mod exists.
+func (s *ModService) authorize(p Publisher, modID string) error {
+	if !p.Token.Allows(modID) {
+		return ErrTokenScope
+	}
+	_, err := s.lookupMod(modID)
+	return err
+}
+
+func (s *ModService) lookupMod(modID string) (*model.ModDetails, error) {
+	mod, err := s.repo.GetModDetails(modID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrModNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mod: %w", err)
+	}
+	return mod, nil
+}
+
+func (s *ModService) lookupVersion(modID, versionID string) (*model.ModVersionDetails, error) {
+	version, err := s.repo.GetModVersionDetails(modID, versionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrVersionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+	return version, nil
+}
+
+// audit records a successful change. A failure to record it is logged, as the change has already been made.
+func (s *ModService) audit(p Publisher, action, modID, versionID, fileID string, detail any) {
+	entry := &model.AuditLogEntry{
+		TokenID:   p.Token.ID,
+		TokenName: p.Token.Name,
+		RemoteIP:  p.RemoteIP,
+		Action:    action,
+		ModID:     modID,
+		VersionID: versionID,
+		FileID:    fileID,
+	}
+	if detail != nil {
+		if b, err := json.Marshal(detail, json.Deterministic(true)); err == nil {
+			entry.Detail = string(b)
+		}
+	}
+	slog.Info("Write API change", "action", action, "mod_id", modID, "version_id", versionID, "file_id", fileID, "token", p.Token.Name, "ip", p.RemoteIP)
+	if err := s.tokens.CreateAuditLogEntry(entry); err != nil {
+		slog.Error("Failed to write audit log entry", "action", action, "mod_id", modID, "error", err)
+	}
+}
+
+func toServerFile(modID, versionID string, file restmodel.ModVersionFile) model.ModVersionFile {
+	f := model.ModVersionFile{
+		ID:             uuid.New().String(),
+		ModID:          &modID,
+		VersionID:      &versionID,
+		Filename:       file.Filename,
+		ContentType:    model.FileType(file.ContentType),
+		Size:           file.Size,
+		TargetPlatform: model.TargetPlatform(file.TargetPlatform),
+		Hashes:         file.Hashes,
+		Downloads:      file.Downloads,
+	}
+	if f.TargetPlatform == "" {
+		f.TargetPlatform = model.TargetPlatformAny
+	}
+	if file.ExtractPath != "" {
+		f.ExtractPath = &file.ExtractPath
+	}
+	return f
+}
+
+func toServerDependencies(deps []restmodel.ModVersionDependency) model.DependencyArray {
+	var converted model.DependencyArray
+	for _, dep := range deps {
+		depType := model.DependencyType(dep.DependencyType)
+		if depType == "" {
+			depType = model.DependencyTypeRequired
+		}
+		converted = append(converted, model.ModVersionDependency{ModID: dep.ModID, VersionID: dep.VersionID, DependencyType: depType})
+	}
+	return converted
+}
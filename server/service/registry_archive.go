@@ -0,0 +1,418 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/server/model"
+)
+
+// ArchivedMod is a mod with all of its versions, in the mods.json format the client reads in local mode.
+type ArchivedMod struct {
+	restmodel.ModDetails
+	ThumbnailURL string                        `json:"thumbnail_url,omitempty"`
+	Versions     []restmodel.ModVersionDetails `json:"versions"`
+}
+
+type RegistryChangeAction string
+
+const (
+	RegistryChangeCreate  RegistryChangeAction = "create"
+	RegistryChangeUpdate  RegistryChangeAction = "update"
+	RegistryChangeReplace RegistryChangeAction = "replace"
+)
+
+// RegistryChange is a mod or version an import creates or changes. VersionID is empty for changes to the mod itself.
+type RegistryChange struct {
+	Action    RegistryChangeAction
+	ModID     string
+	VersionID string
+	// Fields lists what differs from the registry, for updates and replacements.
+	Fields []string
+}
+
+func (c RegistryChange) String() string {
+	var b strings.Builder
+	b.WriteString(string(c.Action))
+	if c.VersionID == "" {
+		fmt.Fprintf(&b, " mod %s", c.ModID)
+	} else {
+		fmt.Fprintf(&b, " version %s of mod %s", c.VersionID, c.ModID)
+	}
+	if len(c.Fields) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(c.Fields, ", "))
+	}
+	return b.String()
+}
+
+// ExportRegistry returns the mods with the given IDs, or every mod when none are given, with all of their versions.
+func (s *ModService) ExportRegistry(modIDs []string) ([]ArchivedMod, error) {
+	if len(modIDs) == 0 {
+		var err error
+		if modIDs, err = s.allModIDs(); err != nil {
+			return nil, err
+		}
+	}
+	mods := make([]ArchivedMod, 0, len(modIDs))
+	for _, modID := range modIDs {
+		mod, err := s.lookupMod(modID)
+		if err != nil {
+			return nil, fmt.Errorf("mod %s: %w", modID, err)
+		}
+		archived := ArchivedMod{ModDetails: toRestMod(*mod), Versions: []restmodel.ModVersionDetails{}}
+		if mod.ThumbnailURI != nil {
+			archived.ThumbnailURL = *mod.ThumbnailURI
+		}
+		versionIDs, err := s.repo.GetModVersionIds(modID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions of %s: %w", modID, err)
+		}
+		for _, versionID := range versionIDs {
+			version, err := s.lookupVersion(modID, versionID)
+			if err != nil {
+				return nil, fmt.Errorf("version %s of mod %s: %w", versionID, modID, err)
+			}
+			archived.Versions = append(archived.Versions, toRestVersion(*version))
+		}
+		mods = append(mods, archived)
+	}
+	return mods, nil
+}
+
+func (s *ModService) allModIDs() ([]string, error) {
+	var ids []string
+	next := ""
+	for {
+		page, nextID, err := s.repo.GetModIds(next, 100)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list mods: %w", err)
+		}
+		ids = append(ids, page...)
+		if len(page) < 100 || nextID == "" {
+			return ids, nil
+		}
+		next = nextID
+	}
+}
+
+// ImportRegistry creates the mods and versions of an export that are missing from the registry and brings the ones
+// that differ in line with it, keeping the IDs, hashes and timestamps of the file. Versions that changed are replaced
+// as a whole; fields of a mod left empty in the file keep their value. Nothing is deleted. With dryRun, the changes
+// are only returned.
+func (s *ModService) ImportRegistry(mods []ArchivedMod, dryRun bool) ([]RegistryChange, error) {
+	if err := validateArchive(mods); err != nil {
+		return nil, err
+	}
+	var changes []RegistryChange
+	for _, mod := range mods {
+		modChanges, err := s.importMod(mod, dryRun)
+		if err != nil {
+			return changes, fmt.Errorf("failed to import mod %s: %w", mod.ID, err)
+		}
+		changes = append(changes, modChanges...)
+	}
+	return changes, nil
+}
+
+func (s *ModService) importMod(mod ArchivedMod, dryRun bool) ([]RegistryChange, error) {
+	var changes []RegistryChange
+	// Versions created by the import get their internal IDs up front, so a new mod is created with its latest version
+	keys := make(map[string]string, len(mod.Versions))
+	for _, version := range mod.Versions {
+		keys[version.VersionID] = uuid.New().String()
+	}
+	existing, err := s.lookupMod(mod.ID)
+	switch {
+	case errors.Is(err, ErrModNotFound):
+		changes = append(changes, RegistryChange{Action: RegistryChangeCreate, ModID: mod.ID})
+		if !dryRun {
+			created := &model.ModDetails{
+				ID:          mod.ID,
+				Name:        mod.Name,
+				Description: mod.Description,
+				Author:      mod.Author,
+				Type:        model.ModType(mod.Type),
+				Tags:        mod.Tags,
+				Links:       model.ModLinks(mod.Links),
+				License:     mod.License,
+				Languages:   mod.Languages,
+				CreatedAt:   mod.CreatedAt,
+				UpdatedAt:   mod.UpdatedAt,
+			}
+			if created.Type == "" {
+				created.Type = model.ModTypeMod
+			}
+			if mod.ThumbnailURL != "" {
+				created.ThumbnailURI = &mod.ThumbnailURL
+			}
+			if mod.LatestVersionID != "" {
+				latestKey := keys[mod.LatestVersionID]
+				created.LatestVersionID = &latestKey
+			}
+			if _, err := s.repo.CreateMod(created); err != nil {
+				return nil, err
+			}
+		}
+	case err != nil:
+		return nil, err
+	default:
+		update, fields := modUpdate(*existing, mod)
+		if len(fields) > 0 {
+			changes = append(changes, RegistryChange{Action: RegistryChangeUpdate, ModID: mod.ID, Fields: fields})
+			if !dryRun {
+				if err := s.repo.UpdateMod(mod.ID, &update); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, version := range mod.Versions {
+		key, change, err := s.importVersion(mod.ID, version, keys[version.VersionID], dryRun)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+		keys[version.VersionID] = key
+	}
+	if existing == nil {
+		return changes, nil
+	}
+
+	// Versions are replaced under their internal IDs, so the latest version only changes when the file says so
+	latest := mod.LatestVersionID
+	if latest == "" || latest == existing.LatestVersionExternal {
+		return changes, nil
+	}
+	changes = append(changes, RegistryChange{Action: RegistryChangeUpdate, ModID: mod.ID, Fields: []string{"latest_version"}})
+	if dryRun {
+		return changes, nil
+	}
+	latestKey, ok := keys[latest]
+	if !ok {
+		version, err := s.lookupVersion(mod.ID, latest)
+		if err != nil {
+			return nil, fmt.Errorf("latest version %s: %w", latest, err)
+		}
+		latestKey = version.ID
+	}
+	if err := s.repo.UpdateMod(mod.ID, &model.ModDetails{LatestVersionID: &latestKey}); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// importVersion returns the internal ID of the version and the change made to it, if any.
+func (s *ModService) importVersion(modID string, version restmodel.ModVersionDetails, key string, dryRun bool) (string, *RegistryChange, error) {
+	change := &RegistryChange{Action: RegistryChangeCreate, ModID: modID, VersionID: version.VersionID}
+	existing, err := s.lookupVersion(modID, version.VersionID)
+	switch {
+	case errors.Is(err, ErrVersionNotFound):
+	case err != nil:
+		return "", nil, err
+	default:
+		key = existing.ID
+		fields := versionDiff(toRestVersion(*existing), version)
+		if len(fields) == 0 {
+			return key, nil, nil
+		}
+		change.Action, change.Fields = RegistryChangeReplace, fields
+	}
+	if dryRun {
+		return key, change, nil
+	}
+
+	details := &model.ModVersionDetails{
+		ID:           key,
+		VersionID:    version.VersionID,
+		ModID:        modID,
+		GameVersions: version.GameVersions,
+		Dependencies: fromRestDependencies(version.Dependencies),
+		Features:     version.Features,
+		Status:       model.VersionStatus(version.Status),
+		StatusReason: version.StatusReason,
+		Changelog:    version.Changelog,
+		CreatedAt:    version.CreatedAt,
+		UpdatedAt:    version.UpdatedAt,
+	}
+	if !version.ReleasedAt.IsZero() {
+		details.ReleasedAt = &version.ReleasedAt
+	}
+	files := make([]model.ModVersionFile, 0, len(version.Files))
+	for _, file := range version.Files {
+		stored := toServerFile(modID, key, file)
+		if file.ID != "" {
+			stored.ID = file.ID
+		}
+		stored.CreatedAt = file.CreatedAt
+		files = append(files, stored)
+	}
+	if err := s.repo.ReplaceModVersion(modID, details, files); err != nil {
+		return "", nil, fmt.Errorf("failed to import version %s: %w", version.VersionID, err)
+	}
+	return key, change, nil
+}
+
+func validateArchive(mods []ArchivedMod) error {
+	seen := make(map[string]bool)
+	for _, mod := range mods {
+		if err := restmodel.ValidateModID(mod.ID); err != nil {
+			return err
+		}
+		if seen[mod.ID] {
+			return fmt.Errorf("mod %s appears more than once", mod.ID)
+		}
+		seen[mod.ID] = true
+		versions := make(map[string]bool)
+		for _, version := range mod.Versions {
+			switch {
+			case strings.TrimSpace(version.VersionID) == "":
+				return fmt.Errorf("mod %s: version_id is required", mod.ID)
+			case version.ModID != "" && version.ModID != mod.ID:
+				return fmt.Errorf("mod %s: version %s belongs to mod %s", mod.ID, version.VersionID, version.ModID)
+			case versions[version.VersionID]:
+				return fmt.Errorf("mod %s: version %s appears more than once", mod.ID, version.VersionID)
+			}
+			versions[version.VersionID] = true
+		}
+		if mod.LatestVersionID != "" && !versions[mod.LatestVersionID] {
+			return fmt.Errorf("mod %s: latest version %s is not in the file", mod.ID, mod.LatestVersionID)
+		}
+	}
+	return nil
+}
+
+// modUpdate returns the update bringing a mod in line with the file and the fields it changes.
+func modUpdate(existing model.ModDetails, mod ArchivedMod) (model.ModDetails, []string) {
+	var update model.ModDetails
+	var fields []string
+	if mod.Name != "" && mod.Name != existing.Name {
+		update.Name = mod.Name
+		fields = append(fields, "name")
+	}
+	if mod.Description != "" && mod.Description != existing.Description {
+		update.Description = mod.Description
+		fields = append(fields, "description")
+	}
+	if mod.Author != "" && mod.Author != existing.Author {
+		update.Author = mod.Author
+		fields = append(fields, "author")
+	}
+	if mod.Type != "" && model.ModType(mod.Type) != existing.Type {
+		update.Type = model.ModType(mod.Type)
+		fields = append(fields, "type")
+	}
+	if mod.ThumbnailURL != "" && (existing.ThumbnailURI == nil || *existing.ThumbnailURI != mod.ThumbnailURL) {
+		update.ThumbnailURI = &mod.ThumbnailURL
+		fields = append(fields, "thumbnail_url")
+	}
+	if len(mod.Tags) > 0 && !slices.Equal([]string(existing.Tags), mod.Tags) {
+		update.Tags = mod.Tags
+		fields = append(fields, "tags")
+	}
+	if mod.Links != (restmodel.ModLinks{}) && model.ModLinks(mod.Links) != existing.Links {
+		update.Links = model.ModLinks(mod.Links)
+		fields = append(fields, "links")
+	}
+	if mod.License != "" && mod.License != existing.License {
+		update.License = mod.License
+		fields = append(fields, "license")
+	}
+	if len(mod.Languages) > 0 && !slices.Equal([]string(existing.Languages), mod.Languages) {
+		update.Languages = mod.Languages
+		fields = append(fields, "languages")
+	}
+	return update, fields
+}
+
+// versionDiff lists the fields that differ between a stored version and the one in the file. UpdatedAt is not
+// compared, as every write to the registry moves it.
+func versionDiff(existing, version restmodel.ModVersionDetails) []string {
+	var fields []string
+	if !slices.Equal(existing.GameVersions, version.GameVersions) {
+		fields = append(fields, "game_versions")
+	}
+	if !slices.Equal(existing.Dependencies, version.Dependencies) {
+		fields = append(fields, "dependencies")
+	}
+	if len(existing.Features)+len(version.Features) > 0 && !reflect.DeepEqual(normalizeFeatures(existing.Features), normalizeFeatures(version.Features)) {
+		fields = append(fields, "features")
+	}
+	if existing.Status != version.Status || existing.StatusReason != version.StatusReason {
+		fields = append(fields, "status")
+	}
+	if existing.Changelog != version.Changelog {
+		fields = append(fields, "changelog")
+	}
+	if !existing.ReleasedAt.Equal(version.ReleasedAt) {
+		fields = append(fields, "released_at")
+	}
+	if !existing.CreatedAt.Equal(version.CreatedAt) {
+		fields = append(fields, "created_at")
+	}
+	if !slices.EqualFunc(existing.Files, version.Files, sameFile) {
+		fields = append(fields, "files")
+	}
+	return fields
+}
+
+func sameFile(a, b restmodel.ModVersionFile) bool {
+	return a.ID == b.ID &&
+		a.Filename == b.Filename &&
+		a.ContentType == b.ContentType &&
+		a.Size == b.Size &&
+		a.ExtractPath == b.ExtractPath &&
+		a.TargetPlatform == b.TargetPlatform &&
+		reflect.DeepEqual(a.Hashes, b.Hashes) &&
+		slices.Equal(a.Downloads, b.Downloads)
+}
+
+// normalizeFeatures treats a missing feature map like an empty one.
+func normalizeFeatures(features map[string]any) map[string]any {
+	if features == nil {
+		return map[string]any{}
+	}
+	return features
+}
+
+func toRestMod(m model.ModDetails) restmodel.ModDetails {
+	return restmodel.ModDetails{
+		ID:              m.ID,
+		Name:            m.Name,
+		Description:     m.Description,
+		Author:          m.Author,
+		Type:            restmodel.ModType(m.Type),
+		Tags:            m.Tags,
+		Links:           restmodel.ModLinks(m.Links),
+		License:         m.License,
+		Languages:       m.Languages,
+		LatestVersionID: m.LatestVersionExternal,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+	}
+}
+
+// fromRestDependencies keeps dependency types as they are, unlike toServerDependencies, so an import stores exactly
+// what was exported.
+func fromRestDependencies(deps []restmodel.ModVersionDependency) model.DependencyArray {
+	if deps == nil {
+		return nil
+	}
+	result := make(model.DependencyArray, len(deps))
+	for i, dep := range deps {
+		result[i] = model.ModVersionDependency{
+			ModID:          dep.ModID,
+			VersionID:      dep.VersionID,
+			DependencyType: model.DependencyType(dep.DependencyType),
+		}
+	}
+	return result
+}
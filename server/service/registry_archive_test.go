@@ -0,0 +1,171 @@
+package service
+
+import (
+	"encoding/json/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	restmodel "github.com/ikafly144/au_mod_installer/common/rest/model"
+	"github.com/ikafly144/au_mod_installer/server/model"
+	gormrepo "github.com/ikafly144/au_mod_installer/server/repository/gorm"
+)
+
+func newSQLiteService(t *testing.T) *ModService {
+	t.Helper()
+	db, err := gormrepo.Open("sqlite::memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	repo := gormrepo.NewGormRepository(db)
+	require.NoError(t, repo.Migrate())
+	return NewModService(repo)
+}
+
+func TestRegistryExportImport(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	archive := []ArchivedMod{{
+		ModDetails: restmodel.ModDetails{
+			ID:              "my-mod",
+			Name:            "My Mod",
+			Author:          "me",
+			Type:            restmodel.ModTypeMod,
+			Tags:            []string{"roles"},
+			Links:           restmodel.ModLinks{Source: "https://github.com/me/my-mod"},
+			License:         "GPL-3.0-only",
+			Languages:       []string{"en", "ja"},
+			LatestVersionID: "1.1.0",
+			CreatedAt:       created,
+			UpdatedAt:       created,
+		},
+		ThumbnailURL: "https://example.com/thumbnail.png",
+		Versions: []restmodel.ModVersionDetails{
+			{
+				VersionID:    "1.0.0",
+				ModID:        "my-mod",
+				GameVersions: []string{"2025.3.25"},
+				Status:       restmodel.VersionStatusYanked,
+				StatusReason: "broken",
+				CreatedAt:    created,
+				UpdatedAt:    created,
+			},
+			{
+				VersionID: "1.1.0",
+				ModID:     "my-mod",
+				Files: []restmodel.ModVersionFile{{
+					ID:             "file-1",
+					Filename:       "MyMod.dll",
+					ContentType:    restmodel.ContentTypePluginDll,
+					Size:           42,
+					TargetPlatform: restmodel.TargetPlatformAny,
+					Hashes:         map[string]string{"sha256": "abc"},
+					Downloads:      []string{"https://example.com/MyMod.dll"},
+					CreatedAt:      created,
+				}},
+				Dependencies: []restmodel.ModVersionDependency{{ModID: "lib", VersionID: "^1.0.0"}},
+				Features:     map[string]any{"direct_join": true},
+				Changelog:    "- Direct join",
+				ReleasedAt:   created,
+				CreatedAt:    created.Add(time.Hour),
+				UpdatedAt:    created.Add(time.Hour),
+			},
+		},
+	}}
+
+	srv := newSQLiteService(t)
+	changes, err := srv.ImportRegistry(archive, true)
+	require.NoError(t, err)
+	assert.Equal(t, []RegistryChange{
+		{Action: RegistryChangeCreate, ModID: "my-mod"},
+		{Action: RegistryChangeCreate, ModID: "my-mod", VersionID: "1.0.0"},
+		{Action: RegistryChangeCreate, ModID: "my-mod", VersionID: "1.1.0"},
+	}, changes)
+	_, err = srv.lookupMod("my-mod")
+	require.ErrorIs(t, err, ErrModNotFound, "a dry run writes nothing")
+
+	_, err = srv.ImportRegistry(archive, false)
+	require.NoError(t, err)
+	exported, err := srv.ExportRegistry(nil)
+	require.NoError(t, err)
+	want, err := json.Marshal(archive)
+	require.NoError(t, err)
+	got, err := json.Marshal(exported)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+
+	changes, err = srv.ImportRegistry(exported, false)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	archive[0].Name = "Renamed"
+	archive[0].License = "MIT"
+	archive[0].LatestVersionID = "1.0.0"
+	archive[0].Versions[1].Files[0].Hashes = map[string]string{"sha256": "def"}
+	changes, err = srv.ImportRegistry(archive, false)
+	require.NoError(t, err)
+	assert.Equal(t, []RegistryChange{
+		{Action: RegistryChangeUpdate, ModID: "my-mod", Fields: []string{"name", "license"}},
+		{Action: RegistryChangeReplace, ModID: "my-mod", VersionID: "1.1.0", Fields: []string{"files"}},
+		{Action: RegistryChangeUpdate, ModID: "my-mod", Fields: []string{"latest_version"}},
+	}, changes)
+	mod, err := srv.lookupMod("my-mod")
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", mod.Name)
+	assert.Equal(t, "MIT", mod.License)
+	assert.Equal(t, "1.0.0", mod.LatestVersionExternal)
+	version, err := srv.lookupVersion("my-mod", "1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, model.StringMap{"sha256": "def"}, version.Files[0].Hashes)
+	assert.True(t, version.CreatedAt.Equal(created.Add(time.Hour)))
+
+	_, err = srv.ImportRegistry([]ArchivedMod{{ModDetails: restmodel.ModDetails{ID: "bad", LatestVersionID: "2.0.0"}}}, true)
+	assert.ErrorContains(t, err, "latest version 2.0.0 is not in the file")
+}
+
+func TestRegistryImport_FailedReplaceKeepsVersion(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	file := func(id string) restmodel.ModVersionFile {
+		return restmodel.ModVersionFile{
+			ID:             id,
+			Filename:       "MyMod.dll",
+			ContentType:    restmodel.ContentTypePluginDll,
+			TargetPlatform: restmodel.TargetPlatformAny,
+			Hashes:         map[string]string{"sha256": "abc"},
+			Downloads:      []string{"https://example.com/MyMod.dll"},
+			CreatedAt:      created,
+		}
+	}
+	archive := []ArchivedMod{{
+		ModDetails: restmodel.ModDetails{ID: "my-mod", Name: "My Mod", Author: "me", Type: restmodel.ModTypeMod, LatestVersionID: "1.1.0"},
+		Versions: []restmodel.ModVersionDetails{
+			{VersionID: "1.0.0", Changelog: "- First release", Files: []restmodel.ModVersionFile{file("file-1")}, CreatedAt: created},
+			{VersionID: "1.1.0", Files: []restmodel.ModVersionFile{file("file-2")}, CreatedAt: created.Add(time.Hour)},
+		},
+	}}
+	srv := newSQLiteService(t)
+	_, err := srv.ImportRegistry(archive, false)
+	require.NoError(t, err)
+
+	// The file ID of the replacement is taken by another version, so its insert fails after the old version is deleted
+	archive[0].LatestVersionID = ""
+	archive[0].Versions = archive[0].Versions[:1]
+	archive[0].Versions[0].Changelog = "- Rewritten"
+	archive[0].Versions[0].Files = []restmodel.ModVersionFile{file("file-2")}
+	_, err = srv.ImportRegistry(archive, false)
+	require.ErrorContains(t, err, "failed to import version 1.0.0")
+
+	version, err := srv.lookupVersion("my-mod", "1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "- First release", version.Changelog)
+	require.Len(t, version.Files, 1)
+	assert.Equal(t, "file-1", version.Files[0].ID)
+	other, err := srv.lookupVersion("my-mod", "1.1.0")
+	require.NoError(t, err)
+	require.Len(t, other.Files, 1)
+	assert.Equal(t, "file-2", other.Files[0].ID)
+}
@@ -1,22 +1,40 @@
 package service
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
 	restcommon "github.com/ikafly144/au_mod_installer/common/rest"
+	"github.com/ikafly144/au_mod_installer/pkg/profile"
+	"github.com/ikafly144/au_mod_installer/server/metrics"
+	"github.com/ikafly144/au_mod_installer/server/model"
+	"github.com/ikafly144/au_mod_installer/server/repository"
+	"github.com/ikafly144/au_mod_installer/server/repository/memory"
 )
 
+// MaxAupackSize is the largest aupack accepted by /share_game.
+const MaxAupackSize = 16 << 20 // 16 MiB
+
 const (
 	shareGameTTL          = 1 * time.Hour
 	shareGameRateWindow   = 10 * time.Minute
 	shareGameMaxPerWindow = 10
+	// aupackGracePeriod keeps a pack that was just stored but is not referenced by a game yet
+	aupackGracePeriod = 10 * time.Minute
+	// expiredGameSweepInterval throttles the deletion of expired games, which are treated as missing until then
+	expiredGameSweepInterval = time.Minute
+	// aupackSweepInterval throttles the collection of unused packs, which may walk the whole store
+	aupackSweepInterval = 5 * time.Minute
 )
 
 var (
@@ -24,62 +42,65 @@ var (
 	ErrShareGameNotFound     = errors.New("shared game not found")
 	ErrShareGameUnauthorized = errors.New("invalid host key")
 	ErrShareGameExpired      = errors.New("shared game expired")
+	ErrAupackTooLarge        = fmt.Errorf("aupack is larger than %d bytes", MaxAupackSize)
+	ErrInvalidAupack         = errors.New("invalid aupack")
+	ErrAupackNotFound        = errors.New("aupack not found")
 )
 
-type sharedGameSession struct {
-	SessionID string
-	HostKey   string
-	IP        string
-	Aupack    []byte
-	Room      restcommon.RoomInfo
-	CreatedAt time.Time
-	ExpiresAt time.Time
-}
-
-type ipRateState struct {
-	WindowStart time.Time
-	Count       int
-}
+var (
+	sharedGamesCreated = metrics.NewCounter("mus_share_game_created_total", "Games shared through /share_game.")
+	shareGameRejected  = metrics.NewCounter("mus_share_game_rate_limited_total", "Shares rejected by the per-IP rate limit.")
+)
 
 type shareGameManager struct {
-	mu             sync.Mutex
-	sessions       map[string]*sharedGameSession
-	sessionByIP    map[string]string
-	rateByIP       map[string]*ipRateState
-	dedupeByIPRoom map[string]*sharedGameSession
+	store repository.SharedGameRepository
+	packs repository.AupackRepository
+
+	sweepMu     sync.Mutex
+	lastExpired time.Time
+	lastSweep   time.Time
 }
 
 func newShareGameManager() *shareGameManager {
 	return &shareGameManager{
-		sessions:       make(map[string]*sharedGameSession),
-		sessionByIP:    make(map[string]string),
-		rateByIP:       make(map[string]*ipRateState),
-		dedupeByIPRoom: make(map[string]*sharedGameSession),
+		store: memory.NewSharedGameRepository(),
+		packs: memory.NewAupackRepository(),
 	}
 }
 
 func (m *shareGameManager) create(ip string, req restcommon.ShareGameRequest) (*restcommon.ShareGameResponse, error) {
 	now := time.Now()
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.cleanupLocked(now)
+	m.cleanup(now)
 
-	if err := m.allowRateLocked(ip, now); err != nil {
-		return nil, err
+	count, err := m.store.CountSharedGame(ip, now, shareGameRateWindow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count shared games: %w", err)
+	}
+	if count > shareGameMaxPerWindow {
+		shareGameRejected.Inc()
+		return nil, ErrShareGameRateLimited
 	}
 
-	roomKey := dedupeRoomKey(ip, req.Aupack, req.Room)
-	if cached, ok := m.dedupeByIPRoom[roomKey]; ok && cached.ExpiresAt.After(now) {
-		return &restcommon.ShareGameResponse{
-			URL:       "/join_game?session_id=" + cached.SessionID,
-			SessionID: cached.SessionID,
-			HostKey:   cached.HostKey,
-			ExpiresAt: cached.ExpiresAt,
-		}, nil
+	if len(req.Aupack) > MaxAupackSize {
+		return nil, ErrAupackTooLarge
 	}
+	if _, _, err := profile.DecodeSharedArchive(bytes.NewReader(req.Aupack), int64(len(req.Aupack))); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAupack, err)
+	}
+	aupackSum := sha256.Sum256(req.Aupack)
+	aupackHash := hex.EncodeToString(aupackSum[:])
 
-	if existingID, ok := m.sessionByIP[ip]; ok && existingID != "" {
-		m.deleteSessionLocked(existingID)
+	roomKey := dedupeRoomKey(ip, aupackHash, req.Room)
+	cached, err := m.store.GetSharedGameByIP(ip)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get shared game: %w", err)
+	}
+	if cached != nil && cached.RoomKey == roomKey && cached.ExpiresAt.After(now) {
+		return shareGameResponse(cached), nil
+	}
+
+	if err := m.packs.PutAupack(aupackHash, req.Aupack, now); err != nil {
+		return nil, fmt.Errorf("failed to store aupack: %w", err)
 	}
 
 	sessionID, err := randomURLToken(24)
@@ -91,150 +112,180 @@ func (m *shareGameManager) create(ip string, req restcommon.ShareGameRequest) (*
 		return nil, err
 	}
 
-	s := &sharedGameSession{
-		SessionID: sessionID,
-		HostKey:   hostKey,
-		IP:        ip,
-		Aupack:    append([]byte(nil), req.Aupack...),
-		Room:      req.Room,
-		CreatedAt: now,
-		ExpiresAt: now.Add(shareGameTTL),
+	game := &model.SharedGame{
+		SessionID:      sessionID,
+		HostKey:        hostKey,
+		IP:             ip,
+		RoomKey:        roomKey,
+		AupackSHA256:   aupackHash,
+		AupackSize:     int64(len(req.Aupack)),
+		LobbyCode:      req.Room.LobbyCode,
+		ServerIP:       req.Room.ServerIP,
+		ServerPort:     req.Room.ServerPort,
+		MatchMakerIP:   req.Room.MatchMakerIp,
+		MatchMakerPort: req.Room.MatchMakerPort,
+		GameVersion:    req.Room.GameVersion,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(shareGameTTL),
 	}
-	m.sessions[sessionID] = s
-	m.sessionByIP[ip] = sessionID
-	m.dedupeByIPRoom[roomKey] = s
-
-	return &restcommon.ShareGameResponse{
-		URL:       "/join_game?session_id=" + sessionID,
-		SessionID: sessionID,
-		HostKey:   hostKey,
-		ExpiresAt: s.ExpiresAt,
-	}, nil
+	if err := m.store.ReplaceSharedGame(game); err != nil {
+		return nil, fmt.Errorf("failed to store shared game: %w", err)
+	}
+	sharedGamesCreated.Inc()
+	return shareGameResponse(game), nil
 }
 
-func (m *shareGameManager) getDownload(sessionID string) (*restcommon.JoinGameDownloadResponse, error) {
-	now := time.Now()
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.cleanupLocked(now)
-
-	s, ok := m.sessions[sessionID]
-	if !ok {
-		return nil, ErrShareGameNotFound
+func (m *shareGameManager) getDownload(sessionID string, inline bool) (*restcommon.JoinGameDownloadResponse, error) {
+	game, err := m.get(sessionID)
+	if err != nil {
+		return nil, err
 	}
-	if now.After(s.ExpiresAt) {
-		m.deleteSessionLocked(sessionID)
-		return nil, ErrShareGameExpired
+	rs := &restcommon.JoinGameDownloadResponse{
+		SessionID:    sessionID,
+		AupackSHA256: game.AupackSHA256,
+		AupackSize:   game.AupackSize,
+		Room:         sharedGameRoom(game),
+		ExpiresAt:    game.ExpiresAt,
+	}
+	if inline {
+		if rs.Aupack, err = m.getAupack(game.AupackSHA256); err != nil {
+			return nil, err
+		}
 	}
-	return &restcommon.JoinGameDownloadResponse{
-		SessionID: sessionID,
-		Aupack:    append([]byte(nil), s.Aupack...),
-		Room:      s.Room,
-		ExpiresAt: s.ExpiresAt,
-	}, nil
+	return rs, nil
 }
 
-func (m *shareGameManager) getSessionMeta(sessionID string) (*sharedGameSession, error) {
-	now := time.Now()
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.cleanupLocked(now)
-	s, ok := m.sessions[sessionID]
-	if !ok {
-		return nil, ErrShareGameNotFound
+func (m *shareGameManager) getAupack(hash string) ([]byte, error) {
+	if sum, err := hex.DecodeString(hash); err != nil || len(sum) != sha256.Size || hash != strings.ToLower(hash) {
+		return nil, ErrAupackNotFound
 	}
-	if now.After(s.ExpiresAt) {
-		m.deleteSessionLocked(sessionID)
-		return nil, ErrShareGameExpired
+	data, err := m.packs.GetAupack(hash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAupackNotFound
 	}
-	cp := *s
-	cp.Aupack = nil
-	return &cp, nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aupack: %w", err)
+	}
+	return data, nil
 }
 
-func (m *shareGameManager) delete(sessionID, hostKey string) error {
-	now := time.Now()
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.cleanupLocked(now)
+func (m *shareGameManager) getRoom(sessionID string) (*restcommon.RoomInfo, error) {
+	game, err := m.get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	room := sharedGameRoom(game)
+	return &room, nil
+}
 
-	s, ok := m.sessions[sessionID]
-	if !ok {
-		return ErrShareGameNotFound
+func (m *shareGameManager) delete(sessionID, hostKey string) error {
+	if _, err := m.authorize(sessionID, hostKey); err != nil {
+		return err
 	}
-	if s.HostKey != hostKey {
-		return ErrShareGameUnauthorized
+	if err := m.store.DeleteSharedGame(sessionID); err != nil {
+		return fmt.Errorf("failed to delete shared game: %w", err)
 	}
-	m.deleteSessionLocked(sessionID)
 	return nil
 }
 
 func (m *shareGameManager) updateExpiration(sessionID, hostKey string) (*restcommon.ShareGameResponse, error) {
-	now := time.Now()
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.cleanupLocked(now)
+	game, err := m.authorize(sessionID, hostKey)
+	if err != nil {
+		return nil, err
+	}
+	game.ExpiresAt = time.Now().Add(shareGameTTL)
+	if err := m.store.UpdateSharedGameExpiration(sessionID, game.ExpiresAt); errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrShareGameNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update shared game: %w", err)
+	}
+	return shareGameResponse(game), nil
+}
 
-	s, ok := m.sessions[sessionID]
-	if !ok {
+// get returns the session, deleting it when it has expired.
+func (m *shareGameManager) get(sessionID string) (*model.SharedGame, error) {
+	game, err := m.store.GetSharedGame(sessionID)
+	if errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrShareGameNotFound
 	}
-	if s.HostKey != hostKey {
-		return nil, ErrShareGameUnauthorized
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shared game: %w", err)
 	}
-
-	s.ExpiresAt = now.Add(shareGameTTL)
-
-	return &restcommon.ShareGameResponse{
-		URL:       "/join_game?session_id=" + sessionID,
-		SessionID: sessionID,
-		HostKey:   hostKey,
-		ExpiresAt: s.ExpiresAt,
-	}, nil
+	if time.Now().After(game.ExpiresAt) {
+		if err := m.store.DeleteSharedGame(sessionID); err != nil {
+			slog.Warn("Failed to delete expired shared game", "sessionId", sessionID, "error", err)
+		}
+		return nil, ErrShareGameExpired
+	}
+	return game, nil
 }
 
-func (m *shareGameManager) allowRateLocked(ip string, now time.Time) error {
-	state, ok := m.rateByIP[ip]
-	if !ok || now.Sub(state.WindowStart) >= shareGameRateWindow {
-		m.rateByIP[ip] = &ipRateState{
-			WindowStart: now,
-			Count:       1,
-		}
-		return nil
+// authorize returns the session if hostKey is its host key. Expired sessions are treated as missing.
+func (m *shareGameManager) authorize(sessionID, hostKey string) (*model.SharedGame, error) {
+	game, err := m.get(sessionID)
+	if errors.Is(err, ErrShareGameExpired) {
+		return nil, ErrShareGameNotFound
 	}
-	if state.Count >= shareGameMaxPerWindow {
-		return ErrShareGameRateLimited
+	if err != nil {
+		return nil, err
 	}
-	state.Count++
-	return nil
+	if subtle.ConstantTimeCompare([]byte(game.HostKey), []byte(hostKey)) != 1 {
+		return nil, ErrShareGameUnauthorized
+	}
+	return game, nil
 }
 
-func (m *shareGameManager) cleanupLocked(now time.Time) {
-	for id, s := range m.sessions {
-		if now.After(s.ExpiresAt) {
-			m.deleteSessionLocked(id)
-		}
+func (m *shareGameManager) cleanup(now time.Time) {
+	// A request already cleaning up is enough
+	if !m.sweepMu.TryLock() {
+		return
+	}
+	defer m.sweepMu.Unlock()
+	if now.Sub(m.lastExpired) < expiredGameSweepInterval {
+		return
+	}
+	m.lastExpired = now
+	if err := m.store.DeleteExpiredSharedGames(now, shareGameRateWindow); err != nil {
+		slog.Warn("Failed to delete expired shared games", "error", err)
+		return
 	}
-}
 
-func (m *shareGameManager) deleteSessionLocked(sessionID string) {
-	s, ok := m.sessions[sessionID]
-	if !ok {
+	if now.Sub(m.lastSweep) < aupackSweepInterval {
 		return
 	}
-	delete(m.sessions, sessionID)
-	if current, ok := m.sessionByIP[s.IP]; ok && current == sessionID {
-		delete(m.sessionByIP, s.IP)
+	m.lastSweep = now
+	inUse, err := m.store.ListSharedGameAupacks()
+	if err != nil {
+		slog.Warn("Failed to list shared aupacks", "error", err)
+		return
+	}
+	if err := m.packs.DeleteUnusedAupacks(inUse, now.Add(-aupackGracePeriod)); err != nil {
+		slog.Warn("Failed to delete unused aupacks", "error", err)
 	}
-	roomKey := dedupeRoomKey(s.IP, s.Aupack, s.Room)
-	if current, ok := m.dedupeByIPRoom[roomKey]; ok && current.SessionID == sessionID {
-		delete(m.dedupeByIPRoom, roomKey)
+}
+
+func shareGameResponse(game *model.SharedGame) *restcommon.ShareGameResponse {
+	return &restcommon.ShareGameResponse{
+		URL:       "/join_game?session_id=" + game.SessionID,
+		SessionID: game.SessionID,
+		HostKey:   game.HostKey,
+		ExpiresAt: game.ExpiresAt,
+	}
+}
+
+func sharedGameRoom(game *model.SharedGame) restcommon.RoomInfo {
+	return restcommon.RoomInfo{
+		LobbyCode:      game.LobbyCode,
+		ServerIP:       game.ServerIP,
+		ServerPort:     game.ServerPort,
+		MatchMakerIp:   game.MatchMakerIP,
+		MatchMakerPort: game.MatchMakerPort,
+		GameVersion:    game.GameVersion,
 	}
 }
 
-func dedupeRoomKey(ip string, aupack []byte, room restcommon.RoomInfo) string {
-	sum := sha256.Sum256(aupack)
-	return fmt.Sprintf("%s|%s|%s|%s|%d|%s", ip, hex.EncodeToString(sum[:]), room.LobbyCode, room.MatchMakerIp, room.MatchMakerPort, room.GameVersion)
+func dedupeRoomKey(ip, aupackHash string, room restcommon.RoomInfo) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%d|%s", ip, aupackHash, room.LobbyCode, room.MatchMakerIp, room.MatchMakerPort, room.GameVersion)
 }
 
 func randomURLToken(size int) (string, error) {
@@ -12,6 +12,7 @@ import (
 
 	restcommon "github.com/ikafly144/au_mod_installer/common/rest"
 	"github.com/ikafly144/au_mod_installer/common/versioning"
+	"github.com/ikafly144/au_mod_installer/server/metrics"
 )
 
 const (
@@ -22,6 +23,8 @@ const (
 
 var errVersionInfoNotModified = errors.New("version info not modified")
 
+var versionInfoLookups = metrics.NewCounter("mus_version_info_cache_total", "Version info lookups by how they were served.", "result")
+
 type VersionInfoProvider interface {
 	GetVersionInfo(ctx context.Context) (*restcommon.VersionInfo, error)
 }
@@ -72,6 +75,7 @@ func (s *VersionInfoService) GetVersionInfo(ctx context.Context) (*restcommon.Ve
 	if s.cached != nil && now.Sub(s.cachedAt) < s.ttl {
 		info := s.cached
 		s.mu.Unlock()
+		versionInfoLookups.Inc("hit")
 		return info, nil
 	}
 	etag := s.etag
@@ -86,6 +90,7 @@ func (s *VersionInfoService) GetVersionInfo(ctx context.Context) (*restcommon.Ve
 				return nil, fmt.Errorf("version info not modified but cache is empty")
 			}
 			s.cachedAt = now
+			versionInfoLookups.Inc("not_modified")
 			return s.cached, nil
 		}
 		return nil, err
@@ -96,6 +101,7 @@ func (s *VersionInfoService) GetVersionInfo(ctx context.Context) (*restcommon.Ve
 	s.cachedAt = now
 	s.etag = newETag
 	s.mu.Unlock()
+	versionInfoLookups.Inc("miss")
 	return info, nil
 }
 